@@ -33,6 +33,9 @@ func main() {
 
 	chat.DB = db
 	chat.StartMessageCleanup(db) // Start 24h TTL cleanup for ephemeral messages
+	if err := chat.StartFanout(dbURL); err != nil {
+		log.Printf("Warning: chat fanout disabled, DMs only reach users on this instance: %v", err)
+	}
 
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
@@ -45,31 +48,56 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to init store: %v", err)
 	}
+	if err := store.BackfillPowerScores(); err != nil {
+		log.Printf("Warning: power scores not backfilled, rankings may be stale: %v", err)
+	}
 
-	// 1. Initialize the Game Engine
-	gameInstance := chibiki.NewGame()
-	gameInstance.OnGameOver = func(winnerTeam int, players map[*chibiki.Player]bool, gameTime float64) {
+	// 1. Initialize the Game Engine, one room per 1v1 match
+	chibikiRooms := chibiki.NewMatchmaker()
+	chibikiRooms.OnGameOver = func(winnerTeam int, players map[*chibiki.Player]bool, gameTime float64, stats []chibiki.MatchStats) {
 		log.Printf("GAME OVER! Winner Team: %d (Duration: %.1fs)", winnerTeam, gameTime)
 
+		// Best effort: match history must never hold up rewards
+		match := data.ChibikiMatch{WinnerTeam: winnerTeam, Duration: gameTime}
+		for _, s := range stats {
+			match.Players = append(match.Players, data.ChibikiMatchPlayer(s))
+		}
+		if err := store.RecordChibikiMatch(match); err != nil {
+			log.Printf("Error recording chibiki match: %v", err)
+		}
+
 		// Anti-farming: reduce rewards for suspiciously short games
 		antiFarmMultiplier := 1.0
 		if gameTime < 60 {
 			antiFarmMultiplier = 0.5
 			log.Printf("[ANTI-FARM] Game was very short (%.1fs), reducing rewards by 50%%", gameTime)
 		}
+		vsBot := false
+		for p := range players {
+			vsBot = vsBot || p.IsBot
+		}
+		if vsBot {
+			antiFarmMultiplier *= chibiki.BotRewardMultiplier
+		}
 
 		for p := range players {
-			if p.UserID == "" || p.UserID == "guest" {
+			if p.IsBot {
 				continue
 			}
 
 			var trophyChange, coinChange, expChange int
 
-			if p.Team == winnerTeam {
+			if winnerTeam == chibiki.DrawTeam {
+				coinChange = int(float64(25) * antiFarmMultiplier)
+				expChange = int(float64(75) * antiFarmMultiplier)
+			} else if p.Team == winnerTeam {
 				trophyChange = int(float64(30) * antiFarmMultiplier)
 				coinChange = int(float64(50) * antiFarmMultiplier)
 				expChange = int(float64(150) * antiFarmMultiplier)
-				store.AwardMedals(p.UserID, "first_win")
+				if !vsBot {
+					store.IncrementMedalProgress(p.UserID, "first_win", 1)
+					store.IncrementMedalProgress(p.UserID, "ten_wins", 1)
+				}
 			} else {
 				trophyChange = int(float64(-15) * antiFarmMultiplier)
 				coinChange = int(float64(10) * antiFarmMultiplier)
@@ -77,47 +105,76 @@ func main() {
 			}
 
 			// USE THE NEW FUNCTION
-			err := store.ProcessGameResult(p.UserID, trophyChange, coinChange, expChange)
+			_, err := store.ProcessGameResult(p.UserID, trophyChange, coinChange, expChange, data.ReasonChibikiMatch)
 			if err != nil {
 				log.Printf("Error saving stats for %s: %v", p.UserID, err)
 			}
 		}
 	}
 
-	if err := gameInstance.LoadUnits("internal/data/units.json"); err != nil {
-		log.Printf("Warning: Could not load units.json: %v", err)
+	if err := chibikiRooms.LoadUnits("internal/data/units.json"); err != nil {
+		log.Printf("Warning: Could not load units.json, no cards will be playable: %v", err)
 	}
-	gameInstance.InitTowers()
-	go gameInstance.StartLoop()
+
+	chat.AreFriends = store.AreFriends
+	chat.FriendsOnlyDMs = os.Getenv("CHAT_FRIENDS_ONLY") == "true"
+	chat.CanDM = store.CanDM
 
 	presenceService := presence.NewService(db)
-	bobikGame := bobikshooter.NewGame(store)
+	presenceService.Subscribe(chat.MainHub.PushPresence)
+	bobikRooms := bobikshooter.NewRooms(store)
+	if err := bobikRooms.LoadCatalog("internal/data/bobik_shop.json"); err != nil {
+		log.Printf("Warning: Could not load bobik_shop.json, using the built-in buy menu: %v", err)
+	}
+	if err := bobikRooms.LoadMap("internal/data/bobik_map.json"); err != nil {
+		log.Printf("Warning: Could not load bobik_map.json, using the built-in map: %v", err)
+	}
 
 	partyGame := party.NewGame(store)
+	if err := partyGame.LoadPrompts("internal/data/prompts.json"); err != nil {
+		log.Printf("Warning: Could not load prompts.json, using the built-in prompts: %v", err)
+	}
+	if err := partyGame.LoadBannedWords("internal/data/banned_words.json"); err != nil {
+		log.Printf("Warning: Could not load banned_words.json, party answers go unfiltered: %v", err)
+	}
 	slotixGame := slotix.NewGame(store)
 	upsidedownGame := upsidedown.NewGame(store)
 
-	authService := auth.NewAuth(db)
+	authService := auth.NewAuth(db, store)
+	authService.StartSessionCleanup()
 	http.HandleFunc("/register", authService.RegisterHandler)
 	http.HandleFunc("/login", authService.LoginHandler)
 	http.HandleFunc("/logout", authService.LogoutHandler)
 	http.HandleFunc("/settings/language", authService.UpdateLanguageHandler)
 	http.HandleFunc("/friends/add", authService.AddFriendHandler)
 	http.HandleFunc("/friends/remove", authService.RemoveFriendHandler)
+	http.HandleFunc("/friends/accept", authService.AcceptFriendHandler)
+	http.HandleFunc("/friends/decline", authService.DeclineFriendHandler)
+	http.HandleFunc("/friends/requests", authService.FriendRequestsHandler)
 	http.HandleFunc("/presence/ping", presenceService.PingHandler)
+	http.HandleFunc("/ws/presence", presenceService.HandleWS)
 
-	http.HandleFunc("/ws", chibiki.NewWebsocketHandler(gameInstance))
-	http.HandleFunc("/ws/bobik", bobikGame.HandleWS)
+	http.HandleFunc("/ws", chibiki.NewWebsocketHandler(chibikiRooms, store))
+	http.HandleFunc("/deck/save", chibiki.NewDeckSaveHandler(chibikiRooms, store))
+	http.HandleFunc("/admin/chibiki/reload-units", chibiki.NewReloadUnitsHandler(chibikiRooms, os.Getenv("ADMIN_TOKEN")))
+	http.HandleFunc("/ws/bobik", bobikRooms.HandleWS)
+	http.HandleFunc("/bobik/modes", bobikshooter.ModesHandler)
+	http.HandleFunc("/bobik/stats", lobby.NewBobikStatsHandler(store))
 
 	http.HandleFunc("/ws/chat", chat.HandleWS)
 	http.HandleFunc("/chat/history", chat.HistoryHandler)
 	http.HandleFunc("/chat/delivered", chat.DeliveredHandler)
 	http.HandleFunc("/chat/seen", chat.SeenHandler)
+	http.HandleFunc("/chat/search", chat.SearchHandler)
 
 	// Lobby Pages
 	http.HandleFunc("/friends", lobby.NewFriendsHandler(store))
 	http.HandleFunc("/shop", lobby.NewShopHandler(store))
 	http.HandleFunc("/shop/buy", lobby.NewBuyHandler(store))
+	http.HandleFunc("/wallet", lobby.NewWalletHandler(store))
+	http.HandleFunc("/daily/claim", lobby.NewDailyClaimHandler(store))
+	http.HandleFunc("/account/export", lobby.NewExportHandler(store))
+	http.HandleFunc("/settings/privacy", lobby.NewPrivacySettingsHandler(store))
 	http.HandleFunc("/customize", lobby.NewCustomizeHandler(store))
 	http.HandleFunc("/customize/save", lobby.NewCustomizeSaveHandler(store))
 	http.HandleFunc("/bobik", lobby.NewBobikHandler(store))
@@ -125,6 +182,13 @@ func main() {
 
 	http.HandleFunc("/game", lobby.NewGameHandler(store))
 	http.HandleFunc("/", lobby.NewHandler(store))
+	http.HandleFunc("/lobby/status", lobby.NewStatusHandler(map[string]lobby.LiveGame{
+		"chibiki":    chibikiRooms,
+		"bobik":      bobikRooms,
+		"party":      partyGame,
+		"slotix":     slotixGame,
+		"upsidedown": upsidedownGame,
+	}))
 
 	http.HandleFunc("/party", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "web/templates/party.html")
@@ -138,6 +202,7 @@ func main() {
 		http.ServeFile(w, r, "web/templates/slotix.html")
 	})
 	http.HandleFunc("/ws/slotix", slotixGame.HandleWS)
+	http.HandleFunc("/slotix/verify", slotixGame.HandleVerify)
 
 	// The Upside Down - Stranger Things Survival
 	http.HandleFunc("/upsidedown", func(w http.ResponseWriter, r *http.Request) {
@@ -145,11 +210,14 @@ func main() {
 	})
 	http.HandleFunc("/ws/upsidedown", upsidedownGame.HandleWS)
 	http.HandleFunc("/upsidedown/shop", lobby.NewUpsideDownShopHandler(store))
+	http.HandleFunc("/upsidedown/meta", lobby.NewUpsideDownMetaHandler(store))
+	http.HandleFunc("/upsidedown/buy", lobby.NewUpsideDownBuyHandler(store))
 
 	http.HandleFunc("/express", lobby.NewExpressHandler(store))
 	http.HandleFunc("/fishing", lobby.NewFishingHandler(store))
 	http.HandleFunc("/warthunder", warthunder.NewHandler(store))
 	http.HandleFunc("/api/warthunder", warthunder.NewAPIHandler(store))
+	http.HandleFunc("/api/warthunder/replay", warthunder.NewReplayHandler(store))
 
 	fs := http.FileServer(http.Dir("./web/static"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
@@ -159,7 +227,7 @@ func main() {
 		port = "8080"
 	}
 	log.Println("Server starting on port " + port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(":"+port, authService.Middleware(http.DefaultServeMux)); err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
 }
@@ -186,8 +254,7 @@ func applySchema(db *sql.DB) error {
 			banner_color TEXT NOT NULL DEFAULT 'default',
 			
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-			UNIQUE (nickname, tag)
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 		);
 		`,
 		// Migrations for existing DBs
@@ -195,6 +262,62 @@ func applySchema(db *sql.DB) error {
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS banner_color TEXT NOT NULL DEFAULT 'default';`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS custom_avatar TEXT NOT NULL DEFAULT '';`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS upside_down_meta TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS power_score INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE INDEX IF NOT EXISTS idx_users_power_score ON users (power_score DESC);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS gems INTEGER NOT NULL DEFAULT 0;`,
+		// Case-folded nickname for lookups; "Alice#1" and "alice#1" are the same account.
+		// If old data has case-only duplicates on the same tag the unique index fails
+		// loudly here instead of letting logins pick one at random.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS nickname_lower TEXT NOT NULL DEFAULT '';`,
+		`UPDATE users SET nickname_lower = lower(nickname) WHERE nickname_lower <> lower(nickname);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_nickname_lower_tag ON users (nickname_lower, tag);`,
+		`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_nickname_tag_key;`,
+		// Matches leaderboardOrder; needs nickname_lower from just above
+		`CREATE INDEX IF NOT EXISTS idx_users_leaderboard ON users (trophies DESC, level DESC, nickname_lower, id);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS current_activity TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS chibiki_deck TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_daily_claim DATE;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS daily_streak INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS offline_at TIMESTAMPTZ;`,
+		`
+		CREATE TABLE IF NOT EXISTS coin_ledger (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			delta INTEGER NOT NULL,
+			balance_after INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			ref_id TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_coin_ledger_user ON coin_ledger (user_id, id DESC);`,
+		`
+		CREATE TABLE IF NOT EXISTS purchases (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			item_id TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			price INTEGER NOT NULL,
+			coins_granted INTEGER NOT NULL DEFAULT 0,
+			gems_granted INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_purchases_user ON purchases (user_id, id DESC);`,
+		`
+		CREATE TABLE IF NOT EXISTS game_state (
+			key TEXT PRIMARY KEY,
+			value BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS warthunder_replays (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			replay JSONB NOT NULL,
+			finished_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
 
 		`
 		CREATE TABLE IF NOT EXISTS medals (
@@ -214,6 +337,21 @@ func applySchema(db *sql.DB) error {
 		);
 		`,
 		`
+		CREATE TABLE IF NOT EXISTS user_medal_progress (
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			medal_id TEXT NOT NULL REFERENCES medals(id) ON DELETE CASCADE,
+			progress INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, medal_id)
+		);
+		`,
+		// Medals already held start with one unit of progress; they stay awarded either way
+		`
+		INSERT INTO user_medal_progress (user_id, medal_id, progress)
+		SELECT user_id, medal_id, 1 FROM user_medals
+		ON CONFLICT DO NOTHING;
+		`,
+		`
 		CREATE TABLE IF NOT EXISTS friendships (
 			id BIGSERIAL PRIMARY KEY,
 			requester_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -226,6 +364,53 @@ func applySchema(db *sql.DB) error {
 		`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_pair ON friendships (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id));`,
 		`
+		CREATE TABLE IF NOT EXISTS chibiki_matches (
+			id BIGSERIAL PRIMARY KEY,
+			winner_team INTEGER NOT NULL,
+			duration_seconds DOUBLE PRECISION NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS chibiki_match_players (
+			match_id BIGINT NOT NULL REFERENCES chibiki_matches(id) ON DELETE CASCADE,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			team INTEGER NOT NULL,
+			elixir_spent DOUBLE PRECISION NOT NULL DEFAULT 0,
+			units_deployed INTEGER NOT NULL DEFAULT 0,
+			damage_dealt DOUBLE PRECISION NOT NULL DEFAULT 0,
+			PRIMARY KEY (match_id, user_id)
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_chibiki_match_players_user ON chibiki_match_players (user_id, match_id DESC);`,
+		`
+		CREATE TABLE IF NOT EXISTS bobik_stats (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			kills INTEGER NOT NULL DEFAULT 0,
+			deaths INTEGER NOT NULL DEFAULT 0,
+			games_played INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMPTZ NOT NULL
+		);
+		`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);`,
+		`
+		CREATE TABLE IF NOT EXISTS user_settings (
+			user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			allow_dms_from TEXT NOT NULL DEFAULT 'everyone' CHECK (allow_dms_from IN ('everyone','friends','none')),
+			allow_friend_requests BOOLEAN NOT NULL DEFAULT TRUE,
+			show_activity BOOLEAN NOT NULL DEFAULT TRUE,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		`,
+		`
 		CREATE TABLE IF NOT EXISTS inventory (
 			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			item_id TEXT NOT NULL,
@@ -244,6 +429,9 @@ func applySchema(db *sql.DB) error {
 			seen BOOLEAN NOT NULL DEFAULT FALSE
 		);
 		`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_text_search ON messages USING GIN (to_tsvector('simple', text));`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages (sender_id, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_receiver ON messages (receiver_id, id DESC);`,
 	}
 
 	for _, stmt := range statements {
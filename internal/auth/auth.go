@@ -11,16 +11,19 @@ import (
 	"strings"
 	"time"
 
+	"main/internal/data"
+
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Store *data.Store
 }
 
-func NewAuth(db *sql.DB) *Auth {
-	return &Auth{DB: db}
+func NewAuth(db *sql.DB, store *data.Store) *Auth {
+	return &Auth{DB: db, Store: store}
 }
 
 type registerRequest struct {
@@ -98,20 +101,12 @@ func (a *Auth) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	maxAge := 0
-	if req.Remember {
-		maxAge = 60 * 60 * 24 * 30 // 30 days
+	if err := a.startSession(w, userID, req.Remember); err != nil {
+		log.Println("register session:", err)
+		http.Error(w, "failed to sign in", http.StatusInternalServerError)
+		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user_id",
-		Value:    userID,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   maxAge,
-	})
-
 	resp := registerResponse{
 		UserID:   userID,
 		Nickname: nick,
@@ -123,6 +118,7 @@ func (a *Auth) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // LoginHandler sets the cookie for an existing nickname+tag combo.
+// Nicknames match case-insensitively; display case is whatever was registered.
 func (a *Auth) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -141,15 +137,20 @@ func (a *Auth) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userID string
+	userID, found, err := a.Store.FindUserIDByHandle(nick, req.Tag)
+	if err != nil {
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
 	var storedHash string
 	var storedLang string
-	err := a.DB.QueryRow(`SELECT id, password_hash, COALESCE(language, 'en') FROM users WHERE nickname = $1 AND tag = $2`, nick, req.Tag).Scan(&userID, &storedHash, &storedLang)
+	err = a.DB.QueryRow(`SELECT password_hash, COALESCE(language, 'en') FROM users WHERE id = $1`, userID).Scan(&storedHash, &storedLang)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "user not found", http.StatusNotFound)
-			return
-		}
 		http.Error(w, "lookup failed", http.StatusInternalServerError)
 		return
 	}
@@ -176,18 +177,11 @@ func (a *Auth) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		WHERE id = $2
 	`, lang, userID)
 
-	maxAge := 0
-	if req.Remember {
-		maxAge = 60 * 60 * 24 * 30
+	if err := a.startSession(w, userID, req.Remember); err != nil {
+		log.Println("login session:", err)
+		http.Error(w, "failed to sign in", http.StatusInternalServerError)
+		return
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user_id",
-		Value:    userID,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   maxAge,
-	})
 
 	resp := registerResponse{
 		UserID:   userID,
@@ -238,7 +232,8 @@ func (a *Auth) UpdateLanguageHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"language": lang})
 }
 
-// LogoutHandler clears the auth cookie and marks the user offline.
+// LogoutHandler revokes this browser's session, clears its cookie and marks
+// the user offline. Sessions on other devices stay signed in.
 func (a *Auth) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -247,21 +242,21 @@ func (a *Auth) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID, err := readUserID(r)
 	if err == nil && userID != "" {
-		_, _ = a.DB.Exec(`UPDATE users SET status = 'offline', last_seen = NOW(), updated_at = NOW() WHERE id = $1`, userID)
+		_, _ = a.DB.Exec(`UPDATE users SET status = 'offline', current_activity = '', last_seen = NOW(), updated_at = NOW() WHERE id = $1`, userID)
+	}
+	if c, err := r.Cookie(SessionCookie); err == nil && c.Value != "" {
+		if err := a.Store.DeleteSession(c.Value); err != nil {
+			log.Println("logout:", err)
+		}
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	clearSessionCookie(w)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// AddFriendHandler accepts a nickname+tag and creates an accepted friendship row.
+// AddFriendHandler sends a friend request to a nickname+tag. The friendship
+// is only accepted once the other side agrees, or straight away when they had
+// already asked. Responds with {"status": "pending"|"accepted"}.
 func (a *Auth) AddFriendHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -284,39 +279,123 @@ func (a *Auth) AddFriendHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var targetID string
-	err = a.DB.QueryRow(`
-		SELECT id FROM users WHERE nickname = $1 AND tag = $2
-	`, req.Nickname, req.Tag).Scan(&targetID)
+	targetID, found, err := a.Store.FindUserIDByHandle(req.Nickname, req.Tag)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "user not found", http.StatusNotFound)
-			return
-		}
 		http.Error(w, "lookup failed", http.StatusInternalServerError)
 		return
 	}
+	if !found {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
 	if targetID == reqUserID {
 		http.Error(w, "cannot add yourself", http.StatusBadRequest)
 		return
 	}
+	accepts, err := a.Store.AcceptsFriendRequests(targetID)
+	if err != nil {
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+	if !accepts && !a.Store.AreFriends(reqUserID, targetID) {
+		http.Error(w, "user is not accepting friend requests", http.StatusForbidden)
+		return
+	}
 
-	_, err = a.DB.Exec(`
-		INSERT INTO friendships (requester_id, addressee_id, status)
-		VALUES ($1, $2, 'accepted')
-		ON CONFLICT (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id))
-		DO UPDATE SET status = 'accepted', updated_at = NOW()
-	`, reqUserID, targetID)
+	status, err := a.Store.RequestFriend(reqUserID, targetID)
 	if err != nil {
 		log.Println("add friend:", err)
 		http.Error(w, "failed to add friend", http.StatusInternalServerError)
 		return
 	}
+	if status == data.FriendBlocked {
+		http.Error(w, "user is not accepting friend requests", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// AcceptFriendHandler accepts the pending request from a nickname+tag.
+func (a *Auth) AcceptFriendHandler(w http.ResponseWriter, r *http.Request) {
+	a.answerFriendRequest(w, r, a.Store.AcceptFriend)
+}
+
+// DeclineFriendHandler turns down the pending request from a nickname+tag.
+func (a *Auth) DeclineFriendHandler(w http.ResponseWriter, r *http.Request) {
+	a.answerFriendRequest(w, r, a.Store.DeclineFriend)
+}
+
+// answerFriendRequest runs answer(caller, requester) for the requester named
+// in the body. 404 when they have no request pending.
+func (a *Auth) answerFriendRequest(w http.ResponseWriter, r *http.Request, answer func(addressee, requester string) error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reqUserID, err := readUserID(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req friendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Nickname) == "" || req.Tag <= 0 {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	requesterID, found, err := a.Store.FindUserIDByHandle(req.Nickname, req.Tag)
+	if err != nil {
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	if err := answer(reqUserID, requesterID); err != nil {
+		if errors.Is(err, data.ErrNoFriendRequest) {
+			http.Error(w, "no pending request", http.StatusNotFound)
+			return
+		}
+		log.Println("answer friend request:", err)
+		http.Error(w, "failed to answer request", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// FriendRequestsHandler lists the caller's pending requests.
+// GET /friends/requests -> {"incoming": [...], "outgoing": [...]}
+func (a *Auth) FriendRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	reqUserID, err := readUserID(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	incoming, outgoing, err := a.Store.ListFriendRequests(reqUserID)
+	if err != nil {
+		log.Println("list friend requests:", err)
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"incoming": incoming, "outgoing": outgoing})
+}
+
 // RemoveFriendHandler removes a friendship row between the requester and the target nickname/tag.
+// A pending request in either direction is withdrawn the same way.
 func (a *Auth) RemoveFriendHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -339,11 +418,12 @@ func (a *Auth) RemoveFriendHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var targetID string
-	err = a.DB.QueryRow(`
-		SELECT id FROM users WHERE nickname = $1 AND tag = $2
-	`, req.Nickname, req.Tag).Scan(&targetID)
+	targetID, found, err := a.Store.FindUserIDByHandle(req.Nickname, req.Tag)
 	if err != nil {
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+	if !found {
 		http.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
@@ -376,11 +456,11 @@ func (a *Auth) insertUserWithTag(nickname, password, language string) (int, stri
 
 		var insertedID string
 		err := a.DB.QueryRow(`
-			INSERT INTO users (id, nickname, tag, level, exp, max_exp, status, password_hash, language)
-			VALUES ($1, $2, $3, 1, 0, 1000, 'online', $4, $5)
-			ON CONFLICT (nickname, tag) DO NOTHING
+			INSERT INTO users (id, nickname, nickname_lower, tag, level, exp, max_exp, status, password_hash, language, power_score)
+			VALUES ($1, $2, lower($2), $3, 1, 0, 1000, 'online', $4, $5, $6)
+			ON CONFLICT (nickname_lower, tag) DO NOTHING
 			RETURNING id
-		`, userID, nickname, tag, string(hashed), language).Scan(&insertedID)
+		`, userID, nickname, tag, string(hashed), language, data.ComputePowerScore(1, 0, 0)).Scan(&insertedID)
 
 		if errors.Is(err, sql.ErrNoRows) {
 			continue // Tag collision, retry
@@ -395,11 +475,11 @@ func (a *Auth) insertUserWithTag(nickname, password, language string) (int, stri
 	return 0, "", fmt.Errorf("failed to generate unique tag for %s", nickname)
 }
 
-// readUserID extracts the user_id cookie.
+// readUserID is the signed-in caller, or an error when there's no session.
 func readUserID(r *http.Request) (string, error) {
-	c, err := r.Cookie("user_id")
-	if err != nil || c.Value == "" {
-		return "", errors.New("missing user id cookie")
+	userID, ok := UserID(r)
+	if !ok {
+		return "", errors.New("no session")
 	}
-	return c.Value, nil
+	return userID, nil
 }
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func post(h http.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(raw)))
+	return rec
+}
+
+func TestLoginIgnoresNicknameCase(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	nick := fmt.Sprintf("CaseTest%d", rand.Intn(1e6))
+
+	rec := post(a.RegisterHandler, registerRequest{Nickname: nick, Password: "secret1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	var reg registerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&reg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, reg.UserID) })
+
+	for _, typed := range []string{nick, "casetest" + nick[8:], "CASETEST" + nick[8:]} {
+		rec := post(a.LoginHandler, loginRequest{Nickname: typed, Tag: reg.Tag, Password: "secret1"})
+		if rec.Code != http.StatusOK {
+			t.Errorf("login as %q: %d %s", typed, rec.Code, rec.Body)
+		}
+	}
+
+	var display string
+	if err := db.QueryRow(`SELECT nickname FROM users WHERE id = $1`, reg.UserID).Scan(&display); err != nil {
+		t.Fatal(err)
+	}
+	if display != nick {
+		t.Errorf("display nickname %q, want %q as registered", display, nick)
+	}
+}
+
+func TestRegisterScoresPower(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	rec := post(a.RegisterHandler, registerRequest{Nickname: fmt.Sprintf("Power%d", rand.Intn(1e6)), Password: "secret1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	var reg registerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&reg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, reg.UserID) })
+
+	if _, score, err := store.GetUserRankByPower(reg.UserID); err != nil || score != data.ComputePowerScore(1, 0, 0) {
+		t.Fatalf("new account power %d (%v), want a level 1 score", score, err)
+	}
+}
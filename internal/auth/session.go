@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"main/internal/data"
+)
+
+// SessionCookie holds the opaque session token. The user ID itself never
+// goes in a cookie.
+const SessionCookie = "session"
+
+const (
+	RememberTTL = 30 * 24 * time.Hour // "Remember me" sessions
+	SessionTTL  = 24 * time.Hour      // Everything else; the cookie dies with the browser anyway
+
+	sessionCleanupInterval = time.Hour
+)
+
+type userIDKey struct{}
+
+// Middleware resolves the session cookie once per request, so handlers can
+// ask UserID who is calling. A token that no longer resolves is cleared; a
+// lookup that fails for any other reason is a 500, so a database blip
+// doesn't sign everyone out.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(SessionCookie)
+		if err != nil || c.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := a.Store.SessionUser(c.Value)
+		if errors.Is(err, data.ErrSessionInvalid) {
+			clearSessionCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("[AUTH] Session lookup: %v", err)
+			http.Error(w, "session lookup failed", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, WithUserID(r, userID))
+	})
+}
+
+// WithUserID is r as seen by handlers once userID's session has resolved.
+func WithUserID(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDKey{}, userID))
+}
+
+// UserID is the signed-in caller, as resolved by Middleware.
+func UserID(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value(userIDKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// startSession signs userID in on this browser.
+func (a *Auth) startSession(w http.ResponseWriter, userID string, remember bool) error {
+	ttl, maxAge := SessionTTL, 0
+	if remember {
+		ttl, maxAge = RememberTTL, int(RememberTTL.Seconds())
+	}
+	token, err := a.Store.CreateSession(userID, ttl)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
+	return nil
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// StartSessionCleanup purges expired sessions in the background.
+func (a *Auth) StartSessionCleanup() {
+	go func() {
+		ticker := time.NewTicker(sessionCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := a.Store.PurgeExpiredSessions()
+			if err != nil {
+				log.Printf("[AUTH] Session cleanup: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[AUTH] Purged %d expired sessions", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"main/internal/data/datatest"
+)
+
+// whoAmI echoes the resolved user, or 401.
+var whoAmI = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserID(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte(userID))
+})
+
+func get(h http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func cleared(rec *httptest.ResponseRecorder) bool {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == SessionCookie && c.MaxAge < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUserIDWithoutSession(t *testing.T) {
+	a := &Auth{}
+	if rec := get(a.Middleware(whoAmI), ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("code %d, want 401", rec.Code)
+	}
+	if id, ok := UserID(WithUserID(httptest.NewRequest(http.MethodGet, "/", nil), "u_1")); !ok || id != "u_1" {
+		t.Fatalf("UserID = %q, %v", id, ok)
+	}
+}
+
+func TestMiddlewareResolvesSession(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	id := datatest.User(t, db, 0)
+	token, err := store.CreateSession(id, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := get(a.Middleware(whoAmI), token)
+	if rec.Code != http.StatusOK || rec.Body.String() != id {
+		t.Fatalf("code %d body %q, want %q", rec.Code, rec.Body.String(), id)
+	}
+}
+
+func TestMiddlewareRejectsBadTokens(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	id := datatest.User(t, db, 0)
+	expired, _ := store.CreateSession(id, -time.Minute)
+
+	for name, token := range map[string]string{"expired": expired, "invalid": "forged"} {
+		rec := get(a.Middleware(whoAmI), token)
+		if rec.Code != http.StatusUnauthorized || !cleared(rec) {
+			t.Errorf("%s: code %d cleared %v, want 401 and a cleared cookie", name, rec.Code, cleared(rec))
+		}
+	}
+}
+
+// A lookup that fails for reasons other than a bad token mustn't sign the
+// user out.
+func TestMiddlewareKeepsCookieOnDBError(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	db.Close()
+
+	rec := get(a.Middleware(whoAmI), "whatever")
+	if rec.Code != http.StatusInternalServerError || cleared(rec) {
+		t.Fatalf("code %d cleared %v, want 500 and the cookie kept", rec.Code, cleared(rec))
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	store, db := datatest.Store(t)
+	a := NewAuth(db, store)
+	id := datatest.User(t, db, 0)
+	token, _ := store.CreateSession(id, time.Hour)
+	other, _ := store.CreateSession(id, time.Hour)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+	rec := httptest.NewRecorder()
+	a.Middleware(http.HandlerFunc(a.LogoutHandler)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || !cleared(rec) {
+		t.Fatalf("logout: code %d cleared %v", rec.Code, cleared(rec))
+	}
+
+	if rec := get(a.Middleware(whoAmI), token); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("logged-out token still works: %d", rec.Code)
+	}
+	if rec := get(a.Middleware(whoAmI), other); rec.Code != http.StatusOK {
+		t.Fatalf("other session signed out too: %d", rec.Code)
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+)
+
+// WebsocketUser resolves who is opening a game websocket from their session
+// cookie, never from the query string. Call it before upgrading: on failure
+// it has already written a 401/403 and the handler should just return.
+//
+// A userID query param is still accepted from older clients, but only when
+// it matches the session; anything else is treated as spoofing.
+func WebsocketUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := readUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if q := r.URL.Query().Get("userID"); q != "" && q != userID {
+		log.Printf("[AUTH] Rejected websocket on %s: userID %q does not match session", r.URL.Path, q)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
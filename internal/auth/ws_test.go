@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebsocketUser(t *testing.T) {
+	cases := []struct {
+		name    string
+		session string
+		query   string
+		code    int
+	}{
+		{"no session", "", "", http.StatusUnauthorized},
+		{"no session, query only", "", "?userID=u_1", http.StatusUnauthorized},
+		{"spoofed query", "u_1", "?userID=u_2", http.StatusForbidden},
+		{"session", "u_1", "", http.StatusOK},
+		{"matching query", "u_1", "?userID=u_1", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/ws"+c.query, nil)
+		if c.session != "" {
+			req = WithUserID(req, c.session)
+		}
+		rec := httptest.NewRecorder()
+		id, ok := WebsocketUser(rec, req)
+		if rec.Code != c.code {
+			t.Errorf("%s: code %d, want %d", c.name, rec.Code, c.code)
+		}
+		if ok != (c.code == http.StatusOK) || (ok && id != c.session) {
+			t.Errorf("%s: got %q, %v", c.name, id, ok)
+		}
+	}
+}
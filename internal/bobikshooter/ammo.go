@@ -0,0 +1,118 @@
+package bobikshooter
+
+import "time"
+
+// reloadCooldown is how long a reload keeps a gun from firing. It's a little
+// under the client's 1.5s animation so jitter doesn't eat the first shot.
+const reloadCooldown = 1400 * time.Millisecond
+
+// Reasons a "shoot" is refused, sent back in the "ammo" message
+const (
+	ShotEmpty     = "empty"
+	ShotReloading = "reloading"
+)
+
+// Ammo is what's left for one of a player's guns.
+type Ammo struct {
+	Clip    int `json:"clip"`
+	Reserve int `json:"reserve"`
+}
+
+// ammoFor returns p's ammo for weapon, giving a gun it hasn't fired yet this
+// round a full load. Melee weapons have none and return nil.
+func (p *Player) ammoFor(weapon string) *Ammo {
+	stats, ok := Weapons[weapon]
+	if !ok || stats.Magazine == 0 {
+		return nil
+	}
+	a, ok := p.Ammo[weapon]
+	if !ok {
+		a = &Ammo{Clip: stats.Magazine, Reserve: stats.Reserve}
+		p.Ammo[weapon] = a
+	}
+	return a
+}
+
+// fire spends one round from p's current gun and arms the hit that may
+// follow it. It returns a Shot* reason when the gun can't fire.
+func (p *Player) fire(now time.Time) string {
+	a := p.ammoFor(p.CurrentWeapon)
+	if a == nil {
+		return "" // Melee never runs dry
+	}
+	if now.Before(p.reloadUntil) {
+		return ShotReloading
+	}
+	if a.Clip <= 0 {
+		return ShotEmpty
+	}
+	a.Clip--
+	p.shotPending = true
+	return ""
+}
+
+// takeShot uses up the round a hit with weapon claims to come from. Each
+// fired round backs at most one hit.
+func (p *Player) takeShot(weapon string) bool {
+	if p.ammoFor(weapon) == nil {
+		return true
+	}
+	if !p.shotPending {
+		return false
+	}
+	p.shotPending = false
+	return true
+}
+
+// reload tops the current gun's clip up from its reserve and starts the
+// cooldown. It reports false when there is nothing to do.
+func (p *Player) reload(now time.Time) bool {
+	a := p.ammoFor(p.CurrentWeapon)
+	if a == nil || now.Before(p.reloadUntil) {
+		return false
+	}
+	take := min(Weapons[p.CurrentWeapon].Magazine-a.Clip, a.Reserve)
+	if take <= 0 {
+		return false
+	}
+	a.Clip += take
+	a.Reserve -= take
+	p.shotPending = false
+	p.reloadUntil = now.Add(reloadCooldown)
+	return true
+}
+
+// ammoState is the "ammo" message for p's current gun. Caller holds g.mu.
+func ammoState(p *Player, reason string) map[string]interface{} {
+	msg := map[string]interface{}{"type": "ammo", "weapon": p.CurrentWeapon}
+	if a := p.ammoFor(p.CurrentWeapon); a != nil {
+		msg["clip"], msg["reserve"] = a.Clip, a.Reserve
+	}
+	if reason != "" {
+		msg["reason"] = reason
+	}
+	return msg
+}
+
+// handleShoot is sent for every trigger pull, hit or miss. A refused shot
+// gets the real count back so the HUD can catch up.
+func (g *Game) handleShoot(p *Player, msg map[string]interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if w, ok := msg["weapon"].(string); ok {
+		g.switchWeapon(p, w)
+	}
+	if reason := p.fire(time.Now()); reason != "" {
+		g.sendTo(p, ammoState(p, reason))
+	}
+}
+
+func (g *Game) handleReload(p *Player) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p.reload(time.Now()) {
+		msg := ammoState(p, "")
+		msg["reloadMs"] = reloadCooldown.Milliseconds()
+		g.sendTo(p, msg)
+	}
+}
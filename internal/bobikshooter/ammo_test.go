@@ -0,0 +1,83 @@
+package bobikshooter
+
+import (
+	"testing"
+	"time"
+)
+
+func holding(p *Player, weapon string) *Player {
+	p.Owned[weapon] = true
+	p.CurrentWeapon = weapon
+	return p
+}
+
+func TestFireToEmptyThenReload(t *testing.T) {
+	p := holding(testPlayer("p"), "deagle")
+	mag, reserve := Weapons["deagle"].Magazine, Weapons["deagle"].Reserve
+	now := time.Now()
+
+	for shot := 1; shot <= mag; shot++ {
+		if reason := p.fire(now); reason != "" {
+			t.Fatalf("shot %d refused: %q", shot, reason)
+		}
+	}
+	if reason := p.fire(now); reason != ShotEmpty {
+		t.Fatalf("firing an empty clip: %q, want %q", reason, ShotEmpty)
+	}
+
+	if !p.reload(now) {
+		t.Fatal("reload refused on an empty clip")
+	}
+	if a := p.ammoFor("deagle"); a.Clip != mag || a.Reserve != reserve-mag {
+		t.Fatalf("after reload %d/%d, want %d/%d", a.Clip, a.Reserve, mag, reserve-mag)
+	}
+	if reason := p.fire(now.Add(reloadCooldown / 2)); reason != ShotReloading {
+		t.Errorf("firing mid-reload: %q, want %q", reason, ShotReloading)
+	}
+	if p.reload(now.Add(reloadCooldown / 2)) {
+		t.Error("reloaded again mid-reload")
+	}
+	if reason := p.fire(now.Add(reloadCooldown)); reason != "" {
+		t.Errorf("firing after the cooldown: %q", reason)
+	}
+
+	// Nothing left in reserve, nothing to reload
+	a := p.ammoFor("deagle")
+	a.Clip, a.Reserve = 0, 0
+	if p.reload(now.Add(2 * reloadCooldown)) {
+		t.Error("reloaded from an empty reserve")
+	}
+}
+
+func TestEachRoundBacksOneHit(t *testing.T) {
+	p := holding(testPlayer("p"), "deagle")
+	if p.takeShot("deagle") {
+		t.Fatal("hit accepted with no round fired")
+	}
+	p.fire(time.Now())
+	if !p.takeShot("deagle") || p.takeShot("deagle") {
+		t.Fatal("one round didn't back exactly one hit")
+	}
+	if !p.takeShot("knife") {
+		t.Error("melee hit needs no round")
+	}
+}
+
+func TestHitRejectedOnEmptyMagazine(t *testing.T) {
+	g, p := duel(ModeFFA, "shooter", "target")
+	shooter := holding(p[0], "deagle")
+	shooter.ammoFor("deagle").Clip = 0
+
+	if dealt := shoot(g, shooter, p[1]); dealt != 0 {
+		t.Fatalf("empty magazine dealt %d", dealt)
+	}
+
+	shooter.reload(time.Now())
+	shooter.reloadUntil = time.Time{} // Skip the cooldown
+	if dealt := shoot(g, shooter, p[1]); dealt <= 0 {
+		t.Fatal("reloaded gun dealt nothing")
+	}
+	if a := shooter.ammoFor("deagle"); a.Clip != Weapons["deagle"].Magazine-1 {
+		t.Errorf("clip %d after one shot, want %d", a.Clip, Weapons["deagle"].Magazine-1)
+	}
+}
@@ -0,0 +1,147 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// spawnProtection is how long a freshly spawned player can't be hurt.
+const spawnProtection = 2 * time.Second
+
+// Obstacle is one solid box of the map, standing on the floor. The fields
+// are the client's addBox arguments: centre X/Z, width along X, depth along
+// Z and height.
+type Obstacle struct {
+	X float64 `json:"x"`
+	Z float64 `json:"z"`
+	W float64 `json:"w"`
+	D float64 `json:"d"`
+	H float64 `json:"h"`
+}
+
+// defaultObstacles mirrors the layout hard-coded in bobik.html, so walls
+// block shots even without the map file.
+var defaultObstacles = []Obstacle{
+	{X: -40, Z: -40, W: 8, D: 8, H: 8},
+	{X: 40, Z: 40, W: 10, D: 10, H: 6},
+	{X: -20, Z: 30, W: 6, D: 6, H: 6},
+	{X: 30, Z: -20, W: 8, D: 8, H: 12},
+	{X: 0, Z: 60, W: 15, D: 15, H: 10},
+	{X: 60, Z: 0, W: 12, D: 12, H: 8},
+	{X: -60, Z: 20, W: 10, D: 10, H: 15},
+	{X: 20, Z: -60, W: 12, D: 5, H: 8},
+	{X: -30, Z: -10, W: 6, D: 12, H: 10},
+	{X: 0, Z: -60, W: 120, D: 40, H: 20},
+	{X: -80, Z: 40, W: 40, D: 220, H: 30},
+	{X: 80, Z: 40, W: 40, D: 220, H: 30},
+	{X: 0, Z: 100, W: 80, D: 40, H: 15},
+	{X: -30, Z: 0, W: 30, D: 30, H: 12},
+	{X: 30, Z: 30, W: 30, D: 30, H: 12},
+}
+
+// defaultSpawns are open floor between the default obstacles.
+var defaultSpawns = []Vec3{
+	{X: 0, Y: 15, Z: 0}, {X: -50, Y: 15, Z: -30}, {X: 50, Y: 15, Z: -30}, {X: -40, Y: 15, Z: 60},
+	{X: 40, Y: 15, Z: 65}, {X: 0, Y: 15, Z: 30}, {X: -50, Y: 15, Z: 40}, {X: 50, Y: 15, Z: 10},
+}
+
+// readMap loads and checks the obstacles and spawn points in path.
+func readMap(path string) ([]Obstacle, []Vec3, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var data struct {
+		Obstacles []Obstacle `json:"obstacles"`
+		Spawns    []Vec3     `json:"spawns"`
+	}
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(data.Spawns) == 0 {
+		return nil, nil, fmt.Errorf("%s: no spawns defined", path)
+	}
+	for i, o := range data.Obstacles {
+		if o.W <= 0 || o.D <= 0 || o.H <= 0 {
+			return nil, nil, fmt.Errorf("%s: obstacle %d has a non-positive size", path, i)
+		}
+	}
+	return data.Obstacles, data.Spawns, nil
+}
+
+// respawn puts p back at full health on the spawn farthest from its enemies,
+// shielded for spawnProtection. Caller holds g.mu.
+func (g *Game) respawn(p *Player) {
+	p.Health = maxHealth
+	p.Pos = g.spawnFor(p)
+	p.SpawnProtectedUntil = time.Now().Add(spawnProtection)
+}
+
+// spawnFor picks the spawn whose nearest enemy of p is farthest away;
+// teammates don't count. With nobody to avoid any spawn will do, so ties are
+// broken by starting the scan at a random spawn. Caller holds g.mu.
+func (g *Game) spawnFor(p *Player) Vec3 {
+	start := rand.Intn(len(g.spawns))
+	best, bestDist := g.spawns[start], -1.0
+	for i := range g.spawns {
+		s := g.spawns[(start+i)%len(g.spawns)]
+		nearest := math.Inf(1)
+		for q := range g.players {
+			if q == p || (g.mode.Teams && q.Team == p.Team && p.Team != TeamNone) {
+				continue
+			}
+			nearest = math.Min(nearest, distance3D(s, q.Pos))
+		}
+		if nearest > bestDist {
+			best, bestDist = s, nearest
+		}
+	}
+	return best
+}
+
+// protected reports whether p still has spawn protection.
+func (p *Player) protected(now time.Time) bool {
+	return now.Before(p.SpawnProtectedUntil)
+}
+
+// lineOfSight reports whether nothing solid stands between a and b.
+// Caller holds g.mu.
+func (g *Game) lineOfSight(a, b Vec3) bool {
+	for _, o := range g.obstacles {
+		if o.blocks(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// blocks reports whether the segment a-b passes through o, using the slab
+// method: clip the segment's [0,1] range against each axis in turn.
+func (o Obstacle) blocks(a, b Vec3) bool {
+	lo := [3]float64{o.X - o.W/2, 0, o.Z - o.D/2}
+	hi := [3]float64{o.X + o.W/2, o.H, o.Z + o.D/2}
+	from := [3]float64{a.X, a.Y, a.Z}
+	dir := [3]float64{b.X - a.X, b.Y - a.Y, b.Z - a.Z}
+
+	tMin, tMax := 0.0, 1.0
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if from[i] < lo[i] || from[i] > hi[i] {
+				return false // Parallel to this slab and outside it
+			}
+			continue
+		}
+		t1 := (lo[i] - from[i]) / dir[i]
+		t2 := (hi[i] - from[i]) / dir[i]
+		tMin = math.Max(tMin, math.Min(t1, t2))
+		tMax = math.Min(tMax, math.Max(t1, t2))
+		if tMin > tMax {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package bobikshooter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWallBlocksHit(t *testing.T) {
+	if g := NewGame(nil, modeByID("ffa")); len(g.obstacles) == 0 {
+		t.Fatal("new game has no walls loaded")
+	}
+
+	g, p := duel(ModeFFA, "shooter", "target") // 5 apart along Z
+	g.obstacles = []Obstacle{{X: 0, Z: 2.5, W: 10, D: 1, H: 10}}
+	if dealt := shoot(g, p[0], p[1]); dealt != 0 {
+		t.Fatalf("shot through a wall dealt %d", dealt)
+	}
+
+	g.obstacles = []Obstacle{{X: 0, Z: 2.5, W: 10, D: 1, H: 0.5}} // Waist-high cover, shot goes over
+	if dealt := shoot(g, p[0], p[1]); dealt <= 0 {
+		t.Fatal("shot over low cover dealt nothing")
+	}
+
+	g.obstacles = []Obstacle{{X: 8, Z: 2.5, W: 2, D: 2, H: 10}} // Off to the side
+	if dealt := shoot(g, p[0], p[1]); dealt <= 0 {
+		t.Fatal("unobstructed shot dealt nothing")
+	}
+}
+
+func TestSpawnProtection(t *testing.T) {
+	g, p := duel(ModeFFA, "shooter", "target")
+	shooter, target := p[0], p[1]
+
+	before := time.Now()
+	g.respawn(target)
+	if until := target.SpawnProtectedUntil.Sub(before); until < spawnProtection || until > spawnProtection+time.Second {
+		t.Fatalf("respawn protects for %v, want about %v", until, spawnProtection)
+	}
+	if target.protected(target.SpawnProtectedUntil) {
+		t.Error("protection still on at the deadline")
+	}
+
+	target.Pos = Vec3{X: 0, Y: 1, Z: 5} // respawn moved them; put them back in the line of fire
+	if dealt := shoot(g, shooter, target); dealt != 0 || target.Health != maxHealth {
+		t.Fatalf("hit on a protected player dealt %d, health %d", dealt, target.Health)
+	}
+
+	target.SpawnProtectedUntil = time.Now().Add(-time.Millisecond)
+	if dealt := shoot(g, shooter, target); dealt <= 0 {
+		t.Fatal("hit after protection expired dealt nothing")
+	}
+}
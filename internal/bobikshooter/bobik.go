@@ -2,6 +2,7 @@ package bobikshooter
 
 import (
 	"encoding/json"
+	"log"
 	"math"
 	"math/rand"
 	"net/http"
@@ -10,13 +11,23 @@ import (
 
 	"main/internal/data"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 const (
 	roundDuration = 180 * time.Second
 	maxHealth     = 100
+
+	// How long the final scoreboard keeps riding on state broadcasts after a
+	// round ends, and how long before a new round may start.
+	scoreboardHold = 5 * time.Second
+
+	// A round that drops below two players is paused with its clock frozen.
+	// If nobody comes back within this long it's called off, no rewards.
+	pauseTimeout = 60 * time.Second
+
+	// How often stateLoop advances the round and broadcasts state
+	tick = 50 * time.Millisecond
 )
 
 // WeaponStats defines server-authoritative weapon properties
@@ -25,19 +36,37 @@ type WeaponStats struct {
 	Falloff      float64 // Damage reduction per meter
 	MaxRange     float64 // Maximum effective range
 	HeadshotMult float64 // Headshot damage multiplier
+	Magazine     int     // Rounds per clip, 0 for melee
+	Reserve      int     // Spare rounds at round start
 }
 
 // Server-side weapon definitions - prevents client damage exploits
 // Inspired by CS2 weapon balancing
 var Weapons = map[string]WeaponStats{
 	"knife":   {BaseDamage: 50, Falloff: 0, MaxRange: 3, HeadshotMult: 1.0},
-	"pistol":  {BaseDamage: 22, Falloff: 0.3, MaxRange: 50, HeadshotMult: 2.0},  // Glock
-	"deagle":  {BaseDamage: 55, Falloff: 0.2, MaxRange: 60, HeadshotMult: 2.5},  // Desert Eagle
-	"smg":     {BaseDamage: 18, Falloff: 0.4, MaxRange: 40, HeadshotMult: 1.5},  // P90
-	"shotgun": {BaseDamage: 90, Falloff: 2.0, MaxRange: 15, HeadshotMult: 1.2},  // XM1014
-	"rifle":   {BaseDamage: 36, Falloff: 0.2, MaxRange: 80, HeadshotMult: 2.5},  // AK-47
-	"m4a4":    {BaseDamage: 33, Falloff: 0.15, MaxRange: 90, HeadshotMult: 2.3}, // M4A4
-	"awp":     {BaseDamage: 115, Falloff: 0, MaxRange: 200, HeadshotMult: 1.0},  // AWP (one-shot kill)
+	"pistol":  {BaseDamage: 22, Falloff: 0.3, MaxRange: 50, HeadshotMult: 2.0, Magazine: 20, Reserve: 120}, // Glock
+	"deagle":  {BaseDamage: 55, Falloff: 0.2, MaxRange: 60, HeadshotMult: 2.5, Magazine: 7, Reserve: 35},   // Desert Eagle
+	"smg":     {BaseDamage: 18, Falloff: 0.4, MaxRange: 40, HeadshotMult: 1.5, Magazine: 50, Reserve: 100}, // P90
+	"shotgun": {BaseDamage: 90, Falloff: 2.0, MaxRange: 15, HeadshotMult: 1.2, Magazine: 7, Reserve: 32},   // XM1014
+	"rifle":   {BaseDamage: 36, Falloff: 0.2, MaxRange: 80, HeadshotMult: 2.5, Magazine: 30, Reserve: 90},  // AK-47
+	"m4a4":    {BaseDamage: 33, Falloff: 0.15, MaxRange: 90, HeadshotMult: 2.3, Magazine: 30, Reserve: 90}, // M4A4
+	"awp":     {BaseDamage: 115, Falloff: 0, MaxRange: 200, HeadshotMult: 1.0, Magazine: 5, Reserve: 20},   // AWP (one-shot kill)
+}
+
+// weaponAliases maps client weapon keys onto server names.
+var weaponAliases = map[string]string{
+	"ak47": "rifle",
+}
+
+// starterWeapons is what everyone owns at round start (matches the client's
+// default inventory); the rest have to be bought.
+var starterWeapons = []string{"knife", "pistol", "rifle"}
+
+func normalizeWeapon(w string) string {
+	if alias, ok := weaponAliases[w]; ok {
+		return alias
+	}
+	return w
 }
 
 type Vec3 struct {
@@ -54,48 +83,142 @@ type Player struct {
 	Conn     *websocket.Conn
 	Send     chan []byte
 
-	Pos    Vec3
-	RotY   float64
-	Health int
-	Kills  int
-	Deaths int
-	Score  int
+	Pos      Vec3
+	RotY     float64
+	Health   int
+	Kills    int
+	Deaths   int
+	Score    int
+	Streak   int // Kills since last death
+	Grenades int // Left to throw this round
+
+	Team int // TeamRed or TeamBlue in team modes, else TeamNone
+
+	SpawnProtectedUntil time.Time // Hits are ignored until then
+	GunLevel            int       // Index into gunGameLadder (gun-game only)
+	Spectator           bool      // Watch-only: gets broadcasts, never scores, input ignored
+
+	CurrentWeapon string           // What the player is holding, per their last update
+	Aiming        bool             // Scoped in (AWP only)
+	Owned         map[string]bool  // Weapons they may switch to
+	Ammo          map[string]*Ammo // Per gun, filled on first use each round
+
+	shotPending bool      // A round was fired and no hit has claimed it yet
+	reloadUntil time.Time // Current gun can't fire before this
+}
+
+// resetLoadout gives p the starter weapons with the pistol out and every gun
+// back to a full load.
+func (p *Player) resetLoadout() {
+	p.Owned = make(map[string]bool, len(starterWeapons))
+	for _, w := range starterWeapons {
+		p.Owned[w] = true
+	}
+	p.CurrentWeapon = "pistol"
+	p.Aiming = false
+	p.Ammo = make(map[string]*Ammo)
+	p.Grenades = grenadesPerRound
+	p.shotPending = false
+	p.reloadUntil = time.Time{}
 }
 
 type Game struct {
+	ID          string
 	mu          sync.Mutex
 	store       *data.Store
 	players     map[*Player]bool
+	spectators  map[*Player]bool
 	register    chan *Player
 	unregister  chan *Player
 	broadcast   chan []byte
+	stop        chan struct{}
 	roundActive bool
 	roundEnds   time.Time
+	paused      bool          // Round is on hold waiting for a second player
+	pausedLeft  time.Duration // Round time left when it was paused
+	pausedAt    time.Time
 	dummies     []Vec3 // Practice targets
+	mode        Mode   // Ruleset for the current match
+	catalog     []ShopItem
+	obstacles   []Obstacle // Solid boxes that stop shots
+	spawns      []Vec3
+	projectiles []*Projectile // Grenades in the air
+	grenadeSeq  int
+
+	// Final results of the last round, replayed in state until holdUntil
+	lastScoreboard []map[string]interface{}
+	lastWinnerID   string
+	lastWinTeam    int // Winning side of the last team round, TeamNone on a draw
+	holdUntil      time.Time
 }
 
-func NewGame(store *data.Store) *Game {
-	g := &Game{
-		store:      store,
-		players:    make(map[*Player]bool),
-		register:   make(chan *Player),
-		unregister: make(chan *Player),
-		broadcast:  make(chan []byte, 64),
-		dummies:    generateDummies(),
+// NewGame makes an empty arena playing mode. Start runs it.
+func NewGame(store *data.Store, mode Mode) *Game {
+	return &Game{
+		store:       store,
+		players:     make(map[*Player]bool),
+		spectators:  make(map[*Player]bool),
+		register:    make(chan *Player),
+		unregister:  make(chan *Player),
+		broadcast:   make(chan []byte, 64),
+		stop:        make(chan struct{}),
+		dummies:     generateDummies(),
+		mode:        mode,
+		lastWinTeam: TeamNone,
+		catalog:     defaultCatalog,
+		obstacles:   defaultObstacles,
+		spawns:      defaultSpawns,
 	}
+}
+
+// Start runs the arena's loops until Stop.
+func (g *Game) Start() {
 	go g.run()
 	go g.stateLoop()
-	return g
+}
+
+// Stop ends the arena's loops. Nobody may be left in it.
+func (g *Game) Stop() {
+	close(g.stop)
+}
+
+// dropSpectators disconnects everyone watching; their writePump closes the
+// connection once Send is closed. Called once the room has stopped, so run
+// is no longer fanning out to them.
+func (g *Game) dropSpectators() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for p := range g.spectators {
+		delete(g.spectators, p)
+		close(p.Send)
+	}
+}
+
+// Snapshot reports the arena's head count for the lobby. Spectators aren't
+// counted as playing.
+func (g *Game) Snapshot() data.LiveStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return data.LiveStatus{
+		Players: len(g.players),
+		InMatch: g.roundActive && !g.paused,
+		State:   string(g.mode.ID),
+	}
 }
 
 func (g *Game) run() {
 	for {
 		select {
+		case <-g.stop:
+			return
 		case p := <-g.register:
 			g.mu.Lock()
-			g.players[p] = true
-			if len(g.players) >= 2 && !g.roundActive {
-				g.startRound()
+			if p.Spectator {
+				g.spectators[p] = true
+			} else {
+				g.assignTeam(p)
+				g.players[p] = true
+				g.maybeStartRound()
 			}
 			g.mu.Unlock()
 			g.sendWelcome(p)
@@ -106,15 +229,22 @@ func (g *Game) run() {
 				close(p.Send)
 				p.Conn.Close()
 			}
+			if _, ok := g.spectators[p]; ok {
+				delete(g.spectators, p)
+				close(p.Send)
+				p.Conn.Close()
+			}
 			g.mu.Unlock()
 		case msg := <-g.broadcast:
 			g.mu.Lock()
-			for p := range g.players {
-				select {
-				case p.Send <- msg:
-				default:
-					close(p.Send)
-					delete(g.players, p)
+			for _, set := range []map[*Player]bool{g.players, g.spectators} {
+				for p := range set {
+					select {
+					case p.Send <- msg:
+					default:
+						close(p.Send)
+						delete(set, p)
+					}
 				}
 			}
 			g.mu.Unlock()
@@ -123,102 +253,249 @@ func (g *Game) run() {
 }
 
 func (g *Game) stateLoop() {
-	ticker := time.NewTicker(50 * time.Millisecond)
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		var pay *roundPay
 		g.mu.Lock()
-		if g.roundActive && time.Now().After(g.roundEnds) {
+		g.checkPause()
+		if g.roundActive && !g.paused {
+			g.updateProjectiles(tick.Seconds())
+		}
+		if g.roundActive && !g.paused && time.Now().After(g.roundEnds) {
 			g.roundActive = false
-			g.endRound()
+			pay = g.endRound()
+		} else if !g.roundActive {
+			g.maybeStartRound()
 		}
 		state := g.buildState()
 		g.mu.Unlock()
+		g.settle(pay)
 		g.broadcastJSON(state)
 	}
 }
 
+// maybeStartRound kicks off a round once two players are in and the last
+// scoreboard has had its moment. Caller holds g.mu.
+func (g *Game) maybeStartRound() {
+	if g.roundActive || len(g.players) < 2 || time.Now().Before(g.holdUntil) {
+		return
+	}
+	g.startRound()
+}
+
+// checkPause freezes a round that's down to one player, picks it back up when
+// a second joins, and calls it off if it sits empty or paused too long.
+// Caller holds g.mu.
+func (g *Game) checkPause() {
+	if !g.roundActive {
+		return
+	}
+	switch {
+	case !g.paused && len(g.players) < 2:
+		g.paused = true
+		g.pausedAt = time.Now()
+		g.pausedLeft = time.Until(g.roundEnds)
+		if g.pausedLeft < 0 {
+			g.pausedLeft = 0
+		}
+		g.broadcastJSON(map[string]interface{}{"type": "round_paused", "reason": "waiting_for_players"})
+	case g.paused && len(g.players) >= 2:
+		g.paused = false
+		g.roundEnds = time.Now().Add(g.pausedLeft)
+		g.broadcastJSON(map[string]interface{}{"type": "round_resumed", "timeLeft": int(g.pausedLeft.Seconds())})
+	case g.paused && (len(g.players) == 0 || time.Since(g.pausedAt) > pauseTimeout):
+		g.paused = false
+		g.roundActive = false
+		g.broadcastJSON(map[string]interface{}{"type": "round_aborted", "reason": "not_enough_players"})
+	}
+}
+
 func (g *Game) startRound() {
 	g.roundActive = true
-	g.roundEnds = time.Now().Add(roundDuration)
+	g.paused = false
+	g.roundEnds = time.Now().Add(g.mode.TimeLimit)
+	g.projectiles = nil
+	g.balanceTeams()
 	for p := range g.players {
-		p.Kills, p.Deaths = 0, 0
-		p.Score = 800
-		p.Health = maxHealth
-		p.Pos = randomSpawn()
+		p.Kills, p.Deaths, p.Streak = 0, 0, 0
+		p.GunLevel = 0
+		p.resetLoadout()
+		p.Score = g.mode.StartingScore
+		g.respawn(p)
+		msg := ammoState(p, "")
+		msg["reset"] = true
+		g.sendTo(p, msg)
 	}
 }
 
-func (g *Game) endRound() {
+// endRound scores the round and returns what it owes, for settle. Caller
+// holds g.mu.
+func (g *Game) endRound() *roundPay {
 	var winner *Player
 	maxKills := -1
 	scoreboard := make([]map[string]interface{}, 0, len(g.players))
+	pay := &roundPay{mode: g.mode.ID}
 
 	for p := range g.players {
+		pay.stats = append(pay.stats, roundStats{userID: p.UserID, kills: p.Kills, deaths: p.Deaths})
 		if p.Kills > maxKills {
 			maxKills = p.Kills
 			winner = p
 		}
 		scoreboard = append(scoreboard, map[string]interface{}{
-			"id": p.ID, "name": p.Nickname, "kills": p.Kills, "deaths": p.Deaths,
+			"id": p.ID, "name": p.Nickname, "kills": p.Kills, "deaths": p.Deaths, "team": p.Team,
 		})
 	}
 
+	result := map[string]interface{}{"type": "game_over", "scoreboard": scoreboard, "mode": g.mode.ID}
 	winnerID := ""
-	if winner != nil {
+	g.lastWinTeam = TeamNone
+	if g.mode.Teams {
+		winningTeam, kills := g.payTeam(pay)
+		g.lastWinTeam = winningTeam
+		result["winningTeam"] = winningTeam
+		result["teamKills"] = kills
+	} else if winner != nil {
 		winnerID = winner.ID
-		// Only award if actually played (kills > 0) or simply by being best survivor
-		if winner.UserID != "" && winner.UserID != "guest" {
-			g.store.AdjustCoins(winner.UserID, 100)
-			g.store.AdjustTrophies(winner.UserID, 25)
-			g.store.AwardMedals(winner.UserID, "ten_wins")
+		// Nothing for winning alone or winning a round where nobody scored
+		if rewardable(len(g.players), maxKills) {
+			pay.prizes = append(pay.prizes, prize{userID: winner.UserID, coins: 100, trophies: 25})
 		}
 	}
 
-	g.broadcastJSON(map[string]interface{}{
-		"type": "game_over", "scoreboard": scoreboard, "winnerId": winnerID,
-	})
+	g.lastScoreboard = scoreboard
+	g.lastWinnerID = winnerID
+	g.holdUntil = time.Now().Add(scoreboardHold)
+
+	result["winnerId"] = winnerID
+	g.broadcastJSON(result)
+	return pay
+}
+
+// roundPay is what a finished round owes its players, worked out under g.mu
+// and saved by settle once it's released.
+type roundPay struct {
+	mode   ModeID
+	stats  []roundStats
+	prizes []prize
+}
+
+type roundStats struct {
+	userID        string
+	kills, deaths int
+}
+
+type prize struct {
+	userID          string
+	coins, trophies int
+}
+
+// settle saves a finished round to the database, so callers release g.mu
+// first. A nil pay is a no-op.
+func (g *Game) settle(pay *roundPay) {
+	if pay == nil {
+		return
+	}
+	for _, s := range pay.stats {
+		if err := g.store.RecordShooterStats(s.userID, s.kills, s.deaths); err != nil {
+			log.Printf("[BOBIK] Saving stats for %s failed: %v", s.userID, err)
+		}
+	}
+	for _, w := range pay.prizes {
+		g.store.AdjustCoinsWithReason(w.userID, w.coins, data.ReasonBobikWin, string(pay.mode))
+		g.store.AdjustTrophies(w.userID, w.trophies)
+		g.store.IncrementMedalProgress(w.userID, "first_win", 1)
+		g.store.IncrementMedalProgress(w.userID, "ten_wins", 1)
+	}
+}
+
+// rewardable reports whether a finished round pays out: it needs an opponent
+// and at least one kill.
+func rewardable(participants, topKills int) bool {
+	return participants >= 2 && topKills > 0
 }
 
 func (g *Game) sendWelcome(p *Player) {
 	g.mu.Lock()
-	timeLeft := int(time.Until(g.roundEnds).Seconds())
-	if !g.roundActive {
-		timeLeft = 0
-	} else if timeLeft < 0 {
-		timeLeft = 0
-	}
+	timeLeft := g.timeLeft()
+	paused := g.paused
+	team := p.Team
 	g.mu.Unlock()
 
 	g.sendTo(p, map[string]interface{}{
-		"type": "welcome", "id": p.ID, "nickname": p.Nickname, "roundActive": g.roundActive,
-		"timeLeft": timeLeft, "score": p.Score, "dummies": g.dummies,
+		"type": "welcome", "id": p.ID, "room": g.ID, "nickname": p.Nickname, "roundActive": g.roundActive, "paused": paused,
+		"timeLeft": timeLeft, "score": p.Score, "dummies": g.dummies, "mode": g.mode,
+		"spectator": p.Spectator, "shop": g.catalog, "team": team,
 	})
 }
 
+// timeLeft is the round clock in whole seconds, frozen while paused.
+// Caller holds g.mu.
+func (g *Game) timeLeft() int {
+	if !g.roundActive {
+		return 0
+	}
+	left := time.Until(g.roundEnds)
+	if g.paused {
+		left = g.pausedLeft
+	}
+	if left < 0 {
+		return 0
+	}
+	return int(left.Seconds())
+}
+
 func (g *Game) buildState() map[string]interface{} {
-	timeLeft := 0
-	if g.roundActive {
-		timeLeft = int(time.Until(g.roundEnds).Seconds())
-		if timeLeft < 0 {
-			timeLeft = 0
-		}
+	timeLeft := g.timeLeft()
+	now := time.Now()
+	projectiles := make([]Projectile, 0, len(g.projectiles))
+	for _, pr := range g.projectiles {
+		projectiles = append(projectiles, *pr) // Copied, they move on after we unlock
 	}
 	plist := make([]map[string]interface{}, 0, len(g.players))
 	for p := range g.players {
 		plist = append(plist, map[string]interface{}{
 			"id": p.ID, "name": p.Nickname, "pos": p.Pos, "rotY": p.RotY,
 			"kills": p.Kills, "deaths": p.Deaths, "health": p.Health, "score": p.Score,
+			"gunLevel": p.GunLevel, "weapon": p.CurrentWeapon, "aiming": p.Aiming, "team": p.Team,
+			"protected": p.protected(now), "grenades": p.Grenades,
 		})
 	}
-	return map[string]interface{}{
-		"type": "state", "roundActive": g.roundActive,
-		"playerCount": len(g.players), "timeLeft": timeLeft, "players": plist,
+	state := map[string]interface{}{
+		"type": "state", "roundActive": g.roundActive, "paused": g.paused, "mode": g.mode.ID,
+		"playerCount": len(g.players), "spectatorCount": len(g.spectators),
+		"timeLeft": timeLeft, "players": plist, "projectiles": projectiles,
+	}
+	if g.mode.Teams {
+		state["teamKills"] = g.teamKills()
+	}
+	if !g.roundActive && time.Now().Before(g.holdUntil) {
+		state["scoreboard"] = g.lastScoreboard
+		state["winnerId"] = g.lastWinnerID
+		if g.mode.Teams {
+			state["winningTeam"] = g.lastWinTeam
+		}
 	}
+	return state
 }
 
+// broadcastJSON queues v for every client. It never blocks: callers often
+// hold g.mu, which run needs to fan out, and nothing drains the queue after
+// Stop. A message that finds the queue full is dropped, as a slow client's
+// would be.
 func (g *Game) broadcastJSON(v interface{}) {
 	data, _ := json.Marshal(v)
-	g.broadcast <- data
+	select {
+	case g.broadcast <- data:
+	case <-g.stop:
+	default:
+	}
 }
 
 func (g *Game) sendTo(p *Player, v interface{}) {
@@ -229,10 +506,6 @@ func (g *Game) sendTo(p *Player, v interface{}) {
 	}
 }
 
-func randomSpawn() Vec3 {
-	return Vec3{X: rand.Float64()*160 - 80, Y: 15, Z: rand.Float64()*160 - 80}
-}
-
 func generateDummies() []Vec3 {
 	// 5 random dummies
 	d := make([]Vec3, 5)
@@ -244,30 +517,20 @@ func generateDummies() []Vec3 {
 
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
-func (g *Game) HandleWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
-
-	userID := r.URL.Query().Get("userID")
-	// 1. Fetch real nickname from DB
-	nick := "Guest"
-	tag := 0
-	if userID != "" {
-		if u, ok := g.store.GetUser(userID); ok {
-			nick = u.Nickname
-			tag = u.Tag
-		}
-	}
+// serve plays p in this arena until their connection drops.
+func (g *Game) serve(p *Player) {
+	g.mu.Lock()
+	g.respawn(p)
+	p.Score = g.mode.StartingScore
+	g.mu.Unlock()
 
-	p := &Player{
-		ID: "b_" + uuid.NewString(), UserID: userID, Nickname: nick, Tag: tag,
-		Conn: conn, Send: make(chan []byte, 256),
-		Pos: randomSpawn(), Health: maxHealth, Score: 800,
+	select {
+	case g.register <- p:
+	case <-g.stop:
+		p.Conn.Close()
+		return
 	}
-
-	g.register <- p
+	g.store.SetActivity(p.UserID, data.ActivityBobik)
 	go g.writePump(p)
 	g.readPump(p)
 }
@@ -282,12 +545,22 @@ func (g *Game) writePump(p *Player) {
 }
 
 func (g *Game) readPump(p *Player) {
-	defer func() { g.unregister <- p; p.Conn.Close() }()
+	defer func() {
+		select {
+		case g.unregister <- p:
+		case <-g.stop:
+		}
+		g.store.ClearActivity(p.UserID, data.ActivityBobik)
+		p.Conn.Close()
+	}()
 	for {
 		_, data, err := p.Conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		if p.Spectator {
+			continue // Keep reading so we notice the disconnect
+		}
 		var msg map[string]interface{}
 		if err := json.Unmarshal(data, &msg); err != nil {
 			continue
@@ -296,8 +569,14 @@ func (g *Game) readPump(p *Player) {
 		switch msg["type"] {
 		case "update":
 			g.handleUpdate(p, msg)
+		case "shoot":
+			g.handleShoot(p, msg)
+		case "reload":
+			g.handleReload(p)
 		case "hit":
 			g.handleHit(p, msg)
+		case "throw_grenade":
+			g.handleThrowGrenade(p, msg)
 		case "buy":
 			g.handleBuy(p, msg)
 		case "hit_dummy":
@@ -318,6 +597,24 @@ func (g *Game) handleUpdate(p *Player, msg map[string]interface{}) {
 	if ry, ok := msg["rotY"].(float64); ok {
 		p.RotY = ry
 	}
+	if w, ok := msg["weapon"].(string); ok {
+		g.switchWeapon(p, w)
+	}
+	if aiming, ok := msg["aiming"].(bool); ok {
+		p.Aiming = aiming && p.CurrentWeapon == "awp"
+	}
+}
+
+// switchWeapon puts w in p's hands if they own it; gun game always hands
+// out the current ladder gun instead. Caller holds g.mu.
+func (g *Game) switchWeapon(p *Player, w string) {
+	w = normalizeWeapon(w)
+	if g.mode.ID == ModeGunGame && p.GunLevel < len(gunGameLadder) {
+		w = gunGameLadder[p.GunLevel] // Not the player's choice in gun game
+	}
+	if p.Owned[w] || g.mode.ID == ModeGunGame {
+		p.CurrentWeapon = w
+	}
 }
 
 // distance3D calculates Euclidean distance between two positions
@@ -345,9 +642,29 @@ func (g *Game) handleHit(attacker *Player, msg map[string]interface{}) {
 		}
 	}
 
-	if target == nil || target == attacker || !g.roundActive {
+	if target == nil || target == attacker || !g.roundActive || g.paused {
+		return
+	}
+	if g.friendly(attacker, target) {
+		return // No friendly fire unless the mode allows it
+	}
+	if target.protected(time.Now()) {
+		return // Just respawned
+	}
+
+	// Gun game: the server decides which gun you are holding
+	if g.mode.ID == ModeGunGame && attacker.GunLevel < len(gunGameLadder) {
+		weapon = gunGameLadder[attacker.GunLevel]
+		attacker.CurrentWeapon = weapon
+	} else if weapon = normalizeWeapon(weapon); weapon == "" {
+		weapon = attacker.CurrentWeapon
+	} else if weapon != attacker.CurrentWeapon {
+		// Claimed a gun they aren't holding (or don't own): drop the hit
 		return
 	}
+	if !attacker.takeShot(weapon) {
+		return // No round fired for this hit, e.g. the clip was empty
+	}
 
 	// Get weapon stats (default to pistol if unknown)
 	stats, ok := Weapons[weapon]
@@ -362,6 +679,10 @@ func (g *Game) handleHit(attacker *Player, msg map[string]interface{}) {
 	if dist > stats.MaxRange {
 		return
 	}
+	// No shooting through walls
+	if !g.lineOfSight(attacker.Pos, target.Pos) {
+		return
+	}
 
 	// Calculate damage with distance falloff
 	damage := float64(stats.BaseDamage) - (dist * stats.Falloff)
@@ -377,18 +698,16 @@ func (g *Game) handleHit(attacker *Player, msg map[string]interface{}) {
 	target.Health -= int(damage)
 
 	// Send hit feedback to attacker
-	g.sendTo(attacker, map[string]interface{}{
-		"type": "hit_confirm", "target": targetID, "damage": int(damage), "headshot": isHeadshot,
-	})
+	confirm := map[string]interface{}{
+		"type": "hit_confirm", "target": targetID, "damage": int(damage), "headshot": isHeadshot, "weapon": weapon,
+	}
+	if a := attacker.ammoFor(weapon); a != nil {
+		confirm["clip"], confirm["reserve"] = a.Clip, a.Reserve
+	}
+	g.sendTo(attacker, confirm)
 
 	if target.Health <= 0 {
-		target.Deaths++
-		attacker.Kills++
-		attacker.Score += 300
-		// IMMEDIATE RESPAWN
-		target.Health = maxHealth
-		target.Pos = randomSpawn()
-		target.Score += 100
+		g.recordKill(attacker, target, weapon, isHeadshot)
 	}
 }
 
@@ -406,33 +725,6 @@ func (g *Game) handleDummyHit(p *Player, dummyIdx int) {
 	}
 }
 
-func (g *Game) handleBuy(p *Player, msg map[string]interface{}) {
-	item, _ := msg["item"].(string)
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	cost := 0
-	switch item {
-	case "ammo":
-		cost = 200
-	case "deagle":
-		cost = 700
-	case "smg":
-		cost = 1200
-	case "shotgun":
-		cost = 1800
-	case "m4a4":
-		cost = 3100
-	case "awp":
-		cost = 4750
-	}
-
-	if cost > 0 && p.Score >= cost {
-		p.Score -= cost
-		g.sendTo(p, map[string]interface{}{"type": "buy_ack", "item": item, "success": true, "newScore": p.Score})
-	}
-}
-
 func toFloat(v interface{}) float64 {
 	switch t := v.(type) {
 	case float64:
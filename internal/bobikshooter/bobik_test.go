@@ -0,0 +1,118 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// broadcasts drains the room's queued broadcasts and returns their types.
+func broadcasts(g *Game) []string {
+	var types []string
+	for len(g.broadcast) > 0 {
+		var msg struct{ Type string }
+		json.Unmarshal(<-g.broadcast, &msg)
+		types = append(types, msg.Type)
+	}
+	return types
+}
+
+func TestEndRoundLeavesSavingToCaller(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa")) // No store: any write under g.mu would panic
+	a, b := testPlayer("a"), testPlayer("b")
+	a.UserID, b.UserID = "ua", "ub"
+	a.Kills, a.Deaths = 3, 1
+	b.Kills, b.Deaths = 1, 3
+	g.players[a], g.players[b] = true, true
+
+	g.mu.Lock()
+	pay := g.endRound()
+	g.mu.Unlock()
+
+	if len(pay.stats) != 2 {
+		t.Fatalf("stats for %d players, want both", len(pay.stats))
+	}
+	for _, s := range pay.stats {
+		if s.userID == "ua" && (s.kills != 3 || s.deaths != 1) {
+			t.Errorf("a's stats %+v, want 3/1", s)
+		}
+	}
+	if len(pay.prizes) != 1 || pay.prizes[0].userID != "ua" {
+		t.Errorf("prizes %+v, want one for the winner", pay.prizes)
+	}
+	g.settle(nil)
+}
+
+func TestDropToOnePausesRound(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	a, b := testPlayer("a"), testPlayer("b")
+	g.players[a], g.players[b] = true, true
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.startRound()
+	g.roundEnds = time.Now().Add(90 * time.Second)
+	broadcasts(g)
+
+	delete(g.players, b)
+	g.checkPause()
+	if !g.paused || !g.roundActive {
+		t.Fatalf("paused %v active %v with one player left", g.paused, g.roundActive)
+	}
+	if got := broadcasts(g); len(got) != 1 || got[0] != "round_paused" {
+		t.Fatalf("broadcast %v, want round_paused", got)
+	}
+
+	// The clock is frozen: the old deadline passing changes nothing
+	g.roundEnds = time.Now().Add(-time.Minute)
+	if left := g.timeLeft(); left < 89 || left > 90 {
+		t.Fatalf("%ds left while paused, want the 90 it stopped at", left)
+	}
+
+	g.players[b] = true
+	g.checkPause()
+	if g.paused || !g.roundActive {
+		t.Fatalf("paused %v active %v after a second player came back", g.paused, g.roundActive)
+	}
+	if left := time.Until(g.roundEnds); left < 89*time.Second {
+		t.Errorf("resumed with %v left, want the frozen 90s", left)
+	}
+	if got := broadcasts(g); len(got) != 1 || got[0] != "round_resumed" {
+		t.Errorf("broadcast %v, want round_resumed", got)
+	}
+
+	// Left alone past the timeout, the round is called off
+	delete(g.players, b)
+	g.checkPause()
+	g.pausedAt = time.Now().Add(-pauseTimeout - time.Second)
+	g.checkPause()
+	if g.roundActive || g.paused {
+		t.Errorf("active %v paused %v after the pause timed out", g.roundActive, g.paused)
+	}
+}
+
+func TestSoloRoundEndPaysNothing(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		kills []int
+	}{
+		{"alone", []int{5}},
+		{"nobody scored", []int{0, 0}},
+	} {
+		g := NewGame(nil, modeByID("ffa"))
+		for i, k := range tc.kills {
+			p := testPlayer(string(rune('a' + i)))
+			p.UserID, p.Kills = "u"+p.ID, k
+			g.players[p] = true
+		}
+		g.mu.Lock()
+		pay := g.endRound()
+		g.mu.Unlock()
+
+		if len(pay.prizes) != 0 {
+			t.Errorf("%s: prizes %+v, want none", tc.name, pay.prizes)
+		}
+		if len(pay.stats) != len(tc.kills) {
+			t.Errorf("%s: stats for %d players, want %d", tc.name, len(pay.stats), len(tc.kills))
+		}
+	}
+}
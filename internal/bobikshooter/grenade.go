@@ -0,0 +1,115 @@
+package bobikshooter
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Grenades are the one thing the server simulates itself: thrown along the
+// aim direction, pulled down by gravity, bounced off the floor and walls,
+// and blown up when the fuse runs out.
+const (
+	grenadesPerRound = 2
+	grenadeSpeed     = 45.0 // Units per second off the hand
+	grenadeGravity   = 40.0 // Units per second squared
+	grenadeFuse      = 2.0  // Seconds
+	grenadeRadius    = 18.0
+	grenadeDamage    = 100.0 // At the centre, falling off linearly to 0 at the edge
+	grenadeBounce    = 0.4   // Speed kept off a bounce
+
+	// Player positions are the camera; blasts measure to the chest below it
+	chestDrop = 3.0
+)
+
+// Projectile is a live grenade.
+type Projectile struct {
+	ID    string  `json:"id"`
+	Pos   Vec3    `json:"pos"`
+	Vel   Vec3    `json:"-"`
+	Fuse  float64 `json:"fuse"`
+	Owner *Player `json:"-"`
+}
+
+// handleThrowGrenade throws one of p's grenades along msg's "dir".
+func (g *Game) handleThrowGrenade(p *Player, msg map[string]interface{}) {
+	dirRaw, _ := msg["dir"].(map[string]interface{})
+	dir := Vec3{X: toFloat(dirRaw["x"]), Y: toFloat(dirRaw["y"]), Z: toFloat(dirRaw["z"])}
+	length := math.Sqrt(dir.X*dir.X + dir.Y*dir.Y + dir.Z*dir.Z)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.roundActive || g.paused || p.Grenades <= 0 || length == 0 || math.IsNaN(length) || math.IsInf(length, 0) {
+		return
+	}
+	p.Grenades--
+	g.grenadeSeq++
+	g.projectiles = append(g.projectiles, &Projectile{
+		ID:    fmt.Sprintf("g%d", g.grenadeSeq),
+		Pos:   p.Pos,
+		Vel:   Vec3{X: dir.X / length * grenadeSpeed, Y: dir.Y / length * grenadeSpeed, Z: dir.Z / length * grenadeSpeed},
+		Fuse:  grenadeFuse,
+		Owner: p,
+	})
+}
+
+// updateProjectiles moves every grenade one tick and detonates the ones whose
+// fuse is out. Caller holds g.mu.
+func (g *Game) updateProjectiles(dt float64) {
+	live := g.projectiles[:0]
+	for _, pr := range g.projectiles {
+		pr.Vel.Y -= grenadeGravity * dt
+		next := Vec3{X: pr.Pos.X + pr.Vel.X*dt, Y: pr.Pos.Y + pr.Vel.Y*dt, Z: pr.Pos.Z + pr.Vel.Z*dt}
+		if !g.lineOfSight(pr.Pos, next) {
+			// Hit a wall: knock it back rather than work out which face
+			pr.Vel = Vec3{X: -pr.Vel.X * grenadeBounce, Y: pr.Vel.Y, Z: -pr.Vel.Z * grenadeBounce}
+			next = pr.Pos
+		}
+		if next.Y < 0 {
+			next.Y = 0
+			pr.Vel = Vec3{X: pr.Vel.X * grenadeBounce, Y: -pr.Vel.Y * grenadeBounce, Z: pr.Vel.Z * grenadeBounce}
+		}
+		pr.Pos = next
+
+		pr.Fuse -= dt
+		if pr.Fuse <= 0 {
+			g.detonate(pr)
+			continue
+		}
+		live = append(live, pr)
+	}
+	g.projectiles = live
+}
+
+// detonate damages everyone within grenadeRadius of pr that has a clear line
+// to it, the thrower included, scaled down with distance. Caller holds g.mu.
+func (g *Game) detonate(pr *Projectile) {
+	g.broadcastJSON(map[string]interface{}{"type": "explosion", "id": pr.ID, "pos": pr.Pos, "radius": grenadeRadius})
+
+	now := time.Now()
+	owner := pr.Owner
+	if !g.players[owner] {
+		owner = nil // Left mid-fuse, so nobody gets the kills
+	}
+	for p := range g.players {
+		chest := Vec3{X: p.Pos.X, Y: p.Pos.Y - chestDrop, Z: p.Pos.Z}
+		dist := distance3D(pr.Pos, chest)
+		if dist > grenadeRadius || !g.lineOfSight(pr.Pos, chest) {
+			continue
+		}
+		if p.protected(now) || (owner != nil && p != owner && g.friendly(owner, p)) {
+			continue
+		}
+		damage := int(grenadeDamage * (1 - dist/grenadeRadius))
+		if damage <= 0 {
+			continue
+		}
+		p.Health -= damage
+		if owner != nil && p != owner {
+			g.sendTo(owner, map[string]interface{}{"type": "hit_confirm", "target": p.ID, "damage": damage, "weapon": "grenade"})
+		}
+		if p.Health <= 0 {
+			g.recordKill(owner, p, "grenade", false)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bobikshooter
+
+import "testing"
+
+func TestGrenadeFalloff(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	g.obstacles = nil
+	thrower, near, far := testPlayer("thrower"), testPlayer("near"), testPlayer("far")
+	for _, p := range []*Player{thrower, near, far} {
+		g.players[p] = true
+	}
+	blast := Vec3{X: 0, Y: 1, Z: 0}
+	thrower.Pos = Vec3{X: 100, Y: 1 + chestDrop, Z: 0} // Out of range
+	near.Pos = Vec3{X: 3, Y: 1 + chestDrop, Z: 0}
+	far.Pos = Vec3{X: 12, Y: 1 + chestDrop, Z: 0}
+
+	g.detonate(&Projectile{ID: "g1", Pos: blast, Owner: thrower})
+
+	nearHit, farHit := maxHealth-near.Health, maxHealth-far.Health
+	if nearHit != 83 { // 100 × (1 - 3/18)
+		t.Errorf("near took %d, want 83", nearHit)
+	}
+	if farHit != 33 { // 100 × (1 - 12/18)
+		t.Errorf("far took %d, want 33", farHit)
+	}
+	if farHit <= 0 || farHit >= nearHit {
+		t.Errorf("damage not scaled with distance: near %d, far %d", nearHit, farHit)
+	}
+	if thrower.Health != maxHealth {
+		t.Errorf("thrower outside the radius took %d", maxHealth-thrower.Health)
+	}
+}
+
+func TestGrenadeHurtsThrower(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	g.obstacles = nil
+	thrower := testPlayer("thrower")
+	g.players[thrower] = true
+	thrower.Pos = Vec3{X: 0, Y: 1 + chestDrop, Z: 0}
+
+	g.detonate(&Projectile{ID: "g1", Pos: Vec3{X: 2, Y: 1}, Owner: thrower})
+	if thrower.Health >= maxHealth {
+		t.Error("the thrower's own grenade didn't hurt them")
+	}
+}
+
+func TestGrenadeBlockedByWall(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	g.obstacles = []Obstacle{{X: 4.5, Z: 0, W: 1, D: 20, H: 10}}
+	behind := testPlayer("behind")
+	g.players[behind] = true
+	behind.Pos = Vec3{X: 8, Y: 1 + chestDrop, Z: 0}
+
+	g.detonate(&Projectile{ID: "g1", Pos: Vec3{Y: 1}, Owner: nil})
+	if behind.Health != maxHealth {
+		t.Errorf("player behind a wall took %d", maxHealth-behind.Health)
+	}
+}
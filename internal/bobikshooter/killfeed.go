@@ -0,0 +1,52 @@
+package bobikshooter
+
+import "time"
+
+// streakBonuses is the extra Score for reaching a number of kills in a row
+// without dying.
+var streakBonuses = map[int]int{
+	3:  100,
+	5:  250,
+	10: 500,
+}
+
+// recordKill settles attacker killing target: stats, streaks, the "kill"
+// event for the feed, target's respawn and whether the kill ends the round.
+// A nil attacker, or target itself, is a suicide and credits nobody.
+// Caller holds g.mu.
+func (g *Game) recordKill(attacker, target *Player, weapon string, headshot bool) {
+	target.Deaths++
+	target.Streak = 0
+	event := map[string]interface{}{
+		"type": "kill", "victimId": target.ID, "victim": target.Nickname, "weapon": weapon, "headshot": headshot,
+	}
+	// IMMEDIATE RESPAWN
+	g.respawn(target)
+	if attacker == nil || attacker == target {
+		event["killerId"], event["killer"] = target.ID, target.Nickname
+		g.broadcastJSON(event)
+		return
+	}
+
+	target.Score += 100
+	attacker.Kills++
+	attacker.Score += 300
+	attacker.Streak++
+	bonus := streakBonuses[attacker.Streak]
+	attacker.Score += bonus
+	event["killerId"], event["killer"] = attacker.ID, attacker.Nickname
+	event["streak"], event["streakBonus"] = attacker.Streak, bonus
+	g.broadcastJSON(event)
+
+	progress := attacker.Kills
+	if g.mode.Teams && attacker.Team != TeamNone {
+		progress = g.teamKills()[attacker.Team]
+	} else if g.mode.ID == ModeGunGame {
+		attacker.GunLevel++
+		progress = attacker.GunLevel
+	}
+	// Reaching the mode's target ends the round on the next state tick
+	if g.mode.ScoreToWin > 0 && progress >= g.mode.ScoreToWin {
+		g.roundEnds = time.Now()
+	}
+}
@@ -0,0 +1,45 @@
+package bobikshooter
+
+import "testing"
+
+func TestStreakBonuses(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	killer, victim := testPlayer("killer"), testPlayer("victim")
+	g.players[killer], g.players[victim] = true, true
+
+	want := map[int]int{1: 300, 2: 300, 3: 400, 4: 300, 5: 550}
+	for kill := 1; kill <= 5; kill++ {
+		before := killer.Score
+		g.recordKill(killer, victim, "rifle", false)
+		if got := killer.Score - before; got != want[kill] {
+			t.Errorf("kill %d paid %d, want %d", kill, got, want[kill])
+		}
+	}
+	if killer.Streak != 5 {
+		t.Fatalf("streak %d after 5 kills, want 5", killer.Streak)
+	}
+
+	g.recordKill(victim, killer, "rifle", false)
+	if killer.Streak != 0 {
+		t.Fatalf("streak %d after dying, want 0", killer.Streak)
+	}
+	before := killer.Score
+	for i := 0; i < 3; i++ {
+		g.recordKill(killer, victim, "rifle", false)
+	}
+	if got := killer.Score - before; got != 3*300+100 {
+		t.Errorf("three kills after a death paid %d, want %d", got, 3*300+100)
+	}
+}
+
+func TestSuicideCreditsNobody(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	p := testPlayer("p")
+	g.players[p] = true
+	p.Streak = 2
+
+	g.recordKill(nil, p, "grenade", false)
+	if p.Kills != 0 || p.Deaths != 1 || p.Streak != 0 {
+		t.Errorf("after suicide kills %d deaths %d streak %d, want 0 1 0", p.Kills, p.Deaths, p.Streak)
+	}
+}
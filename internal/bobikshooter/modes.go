@@ -0,0 +1,91 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ModeID identifies a bobik match ruleset
+type ModeID string
+
+const (
+	ModeFFA     ModeID = "ffa"
+	ModeTDM     ModeID = "tdm"
+	ModeGunGame ModeID = "gungame"
+)
+
+// Mode holds the per-match rules. Each room keeps the mode it was opened
+// with, and players are routed to a room playing the mode they asked for.
+type Mode struct {
+	ID            ModeID        `json:"id"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	Teams         bool          `json:"teams"`         // Players are split into two sides
+	FriendlyFire  bool          `json:"friendlyFire"`  // Teammates can damage each other
+	ScoreToWin    int           `json:"scoreToWin"`    // Kills (or gun-game levels) that end the round early, 0 = timer only
+	StartingScore int           `json:"startingScore"` // Buy-menu money each player starts the round with
+	TimeLimit     time.Duration `json:"-"`
+	TimeLimitSec  int           `json:"timeLimit"`
+}
+
+// gunGameLadder is the weapon order in gun-game; a kill advances you one step
+var gunGameLadder = []string{"smg", "shotgun", "rifle", "m4a4", "awp", "deagle", "pistol", "knife"}
+
+var Modes = map[ModeID]Mode{
+	ModeFFA: {
+		ID:            ModeFFA,
+		Name:          "Free For All",
+		Description:   "Everyone for themselves. Most kills wins.",
+		ScoreToWin:    30,
+		StartingScore: 800,
+		TimeLimit:     roundDuration,
+	},
+	ModeTDM: {
+		ID:            ModeTDM,
+		Name:          "Team Deathmatch",
+		Description:   "Two teams, shared kill count.",
+		Teams:         true,
+		ScoreToWin:    50,
+		StartingScore: 800,
+		TimeLimit:     roundDuration,
+	},
+	ModeGunGame: {
+		ID:            ModeGunGame,
+		Name:          "Gun Game",
+		Description:   "Every kill swaps your weapon. Finish the ladder to win.",
+		ScoreToWin:    len(gunGameLadder),
+		StartingScore: 800,
+		TimeLimit:     240 * time.Second,
+	},
+}
+
+// The wire copy of each time limit is filled in once, so every place that
+// sends a mode (the picker, welcome) carries it.
+func init() {
+	for id, m := range Modes {
+		m.TimeLimitSec = int(m.TimeLimit.Seconds())
+		Modes[id] = m
+	}
+}
+
+// modeByID falls back to FFA for unknown or empty ids
+func modeByID(id string) Mode {
+	if m, ok := Modes[ModeID(id)]; ok {
+		return m
+	}
+	return Modes[ModeFFA]
+}
+
+// ModesHandler lists the available modes so the lobby can render a picker
+func ModesHandler(w http.ResponseWriter, r *http.Request) {
+	list := make([]Mode, 0, len(Modes))
+	for _, m := range Modes {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,54 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModeByID(t *testing.T) {
+	if m := modeByID("tdm"); m.ID != ModeTDM || !m.Teams {
+		t.Errorf("tdm resolved to %+v", m)
+	}
+	for _, id := range []string{"", "nonsense"} {
+		if m := modeByID(id); m.ID != ModeFFA {
+			t.Errorf("%q resolved to %s, want ffa", id, m.ID)
+		}
+	}
+}
+
+func TestModesCarryTimeLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ModesHandler(rec, httptest.NewRequest("GET", "/bobik/modes", nil))
+	var list []Mode
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(Modes) {
+		t.Fatalf("%d modes listed, want %d", len(list), len(Modes))
+	}
+	for _, m := range list {
+		if m.TimeLimitSec != int(Modes[m.ID].TimeLimit.Seconds()) || m.TimeLimitSec == 0 {
+			t.Errorf("%s lists timeLimit %d", m.ID, m.TimeLimitSec)
+		}
+	}
+}
+
+func TestWelcomeCarriesTimeLimit(t *testing.T) {
+	g := NewGame(nil, modeByID("gungame"))
+	p := testPlayer("p")
+	g.sendWelcome(p)
+
+	var welcome struct {
+		Mode struct {
+			ID        ModeID `json:"id"`
+			TimeLimit int    `json:"timeLimit"`
+		} `json:"mode"`
+	}
+	if err := json.Unmarshal(<-p.Send, &welcome); err != nil {
+		t.Fatal(err)
+	}
+	if welcome.Mode.ID != ModeGunGame || welcome.Mode.TimeLimit != 240 {
+		t.Fatalf("welcome mode %+v, want gungame with a 240s limit", welcome.Mode)
+	}
+}
@@ -0,0 +1,202 @@
+package bobikshooter
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"main/internal/auth"
+	"main/internal/data"
+
+	"github.com/google/uuid"
+)
+
+// RoomCapacity is the most players one arena takes. Spectators don't count.
+const RoomCapacity = 8
+
+// Rooms runs as many arenas as it takes to seat everyone. Each room keeps the
+// mode it was opened with, runs its own rounds and closes once its last
+// player leaves.
+type Rooms struct {
+	mu      sync.Mutex
+	store   *data.Store
+	rooms   map[string]*Game
+	playing map[*Game]int // Non-spectators routed into each room and not yet gone
+	roomSeq int
+
+	// What new rooms are opened with
+	catalog   []ShopItem
+	obstacles []Obstacle
+	spawns    []Vec3
+}
+
+func NewRooms(store *data.Store) *Rooms {
+	return &Rooms{
+		store:     store,
+		rooms:     make(map[string]*Game),
+		playing:   make(map[*Game]int),
+		catalog:   defaultCatalog,
+		obstacles: defaultObstacles,
+		spawns:    defaultSpawns,
+	}
+}
+
+// LoadCatalog replaces the buy menu in every room with the items in path.
+// The old catalog stays in place if the file is missing or invalid.
+func (m *Rooms) LoadCatalog(path string) error {
+	items, err := readCatalog(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.catalog = items
+	for _, g := range m.rooms {
+		g.mu.Lock()
+		g.catalog = items
+		g.mu.Unlock()
+	}
+	log.Printf("[BOBIK] Loaded %d shop items from %s", len(items), path)
+	return nil
+}
+
+// LoadMap replaces the obstacles and spawn points in every room with the
+// ones in path. The old map stays in place if the file is missing or invalid.
+func (m *Rooms) LoadMap(path string) error {
+	obstacles, spawns, err := readMap(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.obstacles, m.spawns = obstacles, spawns
+	for _, g := range m.rooms {
+		g.mu.Lock()
+		g.obstacles, g.spawns = obstacles, spawns
+		g.mu.Unlock()
+	}
+	log.Printf("[BOBIK] Loaded %d obstacles and %d spawns from %s", len(obstacles), len(spawns), path)
+	return nil
+}
+
+// HandleWS seats the caller in a room and plays them there until they
+// disconnect. ?mode= picks the ruleset, ?room= asks for a specific room and
+// ?spectate=1 watches instead of playing.
+func (m *Rooms) HandleWS(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
+		return
+	}
+	u, ok := m.store.GetUser(userID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	p := &Player{
+		ID: "b_" + uuid.NewString(), UserID: userID, Nickname: u.Nickname, Tag: u.Tag,
+		Conn: conn, Send: make(chan []byte, 256),
+		Health: maxHealth, Team: TeamNone,
+		Spectator: r.URL.Query().Get("spectate") == "1",
+	}
+	p.resetLoadout()
+
+	g := m.join(p, modeByID(r.URL.Query().Get("mode")), r.URL.Query().Get("room"))
+	g.serve(p)
+	m.leave(p, g)
+}
+
+// join picks p's room: roomID if it exists and has space, otherwise the
+// fullest open room playing mode, otherwise a new one. Spectators go to the
+// busiest room of any mode.
+func (m *Rooms) join(p *Player, mode Mode, roomID string) *Game {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g := m.rooms[roomID]
+	if g != nil && !p.Spectator && m.playing[g] >= RoomCapacity {
+		g = nil
+	}
+	if g == nil {
+		for _, room := range m.rooms {
+			if !p.Spectator && (room.mode.ID != mode.ID || m.playing[room] >= RoomCapacity) {
+				continue
+			}
+			if g == nil || m.playing[room] > m.playing[g] {
+				g = room
+			}
+		}
+	}
+	if g == nil {
+		g = m.openRoom(mode)
+	}
+
+	if !p.Spectator {
+		m.playing[g]++
+	}
+	return g
+}
+
+// leave gives up p's seat in g, closing the room once no players are left.
+// Spectators don't keep a room open; they are dropped when it closes. p must
+// already be unregistered from g.
+func (m *Rooms) leave(p *Player, g *Game) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rooms[g.ID] != g {
+		return // Closed under a spectator
+	}
+	if !p.Spectator {
+		m.playing[g]--
+	}
+	if m.playing[g] > 0 {
+		return
+	}
+	delete(m.playing, g)
+	delete(m.rooms, g.ID)
+	g.Stop()
+	g.dropSpectators()
+	log.Printf("[BOBIK] Closed room %s (%d open)", g.ID, len(m.rooms))
+}
+
+// openRoom starts a new empty room playing mode. Caller holds m.mu.
+func (m *Rooms) openRoom(mode Mode) *Game {
+	m.roomSeq++
+	g := NewGame(m.store, mode)
+	g.ID = fmt.Sprintf("bobik-%d", m.roomSeq)
+	g.catalog = m.catalog
+	g.obstacles, g.spawns = m.obstacles, m.spawns
+	m.rooms[g.ID] = g
+	g.Start()
+	log.Printf("[BOBIK] Opened %s room %s (%d open)", mode.ID, g.ID, len(m.rooms))
+	return g
+}
+
+// Snapshot adds up every room for the lobby. State is the mode of the
+// busiest room.
+func (m *Rooms) Snapshot() data.LiveStatus {
+	m.mu.Lock()
+	rooms := make([]*Game, 0, len(m.rooms))
+	for _, g := range m.rooms {
+		rooms = append(rooms, g)
+	}
+	m.mu.Unlock()
+
+	var status data.LiveStatus
+	busiest := -1
+	for _, g := range rooms {
+		s := g.Snapshot()
+		status.Players += s.Players
+		status.InMatch = status.InMatch || s.InMatch
+		if s.Players > busiest {
+			busiest = s.Players
+			status.State = s.State
+		}
+	}
+	return status
+}
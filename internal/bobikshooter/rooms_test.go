@@ -0,0 +1,141 @@
+package bobikshooter
+
+import "testing"
+
+func testPlayer(name string) *Player {
+	p := &Player{ID: name, Nickname: name, Send: make(chan []byte, 256), Health: maxHealth, Team: TeamNone}
+	p.resetLoadout()
+	return p
+}
+
+func stateIDs(g *Game) map[string]bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ids := make(map[string]bool)
+	for _, p := range g.buildState()["players"].([]map[string]interface{}) {
+		ids[p["id"].(string)] = true
+	}
+	return ids
+}
+
+func TestRoomsRunIndependently(t *testing.T) {
+	m := NewRooms(nil)
+	var first []*Player
+	a := m.join(testPlayer("a0"), modeByID("ffa"), "")
+	for i := 1; i < RoomCapacity; i++ {
+		if g := m.join(testPlayer("a"), modeByID("ffa"), ""); g != a {
+			t.Fatalf("player %d went to %s, want %s while it has space", i, g.ID, a.ID)
+		}
+	}
+	b := m.join(testPlayer("b0"), modeByID("ffa"), "")
+	if b == a {
+		t.Fatal("a full room took another player")
+	}
+	defer func() {
+		a.Stop()
+		b.Stop()
+	}()
+
+	// Seat two players in each and start both rounds
+	for _, g := range []*Game{a, b} {
+		g.mu.Lock()
+		for _, name := range []string{g.ID + "-x", g.ID + "-y"} {
+			p := testPlayer(name)
+			first = append(first, p)
+			g.players[p] = true
+		}
+		g.startRound()
+		g.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	a.roundActive = false
+	a.mu.Unlock()
+	b.mu.Lock()
+	active := b.roundActive
+	b.mu.Unlock()
+	if !active {
+		t.Fatal("ending room A's round ended room B's")
+	}
+
+	aIDs, bIDs := stateIDs(a), stateIDs(b)
+	for _, p := range first {
+		if aIDs[p.ID] && bIDs[p.ID] {
+			t.Errorf("%s shows up in both rooms", p.ID)
+		}
+	}
+	if !aIDs[a.ID+"-x"] || bIDs[a.ID+"-x"] {
+		t.Errorf("room A's player in state: A %v, B %v", aIDs[a.ID+"-x"], bIDs[a.ID+"-x"])
+	}
+}
+
+func TestRoomClosesWithLastPlayer(t *testing.T) {
+	m := NewRooms(nil)
+	player := testPlayer("p")
+	g := m.join(player, modeByID("ffa"), "")
+
+	spectator := testPlayer("s")
+	spectator.Spectator = true
+	if m.join(spectator, modeByID(""), "") != g {
+		t.Fatal("spectator wasn't sent to the only room")
+	}
+	g.mu.Lock()
+	g.spectators[spectator] = true
+	g.mu.Unlock()
+
+	m.leave(player, g)
+	if len(m.rooms) != 0 {
+		t.Fatalf("%d rooms open after the last player left, want 0", len(m.rooms))
+	}
+	select {
+	case <-g.stop:
+	default:
+		t.Error("room wasn't stopped")
+	}
+	if _, open := <-spectator.Send; open {
+		t.Error("spectator is still connected to the closed room")
+	}
+
+	// The spectator's own leave afterwards is a no-op
+	m.leave(spectator, g)
+	if len(m.rooms) != 0 || len(m.playing) != 0 {
+		t.Errorf("spectator leave reopened bookkeeping: rooms %d, playing %d", len(m.rooms), len(m.playing))
+	}
+}
+
+func TestBroadcastDoesNotBlock(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	g.mu.Lock()
+	for i := 0; i < cap(g.broadcast)+10; i++ {
+		g.broadcastJSON(map[string]interface{}{"type": "ping"}) // Nothing drains the queue
+	}
+	g.mu.Unlock()
+
+	g.Stop()
+	g.broadcastJSON(map[string]interface{}{"type": "ping"})
+}
+
+func TestRoomsSnapshotAddsUp(t *testing.T) {
+	m := NewRooms(nil)
+	var rooms []*Game
+	for i := 0; i < RoomCapacity+2; i++ {
+		p := testPlayer("p")
+		g := m.join(p, modeByID("ffa"), "")
+		g.mu.Lock()
+		g.players[p] = true
+		g.mu.Unlock()
+		if len(rooms) == 0 || rooms[len(rooms)-1] != g {
+			rooms = append(rooms, g)
+		}
+	}
+	defer func() {
+		for _, g := range rooms {
+			g.Stop()
+		}
+	}()
+
+	s := m.Snapshot()
+	if s.Players != RoomCapacity+2 || s.InMatch || s.State != "ffa" {
+		t.Fatalf("snapshot %+v, want %d players in ffa, no round", s, RoomCapacity+2)
+	}
+}
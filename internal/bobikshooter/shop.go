@@ -0,0 +1,112 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Buy-menu effects. A weapon purchase adds it to the player's owned set, an
+// ammo purchase refills the gun they're holding.
+const (
+	EffectWeapon = "weapon"
+	EffectAmmo   = "ammo"
+)
+
+// ShopItem is one entry of the in-round buy menu.
+type ShopItem struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"` // secondary, primary, utility; the client groups by it
+	Cost        int    `json:"cost"`
+	Effect      string `json:"effect"`
+}
+
+// defaultCatalog is used until LoadCatalog succeeds, so the shop still works
+// without the config file.
+var defaultCatalog = []ShopItem{
+	{ID: "deagle", Name: "Desert Eagle", Description: "High damage pistol. 55 DMG", Category: "secondary", Cost: 700, Effect: EffectWeapon},
+	{ID: "smg", Name: "P90 SMG", Description: "Fast fire rate, 50 rounds. 18 DMG", Category: "primary", Cost: 1200, Effect: EffectWeapon},
+	{ID: "shotgun", Name: "XM1014 Shotgun", Description: "Devastating close range. 90 DMG", Category: "primary", Cost: 1800, Effect: EffectWeapon},
+	{ID: "m4a4", Name: "M4A4", Description: "Accurate rifle. 33 DMG", Category: "primary", Cost: 3100, Effect: EffectWeapon},
+	{ID: "awp", Name: "AWP Sniper", Description: "One shot, one kill. 115 DMG", Category: "primary", Cost: 4750, Effect: EffectWeapon},
+	{ID: "ammo", Name: "Refill Ammo", Description: "Max out current clip & reserve", Category: "utility", Cost: 200, Effect: EffectAmmo},
+}
+
+// readCatalog loads and checks the buy menu in path.
+func readCatalog(path string) ([]ShopItem, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Items []ShopItem `json:"items"`
+	}
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(data.Items) == 0 {
+		return nil, fmt.Errorf("%s: no items defined", path)
+	}
+	seen := map[string]bool{}
+	for _, it := range data.Items {
+		if seen[it.ID] {
+			return nil, fmt.Errorf("%s: duplicate item %q", path, it.ID)
+		}
+		seen[it.ID] = true
+		if it.Cost <= 0 {
+			return nil, fmt.Errorf("%s: item %q has non-positive cost %d", path, it.ID, it.Cost)
+		}
+		switch it.Effect {
+		case EffectWeapon:
+			if _, ok := Weapons[it.ID]; !ok {
+				return nil, fmt.Errorf("%s: item %q is not a known weapon", path, it.ID)
+			}
+		case EffectAmmo:
+		default:
+			return nil, fmt.Errorf("%s: item %q has unknown effect %q", path, it.ID, it.Effect)
+		}
+	}
+	return data.Items, nil
+}
+
+// shopItem looks up an item in the current catalog. Caller holds g.mu.
+func (g *Game) shopItem(id string) (ShopItem, bool) {
+	for _, it := range g.catalog {
+		if it.ID == id {
+			return it, true
+		}
+	}
+	return ShopItem{}, false
+}
+
+func (g *Game) handleBuy(p *Player, msg map[string]interface{}) {
+	id, _ := msg["item"].(string)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	item, ok := g.shopItem(id)
+	if !ok {
+		g.sendTo(p, map[string]interface{}{"type": "buy_ack", "item": id, "success": false, "reason": "unknown_item"})
+		return
+	}
+	if p.Score < item.Cost {
+		g.sendTo(p, map[string]interface{}{"type": "buy_ack", "item": id, "success": false, "reason": "insufficient_funds"})
+		return
+	}
+
+	p.Score -= item.Cost
+	ack := map[string]interface{}{"type": "buy_ack", "item": id, "effect": item.Effect, "success": true, "newScore": p.Score}
+	switch item.Effect {
+	case EffectWeapon:
+		p.Owned[item.ID] = true
+		delete(p.Ammo, item.ID) // Comes fully loaded
+	case EffectAmmo:
+		delete(p.Ammo, p.CurrentWeapon)
+		if a := p.ammoFor(p.CurrentWeapon); a != nil {
+			ack["weapon"], ack["clip"], ack["reserve"] = p.CurrentWeapon, a.Clip, a.Reserve
+		}
+	}
+	g.sendTo(p, ack)
+}
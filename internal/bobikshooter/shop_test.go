@@ -0,0 +1,54 @@
+package bobikshooter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type buyAck struct {
+	Type     string `json:"type"`
+	Item     string `json:"item"`
+	Success  bool   `json:"success"`
+	Reason   string `json:"reason"`
+	NewScore int    `json:"newScore"`
+}
+
+func buy(t *testing.T, g *Game, p *Player, item string) buyAck {
+	t.Helper()
+	g.handleBuy(p, map[string]interface{}{"type": "buy", "item": item})
+	var ack buyAck
+	if err := json.Unmarshal(<-p.Send, &ack); err != nil || ack.Type != "buy_ack" {
+		t.Fatalf("reply %+v, %v", ack, err)
+	}
+	return ack
+}
+
+func TestBuyUnknownItemFails(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	p := testPlayer("p")
+	p.Score = 10000
+
+	ack := buy(t, g, p, "railgun")
+	if ack.Success || ack.Reason != "unknown_item" || ack.Item != "railgun" {
+		t.Fatalf("ack %+v, want a failed unknown_item", ack)
+	}
+	if p.Score != 10000 || p.Owned["railgun"] {
+		t.Errorf("score %d owned %v after a failed buy", p.Score, p.Owned)
+	}
+}
+
+func TestBuyChargesFromCatalog(t *testing.T) {
+	g := NewGame(nil, modeByID("ffa"))
+	p := testPlayer("p")
+	awp, _ := g.shopItem("awp")
+
+	p.Score = awp.Cost - 1
+	if ack := buy(t, g, p, "awp"); ack.Success || ack.Reason != "insufficient_funds" || p.Score != awp.Cost-1 {
+		t.Fatalf("short by one: ack %+v score %d", ack, p.Score)
+	}
+
+	p.Score = awp.Cost + 50
+	if ack := buy(t, g, p, "awp"); !ack.Success || ack.NewScore != 50 || p.Score != 50 || !p.Owned["awp"] {
+		t.Fatalf("buy: ack %+v score %d owned %v", ack, p.Score, p.Owned)
+	}
+}
@@ -0,0 +1,112 @@
+package bobikshooter
+
+// Team sides for modes with Teams set. Everyone is TeamNone in FFA and gun
+// game, and spectators always are.
+const (
+	TeamNone = -1
+	TeamRed  = 0
+	TeamBlue = 1
+)
+
+// The winning side of a team round shares these between its members, so a
+// 2v2 pays each winner twice what a 4v4 does.
+const (
+	teamWinCoins    = 300
+	teamWinTrophies = 60
+)
+
+// assignTeam puts p on whichever side is short a player, red on a tie.
+// Caller holds g.mu.
+func (g *Game) assignTeam(p *Player) {
+	if !g.mode.Teams || p.Spectator {
+		p.Team = TeamNone
+		return
+	}
+	size := g.teamSizes(p)
+	p.Team = TeamRed
+	if size[TeamBlue] < size[TeamRed] {
+		p.Team = TeamBlue
+	}
+}
+
+// balanceTeams evens the sides back out before a round, since leavers can
+// leave one team stacked. Caller holds g.mu.
+func (g *Game) balanceTeams() {
+	for p := range g.players {
+		if !g.mode.Teams {
+			p.Team = TeamNone
+		} else if p.Team != TeamRed && p.Team != TeamBlue {
+			g.assignTeam(p)
+		}
+	}
+	if !g.mode.Teams {
+		return
+	}
+	for p := range g.players {
+		size := g.teamSizes(nil)
+		big, small := TeamRed, TeamBlue
+		if size[TeamBlue] > size[TeamRed] {
+			big, small = TeamBlue, TeamRed
+		}
+		if size[big]-size[small] <= 1 {
+			return
+		}
+		if p.Team == big {
+			p.Team = small
+		}
+	}
+}
+
+// teamSizes counts each side's players, leaving out skip.
+// Caller holds g.mu.
+func (g *Game) teamSizes(skip *Player) [2]int {
+	var size [2]int
+	for p := range g.players {
+		if p != skip && (p.Team == TeamRed || p.Team == TeamBlue) {
+			size[p.Team]++
+		}
+	}
+	return size
+}
+
+// teamKills is each side's combined kill count. Caller holds g.mu.
+func (g *Game) teamKills() [2]int {
+	var kills [2]int
+	for p := range g.players {
+		if p.Team == TeamRed || p.Team == TeamBlue {
+			kills[p.Team] += p.Kills
+		}
+	}
+	return kills
+}
+
+// friendly reports whether a's hits on b should be dropped.
+func (g *Game) friendly(a, b *Player) bool {
+	return g.mode.Teams && !g.mode.FriendlyFire && a.Team != TeamNone && a.Team == b.Team
+}
+
+// payTeam decides a team round and adds the winners' shares to pay. A draw
+// returns TeamNone and pays nobody. Caller holds g.mu.
+func (g *Game) payTeam(pay *roundPay) (winningTeam int, kills [2]int) {
+	kills = g.teamKills()
+	winningTeam = TeamNone
+	switch {
+	case kills[TeamRed] > kills[TeamBlue]:
+		winningTeam = TeamRed
+	case kills[TeamBlue] > kills[TeamRed]:
+		winningTeam = TeamBlue
+	}
+	if winningTeam == TeamNone || !rewardable(len(g.players), kills[winningTeam]) {
+		return winningTeam, kills
+	}
+
+	members := g.teamSizes(nil)[winningTeam]
+	coins, trophies := teamWinCoins/members, teamWinTrophies/members
+	for p := range g.players {
+		if p.Team != winningTeam {
+			continue
+		}
+		pay.prizes = append(pay.prizes, prize{userID: p.UserID, coins: coins, trophies: trophies})
+	}
+	return winningTeam, kills
+}
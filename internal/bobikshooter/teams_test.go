@@ -0,0 +1,48 @@
+package bobikshooter
+
+import (
+	"testing"
+	"time"
+)
+
+// duel is a round under way in mode with no walls, each player 5 apart from
+// the next along Z and none of them spawn protected.
+func duel(mode ModeID, names ...string) (*Game, []*Player) {
+	g := NewGame(nil, modeByID(string(mode)))
+	g.obstacles = nil
+	g.roundActive = true
+	players := make([]*Player, len(names))
+	for i, name := range names {
+		p := testPlayer(name)
+		p.Pos = Vec3{X: 0, Y: 1, Z: float64(5 * i)}
+		g.players[p] = true
+		players[i] = p
+	}
+	return g, players
+}
+
+// shoot fires attacker's gun at target and reports the damage that landed.
+func shoot(g *Game, attacker, target *Player) int {
+	before := target.Health
+	attacker.fire(time.Now())
+	g.handleHit(attacker, map[string]interface{}{"target": target.ID, "weapon": attacker.CurrentWeapon})
+	return before - target.Health
+}
+
+func TestNoFriendlyFireInTeamDeathmatch(t *testing.T) {
+	g, p := duel(ModeTDM, "red1", "red2", "blue")
+	p[0].Team, p[1].Team, p[2].Team = TeamRed, TeamRed, TeamBlue
+
+	if dealt := shoot(g, p[0], p[1]); dealt != 0 {
+		t.Fatalf("shooting a teammate dealt %d", dealt)
+	}
+	if dealt := shoot(g, p[0], p[2]); dealt <= 0 {
+		t.Fatal("shooting the other team dealt nothing")
+	}
+
+	// Free-for-all has no teammates
+	g, p = duel(ModeFFA, "a", "b")
+	if dealt := shoot(g, p[0], p[1]); dealt <= 0 {
+		t.Error("a free-for-all hit dealt nothing")
+	}
+}
@@ -9,11 +9,25 @@ import (
 	"sync"
 	"time"
 
+	"main/internal/auth"
+
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 var DB *sql.DB
 
+// FriendsOnlyDMs restricts direct messages to accepted friends when enabled.
+// AreFriends must be set (main wires it to the store) for the check to apply.
+var (
+	FriendsOnlyDMs bool
+	AreFriends     func(a, b string) bool
+)
+
+// CanDM applies the recipient's own DM setting on top of FriendsOnlyDMs.
+// Main wires it to the store; nil lets everything through.
+var CanDM func(from, to string) bool
+
 // TTL Protocol Constants
 const (
 	MessageTTL      = 24 * time.Hour
@@ -62,19 +76,30 @@ type Client struct {
 	Send   chan []byte
 }
 
+// Hub tracks every open socket per user, so someone signed in on a phone and
+// a laptop gets their messages on both.
 type Hub struct {
-	clients    map[string]*Client
+	clients    map[string]map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan Message
 	mu         sync.Mutex
+
+	// Cross-instance delivery, see fanout.go
+	origin string  // Tags our own notifications so we don't deliver them twice
+	notify *sql.DB // Where to publish, nil until listen
 }
 
-var MainHub = &Hub{
-	clients:    make(map[string]*Client),
-	register:   make(chan *Client),
-	unregister: make(chan *Client),
-	broadcast:  make(chan Message),
+var MainHub = newHub()
+
+func newHub() *Hub {
+	return &Hub{
+		clients:    make(map[string]map[*Client]bool),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		broadcast:  make(chan Message),
+		origin:     uuid.NewString(),
+	}
 }
 
 func init() {
@@ -85,48 +110,89 @@ func (h *Hub) run() {
 	for {
 		select {
 		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client.UserID] = client
-			h.mu.Unlock()
+			h.add(client)
 			log.Printf("[CHAT] User connected: %s", client.UserID)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if c, ok := h.clients[client.UserID]; ok && c == client {
-				delete(h.clients, client.UserID)
-				close(client.Send)
-			}
-			h.mu.Unlock()
+			h.drop(client)
 			log.Printf("[CHAT] User disconnected: %s", client.UserID)
 		}
 	}
 }
 
+func (h *Hub) add(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns, ok := h.clients[client.UserID]
+	if !ok {
+		conns = make(map[*Client]bool)
+		h.clients[client.UserID] = conns
+	}
+	conns[client] = true
+}
+
+// drop removes one connection and leaves the user's others alone. Safe to
+// call twice for the same client, Send is only closed the first time.
+func (h *Hub) drop(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns := h.clients[client.UserID]
+	if !conns[client] {
+		return
+	}
+	delete(conns, client)
+	if len(conns) == 0 {
+		delete(h.clients, client.UserID)
+	}
+	close(client.Send)
+}
+
+// SendDirectMessage delivers to the user's sockets here and forwards to the
+// other server instances, which may hold more of their sessions (see
+// fanout.go).
 func (h *Hub) SendDirectMessage(toUserID string, msg Message) {
+	h.deliverLocal(toUserID, msg)
+	h.publish(toUserID, msg)
+}
+
+// deliverLocal sends msg to every socket the user has on this instance. A
+// socket whose buffer is full is dropped rather than allowed to stall the rest.
+func (h *Hub) deliverLocal(toUserID string, msg Message) {
 	h.mu.Lock()
-	target, ok := h.clients[toUserID]
+	targets := make([]*Client, 0, len(h.clients[toUserID]))
+	for c := range h.clients[toUserID] {
+		targets = append(targets, c)
+	}
 	h.mu.Unlock()
 
-	if ok {
-		data, _ := json.Marshal(msg)
+	if len(targets) == 0 {
+		return
+	}
+	data, _ := json.Marshal(msg)
+	for _, target := range targets {
 		select {
 		case target.Send <- data:
 		default:
-			close(target.Send)
-			h.mu.Lock()
-			delete(h.clients, target.UserID)
-			h.mu.Unlock()
+			h.drop(target)
 		}
 	}
 }
 
+// PushPresence tells friends that userID's status changed. Main subscribes it
+// to the presence service, so friends with only a chat socket open hear too.
+func (h *Hub) PushPresence(userID, status string, friends []string) {
+	for _, id := range friends {
+		h.SendDirectMessage(id, Message{Type: "presence", From: userID, Text: status})
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
 func HandleWS(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("userID")
-	if userID == "" {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
 		return
 	}
 
@@ -165,6 +231,23 @@ func (c *Client) readPump() {
 			msg.From = c.UserID
 			// Routing logic
 			if msg.Type == "dm" && msg.To != "" {
+				if FriendsOnlyDMs && AreFriends != nil && !AreFriends(msg.From, msg.To) {
+					MainHub.SendDirectMessage(c.UserID, Message{
+						Type: "error",
+						To:   msg.To,
+						Text: "You can only message friends",
+					})
+					continue
+				}
+				if CanDM != nil && !CanDM(msg.From, msg.To) {
+					MainHub.SendDirectMessage(c.UserID, Message{
+						Type: "error",
+						To:   msg.To,
+						Text: "This user isn't accepting messages from you",
+					})
+					continue
+				}
+
 				// Save to DB
 				_, err := DB.Exec(`
 					INSERT INTO messages (sender_id, receiver_id, text, delivered, seen)
@@ -212,11 +295,11 @@ func (c *Client) writePump() {
 
 // Helper to get ID from cookie
 func readUserID(r *http.Request) (string, error) {
-	c, err := r.Cookie("user_id")
-	if err != nil || c.Value == "" {
-		return "", errors.New("no user_id cookie")
+	userID, ok := auth.UserID(r)
+	if !ok {
+		return "", errors.New("no session")
 	}
-	return c.Value, nil
+	return userID, nil
 }
 
 func DeliveredHandler(w http.ResponseWriter, r *http.Request) {
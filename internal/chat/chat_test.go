@@ -0,0 +1,34 @@
+package chat
+
+import "testing"
+
+func TestDirectMessageReachesEverySession(t *testing.T) {
+	h := newHub() // Never listens, so delivery stays on this instance
+	phone := &Client{UserID: "u_multi", Send: make(chan []byte, 8)}
+	laptop := &Client{UserID: "u_multi", Send: make(chan []byte, 8)}
+	h.add(phone)
+	h.add(laptop)
+
+	h.SendDirectMessage("u_multi", Message{Type: "dm", From: "u_friend", To: "u_multi", Text: "hi"})
+	for name, c := range map[string]*Client{"phone": phone, "laptop": laptop} {
+		if msg := receive(t, c); msg.Text != "hi" || msg.From != "u_friend" {
+			t.Errorf("%s got %+v", name, msg)
+		}
+	}
+
+	// Closing the phone leaves the laptop connected
+	h.drop(phone)
+	h.drop(phone)
+	if _, open := <-phone.Send; open {
+		t.Fatal("dropped session's channel still open")
+	}
+	h.SendDirectMessage("u_multi", Message{Type: "dm", From: "u_friend", To: "u_multi", Text: "still there?"})
+	if msg := receive(t, laptop); msg.Text != "still there?" {
+		t.Errorf("laptop got %+v after the phone left", msg)
+	}
+
+	h.drop(laptop)
+	if _, ok := h.clients["u_multi"]; ok {
+		t.Error("user kept in the hub with no sessions left")
+	}
+}
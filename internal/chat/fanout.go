@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Cross-instance delivery. Every server LISTENs on one Postgres channel; the
+// sender's instance NOTIFYs every DM and each instance holding one of the
+// recipient's sockets delivers it. A user can be connected to several.
+const (
+	fanoutChannel = "chat_fanout"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7900
+)
+
+type fanoutEnvelope struct {
+	Origin string  `json:"origin"`
+	To     string  `json:"to"`
+	Msg    Message `json:"msg"`
+}
+
+// StartFanout connects MainHub to the other instances, publishing through DB.
+func StartFanout(dsn string) error {
+	_, err := MainHub.listen(dsn, DB)
+	return err
+}
+
+// listen opens a dedicated LISTEN connection (pq needs its own, it can't
+// share the *sql.DB pool), delivers remote messages to h's clients and from
+// then on publishes h's messages through db. Closing the returned listener
+// stops the delivery.
+func (h *Hub) listen(dsn string, db *sql.DB) (*pq.Listener, error) {
+	listener := pq.NewListener(dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[CHAT] Fanout listener event %d: %v", ev, err)
+		}
+	})
+	if err := listener.Listen(fanoutChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	h.mu.Lock()
+	h.notify = db
+	h.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return // Closed
+				}
+				if n == nil {
+					continue // Reconnected, anything sent meanwhile is in history
+				}
+				var env fanoutEnvelope
+				if err := json.Unmarshal([]byte(n.Extra), &env); err != nil || env.Origin == h.origin {
+					continue
+				}
+				h.deliverLocal(env.To, env.Msg)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+	log.Printf("[CHAT] Cross-instance fanout listening (instance %s)", h.origin[:8])
+	return listener, nil
+}
+
+// publish hands a message to the other instances.
+func (h *Hub) publish(toUserID string, msg Message) {
+	h.mu.Lock()
+	db := h.notify
+	h.mu.Unlock()
+	if db == nil {
+		return
+	}
+	payload, _ := json.Marshal(fanoutEnvelope{Origin: h.origin, To: toUserID, Msg: msg})
+	if len(payload) > maxNotifyPayload {
+		log.Printf("[CHAT] Message to %s too large for fanout, recipient will see it in history", toUserID)
+		return
+	}
+	if _, err := db.Exec(`SELECT pg_notify($1, $2)`, fanoutChannel, string(payload)); err != nil {
+		log.Printf("[CHAT] Fanout publish error: %v", err)
+	}
+}
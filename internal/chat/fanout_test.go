@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"main/internal/data/datatest"
+)
+
+// instance is a hub as another server would run it, fanning out through the
+// test database.
+func instance(t *testing.T) *Hub {
+	t.Helper()
+	_, db := datatest.Store(t)
+	h := newHub()
+	listener, err := h.listen(os.Getenv("TEST_DATABASE_URL"), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return h
+}
+
+// receive waits for the next message on c.
+func receive(t *testing.T, c *Client) Message {
+	t.Helper()
+	select {
+	case raw := <-c.Send:
+		var msg Message
+		json.Unmarshal(raw, &msg)
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing delivered")
+	}
+	return Message{}
+}
+
+func TestFanoutReachesOtherInstance(t *testing.T) {
+	a, b := instance(t), instance(t)
+	user := "u_fanout_" + a.origin[:8]
+	onA := &Client{UserID: user, Send: make(chan []byte, 8)}
+	onB := &Client{UserID: user, Send: make(chan []byte, 8)}
+	a.add(onA)
+	b.add(onB)
+
+	a.SendDirectMessage(user, Message{Type: "dm", From: "u_sender", To: user, Text: "across"})
+	for name, c := range map[string]*Client{"same instance": onA, "other instance": onB} {
+		if msg := receive(t, c); msg.Text != "across" || msg.From != "u_sender" {
+			t.Errorf("%s got %+v", name, msg)
+		}
+	}
+
+	// a ignores its own notification rather than delivering twice
+	select {
+	case raw := <-onA.Send:
+		t.Fatalf("delivered twice on the sending instance: %s", raw)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
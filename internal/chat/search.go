@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SearchDefaultLimit = 20
+	SearchMaxLimit     = 50
+)
+
+// SearchResult is one message matching a search, seen from the searcher's side.
+type SearchResult struct {
+	ID        int64     `json:"id"`
+	PartnerID string    `json:"partner_id"`
+	Partner   string    `json:"partner"` // nickname#tag
+	Sender    string    `json:"sender_id"`
+	Text      string    `json:"text"`
+	Time      time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SearchHandler finds the cookie user's messages (sent or received) matching q.
+// Newest first; pass the returned next_before as ?before= for the next page.
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := readUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "Missing 'q' param", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > SearchMaxLimit {
+		limit = SearchDefaultLimit
+	}
+	before, _ := strconv.ParseInt(r.URL.Query().Get("before"), 10, 64)
+
+	// Same TTL window as history, expired rows are about to be purged anyway
+	cutoff := time.Now().Add(-MessageTTL)
+	rows, err := DB.Query(`
+        SELECT m.id, m.sender_id, m.receiver_id, m.text, m.created_at,
+               COALESCE(u.nickname || '#' || u.tag, '')
+        FROM messages m
+        LEFT JOIN users u
+          ON u.id = CASE WHEN m.sender_id = $1 THEN m.receiver_id ELSE m.sender_id END
+        WHERE (m.sender_id = $1 OR m.receiver_id = $1)
+          AND to_tsvector('simple', m.text) @@ plainto_tsquery('simple', $2)
+          AND m.created_at > $3
+          AND ($4 = 0 OR m.id < $4)
+        ORDER BY m.id DESC
+        LIMIT $5
+    `, userID, q, cutoff, before, limit)
+	if err != nil {
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var res SearchResult
+		var receiver string
+		if err := rows.Scan(&res.ID, &res.Sender, &receiver, &res.Text, &res.Time, &res.Partner); err != nil {
+			continue
+		}
+		res.PartnerID = receiver
+		if res.Sender != userID {
+			res.PartnerID = res.Sender
+		}
+		res.ExpiresAt = res.Time.Add(MessageTTL)
+		results = append(results, res)
+	}
+
+	var nextBefore int64
+	if len(results) == limit {
+		nextBefore = results[len(results)-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":     results,
+		"next_before": nextBefore,
+	})
+}
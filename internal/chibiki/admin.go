@@ -0,0 +1,40 @@
+package chibiki
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+// NewReloadUnitsHandler lets an operator push a units.json edit into the
+// running rooms: POST with X-Admin-Token, add ?refresh=1 to also update units
+// already on the field. With no token configured the endpoint is disabled.
+func NewReloadUnitsHandler(m *Matchmaker, adminToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		given := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(adminToken)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		count, err := m.ReloadUnits(r.URL.Query().Get("refresh") == "1")
+		if err != nil {
+			http.Error(w, "Reload failed: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"units":   count,
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package chibiki
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// BotThinkInterval is how often the practice bot looks at the board
+	BotThinkInterval = 2 * time.Second
+	// BotRewardMultiplier scales trophies and coins for matches against the bot
+	BotRewardMultiplier = 0.2
+	// botSaveUp is the elixir the bot banks before starting a push of its own
+	botSaveUp = 8.0
+)
+
+// Where the bot drops cards on its own (top) half
+const (
+	botDefendY = 9.0  // Just in front of its princess towers
+	botPushY   = 14.5 // At the bridge
+)
+
+// newBot makes the practice opponent. It has no account, so the game over
+// handler pays it nothing and match history skips it.
+func newBot(g *GameInstance) *Player {
+	return &Player{
+		ID:    fmt.Sprintf("bot-%s", g.ID),
+		IsBot: true,
+		Send:  make(chan []byte, 256),
+	}
+}
+
+// runBot drives the bot until its room stops. Its Send is drained here; the
+// bot reads the board straight from the game instead.
+func runBot(g *GameInstance, bot *Player) {
+	go func() {
+		for range bot.Send {
+		}
+	}()
+	ticker := time.NewTicker(BotThinkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			g.Mutex.Lock()
+			g.dropPlayer(bot) // Closes Send, ending the drain
+			g.Mutex.Unlock()
+			return
+		case <-ticker.C:
+		}
+		g.Mutex.Lock()
+		g.botMove(bot)
+		g.Mutex.Unlock()
+	}
+}
+
+// botMove plays at most one card. With enemies on its half it defends the
+// lane under the most pressure, otherwise it saves up and pushes the lane
+// whose enemy princess tower is weaker. Caller holds g.Mutex.
+func (g *GameInstance) botMove(bot *Player) string {
+	if g.Phase != PhasePlaying || g.GameOver {
+		return RejectNotPlaying
+	}
+	pState, ok := g.PlayerStates[bot.ID]
+	if !ok {
+		return RejectNotInMatch
+	}
+
+	// Pressure per lane: enemy HP already on the bot's side of the river
+	var pressure [2]float64
+	var threatX, threatY [2]float64
+	var threats [2]int
+	for _, e := range g.Entities {
+		if e.Team == bot.Team || e.HP <= 0 || isTowerKey(e.Key) || !g.onSide(bot.Team, e.Y) {
+			continue
+		}
+		lane := laneOf(e.X)
+		pressure[lane] += e.HP
+		threatX[lane] += e.X
+		threatY[lane] += e.Y
+		threats[lane]++
+	}
+
+	defend := pressure[0] > 0 || pressure[1] > 0
+	lane := 0
+	if defend {
+		if pressure[1] > pressure[0] {
+			lane = 1
+		}
+	} else {
+		if pState.Elixir < botSaveUp {
+			return RejectNoElixir
+		}
+		lane = g.weakerEnemyLane(bot.Team)
+	}
+
+	key, stats, ok := g.botPickCard(pState, defend)
+	if !ok {
+		return RejectNoElixir
+	}
+
+	x := LaneLeftX
+	if lane == 1 {
+		x = LaneRightX
+	}
+	y := botPushY
+	if defend {
+		y = botDefendY
+	}
+	if stats.IsSpell() && threats[lane] > 0 {
+		// Aim at the middle of the push
+		x = threatX[lane] / float64(threats[lane])
+		y = threatY[lane] / float64(threats[lane])
+	}
+	if bot.Team == 0 {
+		y = ArenaHeight - y
+	}
+	return g.spawnLocked(bot, key, x, y)
+}
+
+// botPickCard picks the cheapest affordable card to defend with, or the
+// priciest one to push with. Spells are only worth it on defence.
+func (g *GameInstance) botPickCard(pState *PlayerState, defend bool) (string, UnitStats, bool) {
+	best := ""
+	var bestStats UnitStats
+	for _, key := range pState.Hand {
+		stats, ok := g.UnitData[key]
+		if !ok || float64(stats.Elixir) > pState.Elixir || (stats.IsSpell() && !defend) {
+			continue
+		}
+		if best == "" || (defend && stats.Elixir < bestStats.Elixir) || (!defend && stats.Elixir > bestStats.Elixir) {
+			best, bestStats = key, stats
+		}
+	}
+	return best, bestStats, best != ""
+}
+
+// weakerEnemyLane is the lane (0 left, 1 right) whose enemy princess tower
+// has the least HP left; a fallen tower counts as zero.
+func (g *GameInstance) weakerEnemyLane(team int) int {
+	hp := [2]float64{0, 0}
+	for _, e := range g.Entities {
+		if e.Team != team && e.Key == "princess_tower" && e.HP > 0 {
+			hp[laneOf(e.X)] = e.HP
+		}
+	}
+	if hp[1] < hp[0] {
+		return 1
+	}
+	return 0
+}
+
+// onSide reports whether y is on team's half of the arena.
+func (g *GameInstance) onSide(team int, y float64) bool {
+	if team == 1 {
+		return y < BridgeY
+	}
+	return y > BridgeY
+}
+
+func laneOf(x float64) int {
+	if x < ArenaWidth/2 {
+		return 0
+	}
+	return 1
+}
+
+func isTowerKey(key string) bool {
+	return key == "king_tower" || key == "princess_tower"
+}
@@ -0,0 +1,63 @@
+package chibiki
+
+import "testing"
+
+func TestBotDeploysWithinTenSeconds(t *testing.T) {
+	g, _, bot := playingGame()
+	bot.IsBot = true
+	g.PlayerStates[bot.ID].Elixir = 5 // What a match starts with
+
+	const dt = 1.0 / TickRate
+	thinkEvery := int(BotThinkInterval.Seconds() * TickRate)
+	for tick := 1; tick <= 10*TickRate && units(g, 1) == 0; tick++ {
+		g.Update(dt)
+		if tick%thinkEvery == 0 {
+			g.Mutex.Lock()
+			g.botMove(bot)
+			g.Mutex.Unlock()
+		}
+	}
+	if units(g, 1) == 0 {
+		t.Fatalf("no unit deployed in 10 simulated seconds (elixir %.1f)", g.PlayerStates[bot.ID].Elixir)
+	}
+	for _, e := range g.Entities {
+		if e.Team == 1 && !isTowerKey(e.Key) && !g.onSide(1, e.Y) {
+			t.Errorf("bot deployed %s at y %v, off its own half", e.Key, e.Y)
+		}
+	}
+}
+
+func TestBotDefendsPressuredLane(t *testing.T) {
+	g, _, bot := playingGame()
+	bot.IsBot = true
+	pState := g.PlayerStates[bot.ID]
+	pState.Elixir = 3                                 // Too little to push, enough to defend
+	attacker := place(g, "knight", 0, LaneRightX, 13) // Over the river in the right lane
+
+	// The cheapest answer is the zap, dropped on the attacker
+	if reason := g.botMove(bot); reason != "" {
+		t.Fatalf("bot did not defend: %q", reason)
+	}
+	if attacker.HP != attacker.MaxHP-testCards["zap"].Damage {
+		t.Fatalf("attacker at %v/%v, want zapped", attacker.HP, attacker.MaxHP)
+	}
+
+	// With no spell to hand it drops a unit in front of the tower instead
+	pState.Elixir = 3
+	pState.Hand = []string{"balloon", "archer", "bomber", "knight"}
+	if reason := g.botMove(bot); reason != "" {
+		t.Fatalf("bot did not defend: %q", reason)
+	}
+	var deployed *Entity
+	for _, e := range g.Entities {
+		if e.Team == 1 && !isTowerKey(e.Key) {
+			deployed = e
+		}
+	}
+	if deployed == nil || laneOf(deployed.X) != 1 || deployed.Y != botDefendY {
+		t.Fatalf("bot played %+v, want a defender in the right lane at y %v", deployed, botDefendY)
+	}
+	if deployed.Stats.Elixir != 3 {
+		t.Errorf("defended with a %d elixir card, want the cheapest", deployed.Stats.Elixir)
+	}
+}
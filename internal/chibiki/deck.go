@@ -0,0 +1,92 @@
+package chibiki
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/data"
+)
+
+// DeckSize is how many cards a deck holds: four in hand, one next, three
+// waiting.
+const DeckSize = 8
+
+// DefaultDeck is dealt to anyone without a saved deck, or whose saved deck
+// no longer checks out.
+var DefaultDeck = []string{"morphilina", "dangerlyoha", "yuuechka", "morphe", "classic_morphe", "classic_yuu", "sasavot", "murzik"}
+
+// ValidateDeck checks that deck is exactly DeckSize distinct playable cards.
+func (m *Matchmaker) ValidateDeck(deck []string) error {
+	if len(deck) != DeckSize {
+		return fmt.Errorf("a deck needs exactly %d cards, got %d", DeckSize, len(deck))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool, len(deck))
+	for _, key := range deck {
+		if _, ok := m.units[key]; !ok {
+			return fmt.Errorf("unknown card %q", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("card %q is in the deck twice", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// savedDeck loads userID's deck, or nil (the default) when they have none
+// or it stopped being valid, e.g. a card was removed from units.json.
+func (m *Matchmaker) savedDeck(store *data.Store, userID string) []string {
+	deck, err := store.GetChibikiDeck(userID)
+	if err != nil {
+		log.Printf("[CHIBIKI] Loading deck for %s: %v", userID, err)
+		return nil
+	}
+	if deck == nil || m.ValidateDeck(deck) != nil {
+		return nil
+	}
+	return deck
+}
+
+// NewDeckSaveHandler stores the caller's deck for their next match.
+// POST /deck/save {"deck": ["morphilina", ...]}
+func NewDeckSaveHandler(m *Matchmaker, store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Deck []string `json:"deck"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		if err := m.ValidateDeck(req.Deck); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.SaveChibikiDeck(userID, req.Deck); err != nil {
+			log.Printf("[CHIBIKI] Saving deck for %s: %v", userID, err)
+			http.Error(w, "failed to save deck", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"deck":    req.Deck,
+		})
+	}
+}
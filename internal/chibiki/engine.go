@@ -8,6 +8,8 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"main/internal/data"
 )
 
 const (
@@ -16,9 +18,30 @@ const (
 	LaneRightX = 14.5
 	BridgeY    = 16.0
 
+	// Arena size in tiles
+	ArenaWidth  = 18.0
+	ArenaHeight = 32.0
+
 	// Game Duration Settings
 	DurationNormal   = 120.0 // 2 Minutes
 	DurationOvertime = 90.0  // 1:30 Minutes
+
+	// Units can't be dropped this close to an enemy (no teleport body-blocking)
+	MinSpawnDistance = 1.5
+
+	// Nothing can be deployed within this radius of a king tower
+	KingRegionRadius = 2.5
+
+	// Seconds between the second player joining and the clock starting
+	CountdownDuration = 3.0
+)
+
+// Match phases: WAITING (fewer than two players) -> COUNTDOWN -> PLAYING.
+// The clock, elixir and units only run while PLAYING.
+const (
+	PhaseWaiting   = "waiting"
+	PhaseCountdown = "countdown"
+	PhasePlaying   = "playing"
 )
 
 type PlayerState struct {
@@ -26,41 +49,79 @@ type PlayerState struct {
 	Hand   []string `json:"hand"`
 	Next   string   `json:"next"`
 	Deck   []string `json:"-"`
+
+	// Match stats, handed to OnGameOver
+	ElixirSpent   float64 `json:"-"`
+	UnitsDeployed int     `json:"-"`
+	DamageDealt   float64 `json:"-"`
+}
+
+// MatchStats is one player's share of a finished match.
+type MatchStats struct {
+	UserID        string
+	Team          int
+	ElixirSpent   float64
+	UnitsDeployed int
+	DamageDealt   float64
 }
 
 type GameInstance struct {
+	ID           string // Room name, see Matchmaker
 	Entities     []*Entity
 	UnitData     map[string]UnitStats
 	PlayerStates map[string]*PlayerState
 	GameTime     float64
+	Phase        string
+	Countdown    float64 // Seconds left while in PhaseCountdown
 	Mutex        sync.RWMutex
 	Register     chan *Player
 	Unregister   chan *Player
 	Players      map[*Player]bool
+	Spectators   map[*Player]bool // Watching, not playing, see spectate.go
 
-	OnGameOver func(winnerTeam int, players map[*Player]bool, gameTime float64)
+	OnGameOver func(winnerTeam int, players map[*Player]bool, gameTime float64, stats []MatchStats)
 
 	// Game State Flags
 	GameOver     bool
-	WinnerTeam   int
+	WinnerTeam   int // -1 until decided, and still -1 if it ends in a draw
 	IsOvertime   bool
 	IsTiebreaker bool
+	Crowns       [2]int // Enemy towers each team has destroyed this match
 
 	resultSent bool
+	dropped    map[string]droppedSeat // Player ID -> seat held for a reconnect, see reconnect.go
+	stop       chan struct{}          // Closed by Stop, ends StartLoop
 }
 
 func NewGame() *GameInstance {
-	return &GameInstance{
+	g := &GameInstance{
 		Entities:     make([]*Entity, 0),
 		UnitData:     make(map[string]UnitStats),
 		PlayerStates: make(map[string]*PlayerState),
 		Register:     make(chan *Player),
 		Unregister:   make(chan *Player),
 		Players:      make(map[*Player]bool),
+		Spectators:   make(map[*Player]bool),
 		GameTime:     0,
+		Phase:        PhaseWaiting,
 		GameOver:     false,
 		WinnerTeam:   -1,
 		resultSent:   false,
+		dropped:      make(map[string]droppedSeat),
+		stop:         make(chan struct{}),
+	}
+	g.applyTowerStats()
+	return g
+}
+
+// Snapshot reports who's connected and whether a match is running.
+func (g *GameInstance) Snapshot() data.LiveStatus {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	return data.LiveStatus{
+		Players: len(g.Players),
+		InMatch: g.Phase == PhasePlaying && !g.GameOver,
+		State:   g.Phase,
 	}
 }
 
@@ -68,21 +129,42 @@ func NewGame() *GameInstance {
 func (g *GameInstance) Reset() {
 	g.Mutex.Lock()
 	defer g.Mutex.Unlock()
+	g.resetLocked()
+}
 
+// TryReset is the client-facing reset: it refuses while a match is live
+// between two players, so one side can't wipe the board mid-game.
+func (g *GameInstance) TryReset() bool {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	if !g.GameOver && g.Phase != PhaseWaiting && len(g.Players) >= 2 {
+		return false
+	}
+	g.resetLocked()
+	return true
+}
+
+func (g *GameInstance) resetLocked() {
 	// Reset Game State
 	g.Entities = make([]*Entity, 0)
 	g.GameTime = 0
+	g.Phase = PhaseWaiting
+	g.Countdown = 0
 	g.GameOver = false
 	g.WinnerTeam = -1
 	g.IsOvertime = false
 	g.IsTiebreaker = false
+	g.Crowns = [2]int{}
 	g.resultSent = false
+	g.dropped = make(map[string]droppedSeat)
 
 	// Reset Players (Elixir, Hands)
-	for pID := range g.PlayerStates {
-		deck := []string{"morphilina", "dangerlyoha", "yuuechka", "morphe", "classic_morphe", "classic_yuu", "sasavot", "murzik"}
-		rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
-		g.PlayerStates[pID] = &PlayerState{5.0, deck[:4], deck[4], deck[5:]}
+	// Rebuild from the connected set rather than PlayerStates so a join that
+	// raced the reset (registration takes this same lock) is never skipped,
+	// and states of players who already left don't linger.
+	g.PlayerStates = make(map[string]*PlayerState, len(g.Players))
+	for p := range g.Players {
+		g.InitPlayer(p)
 	}
 
 	// Respawn Towers
@@ -106,88 +188,148 @@ func (g *GameInstance) InitTowers() {
 	g.InitTowersInternal()
 }
 
-func (g *GameInstance) InitPlayer(playerID string) {
-	deck := []string{"morphilina", "dangerlyoha", "yuuechka", "morphe", "classic_morphe", "classic_yuu", "sasavot", "murzik"}
+// InitPlayer deals a fresh hand from the player's deck. Caller must hold
+// g.Mutex.
+func (g *GameInstance) InitPlayer(p *Player) {
+	deck := append([]string(nil), DefaultDeck...)
+	if len(p.Deck) == DeckSize {
+		deck = append(deck[:0], p.Deck...)
+	}
 	rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
-	g.PlayerStates[playerID] = &PlayerState{5.0, deck[:4], deck[4], deck[5:]}
+	g.PlayerStates[p.ID] = &PlayerState{Elixir: 5.0, Hand: deck[:4], Next: deck[4], Deck: deck[5:]}
+}
+
+// SetUnits swaps in a card set without stopping the match. Units already on
+// the field keep their old stats unless refresh is set, in which case they
+// take the new ones (HP scaled to keep the same fraction). The game keeps
+// its own copy, so one parsed units.json can be handed to every room.
+func (g *GameInstance) SetUnits(units map[string]UnitStats, refresh bool) {
+	own := make(map[string]UnitStats, len(units)+2)
+	for k, v := range units {
+		own[k] = v
+	}
+
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.UnitData = own
+	g.applyTowerStats()
+	if refresh {
+		for _, e := range g.Entities {
+			stats, ok := g.UnitData[e.Key]
+			if !ok || e.Key == "king_tower" || e.Key == "princess_tower" {
+				continue
+			}
+			if e.MaxHP > 0 {
+				e.HP = e.HP / e.MaxHP * stats.HP
+			}
+			e.MaxHP = stats.HP
+			e.Stats = stats
+		}
+	}
 }
 
-func (g *GameInstance) LoadUnits(path string) error {
+// readUnits parses and validates a units.json file.
+func readUnits(path string) (map[string]UnitStats, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var data struct {
 		Units map[string]UnitStats `json:"units"`
 	}
-	json.Unmarshal(bytes, &data)
-	g.UnitData = data.Units
-	for k, v := range g.UnitData {
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(data.Units) == 0 {
+		return nil, fmt.Errorf("%s: no units defined", path)
+	}
+	for k, v := range data.Units {
+		if v.HP <= 0 && !v.IsSpell() {
+			return nil, fmt.Errorf("%s: unit %q has non-positive hp %.0f", path, k, v.HP)
+		}
+		if !validTargets[v.Target] {
+			return nil, fmt.Errorf("%s: unit %q has unknown target_type %q", path, k, v.Target)
+		}
+		if !validTypes[v.Type] {
+			return nil, fmt.Errorf("%s: unit %q has unknown unit_type %q", path, k, v.Type)
+		}
 		v.Key = k
-		g.UnitData[k] = v
+		data.Units[k] = v
 	}
-	g.UnitData["king_tower"] = UnitStats{Key: "king_tower", HP: 4000, Range: 7, Damage: 100, HitSpeed: 1, Speed: 0, Target: "ground"}
-	g.UnitData["princess_tower"] = UnitStats{Key: "princess_tower", HP: 2500, Range: 7.5, Damage: 80, HitSpeed: 0.8, Speed: 0, Target: "ground"}
-	return nil
+	return data.Units, nil
+}
+
+// applyTowerStats installs the hardcoded tower stats. Towers never come from
+// units.json, so they are present even if loading the card file failed.
+func (g *GameInstance) applyTowerStats() {
+	g.UnitData["king_tower"] = UnitStats{Key: "king_tower", HP: 4000, Range: 7, Damage: 100, HitSpeed: 1, Speed: 0, Target: "all", Type: TypeBuilding}
+	g.UnitData["princess_tower"] = UnitStats{Key: "princess_tower", HP: 2500, Range: 7.5, Damage: 80, HitSpeed: 0.8, Speed: 0, Target: "all", Type: TypeBuilding}
 }
 
 func (g *GameInstance) StartLoop() {
 	go g.handleConnections()
 	ticker := time.NewTicker(time.Second / TickRate)
 	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
 		dt := 1.0 / float64(TickRate)
 		g.Update(dt)
 		g.BroadcastCustomState()
 	}
 }
 
+// Stop ends StartLoop and the connection handler. Only call it once every
+// player has unregistered.
+func (g *GameInstance) Stop() {
+	close(g.stop)
+}
+
 func (g *GameInstance) handleConnections() {
 	for {
 		select {
+		case <-g.stop:
+			return
 		case player := <-g.Register:
 			g.Mutex.Lock()
 			g.Players[player] = true
 
-			// --- FIX: Dynamic Team Assignment ---
-			// Count how many players are currently in Team 0
-			team0Count := 0
-			for p := range g.Players {
-				if p != player && p.Team == 0 {
-					team0Count++
+			if g.rejoin(player) {
+				fmt.Printf("Player rejoined: %s -> Team %d\n", player.ID, player.Team)
+			} else {
+				// --- FIX: Dynamic Team Assignment ---
+				// Count how many players are currently in Team 0
+				team0Count := 0
+				for p := range g.Players {
+					if p != player && p.Team == 0 {
+						team0Count++
+					}
 				}
-			}
 
-			// If Team 0 is empty, take it. Otherwise, take Team 1.
-			if team0Count == 0 {
-				player.Team = 0
-			} else {
-				player.Team = 1
+				// If Team 0 is empty, take it. Otherwise, take Team 1.
+				if team0Count == 0 {
+					player.Team = 0
+				} else {
+					player.Team = 1
+				}
 			}
 
 			// Initialize State
 			if _, exists := g.PlayerStates[player.ID]; !exists {
-				g.InitPlayer(player.ID)
+				g.InitPlayer(player)
 			}
 			g.Mutex.Unlock()
 
 			fmt.Printf("Player joined: %s (User: %s) -> Team %d\n", player.ID, player.UserID, player.Team)
 
 		case player := <-g.Unregister:
+			// Mid-match the seat is held for a reconnect; the auto-win only
+			// comes once ReconnectGrace runs out, see checkForfeits
 			g.Mutex.Lock()
-			delete(g.Players, player)
-			close(player.Send)
-
-			// Auto-win for remaining player if game was in progress
-			if len(g.Players) == 1 && !g.GameOver && g.GameTime > 0 {
-				for remainingPlayer := range g.Players {
-					fmt.Printf("[CHIBIKI] Player %s disconnected. Auto-win for %s (Team %d)\n",
-						player.ID, remainingPlayer.ID, remainingPlayer.Team)
-					g.finishGame(remainingPlayer.Team)
-					break
-				}
-			}
-
+			g.dropPlayer(player)
 			g.Mutex.Unlock()
 			fmt.Println("Player left:", player.ID)
 		}
@@ -201,18 +343,38 @@ func (g *GameInstance) Update(dt float64) {
 		return
 	}
 
-	// Pause the clock and entities until two players are present.
+	switch g.Phase {
+	case PhaseWaiting:
+		if len(g.Players) >= 2 {
+			g.Phase = PhaseCountdown
+			g.Countdown = CountdownDuration
+		}
+		return
+	case PhaseCountdown:
+		// Someone bailed before kick-off, go back to waiting
+		if len(g.Players) < 2 {
+			g.Phase = PhaseWaiting
+			g.Countdown = 0
+			return
+		}
+		g.Countdown -= dt
+		if g.Countdown > 0 {
+			return
+		}
+		g.Countdown = 0
+		g.Phase = PhasePlaying
+	}
+
+	// Pause the clock and entities if a player drops mid-match.
 	if len(g.Players) < 2 {
+		g.checkForfeits()
 		return
 	}
 
 	g.GameTime += dt
 
-	if !g.IsOvertime && !g.IsTiebreaker {
-		if g.GameTime >= DurationNormal {
-			g.IsOvertime = true
-		}
-	} else if g.IsOvertime && !g.IsTiebreaker {
+	// Normal time running out is decided on crowns further down
+	if g.IsOvertime && !g.IsTiebreaker {
 		if g.GameTime >= DurationNormal+DurationOvertime {
 			g.IsTiebreaker = true
 		}
@@ -220,14 +382,16 @@ func (g *GameInstance) Update(dt float64) {
 
 	if g.IsTiebreaker {
 		drain := 50.0 * dt
+		var losses towerLosses
 		for _, e := range g.Entities {
-			if e.Key == "king_tower" || e.Key == "princess_tower" {
-				e.HP -= drain
-				if e.HP <= 0 {
-					e.HP = 0
-					g.finishGame((e.Team + 1) % 2)
-				}
+			if e.Key != "king_tower" && e.Key != "princess_tower" {
+				continue
 			}
+			e.HP = math.Max(0, e.HP-drain)
+			losses.tally(e)
+		}
+		if winner, ok := losses.winner(true); ok {
+			g.finishGame(winner)
 		}
 		return
 	}
@@ -249,13 +413,13 @@ func (g *GameInstance) Update(dt float64) {
 	activeEntities := g.Entities[:0]
 	towersTeam0 := 0
 	towersTeam1 := 0
-	king0Alive := false
-	king1Alive := false
+	var losses towerLosses
 
 	// Mark if a tower drops during overtime/tiebreaker for sudden death.
 	suddenDeath := g.IsOvertime || g.IsTiebreaker
 	for _, e := range g.Entities {
-		if e.HP > 0 {
+		losses.tally(e)
+		if e.HP > 0 && !e.Stats.IsSpell() {
 			activeEntities = append(activeEntities, e)
 			if e.Key == "princess_tower" {
 				if e.Team == 0 {
@@ -264,33 +428,25 @@ func (g *GameInstance) Update(dt float64) {
 					towersTeam1++
 				}
 			}
-			if e.Key == "king_tower" {
-				if e.Team == 0 {
-					king0Alive = true
-				} else {
-					king1Alive = true
-				}
-			}
-		} else {
-			if e.Key == "king_tower" {
-				g.finishGame((e.Team + 1) % 2)
-			} else if suddenDeath && (e.Key == "princess_tower") {
-				g.finishGame((e.Team + 1) % 2)
-			}
 		}
 	}
 	g.Entities = activeEntities
+	g.Crowns[0] += losses.lost(1)
+	g.Crowns[1] += losses.lost(0)
+	// Decided after the whole tick is counted, so two towers falling
+	// together can't race each other to finishGame
+	if winner, ok := losses.winner(suddenDeath); ok {
+		g.finishGame(winner)
+	}
 	if g.GameOver {
 		return
 	}
 
-	// End of regulation: decide winner if towers differ, otherwise go to overtime
+	// End of regulation: more crowns wins, overtime only on an exact tie
 	if !g.IsOvertime && !g.IsTiebreaker && g.GameTime >= DurationNormal {
-		score0 := towersTeam0 + boolToInt(king0Alive)
-		score1 := towersTeam1 + boolToInt(king1Alive)
-		if score0 != score1 {
+		if g.Crowns[0] != g.Crowns[1] {
 			winner := 0
-			if score1 > score0 {
+			if g.Crowns[1] > g.Crowns[0] {
 				winner = 1
 			}
 			g.finishGame(winner)
@@ -345,8 +501,8 @@ func (g *GameInstance) Update(dt float64) {
 }
 
 func (g *GameInstance) BroadcastCustomState() {
-	g.Mutex.RLock()
-	defer g.Mutex.RUnlock()
+	g.Mutex.Lock() // Write lock, a stalled player gets dropped below
+	defer g.Mutex.Unlock()
 
 	type stateMessage struct {
 		Type        string       `json:"type"`
@@ -356,9 +512,14 @@ func (g *GameInstance) BroadcastCustomState() {
 		Winner      int          `json:"winner"`
 		Overtime    bool         `json:"overtime"`
 		Tiebreaker  bool         `json:"tiebreaker"`
+		Crowns      [2]int       `json:"crowns"`
+		Phase       string       `json:"phase"`
+		Countdown   float64      `json:"countdown"`
 		Me          *PlayerState `json:"me,omitempty"`
 		MyTeam      int          `json:"myTeam,omitempty"`
 		PlayerCount int          `json:"playerCount"`
+		Room        string       `json:"room"` // What friends pass as ?spectate=
+		Spectating  bool         `json:"spectating,omitempty"`
 	}
 
 	base := stateMessage{
@@ -369,7 +530,25 @@ func (g *GameInstance) BroadcastCustomState() {
 		Winner:      g.WinnerTeam,
 		Overtime:    g.IsOvertime,
 		Tiebreaker:  g.IsTiebreaker,
+		Crowns:      g.Crowns,
+		Phase:       g.Phase,
+		Countdown:   g.Countdown,
 		PlayerCount: len(g.Players),
+		Room:        g.ID,
+	}
+
+	// Spectators get the neutral view: every entity, no hand, no team
+	if len(g.Spectators) > 0 {
+		msg := base
+		msg.Spectating = true
+		data, _ := json.Marshal(msg)
+		for spectator := range g.Spectators {
+			select {
+			case spectator.Send <- data:
+			default:
+				g.dropSpectator(spectator)
+			}
+		}
 	}
 
 	for player := range g.Players {
@@ -381,34 +560,78 @@ func (g *GameInstance) BroadcastCustomState() {
 		select {
 		case player.Send <- data:
 		default:
-			close(player.Send)
-			delete(g.Players, player)
+			g.dropPlayer(player)
 		}
 	}
 }
 
-func (g *GameInstance) SpawnUnit(player *Player, key string, x, y float64) {
-	// Anti-Cheat: Validation
-	if (player.Team == 0 && y < BridgeY) || (player.Team == 1 && y > BridgeY) {
-		return
-	}
+// Reasons sent with "spawn_rejected" so the client can tell the player why.
+const (
+	RejectNotPlaying    = "not_playing"
+	RejectWrongSide     = "wrong_side"
+	RejectUnknownCard   = "unknown_card"
+	RejectNotInMatch    = "not_in_match"
+	RejectNoElixir      = "not_enough_elixir"
+	RejectTooClose      = "too_close_to_enemy"
+	RejectCardNotInHand = "card_not_in_hand"
+	RejectOutOfBounds   = "out_of_bounds"
+)
 
-	ownerID := player.ID
+// SpawnUnit plays a card for player. Every refusal is reported back to that
+// player and returned; "" means the unit was spawned.
+func (g *GameInstance) SpawnUnit(player *Player, key string, x, y float64) string {
 	g.Mutex.Lock()
 	defer g.Mutex.Unlock()
 
+	reason := g.spawnLocked(player, key, x, y)
+	if reason != "" {
+		g.rejectSpawn(player, key, reason)
+	}
+	return reason
+}
+
+func (g *GameInstance) spawnLocked(player *Player, key string, x, y float64) string {
+	// No spawns before kick-off or into a finished game. Overtime and the
+	// tiebreaker are still PhasePlaying, so spawning stays legal there.
+	if g.Phase != PhasePlaying || g.GameOver {
+		return RejectNotPlaying
+	}
+
 	stats, ok := g.UnitData[key]
 	if !ok {
-		return
+		return RejectUnknownCard
+	}
+
+	// Anti-Cheat: Validation. Spells can land anywhere on the arena, units
+	// only on their own half and never inside a king tower.
+	if !inArena(x, y) {
+		return RejectOutOfBounds
+	}
+	if !stats.IsSpell() {
+		if (player.Team == 0 && y < BridgeY) || (player.Team == 1 && y > BridgeY) {
+			return RejectWrongSide
+		}
+		if g.onKingRegion(x, y) {
+			return RejectOutOfBounds
+		}
 	}
 	pState, ok := g.PlayerStates[player.ID]
 	if !ok {
-		return
+		return RejectNotInMatch
 	}
 
 	cost := float64(stats.Elixir)
 	if pState.Elixir < cost {
-		return
+		return RejectNoElixir
+	}
+
+	for _, e := range g.Entities {
+		if stats.IsSpell() {
+			break // Dropping a spell on top of the enemy is the point
+		}
+		if e.Team != player.Team && e.HP > 0 && math.Hypot(e.X-x, e.Y-y) < MinSpawnDistance {
+			return RejectTooClose
+		}
 	}
 
 	cardIdx := -1
@@ -419,17 +642,89 @@ func (g *GameInstance) SpawnUnit(player *Player, key string, x, y float64) {
 		}
 	}
 	if cardIdx == -1 {
-		return
+		return RejectCardNotInHand
 	}
 
 	pState.Elixir -= cost
+	pState.ElixirSpent += cost
+	pState.UnitsDeployed++
 	pState.Hand[cardIdx] = pState.Next
 	if len(pState.Deck) > 0 {
 		pState.Next = pState.Deck[0]
 		pState.Deck = pState.Deck[1:]
 		pState.Deck = append(pState.Deck, key)
 	}
-	g.SpawnEntity(key, ownerID, player.Team, x, y)
+	if stats.IsSpell() {
+		g.castSpell(stats, player, x, y)
+		return ""
+	}
+	g.SpawnEntity(key, player.ID, player.Team, x, y)
+	return ""
+}
+
+// inArena reports whether (x, y) is on the board. Written so NaN fails too.
+func inArena(x, y float64) bool {
+	return x >= 0 && x <= ArenaWidth && y >= 0 && y <= ArenaHeight
+}
+
+// onKingRegion reports whether (x, y) is within KingRegionRadius of either
+// king tower. Caller holds the lock.
+func (g *GameInstance) onKingRegion(x, y float64) bool {
+	for _, e := range g.Entities {
+		if e.Key == "king_tower" && math.Hypot(e.X-x, e.Y-y) < KingRegionRadius {
+			return true
+		}
+	}
+	return false
+}
+
+// castSpell hits every enemy within the spell's Range of (x, y). Anything it
+// kills, towers included, is cleared and scored by the next Update like any
+// other death. Caller holds the lock.
+func (g *GameInstance) castSpell(stats UnitStats, player *Player, x, y float64) {
+	hits := 0
+	for _, e := range g.Entities {
+		if e.Team == player.Team || e.HP <= 0 || math.Hypot(e.X-x, e.Y-y) > stats.Range {
+			continue
+		}
+		g.damage(e, stats.Damage, player.ID)
+		hits++
+	}
+	g.broadcastLocked(map[string]interface{}{
+		"type": "spell", "key": stats.Key, "team": player.Team, "x": x, "y": y, "radius": stats.Range, "hits": hits,
+	})
+}
+
+// rejectSpawn tells just this player why their card didn't go down. Caller
+// holds the lock; players BroadcastState already dropped are skipped since
+// their Send channel is closed.
+func (g *GameInstance) rejectSpawn(player *Player, key, reason string) {
+	if _, ok := g.Players[player]; !ok {
+		return
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":   "spawn_rejected",
+		"key":    key,
+		"reason": reason,
+	})
+	select {
+	case player.Send <- data:
+	default: // Slow client, it'll catch up from the next state
+	}
+}
+
+// broadcastLocked sends v to everyone still connected, skipping anyone whose
+// buffer is full. Caller holds the lock.
+func (g *GameInstance) broadcastLocked(v interface{}) {
+	data, _ := json.Marshal(v)
+	for _, set := range []map[*Player]bool{g.Players, g.Spectators} {
+		for player := range set {
+			select {
+			case player.Send <- data:
+			default:
+			}
+		}
+	}
 }
 
 // --- Helper Functions ---
@@ -457,7 +752,7 @@ func (g *GameInstance) FindTarget(e *Entity) *Entity {
 	} // Towers see exactly their range
 
 	for _, other := range g.Entities {
-		if other.Team == e.Team || other.HP <= 0 {
+		if other.Team == e.Team || other.HP <= 0 || !e.Stats.CanHit(other.Stats) {
 			continue
 		}
 		dist := g.Distance(e, other)
@@ -469,7 +764,33 @@ func (g *GameInstance) FindTarget(e *Entity) *Entity {
 	return closest
 }
 func (g *GameInstance) Distance(e1, e2 *Entity) float64 { return math.Hypot(e2.X-e1.X, e2.Y-e1.Y) }
-func (g *GameInstance) Attack(attacker, target *Entity) { target.HP -= attacker.Stats.Damage }
+
+// Attack hits target, and with a splash radius every other enemy the attacker
+// could target within that distance of it, towers included.
+func (g *GameInstance) Attack(attacker, target *Entity) {
+	g.damage(target, attacker.Stats.Damage, attacker.OwnerID)
+	if attacker.Stats.SplashRadius <= 0 {
+		return
+	}
+	for _, e := range g.Entities {
+		if e == target || e.Team == attacker.Team || e.HP <= 0 || !attacker.Stats.CanHit(e.Stats) {
+			continue
+		}
+		if g.Distance(target, e) <= attacker.Stats.SplashRadius {
+			g.damage(e, attacker.Stats.Damage, attacker.OwnerID)
+		}
+	}
+}
+
+// damage takes amount off e and credits whatever actually landed to the
+// player who owns the source. Towers belong to "server" and aren't counted.
+func (g *GameInstance) damage(e *Entity, amount float64, ownerID string) {
+	dealt := math.Min(e.HP, amount)
+	e.HP -= dealt
+	if pState, ok := g.PlayerStates[ownerID]; ok {
+		pState.DamageDealt += dealt
+	}
+}
 func (g *GameInstance) MoveTowards(e *Entity, tx, ty, dt float64) {
 	dx := tx - e.X
 	dy := ty - e.Y
@@ -489,6 +810,11 @@ func (g *GameInstance) MoveDownLane(e *Entity, dt float64) {
 	if e.Team == 1 {
 		towerY = 29.0
 	}
+	// Fliers ignore the river and head straight for the king
+	if e.Stats.Kind() == TypeFlying {
+		g.MoveTowards(e, ArenaWidth/2, towerY, dt)
+		return
+	}
 	onMySide := (e.Team == 0 && e.Y > BridgeY) || (e.Team == 1 && e.Y < BridgeY)
 	if onMySide && math.Abs(e.Y-BridgeY) > 1.0 {
 		g.MoveTowards(e, targetX, BridgeY, dt)
@@ -497,6 +823,11 @@ func (g *GameInstance) MoveDownLane(e *Entity, dt float64) {
 	}
 }
 
+// DrawTeam is the winning team of a match nobody won.
+const DrawTeam = -1
+
+// finishGame ends the match in winningTeam's favour, or as a draw when it
+// is DrawTeam.
 func (g *GameInstance) finishGame(winningTeam int) {
 	if g.GameOver || g.resultSent {
 		return
@@ -511,7 +842,92 @@ func (g *GameInstance) finishGame(winningTeam int) {
 			playersCopy[p] = true
 		}
 		gameTime := g.GameTime // Capture for anti-farming check
-		go g.OnGameOver(winningTeam, playersCopy, gameTime)
+		go g.OnGameOver(winningTeam, playersCopy, gameTime, g.matchStats())
+	}
+}
+
+// matchStats collects every player still holding a seat, connected or
+// waiting on a reconnect. Caller holds the lock.
+func (g *GameInstance) matchStats() []MatchStats {
+	teams := make(map[string]int, len(g.Players)+len(g.dropped))
+	for p := range g.Players {
+		if !p.IsBot {
+			teams[p.ID] = p.Team
+		}
+	}
+	for id, seat := range g.dropped {
+		teams[id] = seat.team
+	}
+	stats := make([]MatchStats, 0, len(teams))
+	for id, team := range teams {
+		pState, ok := g.PlayerStates[id]
+		if !ok {
+			continue
+		}
+		stats = append(stats, MatchStats{
+			UserID:        id,
+			Team:          team,
+			ElixirSpent:   pState.ElixirSpent,
+			UnitsDeployed: pState.UnitsDeployed,
+			DamageDealt:   pState.DamageDealt,
+		})
+	}
+	return stats
+}
+
+// towerLosses tallies the towers that fell during one tick.
+type towerLosses struct {
+	kingDown     [2]bool
+	princessDown [2]int
+	standing     [2]int // Towers of either kind still up
+}
+
+func (l *towerLosses) tally(e *Entity) {
+	if e.Team < 0 || e.Team > 1 || (e.Key != "king_tower" && e.Key != "princess_tower") {
+		return
+	}
+	switch {
+	case e.HP > 0:
+		l.standing[e.Team]++
+	case e.Key == "king_tower":
+		l.kingDown[e.Team] = true
+	default:
+		l.princessDown[e.Team]++
+	}
+}
+
+// lost is how many of team's towers fell this tick.
+func (l *towerLosses) lost(team int) int {
+	return l.princessDown[team] + boolToInt(l.kingDown[team])
+}
+
+// winner decides a match ended by towers falling this tick. A king going
+// down always beats princess towers falling on the same tick; princess
+// towers only count in sudden death. If both sides lose the same kind of
+// tower at once, whoever has more left standing wins, and a dead even split
+// is a draw, reported as DrawTeam. ok is false when nothing decisive fell.
+func (l *towerLosses) winner(suddenDeath bool) (int, bool) {
+	lost := l.kingDown[0] || l.kingDown[1]
+	down := [2]bool{l.kingDown[0], l.kingDown[1]}
+	if !lost && suddenDeath {
+		lost = l.princessDown[0] > 0 || l.princessDown[1] > 0
+		down = [2]bool{l.princessDown[0] > 0, l.princessDown[1] > 0}
+	}
+	if !lost {
+		return 0, false
+	}
+
+	switch {
+	case down[0] && !down[1]:
+		return 1, true
+	case down[1] && !down[0]:
+		return 0, true
+	case l.standing[1] > l.standing[0]:
+		return 1, true
+	case l.standing[0] > l.standing[1]:
+		return 0, true
+	default:
+		return DrawTeam, true
 	}
 }
 
@@ -0,0 +1,153 @@
+package chibiki
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkStates fails unless exactly the connected players have a hand.
+func checkStates(t *testing.T, g *GameInstance) {
+	t.Helper()
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	for p := range g.Players {
+		if g.PlayerStates[p.ID] == nil {
+			t.Errorf("%s is connected without a hand", p.ID)
+		}
+	}
+	if len(g.PlayerStates) != len(g.Players) {
+		t.Errorf("%d hands for %d players", len(g.PlayerStates), len(g.Players))
+	}
+}
+
+// settle waits for handleConnections to finish whatever it last received.
+// Unregistering a stranger is a no-op, and the loop only takes the second
+// one after it is done with the first.
+func settle(g *GameInstance) {
+	stranger := &Player{ID: "stranger"}
+	g.Unregister <- stranger
+	g.Unregister <- stranger
+}
+
+func TestResetWhileReconnecting(t *testing.T) {
+	g := NewGame()
+	go g.handleConnections()
+	defer g.Stop()
+
+	const clients, rounds = 4, 50
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				p := &Player{ID: id, UserID: id, Send: make(chan []byte, 8)}
+				g.Register <- p
+				if r < rounds-1 {
+					g.Unregister <- p
+				}
+			}
+		}(fmt.Sprintf("u%d", i))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for resets := 0; ; resets++ {
+		select {
+		case <-done:
+			settle(g)
+			checkStates(t, g)
+			g.Reset()
+			checkStates(t, g)
+			g.Mutex.RLock()
+			n := len(g.Players)
+			g.Mutex.RUnlock()
+			if n != clients {
+				t.Fatalf("%d players connected after %d resets, want %d", n, resets, clients)
+			}
+			return
+		default:
+			g.Reset()
+		}
+	}
+}
+
+func TestSnapshotCountsRegistered(t *testing.T) {
+	g := NewGame()
+	go g.handleConnections()
+	defer g.Stop()
+
+	a, b := &Player{ID: "a", Send: make(chan []byte, 8)}, &Player{ID: "b", Send: make(chan []byte, 8)}
+	g.Register <- a
+	g.Register <- b
+	settle(g)
+	if s := g.Snapshot(); s.Players != 2 || s.InMatch || s.State != PhaseWaiting {
+		t.Fatalf("snapshot %+v, want 2 waiting players", s)
+	}
+
+	g.Unregister <- a
+	settle(g)
+	if s := g.Snapshot(); s.Players != 1 {
+		t.Fatalf("%d players after one left, want 1", s.Players)
+	}
+}
+
+func TestReconnectWithinGrace(t *testing.T) {
+	g, p0, _ := playingGame()
+	go g.handleConnections()
+	defer g.Stop()
+	g.Mutex.Lock()
+	g.GameTime = 30
+	hand := g.PlayerStates["p0"]
+	hand.Elixir = 4.5
+	g.Mutex.Unlock()
+
+	g.Unregister <- p0
+	settle(g)
+	if !g.HoldsSeat("p0") {
+		t.Fatal("no seat held for a player who dropped mid-match")
+	}
+	g.Update(1)
+	if g.GameTime != 30 || g.GameOver {
+		t.Fatalf("clock at %v, over %v while a player is away; want paused", g.GameTime, g.GameOver)
+	}
+
+	back := &Player{ID: "p0", UserID: "p0", Send: make(chan []byte, 64)}
+	g.Register <- back
+	settle(g)
+	g.Mutex.RLock()
+	team, state := back.Team, g.PlayerStates["p0"]
+	g.Mutex.RUnlock()
+	if team != 0 || state != hand || state.Elixir != 4.5 {
+		t.Fatalf("rejoined on team %d with elixir %v; want their old team and hand", team, state.Elixir)
+	}
+	if g.HoldsSeat("p0") {
+		t.Error("seat still held after rejoining")
+	}
+	g.Update(1)
+	if g.GameTime != 31 {
+		t.Errorf("clock at %v after rejoining, want it running again", g.GameTime)
+	}
+}
+
+func TestGraceRunsOutForfeits(t *testing.T) {
+	g, _, p1 := playingGame()
+	g.GameTime = 30
+	g.dropPlayer(p1)
+	seat := g.dropped["p1"]
+	seat.at = seat.at.Add(-ReconnectGrace - time.Second)
+	g.dropped["p1"] = seat
+
+	g.Update(1)
+	if !g.GameOver || g.WinnerTeam != 0 {
+		t.Fatalf("over %v winner %d, want team 0 to win on the forfeit", g.GameOver, g.WinnerTeam)
+	}
+	if g.HoldsSeat("p1") {
+		t.Error("forfeited seat still held")
+	}
+}
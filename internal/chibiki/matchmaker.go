@@ -0,0 +1,181 @@
+package chibiki
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"main/internal/data"
+)
+
+// Matchmaker runs one GameInstance per 1v1 room. Players are paired in
+// arrival order: the first fills a fresh room, the second joins it and the
+// room is closed to newcomers. A room is torn down when its last player
+// leaves. One left alone mid-match never gets a new opponent dropped in: the
+// one who dropped either reconnects or forfeits (see reconnect.go).
+type Matchmaker struct {
+	mu        sync.Mutex
+	rooms     map[string]*GameInstance
+	seats     map[*GameInstance]int // Players routed into each room and not yet gone
+	waiting   *GameInstance         // Room with one player, nil when none
+	waitingID string                // Who is in it
+	roomSeq   int
+
+	units     map[string]UnitStats
+	unitsPath string
+
+	OnGameOver func(winnerTeam int, players map[*Player]bool, gameTime float64, stats []MatchStats)
+}
+
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{
+		rooms: make(map[string]*GameInstance),
+		seats: make(map[*GameInstance]int),
+		units: make(map[string]UnitStats),
+	}
+}
+
+// LoadUnits reads the card file every room plays with.
+func (m *Matchmaker) LoadUnits(path string) error {
+	units, err := readUnits(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.units = units
+	m.unitsPath = path
+	m.mu.Unlock()
+	log.Printf("[CHIBIKI] Loaded %d units from %s", len(units), path)
+	return nil
+}
+
+// ReloadUnits re-reads the card file LoadUnits used and pushes it into every
+// running room, see SetUnits. A bad file leaves the current cards untouched.
+func (m *Matchmaker) ReloadUnits(refresh bool) (int, error) {
+	m.mu.Lock()
+	path := m.unitsPath
+	m.mu.Unlock()
+	if path == "" {
+		return 0, fmt.Errorf("no units file loaded yet")
+	}
+
+	units, err := readUnits(path)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.units = units
+	for _, g := range m.rooms {
+		g.SetUnits(units, refresh)
+	}
+	log.Printf("[CHIBIKI] Reloaded %d units from %s into %d rooms (refresh=%v)", len(units), path, len(m.rooms), refresh)
+	return len(units), nil
+}
+
+// Join seats p in a room and registers them there. A player reconnecting
+// within ReconnectGrace goes back to the match they dropped out of.
+func (m *Matchmaker) Join(p *Player) *GameInstance {
+	m.mu.Lock()
+	g := m.heldRoom(p.ID)
+	switch {
+	case g != nil:
+	case m.waiting == nil || m.waitingID == p.ID:
+		// Nobody to pair with, or only themselves from another tab
+		g = m.openRoom()
+		m.waiting, m.waitingID = g, p.ID
+	default:
+		g = m.waiting
+		m.waiting, m.waitingID = nil, "" // Now full
+	}
+	m.seats[g]++
+	m.mu.Unlock()
+
+	g.Register <- p
+	return g
+}
+
+// JoinBot seats p in a private room against the practice bot. The room is
+// never offered to anyone else and closes when p leaves.
+func (m *Matchmaker) JoinBot(p *Player) *GameInstance {
+	m.mu.Lock()
+	g := m.openRoom()
+	m.seats[g]++
+	m.mu.Unlock()
+
+	bot := newBot(g)
+	g.Register <- p // Registered first, so p takes Team 0 and the bot Team 1
+	g.Register <- bot
+	go runBot(g, bot)
+	return g
+}
+
+// Leave unregisters p from g and shuts the room down once it's empty.
+func (m *Matchmaker) Leave(p *Player, g *GameInstance) {
+	// Dropped synchronously rather than through Unregister, so a held seat
+	// is already on record if the same user reconnects straight away
+	g.Mutex.Lock()
+	g.dropPlayer(p)
+	g.Mutex.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seats[g]--
+	if m.seats[g] > 0 {
+		return
+	}
+	delete(m.seats, g)
+	delete(m.rooms, g.ID)
+	if m.waiting == g {
+		m.waiting, m.waitingID = nil, ""
+	}
+	g.Stop()
+	g.dropSpectators()
+	log.Printf("[CHIBIKI] Closed room %s (%d open)", g.ID, len(m.rooms))
+}
+
+// heldRoom finds the room holding a seat for playerID. Caller holds m.mu.
+func (m *Matchmaker) heldRoom(playerID string) *GameInstance {
+	for _, g := range m.rooms {
+		if g.HoldsSeat(playerID) {
+			return g
+		}
+	}
+	return nil
+}
+
+// openRoom starts a new empty room. Caller holds m.mu.
+func (m *Matchmaker) openRoom() *GameInstance {
+	m.roomSeq++
+	g := NewGame()
+	g.ID = fmt.Sprintf("room-%d", m.roomSeq)
+	g.OnGameOver = m.OnGameOver
+	g.SetUnits(m.units, false)
+	g.InitTowers()
+	m.rooms[g.ID] = g
+	go g.StartLoop()
+	log.Printf("[CHIBIKI] Opened room %s (%d open)", g.ID, len(m.rooms))
+	return g
+}
+
+// Snapshot adds up every room for the lobby.
+func (m *Matchmaker) Snapshot() data.LiveStatus {
+	m.mu.Lock()
+	rooms := make([]*GameInstance, 0, len(m.rooms))
+	for _, g := range m.rooms {
+		rooms = append(rooms, g)
+	}
+	m.mu.Unlock()
+
+	status := data.LiveStatus{State: PhaseWaiting}
+	for _, g := range rooms {
+		s := g.Snapshot()
+		status.Players += s.Players
+		if s.InMatch {
+			status.InMatch = true
+			status.State = PhasePlaying
+		}
+	}
+	return status
+}
@@ -8,4 +8,8 @@ type Player struct {
 	Team   int
 	Conn   *websocket.Conn
 	Send   chan []byte
+	Deck   []string // Cards this player brought, nil for the default deck
+	IsBot  bool     // The practice opponent, see bot.go
+
+	Spectator bool // Watching only, see spectate.go
 }
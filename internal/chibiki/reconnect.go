@@ -0,0 +1,76 @@
+package chibiki
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReconnectGrace is how long a player who drops mid-match has to come back
+// before the seat is forfeited. The match is paused meanwhile (Update stops
+// while fewer than two players are connected).
+const ReconnectGrace = 20 * time.Second
+
+// droppedSeat holds a disconnected player's place in a running match.
+type droppedSeat struct {
+	team int
+	at   time.Time
+}
+
+// matchLive reports whether leaving now would cost the match.
+func (g *GameInstance) matchLive() bool {
+	return g.Phase == PhasePlaying && !g.GameOver && g.GameTime > 0
+}
+
+// dropPlayer takes player off the board. Mid-match their team and hand are
+// held for ReconnectGrace under their ID, which is their user ID, so the
+// same account reconnecting picks up where it left off. Caller holds
+// g.Mutex.
+func (g *GameInstance) dropPlayer(player *Player) {
+	if _, ok := g.Players[player]; !ok {
+		return
+	}
+	delete(g.Players, player)
+	close(player.Send)
+
+	if g.matchLive() {
+		g.dropped[player.ID] = droppedSeat{team: player.Team, at: time.Now()}
+		fmt.Printf("[CHIBIKI] %s dropped from %s, holding Team %d for %s\n", player.ID, g.ID, player.Team, ReconnectGrace)
+		return
+	}
+	delete(g.PlayerStates, player.ID)
+}
+
+// rejoin seats player back in their held place. ok is false when nothing is
+// held for them. Caller holds g.Mutex.
+func (g *GameInstance) rejoin(player *Player) bool {
+	seat, ok := g.dropped[player.ID]
+	if !ok {
+		return false
+	}
+	delete(g.dropped, player.ID)
+	player.Team = seat.team
+	return true
+}
+
+// HoldsSeat reports whether userID has a place waiting in this match.
+func (g *GameInstance) HoldsSeat(userID string) bool {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	_, ok := g.dropped[userID]
+	return ok && !g.GameOver
+}
+
+// checkForfeits ends the match once a dropped player's grace runs out. The
+// side still holding a seat wins. Caller holds g.Mutex.
+func (g *GameInstance) checkForfeits() {
+	for id, seat := range g.dropped {
+		if time.Since(seat.at) < ReconnectGrace {
+			continue
+		}
+		if g.matchLive() {
+			fmt.Printf("[CHIBIKI] %s did not return to %s. Auto-win for Team %d\n", id, g.ID, 1-seat.team)
+			g.finishGame(1 - seat.team) // Before the delete, so their stats are still counted
+		}
+		delete(g.dropped, id)
+	}
+}
@@ -0,0 +1,204 @@
+package chibiki
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// testCards covers each kind of card the engine treats differently.
+var testCards = map[string]UnitStats{
+	"knight":  {Key: "knight", Elixir: 3, HP: 600, Damage: 100, HitSpeed: 1, Speed: 4, Range: 1, Target: "ground"},
+	"archer":  {Key: "archer", Elixir: 3, HP: 300, Damage: 60, HitSpeed: 1, Speed: 4, Range: 5, Target: "ground"},
+	"hunter":  {Key: "hunter", Elixir: 3, HP: 300, Damage: 60, HitSpeed: 1, Speed: 4, Range: 5, Target: "air"},
+	"balloon": {Key: "balloon", Elixir: 5, HP: 800, Damage: 300, HitSpeed: 2, Speed: 3, Range: 1, Target: "ground", Type: TypeFlying},
+	"bomber":  {Key: "bomber", Elixir: 4, HP: 400, Damage: 100, HitSpeed: 1, Speed: 4, Range: 4, Target: "ground", SplashRadius: 1.5},
+	"zap":     {Key: "zap", Elixir: 2, Damage: 150, Range: 2.5, Target: "spell"},
+}
+
+// playingGame is a match under way between p0 at the bottom and p1 at the
+// top, both with full elixir and knight, archer, balloon and zap in hand.
+func playingGame() (*GameInstance, *Player, *Player) {
+	g := NewGame()
+	g.SetUnits(testCards, false)
+	p0 := &Player{ID: "p0", Team: 0, Send: make(chan []byte, 64)}
+	p1 := &Player{ID: "p1", Team: 1, Send: make(chan []byte, 64)}
+	g.Players[p0], g.Players[p1] = true, true
+	g.resetLocked()
+	g.Phase = PhasePlaying
+	for _, p := range []*Player{p0, p1} {
+		g.PlayerStates[p.ID] = &PlayerState{
+			Elixir: 10,
+			Hand:   []string{"knight", "archer", "balloon", "zap"},
+			Next:   "hunter",
+			Deck:   []string{"bomber"},
+		}
+	}
+	return g, p0, p1
+}
+
+// rejection is the reason in p's last spawn_rejected message, "" if none.
+func rejection(p *Player) string {
+	reason := ""
+	for len(p.Send) > 0 {
+		var msg struct{ Type, Reason string }
+		json.Unmarshal(<-p.Send, &msg)
+		if msg.Type == "spawn_rejected" {
+			reason = msg.Reason
+		}
+	}
+	return reason
+}
+
+// units counts team's non-tower entities.
+func units(g *GameInstance, team int) int {
+	n := 0
+	for _, e := range g.Entities {
+		if e.Team == team && e.Key != "king_tower" && e.Key != "princess_tower" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSpawnTooCloseToEnemy(t *testing.T) {
+	g, p0, _ := playingGame()
+	g.SpawnEntity("knight", "p1", 1, 9, 20) // Pushed over onto p0's half
+
+	if reason := g.SpawnUnit(p0, "knight", 9.5, 20.5); reason != RejectTooClose {
+		t.Fatalf("spawn on top of an enemy: %q, want %q", reason, RejectTooClose)
+	}
+	if got := rejection(p0); got != RejectTooClose {
+		t.Errorf("player told %q", got)
+	}
+	if g.PlayerStates["p0"].Elixir != 10 || units(g, 0) != 0 {
+		t.Fatal("a refused spawn cost elixir or placed a unit")
+	}
+
+	// Out of reach is fine, and spells may land right on top of it
+	if reason := g.SpawnUnit(p0, "knight", 9, 20+MinSpawnDistance+0.1); reason != "" {
+		t.Fatalf("spawn clear of the enemy: %q", reason)
+	}
+	if reason := g.SpawnUnit(p0, "zap", 9, 20); reason != "" {
+		t.Fatalf("spell on the enemy: %q", reason)
+	}
+}
+
+func TestSpawnRejectionReasons(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(g *GameInstance)
+		key   string
+		x, y  float64
+		want  string
+	}{
+		{"before kick-off", func(g *GameInstance) { g.Phase = PhaseWaiting }, "knight", 9, 24, RejectNotPlaying},
+		{"unknown card", nil, "dragon", 9, 24, RejectUnknownCard},
+		{"off the board", nil, "knight", -1, 24, RejectOutOfBounds},
+		{"on the king tower", nil, "knight", 9, 29, RejectOutOfBounds},
+		{"enemy half", nil, "knight", 9, 10, RejectWrongSide},
+		{"not seated", func(g *GameInstance) { delete(g.PlayerStates, "p0") }, "knight", 9, 24, RejectNotInMatch},
+		{"short of elixir", func(g *GameInstance) { g.PlayerStates["p0"].Elixir = 2 }, "knight", 9, 24, RejectNoElixir},
+		{"on an enemy", func(g *GameInstance) { g.SpawnEntity("knight", "p1", 1, 9, 24) }, "knight", 9, 24, RejectTooClose},
+		{"next card, not in hand", nil, "hunter", 9, 24, RejectCardNotInHand},
+	} {
+		g, p0, _ := playingGame()
+		if tc.setup != nil {
+			tc.setup(g)
+		}
+		before := len(g.Entities)
+
+		if reason := g.SpawnUnit(p0, tc.key, tc.x, tc.y); reason != tc.want {
+			t.Errorf("%s: rejected with %q, want %q", tc.name, reason, tc.want)
+			continue
+		}
+		if got := rejection(p0); got != tc.want {
+			t.Errorf("%s: player told %q, want %q", tc.name, got, tc.want)
+		}
+		if st := g.PlayerStates["p0"]; len(g.Entities) != before || (st != nil && st.UnitsDeployed != 0) {
+			t.Errorf("%s: a refused spawn still deployed", tc.name)
+		}
+	}
+}
+
+func TestSpawnBounds(t *testing.T) {
+	g, p0, p1 := playingGame()
+	for _, tc := range []struct {
+		p    *Player
+		key  string
+		x, y float64
+		want string
+	}{
+		{p0, "knight", -9999, 24, RejectOutOfBounds},
+		{p0, "knight", ArenaWidth + 0.5, 24, RejectOutOfBounds},
+		{p0, "knight", math.NaN(), 24, RejectOutOfBounds},
+		{p0, "knight", 9, ArenaHeight + 1, RejectOutOfBounds},
+		{p0, "zap", -1, 10, RejectOutOfBounds}, // Spells still land on the board
+		{p0, "knight", 9, BridgeY - 0.5, RejectWrongSide},
+		{p1, "knight", 9, BridgeY + 0.5, RejectWrongSide},
+		{p0, "knight", 9, 28, RejectOutOfBounds}, // Own king tower
+		{p1, "knight", 10, 3, RejectOutOfBounds},
+	} {
+		if reason := g.SpawnUnit(tc.p, tc.key, tc.x, tc.y); reason != tc.want {
+			t.Errorf("%s %s at (%v, %v): %q, want %q", tc.p.ID, tc.key, tc.x, tc.y, reason, tc.want)
+		}
+	}
+	if units(g, 0)+units(g, 1) != 0 {
+		t.Fatal("a refused spawn placed a unit")
+	}
+
+	// Valid deployments, right up to the edges of each side
+	for _, tc := range []struct {
+		p    *Player
+		key  string
+		x, y float64
+	}{
+		{p0, "knight", 0, BridgeY},
+		{p0, "archer", ArenaWidth, ArenaHeight},
+		{p0, "zap", 9, 3}, // A spell on the enemy king
+		{p1, "knight", 0, 0},
+		{p1, "archer", ArenaWidth, BridgeY},
+	} {
+		if reason := g.SpawnUnit(tc.p, tc.key, tc.x, tc.y); reason != "" {
+			t.Errorf("%s %s at (%v, %v): %q, want it placed", tc.p.ID, tc.key, tc.x, tc.y, reason)
+		}
+	}
+	if units(g, 0) != 2 || units(g, 1) != 2 {
+		t.Errorf("%d and %d units placed, want 2 each", units(g, 0), units(g, 1))
+	}
+}
+
+func TestSpawnAfterGameOverIsNoOp(t *testing.T) {
+	g, p0, _ := playingGame()
+	g.GameOver = true
+	before := len(g.Entities)
+
+	if reason := g.SpawnUnit(p0, "knight", 9, 24); reason != RejectNotPlaying {
+		t.Fatalf("spawn into a finished game: %q, want %q", reason, RejectNotPlaying)
+	}
+	st := g.PlayerStates["p0"]
+	if len(g.Entities) != before || st.Elixir != 10 || st.UnitsDeployed != 0 || st.Hand[0] != "knight" {
+		t.Fatalf("finished game changed: %d entities, elixir %v, hand %v", len(g.Entities), st.Elixir, st.Hand)
+	}
+
+	// Overtime is still play
+	g.GameOver, g.IsOvertime = false, true
+	if reason := g.SpawnUnit(p0, "knight", 9, 24); reason != "" {
+		t.Fatalf("spawn in overtime: %q", reason)
+	}
+}
+
+func TestResetRefusedMidMatch(t *testing.T) {
+	g, p0, _ := playingGame()
+	g.SpawnUnit(p0, "knight", 9, 24)
+	if g.TryReset() {
+		t.Fatal("one player reset a live match")
+	}
+	if units(g, 0) != 1 {
+		t.Fatal("the board was wiped")
+	}
+	g.GameOver = true
+	if !g.TryReset() || units(g, 0) != 0 {
+		t.Fatal("no reset after the match ended")
+	}
+}
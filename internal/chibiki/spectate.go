@@ -0,0 +1,66 @@
+package chibiki
+
+import "fmt"
+
+// Spectators watch a room without taking part: they get every state frame
+// with no "me" and no team, never hold a PlayerState and never count towards
+// the two players a match needs. They live outside g.Players, so coming and
+// going can't start, pause or reset a match.
+
+// newSpectator makes the read-only seat for userID. The ID is kept apart from
+// the user's own, so watching your own match can't touch your PlayerState.
+func newSpectator(userID string) *Player {
+	return &Player{
+		ID:        fmt.Sprintf("spectate-%s", userID),
+		UserID:    userID,
+		Team:      -1,
+		Send:      make(chan []byte, 256),
+		Spectator: true,
+	}
+}
+
+// AddSpectator starts sending state frames to p.
+func (g *GameInstance) AddSpectator(p *Player) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.Spectators[p] = true
+	fmt.Printf("[CHIBIKI] %s is watching %s\n", p.UserID, g.ID)
+}
+
+// RemoveSpectator stops p's frames and closes its Send. Safe to call twice.
+func (g *GameInstance) RemoveSpectator(p *Player) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.dropSpectator(p)
+}
+
+// dropSpectator is RemoveSpectator for callers already holding g.Mutex.
+func (g *GameInstance) dropSpectator(p *Player) {
+	if _, ok := g.Spectators[p]; !ok {
+		return
+	}
+	delete(g.Spectators, p)
+	close(p.Send)
+}
+
+// dropSpectators sends every spectator away, used when the room closes.
+func (g *GameInstance) dropSpectators() {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	for p := range g.Spectators {
+		g.dropSpectator(p)
+	}
+}
+
+// Spectate adds p as a spectator of roomID. ok is false when no such room is
+// open.
+func (m *Matchmaker) Spectate(p *Player, roomID string) (*GameInstance, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock() // Held so the room can't close in between
+	g, ok := m.rooms[roomID]
+	if !ok {
+		return nil, false
+	}
+	g.AddSpectator(p)
+	return g, true
+}
@@ -0,0 +1,76 @@
+package chibiki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"main/internal/auth"
+)
+
+// spectators is how many are watching g right now.
+func spectators(g *GameInstance) int {
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	return len(g.Spectators)
+}
+
+// waitFor polls cond for up to a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestSpectatorWatchesButCannotSpawn(t *testing.T) {
+	m := NewMatchmaker()
+	g, _, _ := playingGame()
+	g.ID = "room-1"
+	m.rooms[g.ID] = g // Not started, the test drives the broadcasts
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NewWebsocketHandler(m, nil)(w, auth.WithUserID(r, "p0")) // Watching their own match
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?spectate=room-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the spectator to join", func() bool { return spectators(g) == 1 })
+
+	g.BroadcastCustomState()
+	var state map[string]interface{}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state["type"] != "state" || state["spectating"] != true || state["me"] != nil {
+		t.Fatalf("spectator got %v, want the neutral state view", state)
+	}
+	if entities, _ := state["entities"].([]interface{}); len(entities) != len(g.Entities) {
+		t.Errorf("spectator sees %d entities, want all %d", len(entities), len(g.Entities))
+	}
+
+	spawn, _ := json.Marshal(map[string]interface{}{"type": "spawn", "key": "knight", "x": 9, "y": 24})
+	conn.WriteMessage(websocket.TextMessage, spawn)
+	conn.Close()
+	// The server only notices the close after reading past the spawn
+	waitFor(t, "the spectator to leave", func() bool { return spectators(g) == 0 })
+
+	g.Mutex.RLock()
+	defer g.Mutex.RUnlock()
+	if units(g, 0)+units(g, 1) != 0 {
+		t.Error("a spectator's spawn reached the board")
+	}
+	if st := g.PlayerStates["p0"]; st.Elixir != 10 || len(g.PlayerStates) != 2 || len(g.Players) != 2 {
+		t.Errorf("spectating changed the match: elixir %v, %d states, %d players", st.Elixir, len(g.PlayerStates), len(g.Players))
+	}
+}
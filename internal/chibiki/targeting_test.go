@@ -0,0 +1,84 @@
+package chibiki
+
+import "testing"
+
+// place puts a unit straight onto the board and returns it.
+func place(g *GameInstance, key string, team int, x, y float64) *Entity {
+	g.SpawnEntity(key, "", team, x, y)
+	return g.Entities[len(g.Entities)-1]
+}
+
+func TestTargetingRespectsAirAndGround(t *testing.T) {
+	g, _, _ := playingGame()
+	balloon := place(g, "balloon", 1, 9, 14)
+	knight := place(g, "knight", 1, 9, 12) // Further off than the balloon
+	archer := place(g, "archer", 0, 9, 16)
+	hunter := place(g, "hunter", 0, 9, 16)
+
+	if got := g.FindTarget(archer); got != knight {
+		t.Fatalf("anti-ground archer picked %v, want the knight past the balloon", got)
+	}
+	if got := g.FindTarget(hunter); got != balloon {
+		t.Fatalf("anti-air hunter picked %v, want the balloon", got)
+	}
+
+	knight.HP = 0
+	if got := g.FindTarget(archer); got != nil {
+		t.Errorf("archer with only a balloon in sight picked %s", got.Key)
+	}
+
+	// Towers shoot anything
+	tower := place(g, "princess_tower", 0, 9, 17)
+	if got := g.FindTarget(tower); got != balloon {
+		t.Errorf("tower picked %v, want the balloon", got)
+	}
+}
+
+func TestFliersIgnoreLanes(t *testing.T) {
+	g, _, _ := playingGame()
+	balloon := place(g, "balloon", 0, 9, 24)
+	knight := place(g, "knight", 0, 9, 24)
+
+	g.MoveDownLane(balloon, 0.5)
+	g.MoveDownLane(knight, 0.5)
+	if balloon.X != 9 || balloon.Y >= 24 {
+		t.Errorf("balloon moved to (%v, %v), want straight up the middle at the king", balloon.X, balloon.Y)
+	}
+	if knight.X <= 9 {
+		t.Errorf("knight at x %v, want heading for the lane at %v", knight.X, LaneRightX)
+	}
+}
+
+func TestSplashHitsClump(t *testing.T) {
+	g, _, _ := playingGame()
+	g.SpawnEntity("bomber", "p0", 0, 9, 20)
+	bomber := g.Entities[len(g.Entities)-1]
+	clump := []*Entity{place(g, "knight", 1, 9, 18), place(g, "knight", 1, 10, 18), place(g, "knight", 1, 9, 19)}
+	tower := place(g, "princess_tower", 1, 10, 17) // Towers take splash too
+	far := place(g, "knight", 1, 9, 15)
+	friend := place(g, "knight", 0, 9.5, 18)
+	flier := place(g, "balloon", 1, 8.5, 18) // The bomber can't hit air
+
+	g.Attack(bomber, clump[0])
+
+	for i, e := range append(clump, tower) {
+		if e.HP != e.MaxHP-100 {
+			t.Errorf("enemy %d (%s) at %v/%v, want one splash hit", i, e.Key, e.HP, e.MaxHP)
+		}
+	}
+	for name, e := range map[string]*Entity{"out of radius": far, "friendly": friend, "flying": flier} {
+		if e.HP != e.MaxHP {
+			t.Errorf("%s unit took damage: %v/%v", name, e.HP, e.MaxHP)
+		}
+	}
+	if dealt := g.PlayerStates["p0"].DamageDealt; dealt != 400 {
+		t.Errorf("credited %v damage, want 400", dealt)
+	}
+
+	// Without a radius only the target is hit
+	bomber.Stats.SplashRadius = 0
+	g.Attack(bomber, clump[0])
+	if clump[1].HP != clump[1].MaxHP-100 || clump[0].HP != clump[0].MaxHP-200 {
+		t.Errorf("single-target hit spread: %v and %v", clump[0].HP, clump[1].HP)
+	}
+}
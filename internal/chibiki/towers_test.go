@@ -0,0 +1,78 @@
+package chibiki
+
+import "testing"
+
+func tower(key string, team int, hp float64) *Entity {
+	return &Entity{Key: key, Team: team, HP: hp}
+}
+
+func decide(suddenDeath bool, towers ...*Entity) (int, bool) {
+	var l towerLosses
+	for _, e := range towers {
+		l.tally(e)
+	}
+	return l.winner(suddenDeath)
+}
+
+func TestKingBeatsSimultaneousPrincess(t *testing.T) {
+	// Team 1's king and team 0's princess fall on the same tick in sudden death
+	for i := 0; i < 20; i++ {
+		winner, ok := decide(true,
+			tower("king_tower", 0, 500), tower("princess_tower", 0, 0),
+			tower("king_tower", 1, 0), tower("princess_tower", 1, 300),
+		)
+		if !ok || winner != 0 {
+			t.Fatalf("winner %d, %v; want team 0", winner, ok)
+		}
+	}
+}
+
+func TestBothKingsFallMoreStandingWins(t *testing.T) {
+	winner, ok := decide(false,
+		tower("king_tower", 0, 0), tower("princess_tower", 0, 100),
+		tower("king_tower", 1, 0),
+	)
+	if !ok || winner != 0 {
+		t.Fatalf("winner %d, %v; want team 0 with a tower left", winner, ok)
+	}
+}
+
+func TestExactTowerTieIsDraw(t *testing.T) {
+	winner, ok := decide(false,
+		tower("king_tower", 0, 0), tower("princess_tower", 0, 100),
+		tower("king_tower", 1, 0), tower("princess_tower", 1, 100),
+	)
+	if !ok || winner != DrawTeam {
+		t.Fatalf("winner %d, %v; want a draw", winner, ok)
+	}
+}
+
+func TestPrincessOnlyCountsInSuddenDeath(t *testing.T) {
+	towers := []*Entity{tower("king_tower", 0, 500), tower("princess_tower", 0, 0), tower("king_tower", 1, 500)}
+	if _, ok := decide(false, towers...); ok {
+		t.Fatal("a princess tower decided the match in normal time")
+	}
+	if winner, ok := decide(true, towers...); !ok || winner != 1 {
+		t.Fatalf("sudden death winner %d, %v; want team 1", winner, ok)
+	}
+}
+
+func TestDrawEndsMatch(t *testing.T) {
+	g := &GameInstance{WinnerTeam: -1}
+	g.finishGame(DrawTeam)
+	if !g.GameOver || g.WinnerTeam != DrawTeam {
+		t.Fatalf("game over %v winner %d", g.GameOver, g.WinnerTeam)
+	}
+}
+
+func TestDamageClampsAtZero(t *testing.T) {
+	g := &GameInstance{PlayerStates: map[string]*PlayerState{"p1": {}}}
+	target := tower("princess_tower", 1, 40)
+	g.Attack(&Entity{OwnerID: "p1", Stats: UnitStats{Damage: 100}}, target)
+	if target.HP != 0 {
+		t.Fatalf("hp %v after overkill, want 0", target.HP)
+	}
+	if dealt := g.PlayerStates["p1"].DamageDealt; dealt != 40 {
+		t.Fatalf("credited %v damage, want the 40 that landed", dealt)
+	}
+}
@@ -10,17 +10,66 @@ const (
 	TypeSpell    UnitType = "spell"
 )
 
+// validTypes lists the unit_type values LoadUnits accepts.
+var validTypes = map[UnitType]bool{
+	"":           true,
+	TypeGround:   true,
+	TypeFlying:   true,
+	TypeBuilding: true,
+}
+
+// validTargets lists the target_type values LoadUnits accepts.
+var validTargets = map[string]bool{
+	"ground": true,
+	"air":    true,
+	"all":    true,
+	"spell":  true,
+}
+
 type UnitStats struct {
-	Key      string  `json:"key"`
-	Name     string  `json:"name"`
-	Elixir   int     `json:"elixir"`
-	HP       float64 `json:"hp"`
-	Damage   float64 `json:"damage"`
-	HitSpeed float64 `json:"hit_speed"`
-	Speed    float64 `json:"speed"`
-	Range    float64 `json:"range"`
-	Target   string  `json:"target_type"` // e.g. "ground", "all"
-	Ability  string  `json:"ability"`
+	Key          string   `json:"key"`
+	Name         string   `json:"name"`
+	Elixir       int      `json:"elixir"`
+	HP           float64  `json:"hp"`
+	Damage       float64  `json:"damage"`
+	HitSpeed     float64  `json:"hit_speed"`
+	Speed        float64  `json:"speed"`
+	Range        float64  `json:"range"`
+	SplashRadius float64  `json:"splash_radius"` // Hits every enemy this close to the target, 0 = single target
+	Target       string   `json:"target_type"`   // What it can hit: "ground", "air", "all", or "spell"
+	Type         UnitType `json:"unit_type"`     // What it is, empty means ground
+	Ability      string   `json:"ability"`
+}
+
+// Kind is the unit's type, with ground standing in for an unset one.
+func (s UnitStats) Kind() UnitType {
+	if s.Type == "" {
+		return TypeGround
+	}
+	return s.Type
+}
+
+// CanHit reports whether a unit with these stats may attack target. Flying
+// units are only reachable by "air" and "all" attackers; everything else,
+// buildings included, sits on the ground.
+func (s UnitStats) CanHit(target UnitStats) bool {
+	flying := target.Kind() == TypeFlying
+	switch s.Target {
+	case "all":
+		return true
+	case "air":
+		return flying
+	case "ground":
+		return !flying
+	}
+	return false
+}
+
+// IsSpell reports whether the card is a spell: it lands, deals Damage to
+// every enemy within Range of the drop point, towers included, and is gone.
+// It never becomes an Entity.
+func (s UnitStats) IsSpell() bool {
+	return s.Target == string(TypeSpell)
 }
 
 type Entity struct {
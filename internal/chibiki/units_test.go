@@ -0,0 +1,85 @@
+package chibiki
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadUnitsRejectsBadFiles(t *testing.T) {
+	for name, body := range map[string]string{
+		"malformed":    `{"units": {"knight": {"hp": 100,}}}`,
+		"empty":        `{"units": {}}`,
+		"no hp":        `{"units": {"knight": {"hp": 0, "target_type": "ground"}}}`,
+		"bad target":   `{"units": {"knight": {"hp": 100, "target_type": "sea"}}}`,
+		"bad unittype": `{"units": {"knight": {"hp": 100, "target_type": "ground", "unit_type": "submarine"}}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "units.json")
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if units, err := readUnits(path); err == nil {
+			t.Errorf("%s: loaded %d units, want an error", name, len(units))
+		}
+	}
+}
+
+func TestReadUnitsShippedFile(t *testing.T) {
+	units, err := readUnits(filepath.Join("..", "data", "units.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, u := range units {
+		if u.Key != key {
+			t.Errorf("unit %q keyed as %q", key, u.Key)
+		}
+	}
+}
+
+func TestReloadUnitsChangesCostOfLaterSpawns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "units.json")
+	write := func(elixir string) {
+		body := `{"units": {"knight": {"elixir": ` + elixir + `, "hp": 600, "damage": 100, "hit_speed": 1, "speed": 4, "range": 1, "target_type": "ground"}}}`
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("3")
+	m := NewMatchmaker()
+	if err := m.LoadUnits(path); err != nil {
+		t.Fatal(err)
+	}
+	g, p0, _ := playingGame()
+	g.SetUnits(m.units, false)
+	m.rooms["room-1"] = g // Not started, so nothing ticks under the test
+
+	if reason := g.SpawnUnit(p0, "knight", 4, 24); reason != "" {
+		t.Fatalf("spawn before the reload: %q", reason)
+	}
+	write("5")
+	if n, err := m.ReloadUnits(false); err != nil || n != 1 {
+		t.Fatalf("reload: %d units, %v", n, err)
+	}
+	g.PlayerStates["p0"].Hand[0] = "knight" // Cycled out by the first spawn
+	if reason := g.SpawnUnit(p0, "knight", 14, 24); reason != "" {
+		t.Fatalf("spawn after the reload: %q", reason)
+	}
+
+	if elixir := g.PlayerStates["p0"].Elixir; elixir != 10-3-5 {
+		t.Errorf("elixir %v after a knight before and after the reload, want %v", elixir, 10-3-5)
+	}
+	if g.UnitData["king_tower"].HP == 0 {
+		t.Error("reload dropped the tower stats")
+	}
+	for _, e := range g.Entities {
+		if e.Key == "knight" && e.X == 4 && e.Stats.Elixir != 3 {
+			t.Errorf("knight already on the board now costs %d without a refresh", e.Stats.Elixir)
+		}
+	}
+
+	// A broken edit keeps the cards in play
+	os.WriteFile(path, []byte(`{"units": {}}`), 0o644)
+	if _, err := m.ReloadUnits(false); err == nil || g.UnitData["knight"].Elixir != 5 {
+		t.Errorf("bad file: %v, knight costs %d", err, g.UnitData["knight"].Elixir)
+	}
+}
@@ -2,10 +2,11 @@ package chibiki
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-	"time"
+
+	"main/internal/auth"
+	"main/internal/data"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,37 +17,80 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin:     func(r *http.Request) bool { return true },
 }
 
-func NewWebsocketHandler(g *GameInstance) http.HandlerFunc {
+// NewWebsocketHandler seats each connection in a room through m, or against
+// the practice bot with ?bot=1. ?spectate=<room> watches a running room
+// instead, see spectate.go.
+func NewWebsocketHandler(m *Matchmaker, store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.WebsocketUser(w, r)
+		if !ok {
+			return
+		}
+		if roomID := r.URL.Query().Get("spectate"); roomID != "" {
+			serveSpectator(w, r, m, userID, roomID)
+			return
+		}
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
-		userID := r.URL.Query().Get("userID")
-		if userID == "" {
-			userID = "guest" // Default user ID
-		}
-
-		playerID := fmt.Sprintf("p-%d", time.Now().UnixNano())
-
+		// Keyed by account so a dropped player can reclaim their seat
 		player := &Player{
-			ID:     playerID,
+			ID:     userID,
 			UserID: userID,
 			Conn:   conn,
 			Send:   make(chan []byte, 256),
+			Deck:   m.savedDeck(store, userID),
 		}
 
-		g.Register <- player
+		var g *GameInstance
+		if r.URL.Query().Get("bot") == "1" {
+			g = m.JoinBot(player)
+		} else {
+			g = m.Join(player)
+		}
+		store.SetActivity(userID, data.ActivityChibiki)
 		go writePump(player)
-		go readPump(player, g)
+		go readPump(player, m, g, store)
+	}
+}
+
+// serveSpectator connects a read-only watcher. Whatever they send is read
+// and thrown away, so a spawn from a spectator never reaches the game.
+func serveSpectator(w http.ResponseWriter, r *http.Request, m *Matchmaker, userID, roomID string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
 	}
+	spectator := newSpectator(userID)
+	spectator.Conn = conn
+	g, ok := m.Spectate(spectator, roomID)
+	if !ok {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "no such room"))
+		conn.Close()
+		return
+	}
+	go writePump(spectator)
+	go func() {
+		defer func() {
+			g.RemoveSpectator(spectator)
+			conn.Close()
+		}()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
 }
 
-func readPump(p *Player, g *GameInstance) {
+func readPump(p *Player, m *Matchmaker, g *GameInstance, store *data.Store) {
 	defer func() {
-		g.Unregister <- p
+		m.Leave(p, g)
+		store.ClearActivity(p.UserID, data.ActivityChibiki)
 		p.Conn.Close()
 	}()
 
@@ -67,7 +111,9 @@ func readPump(p *Player, g *GameInstance) {
 			if input.Type == "spawn" {
 				g.SpawnUnit(p, input.Key, input.X, input.Y)
 			} else if input.Type == "reset" {
-				g.Reset()
+				if !g.TryReset() {
+					log.Printf("[CHIBIKI] Ignored reset from %s: match in progress", p.ID)
+				}
 			}
 		}
 	}
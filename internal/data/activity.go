@@ -0,0 +1,35 @@
+package data
+
+// Activities shown to friends while a user has a game socket open.
+const (
+	ActivityChibiki    = "chibiki"
+	ActivityBobik      = "bobik"
+	ActivityParty      = "party"
+	ActivitySlotix     = "slotix"
+	ActivityUpsideDown = "upsidedown"
+)
+
+// SetActivity records which game the user just connected to. It also bumps
+// last_seen, since the lobby's presence ping doesn't run inside game pages.
+func (s *Store) SetActivity(userID, activity string) {
+	if userID == "" {
+		return
+	}
+	_, _ = s.db.Exec(`UPDATE users SET current_activity = $1, last_seen = NOW() WHERE id = $2`, activity, userID)
+}
+
+// ClearActivity is called on disconnect. It only clears when the activity
+// still matches, so leaving one game doesn't wipe a newer one from another tab.
+func (s *Store) ClearActivity(userID, activity string) {
+	if userID == "" {
+		return
+	}
+	_, _ = s.db.Exec(`UPDATE users SET current_activity = '', last_seen = NOW() WHERE id = $1 AND current_activity = $2`, userID, activity)
+}
+
+// LiveStatus is a game's current head count, shown on the lobby cards.
+type LiveStatus struct {
+	Players int    `json:"players"`
+	InMatch bool   `json:"inMatch"`         // A match/round is underway
+	State   string `json:"state,omitempty"` // Game-specific phase, for display
+}
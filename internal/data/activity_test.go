@@ -0,0 +1,49 @@
+package data_test
+
+import (
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+// activityOf is what friendID's friend list shows for userID.
+func activityOf(t *testing.T, s *data.Store, friendID, userID string) (string, string) {
+	t.Helper()
+	friends, err := s.ListFriends(friendID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fr := range friends {
+		if fr.ID == userID {
+			return fr.Activity, fr.Presence
+		}
+	}
+	t.Fatalf("%s not in the friend list", userID)
+	return "", ""
+}
+
+func TestActivityClearsOnDisconnect(t *testing.T) {
+	s, db := datatest.Store(t)
+	me, friend := datatest.User(t, db, 0), datatest.User(t, db, 0)
+	s.RequestFriend(me, friend)
+	if err := s.AcceptFriend(friend, me); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetActivity(me, data.ActivityChibiki)
+	if activity, presence := activityOf(t, s, friend, me); activity != data.ActivityChibiki || presence != "online" {
+		t.Fatalf("connected: %q %q, want chibiki online", activity, presence)
+	}
+
+	// Leaving another game's tab doesn't wipe this one
+	s.ClearActivity(me, data.ActivityBobik)
+	if activity, _ := activityOf(t, s, friend, me); activity != data.ActivityChibiki {
+		t.Fatalf("after leaving bobik: %q", activity)
+	}
+
+	s.ClearActivity(me, data.ActivityChibiki)
+	if activity, _ := activityOf(t, s, friend, me); activity != "" {
+		t.Errorf("after disconnecting: %q, want cleared", activity)
+	}
+}
@@ -0,0 +1,88 @@
+package data
+
+import "time"
+
+// userCacheTTL bounds how stale a cached GetUser result may be. Writes made
+// through the Store invalidate immediately; this only covers writes done by
+// other packages (presence, auth) straight against the DB.
+const userCacheTTL = 2 * time.Second
+
+type cachedUser struct {
+	user    UserData
+	expires time.Time
+}
+
+func (s *Store) cachedUser(id string) (UserData, bool) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	c, ok := s.userCache[id]
+	if !ok {
+		return UserData{}, false
+	}
+	if time.Now().After(c.expires) {
+		delete(s.userCache, id)
+		s.forgetUser(id)
+		return UserData{}, false
+	}
+	return c.user, true
+}
+
+// userGeneration is how many times id has been invalidated. Take it before
+// reading the row and hand it to cacheUser, or to dropRead if the read
+// fails: until then the read counts as in flight.
+func (s *Store) userGeneration(id string) uint64 {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.userReads[id]++
+	return s.userGen[id]
+}
+
+// cacheUser finishes a read, storing u unless the user was invalidated since
+// gen was taken: then u may predate that write and caching it would bring it
+// back.
+func (s *Store) cacheUser(u UserData, gen uint64) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.endRead(u.ID)
+	if s.userGen[u.ID] != gen {
+		s.forgetUser(u.ID)
+		return
+	}
+	s.userCache[u.ID] = cachedUser{user: u, expires: time.Now().Add(userCacheTTL)}
+}
+
+// dropRead finishes a read that found nothing to cache.
+func (s *Store) dropRead(id string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.endRead(id)
+	s.forgetUser(id)
+}
+
+// endRead takes a read off the in-flight count. Caller holds cacheMu.
+func (s *Store) endRead(id string) {
+	if s.userReads[id]--; s.userReads[id] <= 0 {
+		delete(s.userReads, id)
+	}
+}
+
+// forgetUser drops id's generation once nothing is cached for it and no
+// read is in flight: nobody holds a gen to compare against, so starting
+// over from zero is safe. Caller holds cacheMu.
+func (s *Store) forgetUser(id string) {
+	if _, cached := s.userCache[id]; cached || s.userReads[id] > 0 {
+		return
+	}
+	delete(s.userGen, id)
+}
+
+// InvalidateUser drops any cached copy of the user, and any read already in
+// flight from being cached. Call it after writing to the users row outside
+// the Store.
+func (s *Store) InvalidateUser(id string) {
+	s.cacheMu.Lock()
+	delete(s.userCache, id)
+	s.userGen[id]++
+	s.forgetUser(id)
+	s.cacheMu.Unlock()
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func cacheOnlyStore() *Store {
+	return &Store{userCache: make(map[string]cachedUser), userGen: make(map[string]uint64), userReads: make(map[string]int)}
+}
+
+func TestUserCacheHitAndExpiry(t *testing.T) {
+	s := cacheOnlyStore()
+	s.cacheUser(UserData{ID: "u1", Coins: 10}, s.userGeneration("u1"))
+	if u, ok := s.cachedUser("u1"); !ok || u.Coins != 10 {
+		t.Fatalf("cached %+v %v", u, ok)
+	}
+
+	s.cacheMu.Lock()
+	c := s.userCache["u1"]
+	c.expires = time.Now().Add(-time.Millisecond)
+	s.userCache["u1"] = c
+	s.cacheMu.Unlock()
+	if _, ok := s.cachedUser("u1"); ok {
+		t.Fatal("expired entry served")
+	}
+	if len(s.userCache) != 0 || len(s.userGen) != 0 {
+		t.Fatalf("expired entry kept: %d cached, %d generations", len(s.userCache), len(s.userGen))
+	}
+}
+
+func TestCacheForgetsIdleUsers(t *testing.T) {
+	s := cacheOnlyStore()
+
+	// Invalidating someone nobody is reading leaves nothing behind
+	s.cacheUser(UserData{ID: "u1"}, s.userGeneration("u1"))
+	s.InvalidateUser("u1")
+	s.InvalidateUser("u2")
+
+	// A failed read and a read beaten by a write don't either
+	s.userGeneration("u3")
+	s.dropRead("u3")
+	gen := s.userGeneration("u4")
+	s.InvalidateUser("u4")
+	if s.userGen["u4"] == 0 {
+		t.Fatal("generation dropped while a read was in flight")
+	}
+	s.cacheUser(UserData{ID: "u4"}, gen)
+
+	if len(s.userCache) != 0 || len(s.userGen) != 0 || len(s.userReads) != 0 {
+		t.Fatalf("left %d cached, %d generations, %d reads", len(s.userCache), len(s.userGen), len(s.userReads))
+	}
+}
+
+func TestInvalidateDropsEntry(t *testing.T) {
+	s := cacheOnlyStore()
+	s.cacheUser(UserData{ID: "u1"}, s.userGeneration("u1"))
+	s.InvalidateUser("u1")
+	if _, ok := s.cachedUser("u1"); ok {
+		t.Fatal("invalidated entry served")
+	}
+}
+
+func TestStaleReadNotCached(t *testing.T) {
+	s := cacheOnlyStore()
+
+	// A read starts, a write lands and invalidates, then the read finishes
+	gen := s.userGeneration("u1")
+	s.InvalidateUser("u1")
+	s.cacheUser(UserData{ID: "u1", Coins: 100}, gen)
+	if u, ok := s.cachedUser("u1"); ok {
+		t.Fatalf("row read before the invalidation was cached: %+v", u)
+	}
+
+	// Other users are unaffected, and the next read caches again
+	s.cacheUser(UserData{ID: "u2"}, s.userGeneration("u2"))
+	s.cacheUser(UserData{ID: "u1", Coins: 40}, s.userGeneration("u1"))
+	if _, ok := s.cachedUser("u2"); !ok {
+		t.Error("u2 not cached")
+	}
+	if u, ok := s.cachedUser("u1"); !ok || u.Coins != 40 {
+		t.Errorf("fresh read not cached: %+v %v", u, ok)
+	}
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Daily bonus: one claim per UTC day. Claiming on consecutive days builds a
+// streak that pays more, up to DailyStreakCap days; missing a day starts the
+// streak over.
+const (
+	DailyBonusBase    = 50
+	DailyBonusPerDay  = 25
+	DailyStreakCap    = 7
+	dailyClaimDateFmt = "2006-01-02"
+)
+
+var ErrAlreadyClaimed = errors.New("daily bonus already claimed today")
+
+// DailyBonusFor is what the streak-th consecutive day pays.
+func DailyBonusFor(streak int) int {
+	if streak > DailyStreakCap {
+		streak = DailyStreakCap
+	}
+	return DailyBonusBase + DailyBonusPerDay*(streak-1)
+}
+
+// ClaimDailyBonus pays today's bonus and returns it with the new streak.
+func (s *Store) ClaimDailyBonus(userID string) (int, int, error) {
+	return s.claimDailyBonus(userID, time.Now().UTC())
+}
+
+func (s *Store) claimDailyBonus(userID string, now time.Time) (int, int, error) {
+	defer s.InvalidateUser(userID)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes a second claim from another tab wait, then see
+	// today's date and fail
+	var last sql.NullString
+	var streak int
+	err = tx.QueryRow(`
+		SELECT TO_CHAR(last_daily_claim, 'YYYY-MM-DD'), daily_streak
+		FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&last, &streak)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	today := now.Format(dailyClaimDateFmt)
+	yesterday := now.AddDate(0, 0, -1).Format(dailyClaimDateFmt)
+	switch {
+	case last.String == today:
+		return 0, 0, ErrAlreadyClaimed
+	case last.String == yesterday:
+		streak++
+	default:
+		streak = 1
+	}
+	amount := DailyBonusFor(streak)
+
+	var balance int
+	err = tx.QueryRow(`
+		UPDATE users
+		SET coins = coins + $1, last_daily_claim = $2, daily_streak = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING coins
+	`, amount, today, streak, userID).Scan(&balance)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := writeLedger(tx, userID, amount, balance, ReasonDailyBonus, today); err != nil {
+		return 0, 0, err
+	}
+	return amount, streak, tx.Commit()
+}
@@ -0,0 +1,47 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestDailyBonusFor(t *testing.T) {
+	for streak, want := range map[int]int{1: 50, 2: 75, 7: 200, 30: 200} {
+		if got := data.DailyBonusFor(streak); got != want {
+			t.Errorf("day %d pays %d, want %d", streak, got, want)
+		}
+	}
+}
+
+func TestDailyBonusStreak(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	day := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	claim := func(at time.Time, wantCoins, wantStreak int) {
+		t.Helper()
+		coins, streak, err := data.ClaimDailyBonusAt(s, id, at)
+		if err != nil || coins != wantCoins || streak != wantStreak {
+			t.Fatalf("claim at %s: %d coins, streak %d, %v; want %d, %d", at, coins, streak, err, wantCoins, wantStreak)
+		}
+	}
+
+	claim(day, 50, 1)
+	if _, _, err := data.ClaimDailyBonusAt(s, id, day.Add(30*time.Minute)); !errors.Is(err, data.ErrAlreadyClaimed) {
+		t.Fatalf("second claim the same day: %v, want ErrAlreadyClaimed", err)
+	}
+	// Two hours later is already the next UTC day
+	claim(day.Add(2*time.Hour), 75, 2)
+	claim(day.AddDate(0, 0, 2), 100, 3)
+
+	// Skipping a day starts over
+	claim(day.AddDate(0, 0, 4), 50, 1)
+
+	if u, _ := s.GetUserFresh(id); u.Coins != 50+75+100+50 {
+		t.Errorf("balance %d, want %d", u.Coins, 50+75+100+50)
+	}
+}
@@ -0,0 +1,57 @@
+// Package datatest opens a real store for database tests, package data's
+// own (as data_test) included, so every package shares one user fixture.
+// Tests using it are skipped unless TEST_DATABASE_URL names a database that
+// already carries the server's schema (start the server against it once).
+package datatest
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"main/internal/data"
+
+	"github.com/google/uuid"
+)
+
+// Store opens the test database, or skips t when there is none.
+func Store(t *testing.T) (*data.Store, *sql.DB) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// medals.json sits next to the data package, wherever the test runs from
+	_, here, _, _ := runtime.Caller(0)
+	store, err := data.NewStore(db, filepath.Join(filepath.Dir(here), "..", "medals.json"))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store, db
+}
+
+// User inserts a throwaway user holding coins and deletes it when t ends.
+func User(t *testing.T, db *sql.DB, coins int) string {
+	t.Helper()
+	id := "u_test_" + uuid.NewString()
+	nick := fmt.Sprintf("test%d", rand.Intn(1e6))
+	_, err := db.Exec(`
+		INSERT INTO users (id, nickname, nickname_lower, tag, level, exp, max_exp, status, password_hash, language, coins)
+		VALUES ($1, $2, lower($2), $3, 1, 0, 1000, 'online', '', 'en', $4)
+	`, id, nick, rand.Intn(9999)+1, coins)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
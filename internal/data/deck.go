@@ -0,0 +1,36 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// GetChibikiDeck returns the user's saved chibiki deck, nil when they never
+// picked one. The caller checks the keys against the cards in play.
+func (s *Store) GetChibikiDeck(userID string) ([]string, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT chibiki_deck FROM users WHERE id = $1`, userID).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && raw == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var deck []string
+	if err := json.Unmarshal([]byte(raw), &deck); err != nil {
+		return nil, nil // Unreadable counts as never picked
+	}
+	return deck, nil
+}
+
+// SaveChibikiDeck stores the user's deck. Validation is the caller's job,
+// only the game knows which cards exist.
+func (s *Store) SaveChibikiDeck(userID string, deck []string) error {
+	raw, err := json.Marshal(deck)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE users SET chibiki_deck = $1, updated_at = NOW() WHERE id = $2`, string(raw), userID)
+	return err
+}
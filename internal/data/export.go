@@ -0,0 +1,235 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// ExportMessageLimit caps how many chat messages an export includes, newest
+// first. Everything else is exported in full.
+const ExportMessageLimit = 5000
+
+// ErrUserNotFound is returned by ExportUser for an unknown user id.
+var ErrUserNotFound = errors.New("user not found")
+
+// ExportUser writes everything stored about userID to w as one JSON object:
+// profile, settings, medals, medal_progress, inventory, friendships, messages,
+// ledger (coin history, which is where match results are recorded), purchases
+// and warthunder_replay.
+//
+// Rows are streamed as they're read rather than collected first, so a long
+// chat or coin history doesn't balloon memory. Once writing has started an
+// error leaves the JSON truncated; the caller can only log it.
+func (s *Store) ExportUser(userID string, w io.Writer) error {
+	u, ok := s.GetUserFresh(userID)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	ex := &exportWriter{db: s.db, w: w, enc: json.NewEncoder(w)}
+	ex.raw("{")
+	ex.field("exported_at", time.Now().UTC())
+	ex.field("profile", u)
+	if settings, err := s.GetSettings(userID); err != nil {
+		ex.err = err
+	} else {
+		ex.field("settings", settings)
+	}
+
+	ex.rows("medals", `
+		SELECT um.medal_id, COALESCE(m.name, ''), um.awarded_at
+		FROM user_medals um LEFT JOIN medals m ON m.id = um.medal_id
+		WHERE um.user_id = $1 ORDER BY um.awarded_at
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			ID        string    `json:"id"`
+			Name      string    `json:"name"`
+			AwardedAt time.Time `json:"awarded_at"`
+		}
+		err := r.Scan(&row.ID, &row.Name, &row.AwardedAt)
+		return row, err
+	})
+
+	ex.rows("medal_progress", `
+		SELECT medal_id, progress, updated_at FROM user_medal_progress
+		WHERE user_id = $1 ORDER BY medal_id
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			MedalID   string    `json:"medal_id"`
+			Progress  int       `json:"progress"`
+			UpdatedAt time.Time `json:"updated_at"`
+		}
+		err := r.Scan(&row.MedalID, &row.Progress, &row.UpdatedAt)
+		return row, err
+	})
+
+	ex.rows("inventory", `
+		SELECT item_id, acquired_at FROM inventory WHERE user_id = $1 ORDER BY acquired_at
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			ItemID     string    `json:"item_id"`
+			AcquiredAt time.Time `json:"acquired_at"`
+		}
+		err := r.Scan(&row.ItemID, &row.AcquiredAt)
+		return row, err
+	})
+
+	// Only the friend's public handle, nothing else of theirs
+	ex.rows("friendships", `
+		SELECT u.id, u.nickname, u.tag, f.status, f.requester_id = $1, f.created_at
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE f.requester_id = $1 OR f.addressee_id = $1
+		ORDER BY f.created_at
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			UserID    string    `json:"user_id"`
+			Nickname  string    `json:"nickname"`
+			Tag       int       `json:"tag"`
+			Status    string    `json:"status"`
+			Requested bool      `json:"requested_by_me"`
+			Since     time.Time `json:"since"`
+		}
+		err := r.Scan(&row.UserID, &row.Nickname, &row.Tag, &row.Status, &row.Requested, &row.Since)
+		return row, err
+	})
+
+	ex.rows("messages", `
+		SELECT id, sender_id = $1, CASE WHEN sender_id = $1 THEN receiver_id ELSE sender_id END,
+		       text, created_at, delivered, seen
+		FROM messages
+		WHERE sender_id = $1 OR receiver_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, []interface{}{userID, ExportMessageLimit}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			ID        int64     `json:"id"`
+			Sent      bool      `json:"sent"`
+			With      string    `json:"with"`
+			Text      string    `json:"text"`
+			CreatedAt time.Time `json:"created_at"`
+			Delivered bool      `json:"delivered"`
+			Seen      bool      `json:"seen"`
+		}
+		err := r.Scan(&row.ID, &row.Sent, &row.With, &row.Text, &row.CreatedAt, &row.Delivered, &row.Seen)
+		return row, err
+	})
+
+	ex.rows("ledger", `
+		SELECT id, delta, balance_after, reason, ref_id, created_at
+		FROM coin_ledger WHERE user_id = $1 ORDER BY id
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row LedgerEntry
+		err := r.Scan(&row.ID, &row.Delta, &row.BalanceAfter, &row.Reason, &row.RefID, &row.CreatedAt)
+		return row, err
+	})
+
+	ex.rows("purchases", `
+		SELECT item_id, currency, price, coins_granted, gems_granted, created_at
+		FROM purchases WHERE user_id = $1 ORDER BY id
+	`, []interface{}{userID}, func(r *sql.Rows) (interface{}, error) {
+		var row struct {
+			ItemID       string    `json:"item_id"`
+			Currency     string    `json:"currency"`
+			Price        int       `json:"price"`
+			CoinsGranted int       `json:"coins_granted"`
+			GemsGranted  int       `json:"gems_granted"`
+			CreatedAt    time.Time `json:"created_at"`
+		}
+		err := r.Scan(&row.ItemID, &row.Currency, &row.Price, &row.CoinsGranted, &row.GemsGranted, &row.CreatedAt)
+		return row, err
+	})
+
+	if ex.err == nil {
+		if stats, err := s.GetShooterStats(userID); err != nil {
+			ex.err = err
+		} else {
+			ex.field("bobik_stats", stats)
+		}
+	}
+
+	if ex.err == nil {
+		replay, err := s.GetWarthunderReplay(userID)
+		if err != nil {
+			ex.err = err
+		} else if replay != nil {
+			ex.field("warthunder_replay", json.RawMessage(replay))
+		} else {
+			ex.field("warthunder_replay", nil)
+		}
+	}
+
+	ex.raw("}\n")
+	return ex.err
+}
+
+// exportWriter streams a JSON object one field at a time. The first error
+// sticks and every later call becomes a no-op.
+type exportWriter struct {
+	db      *sql.DB
+	w       io.Writer
+	enc     *json.Encoder
+	started bool // A field has been written, so the next needs a comma
+	err     error
+}
+
+func (e *exportWriter) raw(s string) {
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+func (e *exportWriter) key(name string) {
+	if e.started {
+		e.raw(",")
+	}
+	e.started = true
+	b, _ := json.Marshal(name)
+	e.raw(string(b) + ":")
+}
+
+func (e *exportWriter) field(name string, v interface{}) {
+	e.key(name)
+	if e.err == nil {
+		e.err = e.enc.Encode(v)
+	}
+}
+
+// rows writes name as an array with one element per row of query.
+func (e *exportWriter) rows(name, query string, args []interface{}, scan func(*sql.Rows) (interface{}, error)) {
+	if e.err != nil {
+		return
+	}
+	e.key(name)
+	e.raw("[")
+
+	rs, err := e.db.Query(query, args...)
+	if err != nil {
+		e.err = err
+		return
+	}
+	defer rs.Close()
+
+	first := true
+	for rs.Next() && e.err == nil {
+		row, err := scan(rs)
+		if err != nil {
+			e.err = err
+			return
+		}
+		if !first {
+			e.raw(",")
+		}
+		first = false
+		if e.err == nil {
+			e.err = e.enc.Encode(row)
+		}
+	}
+	if e.err == nil {
+		e.err = rs.Err()
+	}
+	e.raw("]")
+}
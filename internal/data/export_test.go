@@ -0,0 +1,47 @@
+package data_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestExportSections(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+
+	var buf bytes.Buffer
+	if err := s.ExportUser(id, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var export map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatalf("export isn't one JSON object: %v", err)
+	}
+	for _, section := range []string{
+		"exported_at", "profile", "settings", "medals", "medal_progress", "inventory",
+		"friendships", "messages", "ledger", "purchases", "warthunder_replay",
+	} {
+		if _, ok := export[section]; !ok {
+			t.Errorf("export has no %q", section)
+		}
+	}
+
+	var profile struct{ ID string }
+	json.Unmarshal(export["profile"], &profile)
+	if profile.ID != id {
+		t.Errorf("profile of %q, want %q", profile.ID, id)
+	}
+}
+
+func TestExportUnknownUser(t *testing.T) {
+	s, _ := datatest.Store(t)
+	var buf bytes.Buffer
+	if err := s.ExportUser("u_missing", &buf); !errors.Is(err, data.ErrUserNotFound) || buf.Len() != 0 {
+		t.Fatalf("err %v with %d bytes written, want ErrUserNotFound and nothing", err, buf.Len())
+	}
+}
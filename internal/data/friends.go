@@ -0,0 +1,112 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Friendship statuses, as stored in friendships.status
+const (
+	FriendPending  = "pending"
+	FriendAccepted = "accepted"
+	FriendBlocked  = "blocked"
+)
+
+// ErrNoFriendRequest is returned when there is no pending request to answer.
+var ErrNoFriendRequest = errors.New("no pending friend request")
+
+// FriendRequest is a pending request as seen by one side of it; the user
+// fields are the other side.
+type FriendRequest struct {
+	UserID    string    `json:"user_id"`
+	Nickname  string    `json:"nickname"`
+	Tag       int       `json:"tag"`
+	AvatarURL string    `json:"avatar_url"`
+	Since     time.Time `json:"since"`
+}
+
+// RequestFriend asks to for a friendship on from's behalf and returns the
+// pair's status afterwards. Asking again is a no-op; asking someone who
+// already asked you accepts their request.
+func (s *Store) RequestFriend(from, to string) (string, error) {
+	var status string
+	err := s.db.QueryRow(`
+		INSERT INTO friendships (requester_id, addressee_id, status)
+		VALUES ($1, $2, 'pending')
+		ON CONFLICT (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id))
+		DO UPDATE SET
+			status = CASE
+				WHEN friendships.status = 'pending' AND friendships.requester_id = EXCLUDED.addressee_id THEN 'accepted'
+				ELSE friendships.status
+			END,
+			updated_at = NOW()
+		RETURNING status
+	`, from, to).Scan(&status)
+	return status, err
+}
+
+// AcceptFriend accepts the pending request from requester to addressee.
+func (s *Store) AcceptFriend(addressee, requester string) error {
+	res, err := s.db.Exec(`
+		UPDATE friendships SET status = 'accepted', updated_at = NOW()
+		WHERE requester_id = $1 AND addressee_id = $2 AND status = 'pending'
+	`, requester, addressee)
+	return pendingAnswered(res, err)
+}
+
+// DeclineFriend drops the pending request from requester to addressee.
+// requester is free to ask again later.
+func (s *Store) DeclineFriend(addressee, requester string) error {
+	res, err := s.db.Exec(`
+		DELETE FROM friendships
+		WHERE requester_id = $1 AND addressee_id = $2 AND status = 'pending'
+	`, requester, addressee)
+	return pendingAnswered(res, err)
+}
+
+// pendingAnswered turns an answer that touched no row into ErrNoFriendRequest.
+func pendingAnswered(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNoFriendRequest
+	}
+	return nil
+}
+
+// ListFriendRequests returns userID's pending requests, the ones waiting on
+// them and the ones they sent, newest first.
+func (s *Store) ListFriendRequests(userID string) (incoming, outgoing []FriendRequest, err error) {
+	rows, err := s.db.Query(`
+		SELECT f.addressee_id = $1, u.id, u.nickname, u.tag, COALESCE(u.custom_avatar, ''), f.updated_at
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE f.status = 'pending' AND (f.requester_id = $1 OR f.addressee_id = $1)
+		ORDER BY f.updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	incoming, outgoing = []FriendRequest{}, []FriendRequest{}
+	for rows.Next() {
+		var req FriendRequest
+		var toMe bool
+		var customAvatar string
+		if err := rows.Scan(&toMe, &req.UserID, &req.Nickname, &req.Tag, &customAvatar, &req.Since); err != nil {
+			return nil, nil, err
+		}
+		req.AvatarURL = AvatarURL(req.Nickname, customAvatar)
+		if toMe {
+			incoming = append(incoming, req)
+		} else {
+			outgoing = append(outgoing, req)
+		}
+	}
+	return incoming, outgoing, rows.Err()
+}
@@ -0,0 +1,146 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+// pending reports how many requests userID has waiting on them and sent.
+func pending(t *testing.T, s *data.Store, userID string) (int, int) {
+	t.Helper()
+	in, out, err := s.ListFriendRequests(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(in), len(out)
+}
+
+func TestFriendRequestAccept(t *testing.T) {
+	s, db := datatest.Store(t)
+	a, b := datatest.User(t, db, 0), datatest.User(t, db, 0)
+
+	if status, err := s.RequestFriend(a, b); err != nil || status != data.FriendPending {
+		t.Fatalf("request: %q, %v", status, err)
+	}
+	if s.AreFriends(a, b) {
+		t.Fatal("friends before the request was accepted")
+	}
+	if in, out := pending(t, s, b); in != 1 || out != 0 {
+		t.Fatalf("addressee sees %d incoming, %d outgoing", in, out)
+	}
+	if err := s.AcceptFriend(a, b); !errors.Is(err, data.ErrNoFriendRequest) {
+		t.Fatalf("requester accepting their own request: %v", err)
+	}
+
+	if err := s.AcceptFriend(b, a); err != nil {
+		t.Fatal(err)
+	}
+	if !s.AreFriends(a, b) {
+		t.Fatal("not friends after accepting")
+	}
+	if in, out := pending(t, s, a); in+out != 0 {
+		t.Errorf("%d requests still pending", in+out)
+	}
+}
+
+func TestFriendRequestDecline(t *testing.T) {
+	s, db := datatest.Store(t)
+	a, b := datatest.User(t, db, 0), datatest.User(t, db, 0)
+	s.RequestFriend(a, b)
+
+	if err := s.DeclineFriend(b, a); err != nil {
+		t.Fatal(err)
+	}
+	if s.AreFriends(a, b) {
+		t.Fatal("friends after declining")
+	}
+	if err := s.AcceptFriend(b, a); !errors.Is(err, data.ErrNoFriendRequest) {
+		t.Fatalf("accepting a declined request: %v", err)
+	}
+	// Declining leaves the way open to ask again
+	if status, err := s.RequestFriend(a, b); err != nil || status != data.FriendPending {
+		t.Fatalf("asking again: %q, %v", status, err)
+	}
+}
+
+func TestDuplicateFriendRequests(t *testing.T) {
+	s, db := datatest.Store(t)
+	a, b := datatest.User(t, db, 0), datatest.User(t, db, 0)
+
+	for i := 0; i < 2; i++ {
+		if status, err := s.RequestFriend(a, b); err != nil || status != data.FriendPending {
+			t.Fatalf("request %d: %q, %v", i+1, status, err)
+		}
+	}
+	if in, _ := pending(t, s, b); in != 1 {
+		t.Fatalf("%d incoming requests after asking twice, want 1", in)
+	}
+
+	// b asking back is as good as accepting
+	if status, err := s.RequestFriend(b, a); err != nil || status != data.FriendAccepted {
+		t.Fatalf("request back: %q, %v", status, err)
+	}
+	if !s.AreFriends(a, b) {
+		t.Fatal("crossed requests didn't make them friends")
+	}
+}
+
+func TestAreFriends(t *testing.T) {
+	s, db := datatest.Store(t)
+	for status, want := range map[string]bool{
+		data.FriendAccepted: true,
+		data.FriendPending:  false,
+		data.FriendBlocked:  false,
+	} {
+		a, b := datatest.User(t, db, 0), datatest.User(t, db, 0)
+		if _, err := db.Exec(`INSERT INTO friendships (requester_id, addressee_id, status) VALUES ($1, $2, $3)`, a, b, status); err != nil {
+			t.Fatal(err)
+		}
+		if s.AreFriends(a, b) != want || s.AreFriends(b, a) != want {
+			t.Errorf("%s: friends %v from a, %v from b; want %v both ways", status, s.AreFriends(a, b), s.AreFriends(b, a), want)
+		}
+	}
+
+	stranger := datatest.User(t, db, 0)
+	if s.AreFriends(stranger, stranger) || s.AreFriends(stranger, "") {
+		t.Error("a user is friends with themselves or nobody")
+	}
+}
+
+func TestListFriendsShowsCustomAvatar(t *testing.T) {
+	s, db := datatest.Store(t)
+	me, plain, styled := datatest.User(t, db, 0), datatest.User(t, db, 0), datatest.User(t, db, 0)
+	for _, friend := range []string{plain, styled} {
+		s.RequestFriend(me, friend)
+		if err := s.AcceptFriend(friend, me); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const avatar = "data:image/png;base64,iVBORw0KGgo="
+	if err := s.UpdateProfileLook(styled, "", "", avatar); err != nil {
+		t.Fatal(err)
+	}
+
+	friends, err := s.ListFriends(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friends) != 2 {
+		t.Fatalf("%d friends, want 2", len(friends))
+	}
+	for _, fr := range friends {
+		switch fr.ID {
+		case styled:
+			if fr.CustomAvatar != avatar || string(fr.AvatarURL) != avatar {
+				t.Errorf("styled friend: avatar %q url %q, want the upload", fr.CustomAvatar, fr.AvatarURL)
+			}
+		case plain:
+			if fr.CustomAvatar != "" || string(fr.AvatarURL) != data.AvatarURL(fr.Nickname, "") {
+				t.Errorf("plain friend: avatar %q url %q, want the generated one", fr.CustomAvatar, fr.AvatarURL)
+			}
+		}
+	}
+}
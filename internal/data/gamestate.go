@@ -0,0 +1,48 @@
+package data
+
+// Counters are single numbers a game keeps across restarts, such as the
+// slotix progressive jackpot. Each lives in its own game_state row.
+
+// GetCounter returns key's value, creating the row at initial when it's new.
+func (s *Store) GetCounter(key string, initial int64) (int64, error) {
+	var value int64
+	err := s.db.QueryRow(`
+		INSERT INTO game_state (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+		RETURNING value
+	`, key, initial).Scan(&value)
+	return value, err
+}
+
+// AddCounter adds delta to key and returns the new value. The row must exist,
+// see GetCounter.
+func (s *Store) AddCounter(key string, delta int64) (int64, error) {
+	var value int64
+	err := s.db.QueryRow(`
+		UPDATE game_state SET value = value + $2, updated_at = NOW()
+		WHERE key = $1
+		RETURNING value
+	`, key, delta).Scan(&value)
+	return value, err
+}
+
+// SwapCounter sets key to reset and returns what it held. The row is locked
+// for the swap, so when two callers race each gets a distinct value: the
+// first takes the whole pot, the second whatever has built up since the
+// reset.
+func (s *Store) SwapCounter(key string, reset int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var old int64
+	if err := tx.QueryRow(`SELECT value FROM game_state WHERE key = $1 FOR UPDATE`, key).Scan(&old); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`UPDATE game_state SET value = $2, updated_at = NOW() WHERE key = $1`, key, reset); err != nil {
+		return 0, err
+	}
+	return old, tx.Commit()
+}
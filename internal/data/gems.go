@@ -0,0 +1,46 @@
+package data
+
+import "fmt"
+
+// Currencies. Coins are the soft currency earned in games; gems are premium
+// and only spent in the shop.
+const (
+	CurrencyCoins = "coins"
+	CurrencyGems  = "gems"
+)
+
+// currencyColumn maps a currency to its users column. Only whitelisted names
+// ever reach the SQL string.
+func currencyColumn(currency string) (string, error) {
+	switch currency {
+	case CurrencyCoins, "":
+		return "coins", nil
+	case CurrencyGems:
+		return "gems", nil
+	default:
+		return "", fmt.Errorf("unknown currency %q", currency)
+	}
+}
+
+// AdjustGems adds (or removes, never below zero) gems.
+func (s *Store) AdjustGems(userID string, delta int) error {
+	_, err := s.db.Exec(`UPDATE users SET gems = GREATEST(0, gems + $1), updated_at = NOW() WHERE id = $2`, delta, userID)
+	s.InvalidateUser(userID)
+	return err
+}
+
+// TrySpendGems deducts cost atomically, failing without side effects when the
+// balance is short.
+func (s *Store) TrySpendGems(userID string, cost int) error {
+	defer s.InvalidateUser(userID)
+
+	res, err := s.db.Exec(`UPDATE users SET gems = gems - $1, updated_at = NOW() WHERE id = $2 AND gems >= $1`, cost, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("insufficient gems")
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package data
+
+// Unexported pieces the database tests in package data_test drive directly.
+// Those tests share datatest's fixtures with every other package.
+var (
+	ClaimDailyBonusAt = (*Store).claimDailyBonus
+	RefreshPowerScore = (*Store).refreshPowerScore
+)
@@ -0,0 +1,131 @@
+package data_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+// seedLadder gives one fresh user each of the trophy counts, high enough to
+// sit above anyone else in the test database, and returns them best first.
+func seedLadder(t *testing.T, db *sql.DB, trophies ...int) []string {
+	t.Helper()
+	ids := make([]string, len(trophies))
+	for i, n := range trophies {
+		ids[i] = datatest.User(t, db, 0)
+		if _, err := db.Exec(`UPDATE users SET trophies = $1 WHERE id = $2`, n, ids[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func aroundIDs(t *testing.T, s *data.Store, userID string, window int) ([]string, []int) {
+	t.Helper()
+	rows, err := s.GetLeaderboardAround(userID, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	var ranks []int
+	for _, u := range rows {
+		ids = append(ids, u.ID)
+		ranks = append(ranks, u.Rank)
+	}
+	return ids, ranks
+}
+
+func TestLeaderboardAround(t *testing.T) {
+	s, db := datatest.Store(t)
+	const top = 2_000_000_000
+	ladder := seedLadder(t, db, top, top-10, top-20, top-30, top-40, top-50, top-60)
+
+	ids, ranks := aroundIDs(t, s, ladder[3], 2)
+	if len(ids) != 5 || ranks[0] != 2 || ranks[4] != 6 {
+		t.Fatalf("around #4: ranks %v, want 2-6", ranks)
+	}
+	for i, id := range ids {
+		if id != ladder[i+1] {
+			t.Errorf("row %d is %s, want %s", i, id, ladder[i+1])
+		}
+	}
+
+	// At the top the window is cut short rather than shifted
+	if _, ranks := aroundIDs(t, s, ladder[0], 2); len(ranks) != 3 || ranks[0] != 1 {
+		t.Errorf("around #1: ranks %v, want 1-3", ranks)
+	}
+
+	unranked := datatest.User(t, db, 0)
+	if ids, _ := aroundIDs(t, s, unranked, 2); len(ids) != 0 {
+		t.Errorf("unranked user got %d rows", len(ids))
+	}
+}
+
+func TestLeaderboardAroundMatchesPaging(t *testing.T) {
+	s, db := datatest.Store(t)
+	low := seedLadder(t, db, 1)[0]
+	rank := 0
+	ids, ranks := aroundIDs(t, s, low, 1)
+	for i, id := range ids {
+		if id == low {
+			rank = ranks[i]
+		}
+	}
+	if rank == 0 {
+		t.Fatalf("a 1 trophy user isn't in their own window: %v", ids)
+	}
+
+	// That rank is the same row in the paged leaderboard
+	page, _, err := s.GetLeaderboard(1, rank-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != low {
+		t.Fatalf("rank %d pages to %v, want %s", rank, page, low)
+	}
+}
+
+func TestLeaderboardOrderAndPaging(t *testing.T) {
+	s, db := datatest.Store(t)
+	const top = 2_000_000_000
+	ladder := seedLadder(t, db, top, top, top, top-10)
+	// Same trophies: higher level first, then nickname
+	for _, u := range []struct {
+		id    string
+		level int
+		nick  string
+	}{{ladder[0], 9, "zed"}, {ladder[1], 12, "yan"}, {ladder[2], 9, "Abe"}} {
+		if _, err := db.Exec(`UPDATE users SET level = $1, nickname = $2 || id, nickname_lower = lower($2 || id) WHERE id = $3`, u.level, u.nick, u.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{ladder[1], ladder[2], ladder[0], ladder[3]}
+
+	page, total, err := s.GetLeaderboard(4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	if total != count {
+		t.Errorf("total %d, want all %d users", total, count)
+	}
+	if len(page) != 4 {
+		t.Fatalf("%d rows for limit 4", len(page))
+	}
+	for i, u := range page {
+		if u.ID != want[i] {
+			t.Errorf("row %d is %s (level %d), want %s", i, u.ID, u.Level, want[i])
+		}
+	}
+
+	next, _, err := s.GetLeaderboard(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next) != 2 || next[0].ID != want[2] || next[1].ID != want[3] {
+		t.Errorf("offset 2 gave %v", next)
+	}
+}
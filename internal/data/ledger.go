@@ -0,0 +1,106 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Ledger reasons. Keep these stable, they are what support greps for.
+const (
+	ReasonShopPurchase = "shop_purchase"
+	ReasonCoinPack     = "coin_pack"
+	ReasonGemExchange  = "gem_exchange"
+	ReasonSlotixBet    = "slotix_bet"
+	ReasonSlotixWin    = "slotix_win"
+	ReasonBobikWin     = "bobik_win"
+	ReasonChibikiMatch = "chibiki_match"
+	ReasonPartyGame    = "party_game"
+	ReasonUpsideDown   = "upsidedown_run"
+	ReasonDailyBonus   = "daily_bonus"
+	ReasonUnspecified  = "unspecified"
+)
+
+// LedgerEntry is one row of a user's coin history.
+type LedgerEntry struct {
+	ID           int64     `json:"id"`
+	Delta        int       `json:"delta"`
+	BalanceAfter int       `json:"balance_after"`
+	Reason       string    `json:"reason"`
+	RefID        string    `json:"ref_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx, so ledger rows can be
+// written inside the same transaction as the balance change.
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// AdjustCoinsWithReason moves coins and records why in coin_ledger, atomically.
+// A debit never takes the balance below zero: if the user can't cover it,
+// nothing changes and ErrInsufficientFunds is returned.
+func (s *Store) AdjustCoinsWithReason(userID string, delta int, reason, refID string) error {
+	defer s.InvalidateUser(userID)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var balance int
+	err = tx.QueryRow(`UPDATE users SET coins = coins + $1, updated_at = NOW() WHERE id = $2 AND coins + $1 >= 0 RETURNING coins`, delta, userID).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) && delta < 0 {
+		return ErrInsufficientFunds
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeLedger(tx, userID, delta, balance, reason, refID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func writeLedger(ex sqlExecer, userID string, delta, balance int, reason, refID string) error {
+	if delta == 0 {
+		return nil
+	}
+	if reason == "" {
+		reason = ReasonUnspecified
+	}
+	_, err := ex.Exec(`
+		INSERT INTO coin_ledger (user_id, delta, balance_after, reason, ref_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, delta, balance, reason, refID)
+	return err
+}
+
+// GetCoinLedger returns the user's most recent coin movements, newest first.
+func (s *Store) GetCoinLedger(userID string, limit int) ([]LedgerEntry, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT id, delta, balance_after, reason, ref_id, created_at
+		FROM coin_ledger
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ID, &e.Delta, &e.BalanceAfter, &e.Reason, &e.RefID, &e.CreatedAt); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
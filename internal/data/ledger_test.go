@@ -0,0 +1,70 @@
+package data_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestAdjustCoinsLedger(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+
+	if err := s.AdjustCoinsWithReason(id, 50, data.ReasonDailyBonus, "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AdjustCoinsWithReason(id, -150, data.ReasonShopPurchase, "hat"); err != nil {
+		t.Fatalf("debit down to exactly zero: %v", err)
+	}
+	entries, err := s.GetCoinLedger(id, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Delta != -150 || entries[0].BalanceAfter != 0 || entries[1].BalanceAfter != 150 {
+		t.Fatalf("ledger %+v", entries)
+	}
+}
+
+func TestAdjustCoinsNoOverdraft(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 30)
+
+	if err := s.AdjustCoinsWithReason(id, -31, data.ReasonSlotixBet, ""); !errors.Is(err, data.ErrInsufficientFunds) {
+		t.Fatalf("overdraft err = %v, want ErrInsufficientFunds", err)
+	}
+	if u, _ := s.GetUserFresh(id); u.Coins != 30 {
+		t.Fatalf("coins %d after a refused debit, want 30", u.Coins)
+	}
+	if entries, _ := s.GetCoinLedger(id, 10); len(entries) != 0 {
+		t.Fatalf("refused debit left %d ledger rows", len(entries))
+	}
+}
+
+func TestAdjustCoinsConcurrentDebits(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	spent := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.AdjustCoinsWithReason(id, -30, data.ReasonSlotixBet, "") == nil {
+				mu.Lock()
+				spent += 30
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	u, _ := s.GetUserFresh(id)
+	if spent != 90 || u.Coins != 10 {
+		t.Fatalf("spent %d leaving %d, want 90 and 10", spent, u.Coins)
+	}
+}
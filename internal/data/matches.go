@@ -0,0 +1,47 @@
+package data
+
+// ChibikiMatch is one finished chibiki match as stored in chibiki_matches.
+type ChibikiMatch struct {
+	WinnerTeam int     // -1 for a draw
+	Duration   float64 // Seconds of game time
+	Players    []ChibikiMatchPlayer
+}
+
+// ChibikiMatchPlayer is one side's numbers for a match.
+type ChibikiMatchPlayer struct {
+	UserID        string
+	Team          int
+	ElixirSpent   float64
+	UnitsDeployed int
+	DamageDealt   float64
+}
+
+// RecordChibikiMatch stores a finished match and its players in one
+// transaction.
+func (s *Store) RecordChibikiMatch(m ChibikiMatch) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var matchID int64
+	err = tx.QueryRow(`
+		INSERT INTO chibiki_matches (winner_team, duration_seconds)
+		VALUES ($1, $2) RETURNING id
+	`, m.WinnerTeam, m.Duration).Scan(&matchID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range m.Players {
+		_, err := tx.Exec(`
+			INSERT INTO chibiki_match_players (match_id, user_id, team, elixir_spent, units_deployed, damage_dealt)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, matchID, p.UserID, p.Team, p.ElixirSpent, p.UnitsDeployed, p.DamageDealt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,58 @@
+package data
+
+// threshold is how much progress a medal needs; one-off medals need 1.
+func (m Medal) threshold() int {
+	if m.Threshold < 1 {
+		return 1
+	}
+	return m.Threshold
+}
+
+// IncrementMedalProgress adds delta to the user's progress on medalID and
+// awards the medal once progress reaches its threshold. It reports whether
+// this call is the one that crossed it.
+func (s *Store) IncrementMedalProgress(userID, medalID string, delta int) (bool, error) {
+	m, ok := s.medals[medalID]
+	if !ok || userID == "" || delta <= 0 {
+		return false, nil
+	}
+
+	var progress int
+	err := s.db.QueryRow(`
+		INSERT INTO user_medal_progress (user_id, medal_id, progress)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, medal_id) DO UPDATE
+		SET progress = user_medal_progress.progress + EXCLUDED.progress, updated_at = NOW()
+		RETURNING progress
+	`, userID, medalID, delta).Scan(&progress)
+	if err != nil {
+		return false, err
+	}
+
+	need := m.threshold()
+	if progress < need || progress-delta >= need {
+		return false, nil
+	}
+	_, err = s.AwardMedals(userID, medalID)
+	return err == nil, err
+}
+
+// GetMedalProgress returns the user's progress per medal, including medals
+// already awarded.
+func (s *Store) GetMedalProgress(userID string) (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT medal_id, progress FROM user_medal_progress WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int{}
+	for rows.Next() {
+		var id string
+		var p int
+		if err := rows.Scan(&id, &p); err == nil {
+			out[id] = p
+		}
+	}
+	return out, rows.Err()
+}
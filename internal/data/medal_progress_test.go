@@ -0,0 +1,92 @@
+package data_test
+
+import (
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func hasMedal(t *testing.T, s *data.Store, userID, medalID string) bool {
+	t.Helper()
+	u, ok := s.GetUserFresh(userID)
+	if !ok {
+		t.Fatalf("user %s not found", userID)
+	}
+	for _, id := range u.Medals {
+		if id == medalID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMedalAwardedOnceAtThreshold(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	for win := 1; win < 10; win++ {
+		if crossed, err := s.IncrementMedalProgress(id, "ten_wins", 1); err != nil || crossed {
+			t.Fatalf("win %d: crossed %v, %v", win, crossed, err)
+		}
+	}
+	if hasMedal(t, s, id, "ten_wins") {
+		t.Fatal("ten_wins awarded after 9 wins")
+	}
+
+	if crossed, err := s.IncrementMedalProgress(id, "ten_wins", 1); err != nil || !crossed {
+		t.Fatalf("10th win: crossed %v, %v", crossed, err)
+	}
+	if !hasMedal(t, s, id, "ten_wins") {
+		t.Fatal("ten_wins not awarded after 10 wins")
+	}
+
+	// Progress keeps counting but the award doesn't repeat
+	for win := 11; win <= 12; win++ {
+		if crossed, _ := s.IncrementMedalProgress(id, "ten_wins", 1); crossed {
+			t.Fatalf("win %d crossed again", win)
+		}
+	}
+	if progress, _ := s.GetMedalProgress(id); progress["ten_wins"] != 12 {
+		t.Errorf("progress %d, want 12", progress["ten_wins"])
+	}
+}
+
+func TestMedalProgressJumpPastThreshold(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	if crossed, err := s.IncrementMedalProgress(id, "ten_wins", 15); err != nil || !crossed {
+		t.Fatalf("jumping 0 to 15: crossed %v, %v", crossed, err)
+	}
+	if crossed, _ := s.IncrementMedalProgress(id, "ten_wins", 15); crossed {
+		t.Fatal("15 to 30 crossed again")
+	}
+
+	// One-off medals cross on the first step
+	if crossed, _ := s.IncrementMedalProgress(id, "first_win", 1); !crossed || !hasMedal(t, s, id, "first_win") {
+		t.Fatal("first_win not awarded on the first win")
+	}
+}
+
+func TestMedalProgressIgnoresNonsense(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	for _, tc := range []struct {
+		user, medal string
+		delta       int
+	}{
+		{id, "no_such_medal", 1},
+		{id, "ten_wins", 0},
+		{id, "ten_wins", -5},
+		{"", "ten_wins", 1},
+	} {
+		if crossed, err := s.IncrementMedalProgress(tc.user, tc.medal, tc.delta); crossed || err != nil {
+			t.Errorf("%+v: crossed %v, %v", tc, crossed, err)
+		}
+	}
+	if progress, _ := s.GetMedalProgress(id); len(progress) != 0 {
+		t.Errorf("progress recorded: %v", progress)
+	}
+}
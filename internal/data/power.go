@@ -0,0 +1,112 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Power score weights. Trophies count 1:1, every level is worth a small
+// trophy bonus and medals add a flat amount so completionists rank higher.
+const (
+	PowerLevelWeight  = 25
+	PowerMedalWeight  = 50
+	PowerTrophyWeight = 1
+)
+
+// powerScoreSQL recomputes power_score for the rows matched by the WHERE clause
+// appended by the caller. Kept in one place so the backfill and the per-user
+// refresh can never disagree on the formula.
+var powerScoreSQL = fmt.Sprintf(`
+	UPDATE users u
+	SET power_score = u.level * %d + u.trophies * %d +
+		(SELECT COUNT(*) FROM user_medals um WHERE um.user_id = u.id) * %d
+`, PowerLevelWeight, PowerTrophyWeight, PowerMedalWeight)
+
+// ComputePowerScore mirrors powerScoreSQL for callers that already hold a UserData.
+func ComputePowerScore(level, trophies, medals int) int {
+	return level*PowerLevelWeight + trophies*PowerTrophyWeight + medals*PowerMedalWeight
+}
+
+// powerBackfillKey is the game_state row recording that every user's
+// power_score has been computed. From then on refreshPowerScore keeps each
+// one current as their stats change.
+const powerBackfillKey = "power_score_backfilled"
+
+// BackfillPowerScores computes everyone's power_score once, the first time
+// the server starts after the column was added. Later starts find the
+// marker and do nothing. The row lock keeps two servers starting together
+// from both running it.
+func (s *Store) BackfillPowerScores() error {
+	if _, err := s.GetCounter(powerBackfillKey, 0); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var done int64
+	if err := tx.QueryRow(`SELECT value FROM game_state WHERE key = $1 FOR UPDATE`, powerBackfillKey).Scan(&done); err != nil {
+		return err
+	}
+	if done != 0 {
+		return nil
+	}
+	if _, err := tx.Exec(powerScoreSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE game_state SET value = 1, updated_at = NOW() WHERE key = $1`, powerBackfillKey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// refreshPowerScore recomputes a single user's score after a stat change.
+func (s *Store) refreshPowerScore(userID string) {
+	_, _ = s.db.Exec(powerScoreSQL+` WHERE u.id = $1`, userID)
+}
+
+// GetUserRankByPower returns the 1-based position of the user when everyone is
+// ordered by power_score (ties share a rank) along with their score.
+func (s *Store) GetUserRankByPower(userID string) (rank int, score int, err error) {
+	err = s.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users o WHERE o.power_score > u.power_score) + 1,
+			u.power_score
+		FROM users u
+		WHERE u.id = $1
+	`, userID).Scan(&rank, &score)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, fmt.Errorf("user not found")
+	}
+	return rank, score, err
+}
+
+// GetPowerLeaderboard is the power_score counterpart of GetLeaderboard.
+func (s *Store) GetPowerLeaderboard(limit int) ([]UserData, error) {
+	if limit <= 0 {
+		limit = 15
+	}
+	rows, err := s.db.Query(`
+		SELECT id, nickname, tag, level, trophies, COALESCE(custom_avatar, ''), COALESCE(name_color, 'white'), power_score
+		FROM users
+		ORDER BY power_score DESC, id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []UserData
+	for rows.Next() {
+		var u UserData
+		if err := rows.Scan(&u.ID, &u.Nickname, &u.Tag, &u.Level, &u.Trophies, &u.CustomAvatar, &u.NameColor, &u.PowerScore); err != nil {
+			continue
+		}
+		players = append(players, u)
+	}
+	return players, nil
+}
@@ -0,0 +1,74 @@
+package data_test
+
+import (
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestComputePowerScore(t *testing.T) {
+	// 3*25 + 120*1 + 2*50
+	if got := data.ComputePowerScore(3, 120, 2); got != 295 {
+		t.Fatalf("ComputePowerScore(3, 120, 2) = %d, want 295", got)
+	}
+	if got := data.ComputePowerScore(1, 0, 0); got != data.PowerLevelWeight {
+		t.Fatalf("fresh account scores %d, want %d", got, data.PowerLevelWeight)
+	}
+}
+
+func TestPowerRankOrdering(t *testing.T) {
+	s, db := datatest.Store(t)
+	// Hand-computed: a = 10*25 + 0 + 0 = 250, b = 1*25 + 300 = 325,
+	// c = 1*25 + 100 + 2*50 = 225
+	a, b, c := datatest.User(t, db, 0), datatest.User(t, db, 0), datatest.User(t, db, 0)
+	if _, err := db.Exec(`UPDATE users SET level = 10 WHERE id = $1`, a); err != nil {
+		t.Fatal(err)
+	}
+	data.RefreshPowerScore(s, a)
+	if err := s.AdjustTrophies(b, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AdjustTrophies(c, 100); err != nil {
+		t.Fatal(err)
+	}
+	for _, medal := range []string{"first_win", "ten_wins"} {
+		if _, err := db.Exec(`INSERT INTO user_medals (user_id, medal_id) VALUES ($1, $2)`, c, medal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data.RefreshPowerScore(s, c)
+
+	want := map[string]int{a: 250, b: 325, c: 225}
+	ranks := make(map[string]int)
+	for id, score := range want {
+		rank, got, err := s.GetUserRankByPower(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != score {
+			t.Errorf("score %d, want %d", got, score)
+		}
+		ranks[id] = rank
+	}
+	if !(ranks[b] < ranks[a] && ranks[a] < ranks[c]) {
+		t.Errorf("ranks b=%d a=%d c=%d, want b ahead of a ahead of c", ranks[b], ranks[a], ranks[c])
+	}
+}
+
+func TestBackfillPowerScoresRunsOnce(t *testing.T) {
+	s, db := datatest.Store(t)
+	if err := s.BackfillPowerScores(); err != nil {
+		t.Fatal(err)
+	}
+	id := datatest.User(t, db, 0)
+	if _, err := db.Exec(`UPDATE users SET power_score = 7 WHERE id = $1`, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BackfillPowerScores(); err != nil {
+		t.Fatal(err)
+	}
+	if _, score, _ := s.GetUserRankByPower(id); score != 7 {
+		t.Fatalf("second backfill rewrote the score to %d", score)
+	}
+}
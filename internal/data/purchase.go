@@ -0,0 +1,105 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAlreadyOwned      = errors.New("item already owned")
+)
+
+// CatalogItem describes what buying something costs and what it grants.
+// An item can grant an inventory entry, currency, or both.
+type CatalogItem struct {
+	ID         string
+	Currency   string // CurrencyCoins or CurrencyGems
+	Price      int    // 0 for free grants (e.g. packs paid for outside the game)
+	Inventory  bool   // Adds ID to the user's inventory; fails if already owned
+	GrantCoins int
+	GrantGems  int
+}
+
+// PurchaseResult is the user's balance after a successful purchase.
+type PurchaseResult struct {
+	Coins int `json:"coins"`
+	Gems  int `json:"gems"`
+}
+
+// PurchaseItem charges for item and applies all of its effects in one
+// transaction, recording a purchases row and the coin ledger entries. Any
+// failure (short balance, already owned, DB error) leaves nothing changed.
+func (s *Store) PurchaseItem(userID string, item CatalogItem) (PurchaseResult, error) {
+	defer s.InvalidateUser(userID)
+
+	var res PurchaseResult
+	column, err := currencyColumn(item.Currency)
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	if item.Price > 0 {
+		err = tx.QueryRow(`UPDATE users SET `+column+` = `+column+` - $1, updated_at = NOW() WHERE id = $2 AND `+column+` >= $1 RETURNING coins, gems`,
+			item.Price, userID).Scan(&res.Coins, &res.Gems)
+		if errors.Is(err, sql.ErrNoRows) {
+			return res, ErrInsufficientFunds
+		}
+		if err != nil {
+			return res, err
+		}
+		if column == "coins" {
+			if err := writeLedger(tx, userID, -item.Price, res.Coins, ReasonShopPurchase, item.ID); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	if item.Inventory {
+		r, err := tx.Exec(`INSERT INTO inventory (user_id, item_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, item.ID)
+		if err != nil {
+			return res, err
+		}
+		if n, _ := r.RowsAffected(); n == 0 {
+			return res, ErrAlreadyOwned
+		}
+	}
+
+	if item.GrantCoins != 0 || item.GrantGems != 0 {
+		err = tx.QueryRow(`UPDATE users SET coins = coins + $1, gems = gems + $2, updated_at = NOW() WHERE id = $3 RETURNING coins, gems`,
+			item.GrantCoins, item.GrantGems, userID).Scan(&res.Coins, &res.Gems)
+		if err != nil {
+			return res, err
+		}
+		reason := ReasonCoinPack
+		if item.Price > 0 && column == "gems" {
+			reason = ReasonGemExchange
+		}
+		if err := writeLedger(tx, userID, item.GrantCoins, res.Coins, reason, item.ID); err != nil {
+			return res, err
+		}
+	}
+
+	if item.Price == 0 && item.GrantCoins == 0 && item.GrantGems == 0 {
+		// Nothing touched the balance, read it for the caller
+		if err := tx.QueryRow(`SELECT coins, gems FROM users WHERE id = $1`, userID).Scan(&res.Coins, &res.Gems); err != nil {
+			return res, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO purchases (user_id, item_id, currency, price, coins_granted, gems_granted)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, item.ID, column, item.Price, item.GrantCoins, item.GrantGems)
+	if err != nil {
+		return res, err
+	}
+
+	return res, tx.Commit()
+}
@@ -0,0 +1,67 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestGemPurchaseShortOfGems(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 1000)
+	if err := s.AdjustGems(id, 40); err != nil {
+		t.Fatal(err)
+	}
+	crown := data.CatalogItem{ID: "test_crown", Currency: data.CurrencyGems, Price: 50, Inventory: true}
+
+	if _, err := s.PurchaseItem(id, crown); !errors.Is(err, data.ErrInsufficientFunds) {
+		t.Fatalf("buying for 50 gems with 40: %v, want ErrInsufficientFunds", err)
+	}
+	u, _ := s.GetUserFresh(id)
+	if u.Gems != 40 || u.Coins != 1000 {
+		t.Fatalf("%d gems %d coins after a refused purchase, want 40 and 1000 untouched", u.Gems, u.Coins)
+	}
+
+	// Once there are enough it goes through, still without touching coins
+	s.AdjustGems(id, 10)
+	res, err := s.PurchaseItem(id, crown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Gems != 0 || res.Coins != 1000 {
+		t.Fatalf("balance %+v, want 0 gems and 1000 coins", res)
+	}
+}
+
+func TestAlreadyOwnedRollsBackCharge(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 1000)
+	hat := data.CatalogItem{ID: "test_hat", Currency: data.CurrencyCoins, Price: 300, Inventory: true}
+
+	if _, err := s.PurchaseItem(id, hat); err != nil {
+		t.Fatal(err)
+	}
+	// The second charge runs before the inventory insert finds the hat
+	if _, err := s.PurchaseItem(id, hat); !errors.Is(err, data.ErrAlreadyOwned) {
+		t.Fatalf("buying it again: %v, want ErrAlreadyOwned", err)
+	}
+
+	u, _ := s.GetUserFresh(id)
+	if u.Coins != 700 {
+		t.Errorf("%d coins, want 700 with only the first purchase charged", u.Coins)
+	}
+	ledger, err := s.GetCoinLedger(id, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger) != 1 {
+		t.Errorf("%d ledger rows, want 1", len(ledger))
+	}
+	var purchases int
+	db.QueryRow(`SELECT COUNT(*) FROM purchases WHERE user_id = $1`, id).Scan(&purchases)
+	if purchases != 1 {
+		t.Errorf("%d purchase rows, want 1", purchases)
+	}
+}
@@ -0,0 +1,28 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// SaveWarthunderReplay keeps the user's latest finished War Thunder game,
+// replacing the previous one so storage stays at one replay per user.
+func (s *Store) SaveWarthunderReplay(userID string, replay []byte) error {
+	_, err := s.db.Exec(`
+		INSERT INTO warthunder_replays (user_id, replay, finished_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET replay = EXCLUDED.replay, finished_at = EXCLUDED.finished_at
+	`, userID, replay)
+	return err
+}
+
+// GetWarthunderReplay returns the saved replay JSON, or nil if there is none.
+func (s *Store) GetWarthunderReplay(userID string) ([]byte, error) {
+	var body []byte
+	err := s.db.QueryRow(`SELECT replay FROM warthunder_replays WHERE user_id = $1`, userID).Scan(&body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return body, err
+}
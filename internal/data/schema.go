@@ -0,0 +1,85 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// userSelectSQL is the one query GetUser depends on. Keep it in sync with
+// userScanTargets and userColumns below.
+const userSelectSQL = `
+        SELECT id, nickname, tag, level, exp, max_exp, coins, trophies, 
+		       COALESCE(status, 'offline'), COALESCE(language, 'en'),
+			   COALESCE(name_color, 'white'), COALESCE(banner_color, 'default'),
+			   COALESCE(custom_avatar, ''), COALESCE(upside_down_meta, ''),
+			   power_score, gems
+        FROM users
+        WHERE id = $1
+    `
+
+// userScanTargets are the destinations for a userSelectSQL row, in order.
+func userScanTargets(u *UserData) []interface{} {
+	return []interface{}{
+		&u.ID, &u.Nickname, &u.Tag, &u.Level, &u.Exp, &u.MaxExp, &u.Coins, &u.Trophies,
+		&u.Status, &u.Language, &u.NameColor, &u.BannerColor, &u.CustomAvatar,
+		&u.UpsideDownMeta, &u.PowerScore, &u.Gems,
+	}
+}
+
+// userColumns lists every users column the store reads or writes.
+var userColumns = []string{
+	"id", "nickname", "nickname_lower", "tag", "level", "exp", "max_exp",
+	"coins", "gems", "trophies", "status", "language", "name_color",
+	"banner_color", "custom_avatar", "upside_down_meta", "power_score",
+	"password_hash", "updated_at", "current_activity", "last_seen",
+	"chibiki_deck", "last_daily_claim", "daily_streak", "offline_at",
+}
+
+// CheckSchema fails fast when the users table is missing a column the store
+// expects, so a bad deploy dies at startup with the column names instead of
+// every GetUser silently returning "not found".
+func (s *Store) CheckSchema() error {
+	rows, err := s.db.Query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'users'
+	`)
+	if err != nil {
+		return fmt.Errorf("schema check: %w", err)
+	}
+	defer rows.Close()
+
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("schema check: %w", err)
+		}
+		have[name] = true
+	}
+
+	var missing []string
+	for _, c := range userColumns {
+		if !have[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("schema check: users table is missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	// Prepare the real GetUser query and compare its shape with the Scan
+	// targets; this catches SELECT/Scan drift the column list alone can't.
+	q, err := s.db.Query(userSelectSQL, "")
+	if err != nil {
+		return fmt.Errorf("schema check: GetUser query failed: %w", err)
+	}
+	defer q.Close()
+	cols, err := q.Columns()
+	if err != nil {
+		return fmt.Errorf("schema check: %w", err)
+	}
+	if want := len(userScanTargets(&UserData{})); len(cols) != want {
+		return fmt.Errorf("schema check: GetUser selects %d columns but scans %d", len(cols), want)
+	}
+	return nil
+}
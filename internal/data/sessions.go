@@ -0,0 +1,74 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrSessionInvalid covers every token that doesn't resolve to a user:
+// unknown, expired or logged out.
+var ErrSessionInvalid = errors.New("invalid session")
+
+// hashToken is how a token is stored, so a leaked sessions table can't be
+// replayed as cookies.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession opens a session for userID lasting ttl and returns its
+// token. The token is random and carries nothing about the user.
+func (s *Store) CreateSession(userID string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	_, err := s.db.Exec(`
+		INSERT INTO sessions (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, hashToken(token), userID, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SessionUser resolves token to the user it was issued to.
+func (s *Store) SessionUser(token string) (string, error) {
+	if token == "" {
+		return "", ErrSessionInvalid
+	}
+	var userID string
+	err := s.db.QueryRow(`
+		SELECT user_id FROM sessions
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`, hashToken(token)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrSessionInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// DeleteSession revokes token. The user's other sessions stay valid.
+func (s *Store) DeleteSession(token string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE token_hash = $1`, hashToken(token))
+	return err
+}
+
+// PurgeExpiredSessions drops every session past its expiry.
+func (s *Store) PurgeExpiredSessions() (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,12 @@
+package data
+
+import "testing"
+
+func TestHashTokenIsStable(t *testing.T) {
+	if hashToken("abc") != hashToken("abc") || hashToken("abc") == hashToken("abd") {
+		t.Fatal("hashToken not a stable, distinguishing hash")
+	}
+	if hashToken("abc") == "abc" {
+		t.Fatal("hashToken returned the token")
+	}
+}
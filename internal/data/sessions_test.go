@@ -0,0 +1,61 @@
+package data_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestSessionResolves(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	token, err := s.CreateSession(id, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.SessionUser(token); err != nil || got != id {
+		t.Fatalf("SessionUser = %q, %v; want %q", got, err, id)
+	}
+	// Only the hash is stored, never the token itself
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token_hash = $1`, token).Scan(&n)
+	if n != 0 {
+		t.Fatal("raw token stored")
+	}
+}
+
+func TestSessionRejectsExpiredAndUnknown(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	expired, err := s.CreateSession(id, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range map[string]string{"expired": expired, "unknown": "not-a-token", "empty": ""} {
+		if _, err := s.SessionUser(token); !errors.Is(err, data.ErrSessionInvalid) {
+			t.Errorf("%s: err = %v, want ErrSessionInvalid", name, err)
+		}
+	}
+}
+
+func TestDeleteSessionRevokesOnlyThatSession(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	phone, _ := s.CreateSession(id, time.Hour)
+	laptop, _ := s.CreateSession(id, time.Hour)
+	if err := s.DeleteSession(phone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SessionUser(phone); !errors.Is(err, data.ErrSessionInvalid) {
+		t.Fatalf("revoked session still resolves: %v", err)
+	}
+	if got, err := s.SessionUser(laptop); err != nil || got != id {
+		t.Fatalf("other session lost: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,93 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Who may start a direct message with a user.
+const (
+	DMsFromEveryone = "everyone"
+	DMsFromFriends  = "friends"
+	DMsFromNobody   = "none"
+)
+
+// Settings are a user's privacy choices. A user without a user_settings row
+// gets DefaultSettings, which keeps everything open as it always was.
+type Settings struct {
+	AllowDMsFrom        string `json:"allow_dms_from"`
+	AllowFriendRequests bool   `json:"allow_friend_requests"`
+	ShowActivity        bool   `json:"show_activity"` // Friends see which game you're in
+}
+
+func DefaultSettings() Settings {
+	return Settings{AllowDMsFrom: DMsFromEveryone, AllowFriendRequests: true, ShowActivity: true}
+}
+
+// Valid reports whether every field holds a known value.
+func (s Settings) Valid() bool {
+	switch s.AllowDMsFrom {
+	case DMsFromEveryone, DMsFromFriends, DMsFromNobody:
+		return true
+	}
+	return false
+}
+
+// GetSettings loads userID's settings, falling back to the defaults.
+func (s *Store) GetSettings(userID string) (Settings, error) {
+	st := DefaultSettings()
+	err := s.db.QueryRow(`
+		SELECT allow_dms_from, allow_friend_requests, show_activity
+		FROM user_settings WHERE user_id = $1
+	`, userID).Scan(&st.AllowDMsFrom, &st.AllowFriendRequests, &st.ShowActivity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultSettings(), nil
+	}
+	if err != nil {
+		return DefaultSettings(), err
+	}
+	return st, nil
+}
+
+// SaveSettings replaces userID's settings.
+func (s *Store) SaveSettings(userID string, st Settings) error {
+	if !st.Valid() {
+		return errors.New("invalid settings")
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO user_settings (user_id, allow_dms_from, allow_friend_requests, show_activity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			allow_dms_from = EXCLUDED.allow_dms_from,
+			allow_friend_requests = EXCLUDED.allow_friend_requests,
+			show_activity = EXCLUDED.show_activity,
+			updated_at = NOW()
+	`, userID, st.AllowDMsFrom, st.AllowFriendRequests, st.ShowActivity)
+	return err
+}
+
+// CanDM reports whether from may message to under to's settings. A failed
+// lookup refuses, since the safe mistake is a dropped message.
+func (s *Store) CanDM(from, to string) bool {
+	st, err := s.GetSettings(to)
+	if err != nil {
+		return false
+	}
+	return dmAllowed(st.AllowDMsFrom, s.AreFriends(from, to))
+}
+
+func dmAllowed(allowFrom string, friends bool) bool {
+	switch allowFrom {
+	case DMsFromEveryone:
+		return true
+	case DMsFromFriends:
+		return friends
+	}
+	return false
+}
+
+// AcceptsFriendRequests reports whether userID can be added as a friend.
+func (s *Store) AcceptsFriendRequests(userID string) (bool, error) {
+	st, err := s.GetSettings(userID)
+	return st.AllowFriendRequests, err
+}
@@ -0,0 +1,21 @@
+package data
+
+import "testing"
+
+func TestDMAllowed(t *testing.T) {
+	for _, c := range []struct {
+		from    string
+		friends bool
+		want    bool
+	}{
+		{DMsFromEveryone, false, true},
+		{DMsFromFriends, true, true},
+		{DMsFromFriends, false, false},
+		{DMsFromNobody, true, false},
+		{"bogus", true, false},
+	} {
+		if got := dmAllowed(c.from, c.friends); got != c.want {
+			t.Errorf("dmAllowed(%q, friends %v) = %v", c.from, c.friends, got)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package data_test
+
+import (
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestFriendsOnlyBlocksStrangers(t *testing.T) {
+	s, db := datatest.Store(t)
+	to, friend, stranger := datatest.User(t, db, 0), datatest.User(t, db, 0), datatest.User(t, db, 0)
+	if _, err := db.Exec(`INSERT INTO friendships (requester_id, addressee_id, status) VALUES ($1, $2, 'accepted')`, friend, to); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.CanDM(stranger, to) {
+		t.Fatal("default settings refused a stranger")
+	}
+	st := data.DefaultSettings()
+	st.AllowDMsFrom = data.DMsFromFriends
+	if err := s.SaveSettings(to, st); err != nil {
+		t.Fatal(err)
+	}
+	if s.CanDM(stranger, to) {
+		t.Error("a stranger got through friends-only DMs")
+	}
+	if !s.CanDM(friend, to) {
+		t.Error("a friend was refused under friends-only DMs")
+	}
+	if got, _ := s.GetSettings(to); got != st {
+		t.Errorf("read back %+v, want %+v", got, st)
+	}
+}
+
+func TestSaveSettingsRejectsUnknownValues(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	if err := s.SaveSettings(id, data.Settings{AllowDMsFrom: "aliens"}); err == nil {
+		t.Fatal("saved an unknown DM setting")
+	}
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ShooterStats is a user's lifetime bobik record.
+type ShooterStats struct {
+	Kills       int     `json:"kills"`
+	Deaths      int     `json:"deaths"`
+	GamesPlayed int     `json:"gamesPlayed"`
+	KD          float64 `json:"kd"`
+}
+
+// KDRatio is kills per death. Someone who has never died is credited with
+// their kill count, as if they had died once.
+func KDRatio(kills, deaths int) float64 {
+	if deaths == 0 {
+		return float64(kills)
+	}
+	return float64(kills) / float64(deaths)
+}
+
+// RecordShooterStats adds one finished round to userID's record.
+func (s *Store) RecordShooterStats(userID string, kills, deaths int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO bobik_stats (user_id, kills, deaths, games_played)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (user_id) DO UPDATE SET
+			kills = bobik_stats.kills + EXCLUDED.kills,
+			deaths = bobik_stats.deaths + EXCLUDED.deaths,
+			games_played = bobik_stats.games_played + 1,
+			updated_at = NOW()
+	`, userID, kills, deaths)
+	return err
+}
+
+// GetShooterStats returns userID's record, all zeros if they never finished
+// a round.
+func (s *Store) GetShooterStats(userID string) (ShooterStats, error) {
+	var st ShooterStats
+	err := s.db.QueryRow(`
+		SELECT kills, deaths, games_played FROM bobik_stats WHERE user_id = $1
+	`, userID).Scan(&st.Kills, &st.Deaths, &st.GamesPlayed)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ShooterStats{}, err
+	}
+	st.KD = KDRatio(st.Kills, st.Deaths)
+	return st, nil
+}
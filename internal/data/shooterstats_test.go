@@ -0,0 +1,55 @@
+package data_test
+
+import (
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestKDRatio(t *testing.T) {
+	cases := []struct {
+		kills, deaths int
+		want          float64
+	}{
+		{0, 0, 0},
+		{7, 0, 7},
+		{6, 3, 2},
+		{1, 4, 0.25},
+	}
+	for _, c := range cases {
+		if got := data.KDRatio(c.kills, c.deaths); got != c.want {
+			t.Errorf("KDRatio(%d, %d) = %v, want %v", c.kills, c.deaths, got, c.want)
+		}
+	}
+}
+
+func TestShooterStatsAccumulate(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	if st, err := s.GetShooterStats(id); err != nil || st != (data.ShooterStats{}) {
+		t.Fatalf("fresh user: %+v, %v", st, err)
+	}
+	if err := s.RecordShooterStats(id, 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.GetShooterStats(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Kills != 5 || st.Deaths != 0 || st.GamesPlayed != 1 || st.KD != 5 {
+		t.Fatalf("after one round with no deaths: %+v", st)
+	}
+
+	if err := s.RecordShooterStats(id, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	st, err = s.GetShooterStats(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Kills != 8 || st.Deaths != 4 || st.GamesPlayed != 2 || st.KD != 2 {
+		t.Fatalf("after two rounds: %+v", st)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +20,7 @@ type Medal struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Icon        string `json:"icon"`
+	Threshold   int    `json:"threshold,omitempty"` // Progress needed, see IncrementMedalProgress; 0 means one-off
 }
 
 type UserData struct {
@@ -28,6 +31,7 @@ type UserData struct {
 	Exp            int      `json:"exp"`
 	MaxExp         int      `json:"max_exp"`
 	Coins          int      `json:"coins"`
+	Gems           int      `json:"gems"` // Premium currency, see gems.go
 	Trophies       int      `json:"trophies"`
 	Status         string   `json:"status"`
 	Medals         []string `json:"medals"`
@@ -36,18 +40,31 @@ type UserData struct {
 	BannerColor    string   `json:"banner_color"`
 	CustomAvatar   string   `json:"custom_avatar"`    // Base64 data or empty
 	UpsideDownMeta string   `json:"upside_down_meta"` // JSON for roguelite progression
+	PowerScore     int      `json:"power_score"`      // Weighted level/trophies/medals, see power.go
 }
 
 type Store struct {
 	mu     sync.Mutex
 	db     *sql.DB
 	medals map[string]Medal
+
+	// Separate lock: GetUser is called while s.mu is already held
+	cacheMu   sync.Mutex
+	userCache map[string]cachedUser
+	userGen   map[string]uint64 // Bumped by InvalidateUser, see cacheUser
+	userReads map[string]int    // Reads in flight, see userGeneration
 }
 
 func NewStore(db *sql.DB, medalsPath string) (*Store, error) {
 	s := &Store{
-		db:     db,
-		medals: make(map[string]Medal),
+		db:        db,
+		medals:    make(map[string]Medal),
+		userCache: make(map[string]cachedUser),
+		userGen:   make(map[string]uint64),
+		userReads: make(map[string]int),
+	}
+	if err := s.CheckSchema(); err != nil {
+		return nil, err
 	}
 	if err := s.loadMedals(medalsPath); err != nil {
 		return nil, err
@@ -80,22 +97,28 @@ func (s *Store) loadMedals(path string) error {
 	return nil
 }
 
+// GetUser returns the user, served from a short-lived cache when possible.
 func (s *Store) GetUser(id string) (UserData, bool) {
-	row := s.db.QueryRow(`
-        SELECT id, nickname, tag, level, exp, max_exp, coins, trophies, 
-		       COALESCE(status, 'offline'), COALESCE(language, 'en'),
-			   COALESCE(name_color, 'white'), COALESCE(banner_color, 'default'),
-			   COALESCE(custom_avatar, ''), COALESCE(upside_down_meta, '')
-        FROM users
-        WHERE id = $1
-    `, id)
+	if u, ok := s.cachedUser(id); ok {
+		return u, true
+	}
+	return s.GetUserFresh(id)
+}
+
+// GetUserFresh always reads from the database. Use it where a stale balance
+// would be wrong (read-modify-write, coin checks before spending).
+func (s *Store) GetUserFresh(id string) (UserData, bool) {
+	gen := s.userGeneration(id)
+	row := s.db.QueryRow(userSelectSQL, id)
 
 	var u UserData
-	if err := row.Scan(&u.ID, &u.Nickname, &u.Tag, &u.Level, &u.Exp, &u.MaxExp, &u.Coins, &u.Trophies, &u.Status, &u.Language, &u.NameColor, &u.BannerColor, &u.CustomAvatar, &u.UpsideDownMeta); err != nil {
+	if err := row.Scan(userScanTargets(&u)...); err != nil {
+		s.dropRead(id)
 		return UserData{}, false
 	}
 
 	u.Medals = s.getUserMedalIDs(id)
+	s.cacheUser(u, gen)
 	return u, true
 }
 
@@ -125,7 +148,9 @@ func (s *Store) AwardMedals(userID string, medalIDs ...string) (UserData, error)
 		}
 		_, _ = s.db.Exec(`INSERT INTO user_medals (user_id, medal_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, id)
 	}
-	u, _ := s.GetUser(userID)
+	s.refreshPowerScore(userID)
+	s.InvalidateUser(userID)
+	u, _ := s.GetUserFresh(userID)
 	return u, nil
 }
 
@@ -141,25 +166,42 @@ func (s *Store) MedalDetails(ids []string) []Medal {
 
 func (s *Store) AdjustTrophies(userID string, delta int) error {
 	_, err := s.db.Exec(`UPDATE users SET trophies = GREATEST(0, trophies + $1), updated_at = NOW() WHERE id = $2`, delta, userID)
+	if err == nil {
+		s.refreshPowerScore(userID)
+	}
+	s.InvalidateUser(userID)
 	return err
 }
 
+// AdjustExp adds delta exp, levelling up the same way a match result does
+// and in the same locked transaction. Exp never drops below zero.
 func (s *Store) AdjustExp(userID string, delta int) error {
-	_, err := s.db.Exec(`UPDATE users SET exp = exp + $1 WHERE id = $2`, delta, userID)
+	_, err := s.ProcessGameResult(userID, 0, 0, delta, "")
 	return err
 }
 
 type Friend struct {
-	ID        string
-	Nickname  string
-	Tag       int
-	Level     int
-	Exp       int
-	MaxExp    int
-	Trophies  int
-	Presence  string
-	NameColor string
-	AvatarURL template.URL // Final URL to display
+	ID           string
+	Nickname     string
+	Tag          int
+	Level        int
+	Exp          int
+	MaxExp       int
+	Trophies     int
+	Presence     string
+	Activity     string // Game the friend is in right now, "" when none
+	NameColor    string
+	CustomAvatar string       // Uploaded avatar, empty when using the generated one
+	AvatarURL    template.URL // Final URL to display
+}
+
+// AvatarURL is what to show for a user: their custom avatar if set, otherwise
+// the generated one seeded by nickname.
+func AvatarURL(nickname, customAvatar string) string {
+	if customAvatar != "" {
+		return customAvatar
+	}
+	return fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", nickname)
 }
 
 func (s *Store) ListFriends(userID string) ([]Friend, error) {
@@ -168,8 +210,11 @@ func (s *Store) ListFriends(userID string) ([]Friend, error) {
 			u.id, u.nickname, u.tag, u.level, u.exp, u.max_exp, u.trophies,
 			COALESCE(u.name_color, 'white'),
 			COALESCE(u.custom_avatar, ''),
+			CASE WHEN COALESCE(us.show_activity, TRUE) THEN u.current_activity ELSE '' END,
 			CASE
 				WHEN u.status = 'offline' THEN 'offline'
+				WHEN u.current_activity <> '' THEN 'online'
+				WHEN u.offline_at >= u.last_seen THEN 'offline'
 				WHEN NOW() - u.last_seen <= INTERVAL '60 seconds' THEN u.status
 				WHEN NOW() - u.last_seen <= INTERVAL '5 minutes' THEN 'away'
 				ELSE 'offline'
@@ -179,6 +224,7 @@ func (s *Store) ListFriends(userID string) ([]Friend, error) {
 			(u.id = f.requester_id AND f.addressee_id = $1)
 			OR (u.id = f.addressee_id AND f.requester_id = $1)
 		)
+		LEFT JOIN user_settings us ON us.user_id = u.id
 		WHERE f.status = 'accepted' AND u.id <> $1
 	`, userID)
 
@@ -190,25 +236,56 @@ func (s *Store) ListFriends(userID string) ([]Friend, error) {
 	var friends []Friend
 	for rows.Next() {
 		var fr Friend
-		var customAvatar string
-		if err := rows.Scan(&fr.ID, &fr.Nickname, &fr.Tag, &fr.Level, &fr.Exp, &fr.MaxExp, &fr.Trophies, &fr.NameColor, &customAvatar, &fr.Presence); err != nil {
+		if err := rows.Scan(&fr.ID, &fr.Nickname, &fr.Tag, &fr.Level, &fr.Exp, &fr.MaxExp, &fr.Trophies, &fr.NameColor, &fr.CustomAvatar, &fr.Activity, &fr.Presence); err != nil {
 			continue
 		}
-		// Determine Avatar
-		if customAvatar != "" {
-			fr.AvatarURL = template.URL(customAvatar)
-		} else {
-			fr.AvatarURL = template.URL(fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", fr.Nickname))
-		}
+		fr.AvatarURL = template.URL(AvatarURL(fr.Nickname, fr.CustomAvatar))
 		friends = append(friends, fr)
 	}
 
 	return friends, nil
 }
 
+// FindUserIDByHandle resolves a nickname#tag handle to a user id. Nicknames
+// match case-insensitively via the (nickname_lower, tag) unique index.
+// found is false with a nil error when nobody has that handle.
+func (s *Store) FindUserIDByHandle(nickname string, tag int) (id string, found bool, err error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" || tag <= 0 {
+		return "", false, nil
+	}
+	err = s.db.QueryRow(`SELECT id FROM users WHERE nickname_lower = lower($1) AND tag = $2`, nickname, tag).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// AreFriends reports whether a and b have an accepted friendship in either
+// direction. Pending and blocked rows count as not friends.
+func (s *Store) AreFriends(a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return false
+	}
+	var exists bool
+	_ = s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM friendships
+			WHERE LEAST(requester_id, addressee_id) = LEAST($1, $2)
+			  AND GREATEST(requester_id, addressee_id) = GREATEST($1, $2)
+			  AND status = 'accepted'
+		)
+	`, a, b).Scan(&exists)
+	return exists
+}
+
+// AdjustCoins is AdjustCoinsWithReason without a reason. Prefer the latter so
+// the ledger says where the coins came from.
 func (s *Store) AdjustCoins(userID string, amount int) error {
-	_, err := s.db.Exec(`UPDATE users SET coins = coins + $1 WHERE id = $2`, amount, userID)
-	return err
+	return s.AdjustCoinsWithReason(userID, amount, ReasonUnspecified, "")
 }
 
 func (s *Store) HasItem(userID, itemID string) bool {
@@ -233,31 +310,9 @@ func (s *Store) GetUserInventory(userID string) ([]string, error) {
 	return items, nil
 }
 
-func (s *Store) DeductCoinsAndAddItem(userID, itemID string, cost int) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	res, err := tx.Exec(`UPDATE users SET coins = coins - $1 WHERE id = $2 AND coins >= $1`, cost, userID)
-	if err != nil {
-		return err
-	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("insufficient funds")
-	}
-
-	_, err = tx.Exec(`INSERT INTO inventory (user_id, item_id) VALUES ($1, $2)`, userID, itemID)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
-}
-
 func (s *Store) UpdateProfileLook(userID, nameColor, bannerColor, avatarBase64 string) error {
+	defer s.InvalidateUser(userID)
+
 	if nameColor != "" {
 		_, err := s.db.Exec(`UPDATE users SET name_color = $1 WHERE id = $2`, nameColor, userID)
 		if err != nil {
@@ -280,27 +335,81 @@ func (s *Store) UpdateProfileLook(userID, nameColor, bannerColor, avatarBase64 s
 	return nil
 }
 
-func (s *Store) ProcessGameResult(userID string, trophyDelta, coinDelta, expDelta int) error {
+// ProcessGameResult applies a match outcome (trophies, coins, exp with level
+// ups) in one transaction, with the user's row locked so a concurrent coin
+// change can't be lost. reason is recorded in the coin ledger, e.g.
+// ReasonChibikiMatch. The updated user is returned so callers can tell the
+// client about a level up.
+func (s *Store) ProcessGameResult(userID string, trophyDelta, coinDelta, expDelta int, reason string) (UserData, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 1. Get current stats
-	u, ok := s.GetUser(userID)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return UserData{}, err
+	}
+	defer tx.Rollback()
+
+	var u UserData
+	err = tx.QueryRow(`
+		SELECT id, nickname, coins, trophies, exp, level, max_exp
+		FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&u.ID, &u.Nickname, &u.Coins, &u.Trophies, &u.Exp, &u.Level, &u.MaxExp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserData{}, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return UserData{}, err
+	}
+
+	levelsGained := applyGameResult(&u, trophyDelta, coinDelta, expDelta)
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET coins = $1, trophies = $2, exp = $3, level = $4, max_exp = $5, updated_at = NOW()
+		WHERE id = $6
+	`, u.Coins, u.Trophies, u.Exp, u.Level, u.MaxExp, u.ID)
+	if err != nil {
+		return UserData{}, err
+	}
+	if err := writeLedger(tx, u.ID, coinDelta, u.Coins, reason, ""); err != nil {
+		return UserData{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return UserData{}, err
+	}
+
+	if levelsGained > 0 {
+		fmt.Printf("User %s leveled up to %d!\n", u.Nickname, u.Level)
+	}
+	s.refreshPowerScore(userID)
+	s.InvalidateUser(userID)
+
+	fresh, ok := s.GetUserFresh(userID)
 	if !ok {
-		return fmt.Errorf("user not found")
+		return u, nil // Saved; only the re-read failed
 	}
+	return fresh, nil
+}
 
-	// 2. Apply basic changes
+// applyGameResult adds the deltas to u and rolls exp over into levels,
+// returning how many were gained. Trophies and exp never go below zero.
+func applyGameResult(u *UserData, trophyDelta, coinDelta, expDelta int) int {
 	u.Coins += coinDelta
 	u.Trophies += trophyDelta
 	if u.Trophies < 0 {
 		u.Trophies = 0 // Prevent negative trophies
 	}
 	u.Exp += expDelta
+	if u.Exp < 0 {
+		u.Exp = 0 // Losing exp never costs a level
+	}
+	if u.MaxExp <= 0 {
+		u.MaxExp = 1000 // The column default; a broken row must not spin the loop forever
+	}
 
-	// 3. Level Up Logic
 	// Loop in case they gained enough XP to level up multiple times
-	leveledUp := false
+	levels := 0
 	for u.Exp >= u.MaxExp {
 		u.Exp -= u.MaxExp
 		u.Level++
@@ -311,34 +420,39 @@ func (s *Store) ProcessGameResult(userID string, trophyDelta, coinDelta, expDelt
 			newMaxExp = 50000
 		}
 		u.MaxExp = newMaxExp
-		leveledUp = true
+		levels++
 	}
+	return levels
+}
 
-	// 4. Save back to DB
-	_, err := s.db.Exec(`
-		UPDATE users 
-		SET coins = $1, trophies = $2, exp = $3, level = $4, max_exp = $5, updated_at = NOW()
-		WHERE id = $6
-	`, u.Coins, u.Trophies, u.Exp, u.Level, u.MaxExp, u.ID)
+// leaderboardOrder ranks players: trophies, then level, then nickname, with
+// the id last so ties never shuffle between pages. idx_users_leaderboard
+// covers it.
+const leaderboardOrder = `trophies DESC, level DESC, nickname_lower, id`
 
-	if err == nil && leveledUp {
-		// Optional: You could log this or send a notification
-		fmt.Printf("User %s leveled up to %d!\n", u.Nickname, u.Level)
+// GetLeaderboard fetches one page of players by trophies, plus how many
+// players there are in total for paging.
+func (s *Store) GetLeaderboard(limit, offset int) ([]UserData, int, error) {
+	if limit <= 0 {
+		limit = 15
+	}
+	if offset < 0 {
+		offset = 0
 	}
 
-	return err
-}
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 
-// GetLeaderboard fetches top 15 players by trophies
-func (s *Store) GetLeaderboard() ([]UserData, error) {
 	rows, err := s.db.Query(`
 		SELECT id, nickname, tag, level, trophies, custom_avatar, name_color
-		FROM users 
-		ORDER BY trophies DESC 
-		LIMIT 15
-	`)
+		FROM users
+		ORDER BY `+leaderboardOrder+`
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -358,45 +472,122 @@ func (s *Store) GetLeaderboard() ([]UserData, error) {
 		}
 
 		// Fallback avatar logic
-		if u.CustomAvatar == "" {
-			u.CustomAvatar = fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", u.Nickname)
+		u.CustomAvatar = AvatarURL(u.Nickname, u.CustomAvatar)
+		players = append(players, u)
+	}
+	return players, total, rows.Err()
+}
+
+// RankedUser is a leaderboard row with its absolute position.
+type RankedUser struct {
+	UserData
+	Rank int `json:"rank"`
+}
+
+// GetLeaderboardAround returns the players ranked within window places of
+// userID, ranking everyone the way GetLeaderboard pages them so a rank here
+// is the same row there. Users with no trophies get an empty slice, though
+// they still count toward the ranks of the users around them.
+func (s *Store) GetLeaderboardAround(userID string, window int) ([]RankedUser, error) {
+	if window <= 0 {
+		window = 3
+	}
+	rows, err := s.db.Query(`
+		WITH ranked AS (
+			SELECT id, nickname, tag, level, trophies,
+				   COALESCE(custom_avatar, '') AS custom_avatar,
+				   COALESCE(name_color, 'white') AS name_color,
+				   ROW_NUMBER() OVER (ORDER BY `+leaderboardOrder+`) AS rank
+			FROM users
+		), me AS (
+			SELECT rank FROM ranked WHERE id = $1 AND trophies > 0
+		)
+		SELECT r.id, r.nickname, r.tag, r.level, r.trophies, r.custom_avatar, r.name_color, r.rank
+		FROM ranked r, me
+		WHERE r.rank BETWEEN me.rank - $2 AND me.rank + $2
+		ORDER BY r.rank
+	`, userID, window)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []RankedUser
+	for rows.Next() {
+		var u RankedUser
+		if err := rows.Scan(&u.ID, &u.Nickname, &u.Tag, &u.Level, &u.Trophies, &u.CustomAvatar, &u.NameColor, &u.Rank); err != nil {
+			continue
+		}
+		if u.NameColor == "" {
+			u.NameColor = "white"
 		}
+		u.CustomAvatar = AvatarURL(u.Nickname, u.CustomAvatar)
 		players = append(players, u)
 	}
-	return players, nil
+	return players, rows.Err()
 }
 
 // UpdateUpsideDownMeta saves the roguelite meta-progression data for a user
 func (s *Store) UpdateUpsideDownMeta(userID string, metaJSON string) error {
 	_, err := s.db.Exec(`UPDATE users SET upside_down_meta = $1, updated_at = NOW() WHERE id = $2`, metaJSON, userID)
+	s.InvalidateUser(userID)
 	return err
 }
 
-// AdjustEmberShards is a convenience method for adding ember shards to a user's meta
-func (s *Store) AdjustEmberShards(userID string, delta int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ModifyUpsideDownMeta runs fn on userID's meta blob and saves what it
+// returns, with the row locked throughout so purchases and run payouts
+// can't overwrite each other. An error from fn aborts without saving.
+func (s *Store) ModifyUpsideDownMeta(userID string, fn func(meta string) (string, error)) error {
+	defer s.InvalidateUser(userID)
 
-	user, ok := s.GetUser(userID)
-	if !ok {
-		return fmt.Errorf("user not found")
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	// Parse existing meta or create new
-	var meta struct {
-		EmberShards int `json:"emberShards"`
+	var meta string
+	err = tx.QueryRow(`SELECT upside_down_meta FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&meta)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user not found")
 	}
-	if user.UpsideDownMeta != "" {
-		json.Unmarshal([]byte(user.UpsideDownMeta), &meta)
+	if err != nil {
+		return err
 	}
 
-	meta.EmberShards += delta
-	if meta.EmberShards < 0 {
-		meta.EmberShards = 0
+	updated, err := fn(meta)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE users SET upside_down_meta = $1, updated_at = NOW() WHERE id = $2`, updated, userID); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
+
+// AdjustEmberShards is a convenience method for adding ember shards to a user's meta.
+// Every other key in the blob (upgrades, best runs) is carried over as is.
+func (s *Store) AdjustEmberShards(userID string, delta int) error {
+	return s.ModifyUpsideDownMeta(userID, func(blob string) (string, error) {
+		// Parse existing meta or create new
+		meta := map[string]json.RawMessage{}
+		if blob != "" {
+			if err := json.Unmarshal([]byte(blob), &meta); err != nil {
+				return "", fmt.Errorf("bad upside down meta: %w", err)
+			}
+		}
+
+		var shards int
+		if raw, ok := meta["emberShards"]; ok {
+			json.Unmarshal(raw, &shards)
+		}
+		shards += delta
+		if shards < 0 {
+			shards = 0
+		}
+		meta["emberShards"], _ = json.Marshal(shards)
 
-	// We only update ember shards, let the full meta system handle the rest
-	// For simplicity, just store the whole meta blob
-	newMeta, _ := json.Marshal(meta)
-	return s.UpdateUpsideDownMeta(userID, string(newMeta))
+		newMeta, _ := json.Marshal(meta)
+		return string(newMeta), nil
+	})
 }
@@ -0,0 +1,38 @@
+package data
+
+import "testing"
+
+func TestApplyGameResult(t *testing.T) {
+	for _, c := range []struct {
+		name                 string
+		before               UserData
+		trophies, coins, exp int
+		after                UserData
+		levels               int
+	}{
+		{"no level", UserData{Level: 1, Exp: 100, MaxExp: 1000}, 5, 10, 200,
+			UserData{Level: 1, Exp: 300, MaxExp: 1000, Trophies: 5, Coins: 10}, 0},
+		{"single level up", UserData{Level: 1, Exp: 900, MaxExp: 1000}, 0, 0, 200,
+			UserData{Level: 2, Exp: 100, MaxExp: 1150}, 1},
+		// 3000 pays for 1000 then 1150, leaving 850 toward 1322
+		{"multi-level overflow", UserData{Level: 1, MaxExp: 1000}, 0, 0, 3000,
+			UserData{Level: 3, Exp: 850, MaxExp: 1322}, 2},
+		{"max exp capped", UserData{Level: 40, Exp: 47_000, MaxExp: 48_000}, 0, 0, 1000,
+			UserData{Level: 41, Exp: 0, MaxExp: 50_000}, 1},
+		{"trophy floor", UserData{Level: 1, MaxExp: 1000, Trophies: 10}, -50, 0, 0,
+			UserData{Level: 1, MaxExp: 1000, Trophies: 0}, 0},
+		{"exp floor keeps the level", UserData{Level: 5, Exp: 100, MaxExp: 2000}, 0, 0, -500,
+			UserData{Level: 5, Exp: 0, MaxExp: 2000}, 0},
+		{"broken max exp", UserData{Level: 1, MaxExp: 0}, 0, 0, 1000,
+			UserData{Level: 2, Exp: 0, MaxExp: 1150}, 1},
+	} {
+		u := c.before
+		levels := applyGameResult(&u, c.trophies, c.coins, c.exp)
+		if levels != c.levels || u.Level != c.after.Level || u.Exp != c.after.Exp || u.MaxExp != c.after.MaxExp ||
+			u.Trophies != c.after.Trophies || u.Coins != c.after.Coins {
+			t.Errorf("%s: level %d exp %d/%d trophies %d coins %d (+%d levels), want level %d exp %d/%d trophies %d coins %d (+%d)",
+				c.name, u.Level, u.Exp, u.MaxExp, u.Trophies, u.Coins, levels,
+				c.after.Level, c.after.Exp, c.after.MaxExp, c.after.Trophies, c.after.Coins, c.levels)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package data_test
+
+import (
+	"strings"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestGetUserSeesStoreWrites(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 5)
+	if u, ok := s.GetUser(id); !ok || u.Coins != 5 {
+		t.Fatalf("GetUser %+v %v", u, ok)
+	}
+	if err := s.AdjustCoinsWithReason(id, 20, data.ReasonDailyBonus, ""); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := s.GetUser(id); u.Coins != 25 {
+		t.Fatalf("cached balance %d after a write, want 25", u.Coins)
+	}
+}
+
+func TestAdjustExpLevelsUpAndFloors(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	// 3000 pays for levels at 1000 and 1150, leaving 850 toward 1322
+	if err := s.AdjustExp(id, 3000); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := s.GetUserFresh(id)
+	if u.Level != 3 || u.Exp != 850 || u.MaxExp != 1322 {
+		t.Fatalf("level %d exp %d/%d, want level 3 exp 850/1322", u.Level, u.Exp, u.MaxExp)
+	}
+
+	if err := s.AdjustExp(id, -5000); err != nil {
+		t.Fatal(err)
+	}
+	u, _ = s.GetUserFresh(id)
+	if u.Level != 3 || u.Exp != 0 {
+		t.Fatalf("level %d exp %d after a big loss, want level 3 exp 0", u.Level, u.Exp)
+	}
+}
+
+func TestFindUserIDByHandle(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	var nick string
+	var tag int
+	if err := db.QueryRow(`SELECT nickname, tag FROM users WHERE id = $1`, id).Scan(&nick, &tag); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, handle := range []string{nick, strings.ToUpper(nick), "  " + nick + " "} {
+		if got, found, err := s.FindUserIDByHandle(handle, tag); err != nil || !found || got != id {
+			t.Errorf("%q#%d: %q found=%v %v, want %s", handle, tag, got, found, err, id)
+		}
+	}
+
+	for _, tc := range []struct {
+		nick string
+		tag  int
+	}{
+		{nick, tag%9999 + 1}, // Someone else's tag, or nobody's
+		{nick + "_nobody", tag},
+		{"", tag},
+		{nick, 0},
+	} {
+		got, found, err := s.FindUserIDByHandle(tc.nick, tc.tag)
+		if err != nil || (found && got == id) {
+			t.Errorf("%q#%d: %q found=%v %v, want not %s", tc.nick, tc.tag, got, found, err, id)
+		}
+		if !found && got != "" {
+			t.Errorf("%q#%d: not found but returned %q", tc.nick, tc.tag, got)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package data_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"main/internal/data/datatest"
+)
+
+// Concurrent shard grants must all land: each runs against the locked row,
+// so none overwrites another.
+func TestModifyUpsideDownMetaConcurrent(t *testing.T) {
+	s, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.AdjustEmberShards(id, 5); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	u, _ := s.GetUserFresh(id)
+	var meta struct {
+		EmberShards int `json:"emberShards"`
+	}
+	json.Unmarshal([]byte(u.UpsideDownMeta), &meta)
+	if meta.EmberShards != 100 {
+		t.Fatalf("shards = %d, want 100", meta.EmberShards)
+	}
+}
@@ -1,9 +1,12 @@
 package lobby
 
 import (
+	"encoding/json"
+	"log"
 	"net/http"
 	"path/filepath"
 
+	"main/internal/auth"
 	"main/internal/data"
 )
 
@@ -12,3 +15,28 @@ func NewBobikHandler(store *data.Store) http.HandlerFunc {
 		http.ServeFile(w, r, filepath.Join("web", "templates", "bobik.html"))
 	}
 }
+
+// NewBobikStatsHandler returns a user's lifetime bobik kills, deaths, rounds
+// played and K/D, the caller's own unless ?user= names someone else.
+// GET /bobik/stats
+func NewBobikStatsHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user")
+		if userID == "" {
+			self, ok := auth.UserID(r)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			userID = self
+		}
+		stats, err := store.GetShooterStats(userID)
+		if err != nil {
+			log.Printf("[BOBIK] Stats for %s failed: %v", userID, err)
+			http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -0,0 +1,41 @@
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main/internal/auth"
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+func TestBobikStats(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	store.RecordShooterStats(id, 4, 0)
+	store.RecordShooterStats(id, 2, 3)
+
+	req := httptest.NewRequest(http.MethodGet, "/bobik/stats", nil)
+	rec := httptest.NewRecorder()
+	NewBobikStatsHandler(store).ServeHTTP(rec, auth.WithUserID(req, id))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("code %d", rec.Code)
+	}
+	var st data.ShooterStats
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Kills != 6 || st.Deaths != 3 || st.GamesPlayed != 2 || st.KD != 2 {
+		t.Fatalf("stats %+v", st)
+	}
+}
+
+func TestBobikStatsSignedOut(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewBobikStatsHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bobik/stats", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("code %d, want 401", rec.Code)
+	}
+}
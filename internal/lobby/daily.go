@@ -0,0 +1,49 @@
+package lobby
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/data"
+)
+
+// NewDailyClaimHandler pays the caller's daily bonus.
+// POST /daily/claim -> {"coins":75,"streak":2}
+// A second claim the same UTC day gets 409 with {"error":"already_claimed"}.
+func NewDailyClaimHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		coins, streak, err := store.ClaimDailyBonus(userID)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, data.ErrAlreadyClaimed):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "already_claimed"})
+			return
+		case errors.Is(err, data.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		case err != nil:
+			log.Printf("[DAILY] Claim for %s failed: %v", userID, err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"coins":  coins,
+			"streak": streak,
+		})
+	}
+}
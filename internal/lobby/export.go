@@ -0,0 +1,45 @@
+package lobby
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"main/internal/auth"
+	"main/internal/data"
+)
+
+// NewExportHandler sends the caller a download of everything stored about
+// them. Only the session cookie decides whose data it is; there is no way to
+// ask for someone else's.
+// GET /account/export
+func NewExportHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, ok := store.GetUser(userID); !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="five3space-export-%s.json"`, time.Now().UTC().Format("2006-01-02")))
+		if err := store.ExportUser(userID, w); err != nil {
+			// Headers are long gone by now; the truncated body is the signal
+			if !errors.Is(err, data.ErrUserNotFound) {
+				log.Printf("[EXPORT] Export for %s failed midway: %v", userID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package lobby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportNeedsSession(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewExportHandler(nil)(rec, httptest.NewRequest(http.MethodGet, "/account/export?userID=u_1", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("code %d, want 401", rec.Code)
+	}
+}
@@ -2,11 +2,14 @@ package lobby
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"path/filepath"
+	"strconv"
 
+	"main/internal/auth"
 	"main/internal/data"
 	"main/internal/upsidedown"
 )
@@ -54,10 +57,8 @@ func NewGameHandler(store *data.Store) http.HandlerFunc {
 
 func renderGame(w http.ResponseWriter, r *http.Request, store *data.Store) {
 	userID := "guest"
-	if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-		userID = c.Value
-	} else if q := r.URL.Query().Get("userID"); q != "" {
-		userID = q
+	if id, ok := auth.UserID(r); ok {
+		userID = id
 	}
 
 	lang := normalizeLang(r.URL.Query().Get("lang"))
@@ -91,16 +92,7 @@ func normalizeLang(raw string) string {
 func renderLobby(w http.ResponseWriter, r *http.Request, store *data.Store) {
 	requestedLang := normalizeLang(r.URL.Query().Get("lang"))
 
-	var userID string
-	hadCookie := false
-
-	if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-		userID = c.Value
-		hadCookie = true
-	} else if q := r.URL.Query().Get("userID"); q != "" {
-		userID = q
-		hadCookie = true
-	}
+	userID, hadCookie := auth.UserID(r)
 
 	var selected data.UserData
 	userFound := false
@@ -124,9 +116,6 @@ func renderLobby(w http.ResponseWriter, r *http.Request, store *data.Store) {
 
 	if !userFound {
 		hadCookie = false
-		http.SetCookie(w, &http.Cookie{Name: "user_id", Value: "", Path: "/", MaxAge: -1})
-	} else if userID != "" {
-		http.SetCookie(w, &http.Cookie{Name: "user_id", Value: userID, Path: "/", MaxAge: 86400 * 30})
 	}
 
 	var user User
@@ -136,10 +125,7 @@ func renderLobby(w http.ResponseWriter, r *http.Request, store *data.Store) {
 			MaxExp: 1, Status: "offline", Language: lang, NameColor: "white", BannerColor: "default",
 		}
 	} else {
-		avatar := selected.CustomAvatar
-		if avatar == "" {
-			avatar = fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", selected.Nickname)
-		}
+		avatar := data.AvatarURL(selected.Nickname, selected.CustomAvatar)
 
 		user = User{
 			ID: selected.ID, Nickname: selected.Nickname, Tag: fmt.Sprintf("%04d", selected.Tag),
@@ -156,9 +142,12 @@ func renderLobby(w http.ResponseWriter, r *http.Request, store *data.Store) {
 	}
 	user.XPPercentage = fmt.Sprintf("%d%%", pct)
 
-	friendList, _ := store.ListFriends(user.ID)
+	// Self comes from the user cache; friends (with presence and avatars) are
+	// one query, skipped entirely for guests.
+	friendList := []data.Friend{}
 	medalDetails := []data.Medal{}
 	if userFound {
+		friendList, _ = store.ListFriends(user.ID)
 		medalDetails = store.MedalDetails(selected.Medals)
 	}
 
@@ -221,14 +210,8 @@ func renderLobby(w http.ResponseWriter, r *http.Request, store *data.Store) {
 
 func NewCustomizeSaveHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID := ""
-		if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-			userID = c.Value
-		} else if q := r.URL.Query().Get("userID"); q != "" {
-			userID = q
-		}
-
-		if userID == "" {
+		userID, ok := auth.UserID(r)
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -256,14 +239,8 @@ func NewCustomizeSaveHandler(store *data.Store) http.HandlerFunc {
 
 func NewUpsideDownShopHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID := ""
-		if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-			userID = c.Value
-		} else if q := r.URL.Query().Get("userID"); q != "" {
-			userID = q
-		}
-
-		if userID == "" {
+		userID, ok := auth.UserID(r)
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -280,19 +257,27 @@ func NewUpsideDownShopHandler(store *data.Store) http.HandlerFunc {
 				return
 			}
 
-			success := false
-			if req.Action == "upgrade" {
-				success = meta.PurchaseUpgrade(upsidedown.UpgradeType(req.ID))
-			} else if req.Action == "class" {
-				success = meta.PurchaseClass(upsidedown.ClassID(req.ID))
-			}
-
-			if !success {
+			var err error
+			meta, err = upsidedown.UpdatePlayerMeta(store, userID, func(meta *upsidedown.PlayerMeta) error {
+				success := false
+				if req.Action == "upgrade" {
+					success = meta.PurchaseUpgrade(upsidedown.UpgradeType(req.ID))
+				} else if req.Action == "class" {
+					success = meta.PurchaseClass(upsidedown.ClassID(req.ID))
+				}
+				if !success {
+					return errBuyRefused
+				}
+				return nil
+			})
+			if errors.Is(err, errBuyRefused) {
 				http.Error(w, "Purchase failed (insufficient shards or max level)", http.StatusBadRequest)
 				return
 			}
-
-			upsidedown.SavePlayerMeta(store, userID, meta)
+			if err != nil {
+				http.Error(w, "Failed to save", http.StatusInternalServerError)
+				return
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -302,41 +287,98 @@ func NewUpsideDownShopHandler(store *data.Store) http.HandlerFunc {
 
 func add(a, b int) int { return a + b }
 
+func containsMe(rows []RankedUser) bool {
+	for _, u := range rows {
+		if u.IsMe {
+			return true
+		}
+	}
+	return false
+}
+
 func NewLeaderboardHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		pageData := commonPage(w, r, store)
 
-		rawLeaders, err := store.GetLeaderboard()
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		offset := (page - 1) * LeaderboardPageSize
+
+		rawLeaders, total, err := store.GetLeaderboard(LeaderboardPageSize, offset)
 		if err != nil {
 			rawLeaders = []data.UserData{}
 		}
+		pages := (total + LeaderboardPageSize - 1) / LeaderboardPageSize
+		if pages < 1 {
+			pages = 1
+		}
 
-		var displayLeaders []User
-		for _, u := range rawLeaders {
-			avatarSrc := u.CustomAvatar
-			if avatarSrc == "" {
-				avatarSrc = fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", u.Nickname)
-			}
-
-			displayLeaders = append(displayLeaders, User{
-				Nickname:  u.Nickname,
-				Level:     u.Level,
-				Trophies:  u.Trophies,
-				NameColor: u.NameColor,
-				AvatarURL: template.URL(avatarSrc),
+		var displayLeaders []RankedUser
+		for i, u := range rawLeaders {
+			avatarSrc := data.AvatarURL(u.Nickname, u.CustomAvatar)
+
+			displayLeaders = append(displayLeaders, RankedUser{
+				User: User{
+					ID:        u.ID,
+					Nickname:  u.Nickname,
+					Level:     u.Level,
+					Trophies:  u.Trophies,
+					NameColor: u.NameColor,
+					AvatarURL: template.URL(avatarSrc),
+				},
+				Rank: offset + i + 1,
+				IsMe: u.ID == pageData.User.ID,
 			})
 		}
 
+		// "Around me" slice, only when the player isn't on the page shown
+		var around []RankedUser
+		if pageData.User.ID != "" {
+			rows, err := store.GetLeaderboardAround(pageData.User.ID, LeaderboardAroundWindow)
+			if err == nil && len(rows) > 0 && !containsMe(displayLeaders) {
+				for _, u := range rows {
+					around = append(around, RankedUser{
+						User: User{
+							ID:        u.ID,
+							Nickname:  u.Nickname,
+							Level:     u.Level,
+							Trophies:  u.Trophies,
+							NameColor: u.NameColor,
+							AvatarURL: template.URL(u.CustomAvatar),
+						},
+						Rank: u.Rank,
+						IsMe: u.ID == pageData.User.ID,
+					})
+				}
+			}
+		}
+
 		data := struct {
-			User    User
-			Lang    string
-			Text    Translations
-			Leaders []User
+			User     User
+			Lang     string
+			Text     Translations
+			Leaders  []RankedUser
+			Around   []RankedUser
+			Page     int
+			Pages    int
+			PrevPage int // 0 when on the first page
+			NextPage int // 0 when on the last page
 		}{
 			User:    pageData.User,
 			Lang:    pageData.Lang,
 			Text:    pageData.Text, // Pass translations here!
 			Leaders: displayLeaders,
+			Around:  around,
+			Page:    page,
+			Pages:   pages,
+		}
+		if page > 1 {
+			data.PrevPage = page - 1
+		}
+		if page < pages {
+			data.NextPage = page + 1
 		}
 
 		tmplPath := filepath.Join("web", "templates", "leaderboard.html")
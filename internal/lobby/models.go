@@ -24,6 +24,19 @@ type User struct {
 	Inventory    []string
 }
 
+// RankedUser is a leaderboard row that knows its absolute position.
+type RankedUser struct {
+	User
+	Rank int
+	IsMe bool
+}
+
+// LeaderboardAroundWindow is how many ranks above/below the player are shown.
+const LeaderboardAroundWindow = 3
+
+// LeaderboardPageSize is how many players one leaderboard page lists.
+const LeaderboardPageSize = 15
+
 type GameMode struct {
 	ID           string
 	Title        string
@@ -77,6 +90,7 @@ type Translations struct {
 	LangNote     string
 	StatusNote   string
 	AccountNote  string
+	ExportData   string
 
 	// Friends Page
 	AddFriendBtn    string
@@ -86,6 +100,11 @@ type Translations struct {
 	RemoveAction    string
 	AddFriendHeader string
 	SendRequest     string
+	FriendRequests  string
+	AcceptAction    string
+	DeclineAction   string
+	RequestSent     string
+	PendingLabel    string
 	ChatTitle       string
 
 	// Customize Page
@@ -217,6 +236,7 @@ var texts = map[string]Translations{
 		LangNote:     "We save this to your account so every page uses it.",
 		StatusNote:   "Tip: click the status dot on your avatar to toggle quickly.",
 		AccountNote:  "Remember me keeps you signed in on this device.",
+		ExportData:   "Download my data",
 
 		AddFriendBtn:    "+ Add Friend",
 		NoFriendsTitle:  "No friends yet",
@@ -225,6 +245,11 @@ var texts = map[string]Translations{
 		RemoveAction:    "Remove",
 		AddFriendHeader: "Add Friend",
 		SendRequest:     "Send Request",
+		FriendRequests:  "Friend requests",
+		AcceptAction:    "Accept",
+		DeclineAction:   "Decline",
+		RequestSent:     "Request sent! They'll show up here once they accept.",
+		PendingLabel:    "Pending",
 		ChatTitle:       "Chat",
 
 		CustomizeTitle:   "Customize",
@@ -336,6 +361,7 @@ var texts = map[string]Translations{
 		LangNote:     "Ми збережемо це у твоєму профілі.",
 		StatusNote:   "Порада: тисни на кружечок біля аватарки для швидкої зміни.",
 		AccountNote:  "'Запам'ятати мене' дозволяє не вводити пароль щоразу.",
+		ExportData:   "Завантажити мої дані",
 
 		AddFriendBtn:    "+ Додати друга",
 		NoFriendsTitle:  "Поки що у тебе немає друзів",
@@ -344,6 +370,11 @@ var texts = map[string]Translations{
 		RemoveAction:    "Видалити",
 		AddFriendHeader: "Додати друга",
 		SendRequest:     "Надіслати",
+		FriendRequests:  "Запити в друзі",
+		AcceptAction:    "Прийняти",
+		DeclineAction:   "Відхилити",
+		RequestSent:     "Запит надіслано! Друг з'явиться тут, щойно прийме його.",
+		PendingLabel:    "Очікує",
 		ChatTitle:       "Чат",
 
 		CustomizeTitle:   "Кастомізація",
@@ -455,6 +486,7 @@ var texts = map[string]Translations{
 		LangNote:     "Мы сохраним это в твоем профиле.",
 		StatusNote:   "Совет: нажми на круг у аватарки для быстрой смены.",
 		AccountNote:  "'Запомнить меня' позволяет не вводить пароль каждый раз.",
+		ExportData:   "Скачать мои данные",
 
 		AddFriendBtn:    "+ Добавить друга",
 		NoFriendsTitle:  "Пока нет друзей",
@@ -463,6 +495,11 @@ var texts = map[string]Translations{
 		RemoveAction:    "Удалить",
 		AddFriendHeader: "Добавить друга",
 		SendRequest:     "Отправить",
+		FriendRequests:  "Заявки в друзья",
+		AcceptAction:    "Принять",
+		DeclineAction:   "Отклонить",
+		RequestSent:     "Заявка отправлена! Друг появится здесь, когда примет её.",
+		PendingLabel:    "Ожидает",
 		ChatTitle:       "Чат",
 
 		CustomizeTitle:   "Редактор",
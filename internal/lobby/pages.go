@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"path/filepath"
 
+	"main/internal/auth"
 	"main/internal/data"
 )
 
@@ -34,14 +35,7 @@ func getCurrency(lang string) string {
 func commonPage(w http.ResponseWriter, r *http.Request, store *data.Store) PageData {
 	requestedLang := normalizeLang(r.URL.Query().Get("lang"))
 
-	userID := r.URL.Query().Get("userID")
-	hadCookie := false
-	if userID != "" {
-		hadCookie = true
-	} else if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-		userID = c.Value
-		hadCookie = true
-	}
+	userID, hadCookie := auth.UserID(r)
 
 	var selected data.UserData
 	userFound := false
@@ -63,12 +57,6 @@ func commonPage(w http.ResponseWriter, r *http.Request, store *data.Store) PageD
 
 	if !userFound {
 		hadCookie = false
-		http.SetCookie(w, &http.Cookie{
-			Name:   "user_id",
-			Value:  "",
-			Path:   "/",
-			MaxAge: -1,
-		})
 	}
 
 	var user User
@@ -87,10 +75,7 @@ func commonPage(w http.ResponseWriter, r *http.Request, store *data.Store) PageD
 			Language:  lang,
 		}
 	} else {
-		avatar := selected.CustomAvatar
-		if avatar == "" {
-			avatar = fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s&backgroundColor=ffdfbf", selected.Nickname)
-		}
+		avatar := data.AvatarURL(selected.Nickname, selected.CustomAvatar)
 
 		user = User{
 			ID:        selected.ID,
@@ -114,9 +99,12 @@ func commonPage(w http.ResponseWriter, r *http.Request, store *data.Store) PageD
 	}
 	user.XPPercentage = fmt.Sprintf("%d%%", pct)
 
-	friendList, _ := store.ListFriends(user.ID)
+	// Self comes from the user cache; friends (with presence and avatars) are
+	// one query, skipped entirely for guests.
+	friendList := []data.Friend{}
 	medalDetails := []data.Medal{}
 	if userFound {
+		friendList, _ = store.ListFriends(user.ID)
 		medalDetails = store.MedalDetails(selected.Medals)
 	}
 
@@ -0,0 +1,54 @@
+package lobby
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/data"
+)
+
+// NewPrivacySettingsHandler reads and updates the caller's privacy settings.
+// POST takes any subset of the fields; the rest keep their current values.
+// GET /settings/privacy
+// POST /settings/privacy {"allow_dms_from":"friends","allow_friend_requests":false,"show_activity":true}
+func NewPrivacySettingsHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings, err := store.GetSettings(userID)
+		if err != nil {
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				http.Error(w, "Bad JSON", http.StatusBadRequest)
+				return
+			}
+			if !settings.Valid() {
+				http.Error(w, "Invalid settings", http.StatusBadRequest)
+				return
+			}
+			if err := store.SaveSettings(userID, settings); err != nil {
+				log.Printf("[SETTINGS] Save for %s failed: %v", userID, err)
+				http.Error(w, "Failed to save settings", http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
@@ -2,13 +2,62 @@ package lobby
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
+	"main/internal/auth"
 	"main/internal/data"
 )
 
+// shopItem is one entry of the lobby shop. CoinPrice/GemPrice of 0 means
+// the item isn't sold for that currency, except coin packs, which are free
+// grants on the coin path (the payment happens outside the game).
+type shopItem struct {
+	Name      string
+	CoinPrice int
+	GemPrice  int
+	Pack      int // Coins granted; cosmetics grant an inventory entry instead
+}
+
+var shopItems = map[string]shopItem{
+	"coins_1000":   {Name: "1000 Coins", GemPrice: 100, Pack: 1000},
+	"coins_5000":   {Name: "5000 Coins", GemPrice: 450, Pack: 5000},
+	"pack_support": {Name: "Supporter Pack", Pack: 1000},
+	"pack_founder": {Name: "Founder Pack", Pack: 5000},
+	"frame_neon":   {Name: "Neon Frame", CoinPrice: 2500, GemPrice: 250},
+	"banner_gold":  {Name: "Gold Banner", CoinPrice: 5000, GemPrice: 500},
+	"name_rainbow": {Name: "Rainbow Name", CoinPrice: 8000, GemPrice: 800},
+	"name_gold":    {Name: "Gold Name", CoinPrice: 4000, GemPrice: 400},
+	"banner_cyber": {Name: "Cyber Banner", CoinPrice: 3500, GemPrice: 350},
+}
+
+// catalogItem turns a shop entry into what the store charges and grants for
+// the chosen currency. ok is false when the item isn't sold for it.
+func catalogItem(itemID, currency string) (data.CatalogItem, shopItem, bool) {
+	it, ok := shopItems[itemID]
+	if !ok {
+		return data.CatalogItem{}, it, false
+	}
+	price := it.CoinPrice
+	if currency == data.CurrencyGems {
+		price = it.GemPrice
+		if price == 0 {
+			return data.CatalogItem{}, it, false
+		}
+	} else if price == 0 && it.Pack == 0 {
+		return data.CatalogItem{}, it, false
+	}
+	return data.CatalogItem{
+		ID:         itemID,
+		Currency:   currency,
+		Price:      price,
+		Inventory:  it.Pack == 0,
+		GrantCoins: it.Pack,
+	}, it, true
+}
+
 type BuyRequest struct {
 	ItemID   string `json:"item_id"`
 	Currency string `json:"currency"`
@@ -16,12 +65,11 @@ type BuyRequest struct {
 
 func NewBuyHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		c, err := r.Cookie("user_id")
-		if err != nil || c.Value == "" {
+		userID, ok := auth.UserID(r)
+		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		userID := c.Value
 
 		var req BuyRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -29,99 +77,49 @@ func NewBuyHandler(store *data.Store) http.HandlerFunc {
 			return
 		}
 
-		var successMsg string
-		var newBalance int
-
-		switch req.ItemID {
-		case "coins_1000", "pack_support":
-			if err := store.AdjustCoins(userID, 1000); err != nil {
-				http.Error(w, "DB Error", http.StatusInternalServerError)
-				return
-			}
-			successMsg = "Payment Successful! +1000 Coins"
-
-		case "coins_5000", "pack_founder":
-			if err := store.AdjustCoins(userID, 5000); err != nil {
-				http.Error(w, "DB Error", http.StatusInternalServerError)
-				return
-			}
-			successMsg = "Payment Successful! +5000 Coins"
-
-		// --- COSMETICS ---
-		case "frame_neon":
-			newBalance, err = processCoinPurchase(store, userID, "frame_neon", 2500)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-				return
-			}
-			successMsg = "Neon Frame Purchased!"
-
-		case "banner_gold":
-			newBalance, err = processCoinPurchase(store, userID, "banner_gold", 5000)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-				return
-			}
-			successMsg = "Gold Banner Purchased!"
-
-		case "name_rainbow":
-			newBalance, err = processCoinPurchase(store, userID, "name_rainbow", 8000)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-				return
-			}
-			successMsg = "Rainbow Name Purchased!"
-
-		case "name_gold":
-			newBalance, err = processCoinPurchase(store, userID, "name_gold", 4000)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-				return
-			}
-			successMsg = "Gold Name Purchased!"
-
-		case "banner_cyber":
-			newBalance, err = processCoinPurchase(store, userID, "banner_cyber", 3500)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-				return
-			}
-			successMsg = "Cyber Banner Purchased!"
+		currency := req.Currency
+		if currency == "" {
+			currency = data.CurrencyCoins
+		}
+		if currency != data.CurrencyCoins && currency != data.CurrencyGems {
+			http.Error(w, "Unknown currency", http.StatusBadRequest)
+			return
+		}
 
-		default:
+		item, info, ok := catalogItem(req.ItemID, currency)
+		if !ok {
 			http.Error(w, "Unknown Item", http.StatusBadRequest)
 			return
 		}
 
-		if newBalance == 0 {
-			u, _ := store.GetUser(userID)
-			newBalance = u.Coins
+		balance, err := store.PurchaseItem(userID, item)
+		switch {
+		case errors.Is(err, data.ErrInsufficientFunds):
+			http.Error(w, fmt.Sprintf("Not enough %s!", currency), http.StatusPaymentRequired)
+			return
+		case errors.Is(err, data.ErrAlreadyOwned):
+			http.Error(w, "You already own this item", http.StatusPaymentRequired)
+			return
+		case err != nil:
+			log.Println("Purchase error:", err)
+			http.Error(w, "Transaction failed", http.StatusPaymentRequired)
+			return
+		}
+
+		msg := info.Name + " Purchased!"
+		switch {
+		case item.GrantCoins > 0 && item.Price == 0:
+			msg = fmt.Sprintf("Payment Successful! +%d Coins", item.GrantCoins)
+		case item.GrantCoins > 0:
+			msg = fmt.Sprintf("+%d Coins for %d Gems", item.GrantCoins, item.Price)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
-			"message": successMsg,
-			"coins":   newBalance,
+			"message": msg,
+			"coins":   balance.Coins,
+			"gems":    balance.Gems,
 		})
 	}
 }
-
-func processCoinPurchase(store *data.Store, userID, itemID string, cost int) (int, error) {
-	user, ok := store.GetUser(userID)
-	if !ok {
-		return 0, fmt.Errorf("User not found")
-	}
-	if user.Coins < cost {
-		return 0, fmt.Errorf("Not enough coins!")
-	}
-	if store.HasItem(userID, itemID) {
-		return 0, fmt.Errorf("You already own this item")
-	}
-
-	if err := store.DeductCoinsAndAddItem(userID, itemID, cost); err != nil {
-		log.Println("Purchase error:", err)
-		return 0, fmt.Errorf("Transaction failed")
-	}
-	return user.Coins - cost, nil
-}
@@ -0,0 +1,27 @@
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"main/internal/data"
+)
+
+// LiveGame is a running game manager the lobby can ask for a head count.
+type LiveGame interface {
+	Snapshot() data.LiveStatus
+}
+
+// NewStatusHandler serves the live status of each game, keyed by the same
+// IDs as the lobby's mode cards.
+func NewStatusHandler(games map[string]LiveGame) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := make(map[string]data.LiveStatus, len(games))
+		for id, g := range games {
+			status[id] = g.Snapshot()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(status)
+	}
+}
@@ -0,0 +1,34 @@
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main/internal/data"
+)
+
+type fixedGame data.LiveStatus
+
+func (f fixedGame) Snapshot() data.LiveStatus { return data.LiveStatus(f) }
+
+func TestStatusHandler(t *testing.T) {
+	h := NewStatusHandler(map[string]LiveGame{
+		"chibiki": fixedGame{Players: 2, InMatch: true, State: "playing"},
+		"slotix":  fixedGame{Players: 5},
+	})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/lobby/status", nil))
+
+	var got map[string]data.LiveStatus
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got["chibiki"] != (data.LiveStatus{Players: 2, InMatch: true, State: "playing"}) || got["slotix"].Players != 5 {
+		t.Fatalf("status %+v", got)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("live status may be cached")
+	}
+}
@@ -0,0 +1,110 @@
+package lobby
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/internal/auth"
+	"main/internal/data"
+	"main/internal/upsidedown"
+)
+
+// NewUpsideDownMetaHandler returns the caller's meta-progression with every
+// upgrade and class priced for them.
+// GET /upsidedown/meta
+func NewUpsideDownMetaHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		meta := upsidedown.LoadPlayerMeta(store, userID)
+		upgrades, classes := meta.Offers()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"meta":     meta,
+			"upgrades": upgrades,
+			"classes":  classes,
+		})
+	}
+}
+
+// errBuyRefused rolls back a buy that failed its checks.
+var errBuyRefused = errors.New("purchase refused")
+
+// NewUpsideDownBuyHandler buys one upgrade level or unlocks one class.
+// Refusals come back as 400 with {"error": reason}, reason being one of the
+// upsidedown.Refuse* values.
+// POST /upsidedown/buy {"type":"max_health"} or {"class":"scout"}
+func NewUpsideDownBuyHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Type  string `json:"type"`
+			Class string `json:"class"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Bad JSON", http.StatusBadRequest)
+			return
+		}
+
+		// Check and buy against the locked row, so two quick buys can't both
+		// spend the same shards and a run payout can't be overwritten
+		resp := map[string]interface{}{}
+		var refusal string
+		meta, err := upsidedown.UpdatePlayerMeta(store, userID, func(meta *upsidedown.PlayerMeta) error {
+			refusal = upsidedown.RefuseUnknown
+			switch {
+			case req.Type != "":
+				t := upsidedown.UpgradeType(req.Type)
+				if refusal = meta.UpgradeRefusal(t); refusal == "" {
+					meta.PurchaseUpgrade(t)
+					resp["type"] = t
+					resp["level"] = meta.UpgradeLevels[t]
+				}
+			case req.Class != "":
+				id := upsidedown.ClassID(req.Class)
+				if refusal = meta.ClassRefusal(id); refusal == "" {
+					meta.PurchaseClass(id)
+					resp["class"] = id
+				}
+			}
+			if refusal != "" {
+				return errBuyRefused
+			}
+			return nil
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if refusal != "" && meta != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":       refusal,
+				"emberShards": meta.EmberShards,
+			})
+			return
+		}
+
+		if err != nil {
+			log.Printf("[UPSIDEDOWN] Buy for %s failed to save: %v", userID, err)
+			http.Error(w, "Failed to save", http.StatusInternalServerError)
+			return
+		}
+		resp["emberShards"] = meta.EmberShards
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,75 @@
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main/internal/auth"
+	"main/internal/data"
+	"main/internal/data/datatest"
+	"main/internal/upsidedown"
+)
+
+// buy posts body to the buy handler as userID.
+func buy(t *testing.T, store *data.Store, userID, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/upsidedown/buy", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	NewUpsideDownBuyHandler(store).ServeHTTP(rec, auth.WithUserID(req, userID))
+	var out map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&out)
+	return rec.Code, out
+}
+
+func giveShards(t *testing.T, store *data.Store, userID string, meta *upsidedown.PlayerMeta) {
+	t.Helper()
+	if err := store.UpdateUpsideDownMeta(userID, meta.ToJSON()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsideDownBuyUpgrade(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	meta := upsidedown.NewPlayerMeta()
+	meta.EmberShards = meta.GetUpgradeCost(upsidedown.UpgradeMaxHealth) + 10
+	giveShards(t, store, id, meta)
+
+	code, out := buy(t, store, id, `{"type":"max_health"}`)
+	if code != http.StatusOK || out["level"] != 1.0 || out["emberShards"] != 10.0 {
+		t.Fatalf("code %d body %v", code, out)
+	}
+	if saved := upsidedown.LoadPlayerMeta(store, id); saved.UpgradeLevels[upsidedown.UpgradeMaxHealth] != 1 {
+		t.Fatal("upgrade not saved")
+	}
+}
+
+func TestUpsideDownBuyOverMax(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	meta := upsidedown.NewPlayerMeta()
+	meta.UpgradeLevels[upsidedown.UpgradeMaxHealth] = upsidedown.Upgrades[upsidedown.UpgradeMaxHealth].MaxLevel
+	meta.EmberShards = 100000
+	giveShards(t, store, id, meta)
+
+	code, out := buy(t, store, id, `{"type":"max_health"}`)
+	if code != http.StatusBadRequest || out["error"] != upsidedown.RefuseMaxLevel || out["emberShards"] != 100000.0 {
+		t.Fatalf("code %d body %v", code, out)
+	}
+}
+
+func TestUpsideDownBuyUnaffordable(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	code, out := buy(t, store, id, `{"class":"scout"}`)
+	if code != http.StatusBadRequest || out["error"] != upsidedown.RefuseNoShards {
+		t.Fatalf("code %d body %v", code, out)
+	}
+	if upsidedown.LoadPlayerMeta(store, id).UnlockedClasses[upsidedown.ClassScout] {
+		t.Fatal("class unlocked without shards")
+	}
+}
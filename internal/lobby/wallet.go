@@ -0,0 +1,42 @@
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"main/internal/auth"
+	"main/internal/data"
+)
+
+// NewWalletHandler returns the caller's balances and recent coin ledger.
+// GET /wallet?limit=50
+func NewWalletHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.UserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		u, ok := store.GetUser(userID)
+		if !ok {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		entries, err := store.GetCoinLedger(userID, limit)
+		if err != nil {
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"coins":   u.Coins,
+			"gems":    u.Gems,
+			"entries": entries,
+		})
+	}
+}
@@ -2,13 +2,15 @@ package party
 
 import (
 	"encoding/json"
-	"fmt"
+	"main/internal/auth"
 	"main/internal/data"
 	"math/rand"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 )
@@ -19,52 +21,48 @@ const (
 	RoundDuration = 30
 	VoteDuration  = 15
 	TotalRounds   = 3
+
+	ReactCooldown = 500 * time.Millisecond // Min gap between a player's reactions
+	MaxEmojiBytes = 16                     // Enough for any ZWJ sequence we care about
+
+	ByeBonus = 150 // For an answer left without an opponent
+)
+
+// Limits on what the host may pick. Times are in seconds.
+const (
+	MinRounds     = 1
+	MaxRounds     = 8
+	MinAnswerTime = 10
+	MaxAnswerTime = 120
+	MinVoteTime   = 5
+	MaxVoteTime   = 60
 )
 
-// LocalizedPrompts provides prompts in all supported languages
-var LocalizedPrompts = map[string][]string{
-	"en": {
-		"The worst thing to say at a funeral.",
-		"A rejected crayon color name.",
-		"What actually killed the dinosaurs?",
-		"A bad icebreaker for a first date.",
-		"The most useless superhero: ___-Man.",
-		"What you should never say to a cop?",
-		"The best way to break up with someone.",
-		"The inscription on your tombstone.",
-		"The reason you got fired from your dream job.",
-	},
-	"ua": {
-		"Найгірше, що можна сказати на похороні.",
-		"Відхилена назва для нового кольору олівця.",
-		"Що насправді вбило динозаврів?",
-		"Поганий спосіб почати перше побачення.",
-		"Найбільш непотрібний супергерой: Людина-...",
-		"Що не варто говорити поліцейському?",
-		"Найкращий спосіб розлучитися з дівчиною.",
-		"Напис на твоєму надгробку.",
-		"Причина, через яку тебе звільнили з роботи мрії.",
-	},
-	"ru": {
-		"Самое худшее, что можно сказать на похоронах.",
-		"Отвергнутое название для нового цвета карандаша.",
-		"Что на самом деле убило динозавров?",
-		"Плохой ледокол для первого свидания.",
-		"Самый бесполезный супергерой: Человек-...",
-		"Что нельзя говорить полицейскому?",
-		"Лучший способ расстаться с девушкой.",
-		"Надпись на твоем надгробии.",
-		"Причина, по которой тебя уволили с работы мечты.",
-	},
+// Config is the host's settings for one game.
+type Config struct {
+	Rounds     int `json:"rounds"`
+	AnswerTime int `json:"answerTime"`
+	VoteTime   int `json:"voteTime"`
 }
 
-// getPrompt returns a random prompt in the appropriate language
-func getPrompt(lang string) string {
-	prompts, ok := LocalizedPrompts[lang]
-	if !ok {
-		prompts = LocalizedPrompts["en"] // Fallback to English
+// DefaultConfig is used for anything the host leaves out.
+var DefaultConfig = Config{Rounds: TotalRounds, AnswerTime: RoundDuration, VoteTime: VoteDuration}
+
+// clamped fills zero fields from DefaultConfig and pulls the rest into range.
+func (c Config) clamped() Config {
+	if c.Rounds == 0 {
+		c.Rounds = DefaultConfig.Rounds
+	}
+	if c.AnswerTime == 0 {
+		c.AnswerTime = DefaultConfig.AnswerTime
+	}
+	if c.VoteTime == 0 {
+		c.VoteTime = DefaultConfig.VoteTime
 	}
-	return prompts[rand.Intn(len(prompts))]
+	c.Rounds = min(max(c.Rounds, MinRounds), MaxRounds)
+	c.AnswerTime = min(max(c.AnswerTime, MinAnswerTime), MaxAnswerTime)
+	c.VoteTime = min(max(c.VoteTime, MinVoteTime), MaxVoteTime)
+	return c
 }
 
 type Player struct {
@@ -76,6 +74,13 @@ type Player struct {
 	Send     chan []byte
 	Answer   string
 	Voted    bool
+	Lang     string // Prompt language asked for on connect
+	joined   int    // Join order, for picking the next host
+
+	active     bool // Answered or voted this round
+	idleRounds int  // Rounds in a row with neither
+
+	lastReact time.Time
 }
 
 type Game struct {
@@ -90,9 +95,20 @@ type Game struct {
 	round         int
 	timer         int
 	currentPrompt string
+	answersLocked bool // Everyone answered and the short buffer is running
+	config        Config
+	hostID        string // Player who may start the game; the earliest still here
+	joinSeq       int
+
+	prompts     map[string][]string   // Packs by language
+	lang        string                // This game's language, picked at start
+	usedPrompts map[string]bool       // Already played this game
+	banned      map[string]bool       // Words masked out of answers, lower case
+	dropped     map[string]heldPlayer // Signed-in players waiting to reconnect, by ID
 
 	// Voting Logic
 	answers    []*Player // List of players who answered
+	bye        *Player   // Odd one out this round, scored without a vote
 	matchIndex int       // Current pair index being voted on
 	matchA     *Player
 	matchB     *Player
@@ -102,17 +118,34 @@ type Game struct {
 
 func NewGame(store *data.Store) *Game {
 	g := &Game{
-		store:      store,
-		players:    make(map[string]*Player),
-		register:   make(chan *Player),
-		unregister: make(chan *Player),
-		broadcast:  make(chan []byte),
-		state:      "LOBBY",
+		store:       store,
+		players:     make(map[string]*Player),
+		register:    make(chan *Player),
+		unregister:  make(chan *Player),
+		broadcast:   make(chan []byte),
+		state:       "LOBBY",
+		config:      DefaultConfig,
+		prompts:     LocalizedPrompts,
+		lang:        DefaultPromptLang,
+		usedPrompts: map[string]bool{},
+		dropped:     map[string]heldPlayer{},
 	}
 	go g.run()
 	return g
 }
 
+// Snapshot reports the room's head count for the lobby. Joining is only
+// possible while it's still in LOBBY.
+func (g *Game) Snapshot() data.LiveStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return data.LiveStatus{
+		Players: len(g.players),
+		InMatch: g.state != "LOBBY",
+		State:   g.state,
+	}
+}
+
 func (g *Game) run() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -121,12 +154,33 @@ func (g *Game) run() {
 		select {
 		case p := <-g.register:
 			g.mu.Lock()
-			// Only allow join in Lobby and if space available
-			if g.state != "LOBBY" || len(g.players) >= MaxPlayers {
+			if old, ok := g.dropped[p.ID]; ok {
+				// Back within the grace period: pick up where they left off
+				delete(g.dropped, p.ID)
+				g.reattach(p, old.player)
+				g.sendTo(p, map[string]interface{}{"type": "welcome", "id": p.ID, "rejoined": true})
+				g.mu.Unlock()
+				g.broadcastState()
+			} else if old, ok := g.players[p.ID]; ok {
+				// Same account on a new connection (a reload, usually): the new
+				// one takes over and the old one is let go
+				close(old.Send)
+				g.reattach(p, old)
+				g.sendTo(p, map[string]interface{}{"type": "welcome", "id": p.ID, "rejoined": true})
+				g.mu.Unlock()
+				g.broadcastState()
+			} else if g.state != "LOBBY" || len(g.players) >= MaxPlayers {
+				// Otherwise only allow join in Lobby and if space available
 				g.mu.Unlock()
 				p.Conn.Close()
 			} else {
+				g.joinSeq++
+				p.joined = g.joinSeq
 				g.players[p.ID] = p
+				if g.hostID == "" {
+					g.hostID = p.ID
+				}
+				g.sendTo(p, map[string]interface{}{"type": "welcome", "id": p.ID})
 				g.mu.Unlock()
 				// Broadcast state immediately so new player sees themselves
 				g.broadcastState()
@@ -134,11 +188,22 @@ func (g *Game) run() {
 
 		case p := <-g.unregister:
 			g.mu.Lock()
-			if _, ok := g.players[p.ID]; ok {
+			if g.players[p.ID] == p {
 				delete(g.players, p.ID)
 				close(p.Send)
-				// If game is running and players drop below min, reset
-				if len(g.players) < MinPlayers && g.state != "LOBBY" {
+				if g.gameLive() {
+					g.hold(p)
+				}
+				if g.hostID == p.ID {
+					g.promoteHost()
+				}
+				// Nobody left to vote on in this match, move straight on
+				if g.state == "VOTING" && !g.present(g.matchA) && !g.present(g.matchB) {
+					g.nextMatch()
+				}
+				// If game is running and players drop below min with nobody
+				// due back, reset. Held seats get their grace period first.
+				if len(g.players) < MinPlayers && len(g.dropped) == 0 && g.state != "LOBBY" {
 					g.mu.Unlock() // Unlock before reset
 					g.resetGame()
 				} else {
@@ -157,6 +222,9 @@ func (g *Game) run() {
 				default:
 					close(p.Send)
 					delete(g.players, p.ID)
+					if g.hostID == p.ID {
+						g.promoteHost()
+					}
 				}
 			}
 			g.mu.Unlock()
@@ -175,93 +243,112 @@ func (g *Game) tick() {
 			g.timer--
 		}
 		if g.timer == 0 {
-			g.mu.Unlock() // Unlock before nextPhase
+			// Same lock as HandleMsg, so an answer racing the deadline
+			// lands either before the phase change or not at all
 			g.nextPhase()
-			return
 		}
 	}
+	g.expireHeld()
+	if len(g.players) < MinPlayers && len(g.dropped) == 0 && g.state != "LOBBY" {
+		g.mu.Unlock()
+		g.resetGame()
+		return
+	}
+	running := g.state != "LOBBY"
 	g.mu.Unlock()
 
 	// Broadcast timer updates every second if game is running
-	if g.state != "LOBBY" {
+	if running {
 		g.broadcastState()
 	}
 }
 
+// nextPhase advances the state machine. Caller holds g.mu.
 func (g *Game) nextPhase() {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
 	switch g.state {
 	case "INPUT":
+		g.fillMissingAnswers()
 		g.state = "VOTING"
 		g.startVotingPhase()
 	case "VOTING":
 		g.resolveVote()
 	case "RESULT":
+		g.countIdle()
 		g.round++
-		if g.round > TotalRounds {
+		if g.round > g.config.Rounds {
 			g.endGame()
 		} else {
 			g.startRound()
 		}
 	}
-	// State change needs broadcast, handled by next tick or manual call?
-	// Better call it here to be snappy.
-	// We unlock inside broadcastState helper, so we release lock first? No.
-	// Let's release lock then broadcast.
 }
 
 func (g *Game) startRound() {
 	g.state = "INPUT"
-	g.timer = RoundDuration
-	// TODO: Could detect player language preference from first player
-	// For now, default to Russian to match original behavior
-	g.currentPrompt = getPrompt("ru")
-	for _, p := range g.players {
+	g.timer = g.config.AnswerTime
+	g.currentPrompt = g.pickPrompt()
+	g.answersLocked = false
+	g.bye = nil
+	for _, p := range g.seated() {
 		p.Answer = ""
 		p.Voted = false
+		if g.round == 1 {
+			p.active, p.idleRounds = false, 0
+		}
 	}
 }
 
 func (g *Game) startVotingPhase() {
 	g.answers = make([]*Player, 0)
-	for _, p := range g.players {
+	for _, p := range g.seated() {
 		if p.Answer != "" {
 			g.answers = append(g.answers, p)
 		}
 	}
 
-	// If less than 2 answers, skip to results
-	if len(g.answers) < 2 {
-		g.state = "RESULT"
-		g.timer = 10
-		return
-	}
-
 	rand.Shuffle(len(g.answers), func(i, j int) {
 		g.answers[i], g.answers[j] = g.answers[j], g.answers[i]
 	})
 
-	// Handle odd number of answers: give last player a "bye" with participation points
+	// The odd answer out, a lone one included, gets a bye: participation
+	// points and no vote
 	if len(g.answers)%2 == 1 {
-		byePlayer := g.answers[len(g.answers)-1]
-		byePlayer.Score += 150                   // Bye bonus - they participated but don't get voted on
-		g.answers = g.answers[:len(g.answers)-1] // Remove from voting pool
+		g.bye = g.answers[len(g.answers)-1]
+		g.bye.Score += ByeBonus
+		g.answers = g.answers[:len(g.answers)-1]
+	}
+
+	// Nothing left to vote on, skip to results
+	if len(g.answers) < 2 {
+		g.state = "RESULT"
+		g.timer = 10
+		return
 	}
 
 	g.matchIndex = 0
 	g.nextMatch()
 }
 
+// present reports whether p is still connected. matchA/matchB and answers
+// keep pointers to players who may have left mid-round.
+func (g *Game) present(p *Player) bool {
+	return p != nil && g.players[p.ID] == p
+}
+
 func (g *Game) nextMatch() {
+	// Skip pairs where both contestants have already left
+	for g.matchIndex+1 < len(g.answers) &&
+		!g.present(g.answers[g.matchIndex]) && !g.present(g.answers[g.matchIndex+1]) {
+		g.matchIndex += 2
+	}
+
 	if g.matchIndex+1 < len(g.answers) {
 		g.state = "VOTING"
 		g.matchA = g.answers[g.matchIndex]
 		g.matchB = g.answers[g.matchIndex+1]
 		g.votesA = 0
 		g.votesB = 0
-		g.timer = VoteDuration
+		g.timer = g.config.VoteTime
 		g.matchIndex += 2
 
 		for _, p := range g.players {
@@ -283,10 +370,12 @@ func (g *Game) resolveVote() {
 		pointsB += 250
 	}
 
-	if g.matchA != nil {
+	// Votes still decide the match, but a contestant who disconnected
+	// forfeits their points; the one who stayed keeps theirs.
+	if g.present(g.matchA) {
 		g.matchA.Score += pointsA
 	}
-	if g.matchB != nil {
+	if g.present(g.matchB) {
 		g.matchB.Score += pointsB
 	}
 
@@ -297,10 +386,8 @@ func (g *Game) endGame() {
 	g.state = "GAME_OVER"
 	g.timer = 0
 
-	ranking := make([]*Player, 0, len(g.players))
-	for _, p := range g.players {
-		ranking = append(ranking, p)
-	}
+	// Players still inside their reconnect grace are ranked and paid too
+	ranking := g.seated()
 	sort.Slice(ranking, func(i, j int) bool {
 		return ranking[i].Score > ranking[j].Score
 	})
@@ -308,7 +395,8 @@ func (g *Game) endGame() {
 	playerCount := len(ranking)
 
 	for rank, p := range ranking {
-		if p.UserID == "guest" || p.UserID == "" {
+		// Nothing for sitting the whole game out
+		if g.idleAllGame(p) {
 			continue
 		}
 
@@ -340,8 +428,9 @@ func (g *Game) endGame() {
 			}
 		}
 
-		g.store.ProcessGameResult(p.UserID, trophies, coins, exp)
+		g.store.ProcessGameResult(p.UserID, trophies, coins, exp, data.ReasonPartyGame)
 	}
+	g.kickIdle()
 }
 
 func (g *Game) resetGame() {
@@ -354,6 +443,7 @@ func (g *Game) resetGame() {
 	g.state = "LOBBY"
 	g.round = 0
 	g.timer = 0
+	g.dropped = map[string]heldPlayer{}
 	for _, p := range g.players {
 		p.Score = 0
 		p.Answer = ""
@@ -387,12 +477,22 @@ func (g *Game) broadcastState() {
 		"round":   g.round,
 		"players": pList,
 		"prompt":  g.currentPrompt,
+		"lang":    g.lang,
+		"host":    g.hostID,
+		"config":  g.config,
+		"locked":  g.answersLocked,
+	}
+
+	if g.bye != nil && (g.state == "VOTING" || g.state == "RESULT") {
+		state["bye"] = map[string]interface{}{
+			"id": g.bye.ID, "name": g.bye.Nickname, "text": g.bye.Answer, "bonus": ByeBonus,
+		}
 	}
 
 	if g.state == "VOTING" && g.matchA != nil && g.matchB != nil {
 		state["match"] = map[string]interface{}{
-			"a_id": g.matchA.ID, "a_text": g.matchA.Answer,
-			"b_id": g.matchB.ID, "b_text": g.matchB.Answer,
+			"a_id": g.matchA.ID, "a_text": g.matchA.Answer, "a_left": !g.present(g.matchA),
+			"b_id": g.matchB.ID, "b_text": g.matchB.Answer, "b_left": !g.present(g.matchB),
 		}
 	}
 
@@ -406,9 +506,11 @@ func (g *Game) broadcastState() {
 
 func (g *Game) HandleMsg(p *Player, msg []byte) {
 	var input struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-		Vote string `json:"vote"` // "A" or "B"
+		Type   string  `json:"type"`
+		Text   string  `json:"text"`
+		Vote   string  `json:"vote"` // "A" or "B"
+		Emoji  string  `json:"emoji"`
+		Config *Config `json:"config"` // Host's settings, with "start"
 	}
 	if err := json.Unmarshal(msg, &input); err != nil {
 		return
@@ -417,16 +519,36 @@ func (g *Game) HandleMsg(p *Player, msg []byte) {
 	g.mu.Lock()
 
 	// Start Game Logic
+	if input.Type == "start" && p.ID != g.hostID {
+		g.sendTo(p, map[string]interface{}{"type": "start_rejected", "reason": "not_host"})
+		g.mu.Unlock()
+		return
+	}
 	if input.Type == "start" && g.state == "LOBBY" && len(g.players) >= MinPlayers {
+		g.config = DefaultConfig
+		if input.Config != nil {
+			g.config = input.Config.clamped()
+		}
 		g.round = 1
+		g.lang = g.gameLang(p)
+		g.usedPrompts = map[string]bool{}
 		g.startRound()
 		g.mu.Unlock()
 		g.broadcastState()
 		return
 	}
 
-	if input.Type == "answer" && g.state == "INPUT" {
-		p.Answer = input.Text
+	if input.Type == "answer" {
+		// Answers can be edited until everyone's in and the buffer starts.
+		// After that, or once the round has moved on, they're refused.
+		text := strings.TrimSpace(input.Text)
+		if reason := g.answerRefusal(text); reason != "" {
+			g.sendTo(p, map[string]interface{}{"type": "answer_rejected", "reason": reason})
+			g.mu.Unlock()
+			return
+		}
+		p.Answer = maskBanned(text, g.banned)
+		p.active = true
 
 		// Check if everyone answered
 		allAnswered := true
@@ -437,8 +559,12 @@ func (g *Game) HandleMsg(p *Player, msg []byte) {
 			}
 		}
 		if allAnswered {
-			g.timer = 3 // Short buffer
+			g.answersLocked = true
+			if g.timer > 3 {
+				g.timer = 3 // Short buffer, never an extension
+			}
 		}
+		g.sendTo(p, map[string]interface{}{"type": "answer_accepted", "locked": g.answersLocked, "text": p.Answer})
 		g.mu.Unlock()
 		g.broadcastState()
 		return
@@ -452,36 +578,98 @@ func (g *Game) HandleMsg(p *Player, msg []byte) {
 			g.votesB++
 		}
 		p.Voted = true
+		p.active = true
+	}
+
+	// Reactions are fire-and-forget, only while a match is on screen
+	if input.Type == "react" && g.state == "VOTING" {
+		emoji := strings.TrimSpace(input.Emoji)
+		if !isReaction(emoji) || time.Since(p.lastReact) < ReactCooldown {
+			g.mu.Unlock()
+			return
+		}
+		p.lastReact = time.Now()
+		g.mu.Unlock()
+
+		out, _ := json.Marshal(map[string]interface{}{
+			"type":  "react",
+			"emoji": emoji,
+			"from":  p.ID,
+		})
+		go func() {
+			g.broadcast <- out
+		}()
+		return
 	}
 	g.mu.Unlock()
 }
 
+// promoteHost hands the host role to whoever has been here longest, or
+// nobody when the room is empty. Caller holds g.mu.
+func (g *Game) promoteHost() {
+	g.hostID = ""
+	first := 0
+	for _, p := range g.players {
+		if g.hostID == "" || p.joined < first {
+			g.hostID, first = p.ID, p.joined
+		}
+	}
+}
+
+// answerRefusal says why an answer can't be taken right now, or "" if it can.
+// Caller holds g.mu.
+func (g *Game) answerRefusal(text string) string {
+	switch {
+	case g.state != "INPUT" || g.timer <= 0:
+		return "round_over"
+	case g.answersLocked:
+		return "locked"
+	case text == "":
+		return "empty"
+	case utf8.RuneCountInString(text) > MaxAnswerRunes:
+		return "too_long"
+	}
+	return ""
+}
+
+// sendTo queues v for p alone. Caller holds g.mu; a player still in
+// g.players always has an open Send channel.
+func (g *Game) sendTo(p *Player, v interface{}) {
+	if g.players[p.ID] != p {
+		return
+	}
+	msg, _ := json.Marshal(v)
+	select {
+	case p.Send <- msg:
+	default:
+	}
+}
+
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 func HandleWS(g *Game, w http.ResponseWriter, r *http.Request, store *data.Store) {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
+		return
+	}
+	u, ok := store.GetUser(userID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	userID := r.URL.Query().Get("userID")
-	nick := "Guest"
-	if userID != "" {
-		if u, ok := store.GetUser(userID); ok {
-			nick = u.Nickname
-		}
-	}
-
-	// Ensure unique ID for every connection
-	pID := fmt.Sprintf("p_%d_%d", time.Now().UnixNano(), rand.Intn(1000))
-
 	p := &Player{
-		ID:     pID,
-		UserID: userID, Nickname: nick,
+		ID:     playerID(userID),
+		UserID: userID, Nickname: u.Nickname, Lang: promptLang(r.URL.Query().Get("lang")),
 		Conn: conn, Send: make(chan []byte, 256),
 	}
 
 	g.register <- p
+	store.SetActivity(userID, data.ActivityParty)
 
 	go func() {
 		for msg := range p.Send {
@@ -491,7 +679,11 @@ func HandleWS(g *Game, w http.ResponseWriter, r *http.Request, store *data.Store
 	}()
 
 	go func() {
-		defer func() { g.unregister <- p; conn.Close() }()
+		defer func() {
+			g.unregister <- p
+			store.ClearActivity(userID, data.ActivityParty)
+			conn.Close()
+		}()
 		for {
 			_, msg, err := conn.ReadMessage()
 			if err != nil {
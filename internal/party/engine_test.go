@@ -0,0 +1,170 @@
+package party
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lobby is a party game in its lobby with the given players, the first the
+// host. Nothing runs it, so broadcasts just queue up.
+func lobby(ids ...string) *Game {
+	g := &Game{
+		players:     map[string]*Player{},
+		broadcast:   make(chan []byte, 64),
+		state:       "LOBBY",
+		config:      DefaultConfig,
+		prompts:     LocalizedPrompts,
+		lang:        DefaultPromptLang,
+		usedPrompts: map[string]bool{},
+		dropped:     map[string]heldPlayer{},
+	}
+	for i, id := range ids {
+		g.players[id] = &Player{ID: id, Send: make(chan []byte, 16), joined: i + 1}
+	}
+	g.hostID = ids[0]
+	return g
+}
+
+func TestOnlyHostStarts(t *testing.T) {
+	g := lobby("host", "guest1", "guest2")
+	g.HandleMsg(g.players["guest1"], []byte(`{"type":"start","config":{"rounds":1}}`))
+	if g.state != "LOBBY" {
+		t.Fatalf("a non-host start moved the game to %s", g.state)
+	}
+	if msg := string(<-g.players["guest1"].Send); !strings.Contains(msg, "not_host") {
+		t.Errorf("non-host was told %s", msg)
+	}
+
+	g.HandleMsg(g.players["host"], []byte(`{"type":"start","config":{"rounds":99,"answerTime":1}}`))
+	if g.state != "INPUT" {
+		t.Fatalf("host start left the game in %s", g.state)
+	}
+	if g.config.Rounds != MaxRounds || g.config.AnswerTime != MinAnswerTime || g.config.VoteTime != DefaultConfig.VoteTime {
+		t.Errorf("config %+v, want clamped to %d rounds, %ds answers and the default vote time", g.config, MaxRounds, MinAnswerTime)
+	}
+}
+
+func TestHostPromotedOnLeave(t *testing.T) {
+	g := lobby("host", "second", "third")
+	delete(g.players, "host")
+	g.promoteHost()
+	if g.hostID != "second" {
+		t.Fatalf("host passed to %q, want the next to join", g.hostID)
+	}
+	g.players = map[string]*Player{}
+	g.promoteHost()
+	if g.hostID != "" {
+		t.Errorf("empty room kept host %q", g.hostID)
+	}
+}
+
+// answered starts voting on a round where the named players answered.
+func answered(g *Game, ids ...string) {
+	for _, id := range ids {
+		g.players[id].Answer = "answer from " + id
+	}
+	g.startVotingPhase()
+}
+
+func TestThreeAnswersGiveABye(t *testing.T) {
+	g := lobby("a", "b", "c")
+	answered(g, "a", "b", "c")
+
+	if g.state != "VOTING" || g.bye == nil {
+		t.Fatalf("state %s, bye %v", g.state, g.bye)
+	}
+	if g.bye == g.matchA || g.bye == g.matchB || g.matchA == g.matchB {
+		t.Fatalf("bye %s plays in %s vs %s", g.bye.ID, g.matchA.ID, g.matchB.ID)
+	}
+	if g.bye.Score != ByeBonus {
+		t.Errorf("bye scored %d, want %d", g.bye.Score, ByeBonus)
+	}
+
+	g.resolveVote()
+	g.nextMatch()
+	if g.state != "RESULT" {
+		t.Errorf("state %s after the only match, want RESULT", g.state)
+	}
+}
+
+func TestLoneAnswerSkipsVoting(t *testing.T) {
+	g := lobby("a", "b")
+	answered(g, "a")
+	if g.state != "RESULT" {
+		t.Fatalf("state %s with one answer, want RESULT", g.state)
+	}
+	if g.bye != g.players["a"] || g.players["a"].Score != ByeBonus {
+		t.Errorf("lone answer: bye %v, score %d", g.bye, g.players["a"].Score)
+	}
+}
+
+func TestAnswerRacingDeadline(t *testing.T) {
+	accepted, rejected := 0, 0
+	for i := 0; i < 200; i++ {
+		g := lobby("a", "b", "c")
+		g.round = 1
+		g.startRound()
+		g.timer = 1 // The next tick ends the round
+		g.players["a"].Answer, g.players["b"].Answer = "from a", "from b"
+		late := g.players["c"]
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			g.HandleMsg(late, []byte(`{"type":"answer","text":"just in time"}`))
+		}()
+		go func() {
+			defer wg.Done()
+			g.tick()
+		}()
+		wg.Wait()
+
+		var reply struct{ Type, Reason string }
+		json.Unmarshal(<-late.Send, &reply)
+		g.mu.Lock()
+		state, answer := g.state, late.Answer
+		g.mu.Unlock()
+
+		if state == "INPUT" {
+			t.Fatalf("run %d: still taking answers after the deadline", i)
+		}
+		// Either counted in full or cleanly refused, never half of each
+		switch {
+		case reply.Type == "answer_accepted" && answer == "just in time":
+			accepted++
+		case reply.Type == "answer_rejected" && reply.Reason == "round_over" && answer == NoAnswer:
+			rejected++
+		default:
+			t.Fatalf("run %d: told %s %q, answer went in as %q", i, reply.Type, reply.Reason, answer)
+		}
+	}
+	t.Logf("%d answers beat the deadline, %d missed it", accepted, rejected)
+}
+
+func TestContestantLeavesMidVote(t *testing.T) {
+	g := lobby("a", "b", "c", "d")
+	answered(g, "a", "b")
+	left, stayed := g.matchA, g.matchB
+
+	// What unregister does with a contestant mid-game
+	delete(g.players, left.ID)
+	g.hold(left)
+
+	g.HandleMsg(g.players["c"], []byte(`{"type":"vote","vote":"A"}`))
+	g.HandleMsg(g.players["d"], []byte(`{"type":"vote","vote":"B"}`))
+	g.HandleMsg(stayed, []byte(`{"type":"vote","vote":"B"}`))
+	g.resolveVote()
+
+	if left.Score != 0 {
+		t.Errorf("the contestant who left scored %d", left.Score)
+	}
+	if want := 2*100 + 250; stayed.Score != want {
+		t.Errorf("the contestant who stayed scored %d, want %d", stayed.Score, want)
+	}
+	if g.state != "RESULT" {
+		t.Errorf("state %s after the only match, want RESULT", g.state)
+	}
+}
@@ -0,0 +1,101 @@
+package party
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// MaxAnswerRunes is the longest answer taken, in characters.
+const MaxAnswerRunes = 140
+
+// readBannedWords loads the word list in path, a JSON array of strings.
+// Words are matched whole and without regard to case.
+func readBannedWords(path string) (map[string]bool, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	if err := json.Unmarshal(bytes, &words); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	banned := make(map[string]bool, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			banned[w] = true
+		}
+	}
+	return banned, nil
+}
+
+// LoadBannedWords replaces the answer filter's word list with the one in
+// path. The old list stays in place if the file is missing or invalid.
+func (g *Game) LoadBannedWords(path string) error {
+	banned, err := readBannedWords(path)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.banned = banned
+	g.mu.Unlock()
+	log.Printf("[PARTY] Loaded %d banned words from %s", len(banned), path)
+	return nil
+}
+
+// maskBanned replaces every banned word in text with as many asterisks as it
+// has letters. A word is a run of letters and digits, so "ass" in "class"
+// is left alone.
+func maskBanned(text string, banned map[string]bool) string {
+	if len(banned) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if banned[strings.ToLower(string(runes[start:end]))] {
+			for i := start; i < end; i++ {
+				runes[i] = '*'
+			}
+		}
+		start = end
+	}
+	return string(runes)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isReaction reports whether s is fit to show as a vote reaction: a short
+// run of emoji and nothing else. Letters and digits are refused outright,
+// so reactions can't carry the words the answer filter masks.
+func isReaction(s string) bool {
+	if s == "" || len(s) > MaxEmojiBytes {
+		return false
+	}
+	symbol := false
+	for _, r := range s {
+		switch {
+		case r < 0x80 || isWordRune(r) || unicode.IsSpace(r):
+			return false
+		case unicode.Is(unicode.So, r):
+			symbol = true
+		case unicode.Is(unicode.Sk, r), unicode.Is(unicode.Mn, r), unicode.Is(unicode.Cf, r):
+			// Skin tones, variation selectors and joiners between symbols
+		default:
+			return false
+		}
+	}
+	return symbol
+}
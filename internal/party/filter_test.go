@@ -0,0 +1,75 @@
+package party
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaskBanned(t *testing.T) {
+	banned := map[string]bool{"ass": true, "darn": true}
+	cases := map[string]string{
+		"you ass":         "you ***",
+		"Darn it, ASS!":   "**** it, ***!",
+		"first class":     "first class",
+		"nothing to hide": "nothing to hide",
+	}
+	for in, want := range cases {
+		if got := maskBanned(in, banned); got != want {
+			t.Errorf("maskBanned(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIsReaction(t *testing.T) {
+	for _, ok := range []string{"👍", "❤️", "😂", "👍🏽", "🇺🇦", "❤️‍🔥"} {
+		if !isReaction(ok) {
+			t.Errorf("%q refused", ok)
+		}
+	}
+	for _, bad := range []string{"", "ass", "a👍", "👍1", "１", "ж", "<3", "👍 👍", "😂😂😂😂😂"} {
+		if isReaction(bad) {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestAnswerChecks(t *testing.T) {
+	g := lobby("a", "b")
+	g.state, g.timer = "INPUT", 30
+	g.banned = map[string]bool{"darn": true}
+	a := g.players["a"]
+
+	for name, text := range map[string]string{
+		"too_long": strings.Repeat("я", MaxAnswerRunes+1),
+		"empty":    " \t ",
+	} {
+		g.HandleMsg(a, []byte(fmt.Sprintf(`{"type":"answer","text":%q}`, text)))
+		if a.Answer != "" {
+			t.Fatalf("%s answer taken as %q", name, a.Answer)
+		}
+		if msg := string(<-a.Send); !strings.Contains(msg, name) {
+			t.Errorf("%s answer refused with %s", name, msg)
+		}
+	}
+
+	g.HandleMsg(a, []byte(fmt.Sprintf(`{"type":"answer","text":"  %s darn it  "}`, strings.Repeat("я", MaxAnswerRunes-12))))
+	if !strings.HasSuffix(a.Answer, " **** it") {
+		t.Errorf("answer stored as %q, want trimmed with the banned word masked", a.Answer)
+	}
+}
+
+func TestReadBannedWords(t *testing.T) {
+	banned, err := readBannedWords("../data/banned_words.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banned) == 0 {
+		t.Fatal("no banned words loaded")
+	}
+	for w := range banned {
+		if w != strings.ToLower(strings.TrimSpace(w)) {
+			t.Errorf("%q isn't normalized", w)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package party
+
+import "time"
+
+// NoAnswer is entered for a player who lets the answer timer run out, so they
+// still get a matchup and a chance at points.
+const NoAnswer = "(no answer)"
+
+// idleKickDelay gives the "kicked" message time to go out before the socket
+// is closed.
+const idleKickDelay = time.Second
+
+// fillMissingAnswers enters NoAnswer for every connected player who hasn't
+// answered. Players held for a reconnect are left out. Caller holds g.mu.
+func (g *Game) fillMissingAnswers() {
+	for _, p := range g.players {
+		if p.Answer == "" {
+			p.Answer = NoAnswer
+		}
+	}
+}
+
+// countIdle closes out a round's activity: a player who neither answered nor
+// voted in it adds an idle round, anyone else starts over. Caller holds g.mu.
+func (g *Game) countIdle() {
+	for _, p := range g.seated() {
+		if p.active {
+			p.idleRounds = 0
+		} else {
+			p.idleRounds++
+		}
+		p.active = false
+	}
+}
+
+// idleAllGame reports whether p sat out every round of the game.
+// Caller holds g.mu.
+func (g *Game) idleAllGame(p *Player) bool {
+	return p.idleRounds >= g.config.Rounds
+}
+
+// kickIdle disconnects everyone who sat out the whole game. Caller holds g.mu.
+func (g *Game) kickIdle() {
+	for _, p := range g.players {
+		if !g.idleAllGame(p) {
+			continue
+		}
+		g.sendTo(p, map[string]interface{}{"type": "kicked", "reason": "afk"})
+		conn := p.Conn
+		time.AfterFunc(idleKickDelay, func() { conn.Close() })
+	}
+}
@@ -0,0 +1,37 @@
+package party
+
+import "testing"
+
+func TestSilentPlayerStillPlays(t *testing.T) {
+	g := lobby("talker", "silent")
+	g.state = "INPUT"
+	talker, silent := g.players["talker"], g.players["silent"]
+	talker.Answer, talker.active = "something", true
+
+	g.nextPhase()
+	if g.state != "VOTING" || silent.Answer != NoAnswer {
+		t.Fatalf("state %s, silent answer %q", g.state, silent.Answer)
+	}
+	if (g.matchA != silent && g.matchB != silent) || (g.matchA != talker && g.matchB != talker) {
+		t.Fatalf("match is %s vs %s, want the silent player's placeholder in it", g.matchA.ID, g.matchB.ID)
+	}
+
+	// Nobody votes: the match still ends, as a tie
+	g.nextPhase()
+	if g.state != "RESULT" || talker.Score != 0 || silent.Score != 0 {
+		t.Fatalf("after an unvoted match: state %s, scores %d and %d", g.state, talker.Score, silent.Score)
+	}
+}
+
+func TestIdleAllGame(t *testing.T) {
+	g := lobby("talker", "silent")
+	g.config.Rounds = 2
+	talker, silent := g.players["talker"], g.players["silent"]
+	for round := 0; round < 2; round++ {
+		talker.active = true
+		g.countIdle()
+	}
+	if g.idleAllGame(talker) || !g.idleAllGame(silent) {
+		t.Fatalf("idle rounds %d and %d over 2 rounds", talker.idleRounds, silent.idleRounds)
+	}
+}
@@ -0,0 +1,140 @@
+package party
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// DefaultPromptLang is played when nobody asked for a language that has a
+// pack.
+const DefaultPromptLang = "ru"
+
+// LocalizedPrompts is the built-in prompt pack per language, used until
+// LoadPrompts succeeds.
+var LocalizedPrompts = map[string][]string{
+	"en": {
+		"The worst thing to say at a funeral.",
+		"A rejected crayon color name.",
+		"What actually killed the dinosaurs?",
+		"A bad icebreaker for a first date.",
+		"The most useless superhero: ___-Man.",
+		"What you should never say to a cop?",
+		"The best way to break up with someone.",
+		"The inscription on your tombstone.",
+		"The reason you got fired from your dream job.",
+	},
+	"ua": {
+		"Найгірше, що можна сказати на похороні.",
+		"Відхилена назва для нового кольору олівця.",
+		"Що насправді вбило динозаврів?",
+		"Поганий спосіб почати перше побачення.",
+		"Найбільш непотрібний супергерой: Людина-...",
+		"Що не варто говорити поліцейському?",
+		"Найкращий спосіб розлучитися з дівчиною.",
+		"Напис на твоєму надгробку.",
+		"Причина, через яку тебе звільнили з роботи мрії.",
+	},
+	"ru": {
+		"Самое худшее, что можно сказать на похоронах.",
+		"Отвергнутое название для нового цвета карандаша.",
+		"Что на самом деле убило динозавров?",
+		"Плохой ледокол для первого свидания.",
+		"Самый бесполезный супергерой: Человек-...",
+		"Что нельзя говорить полицейскому?",
+		"Лучший способ расстаться с девушкой.",
+		"Надпись на твоем надгробии.",
+		"Причина, по которой тебя уволили с работы мечты.",
+	},
+}
+
+// readPrompts loads and checks the prompt packs in path.
+func readPrompts(path string) (map[string][]string, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var packs map[string][]string
+	if err := json.Unmarshal(bytes, &packs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(packs[DefaultPromptLang]) == 0 {
+		return nil, fmt.Errorf("%s: no %q prompts to fall back on", path, DefaultPromptLang)
+	}
+	for lang, prompts := range packs {
+		if len(prompts) == 0 {
+			return nil, fmt.Errorf("%s: %q has no prompts", path, lang)
+		}
+	}
+	return packs, nil
+}
+
+// LoadPrompts replaces the prompt packs with the ones in path, keyed by
+// language. The old packs stay in place if the file is missing or invalid.
+func (g *Game) LoadPrompts(path string) error {
+	packs, err := readPrompts(path)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.prompts = packs
+	g.mu.Unlock()
+	log.Printf("[PARTY] Loaded prompts in %d languages from %s", len(packs), path)
+	return nil
+}
+
+// gameLang is the language most players asked for that has a pack, a tie
+// going to starter's. Caller holds g.mu.
+func (g *Game) gameLang(starter *Player) string {
+	votes := map[string]int{}
+	for _, p := range g.players {
+		if len(g.prompts[p.Lang]) > 0 {
+			votes[p.Lang]++
+		}
+	}
+	best := DefaultPromptLang
+	if votes[starter.Lang] > 0 {
+		best = starter.Lang
+	}
+	for lang, n := range votes {
+		if n > votes[best] {
+			best = lang
+		}
+	}
+	return best
+}
+
+// pickPrompt draws a prompt this game hasn't had yet from its language's
+// pack. A pack smaller than the game starts over once it runs out.
+// Caller holds g.mu.
+func (g *Game) pickPrompt() string {
+	pack := g.prompts[g.lang]
+	if len(pack) == 0 {
+		pack = g.prompts[DefaultPromptLang]
+	}
+	fresh := make([]string, 0, len(pack))
+	for _, prompt := range pack {
+		if !g.usedPrompts[prompt] {
+			fresh = append(fresh, prompt)
+		}
+	}
+	if len(fresh) == 0 {
+		g.usedPrompts = map[string]bool{}
+		fresh = pack
+	}
+	prompt := fresh[rand.Intn(len(fresh))]
+	g.usedPrompts[prompt] = true
+	return prompt
+}
+
+// promptLang tidies a ?lang= value; the lobby calls Ukrainian "ua".
+func promptLang(raw string) string {
+	lang := strings.ToLower(strings.TrimSpace(raw))
+	if lang == "uk" {
+		return "ua"
+	}
+	return lang
+}
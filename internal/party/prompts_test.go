@@ -0,0 +1,50 @@
+package party
+
+import "testing"
+
+func promptGame(packs map[string][]string, langs ...string) *Game {
+	g := &Game{players: map[string]*Player{}, prompts: packs, lang: DefaultPromptLang, usedPrompts: map[string]bool{}}
+	for i, lang := range langs {
+		id := string(rune('a' + i))
+		g.players[id] = &Player{ID: id, Lang: lang}
+	}
+	return g
+}
+
+func TestEnglishGameServesEnglish(t *testing.T) {
+	packs, err := readPrompts("../data/prompts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	russian := map[string]bool{}
+	for _, p := range packs["ru"] {
+		russian[p] = true
+	}
+
+	g := promptGame(packs, "en", "en", "ru")
+	g.lang = g.gameLang(g.players["c"])
+	if g.lang != "en" {
+		t.Fatalf("game language %q, want en", g.lang)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < len(packs["en"]); i++ {
+		prompt := g.pickPrompt()
+		if russian[prompt] {
+			t.Fatalf("English game served %q", prompt)
+		}
+		if seen[prompt] {
+			t.Fatalf("%q came up twice before the pack ran out", prompt)
+		}
+		seen[prompt] = true
+	}
+}
+
+func TestMissingLanguageFallsBack(t *testing.T) {
+	g := promptGame(LocalizedPrompts, "de", "fr")
+	if lang := g.gameLang(g.players["a"]); lang != DefaultPromptLang {
+		t.Fatalf("game language %q with no pack for anyone, want %q", lang, DefaultPromptLang)
+	}
+	if promptLang(" UK ") != "ua" {
+		t.Error("uk isn't read as Ukrainian")
+	}
+}
@@ -0,0 +1,83 @@
+package party
+
+import (
+	"log"
+	"time"
+)
+
+// ReconnectGrace is how long a player who drops mid-game keeps
+// their place. The game carries on without them meanwhile.
+const ReconnectGrace = 20 * time.Second
+
+// heldPlayer is a dropped player's place in a running game.
+type heldPlayer struct {
+	player *Player
+	at     time.Time
+}
+
+// playerID is the ID a connection plays under. It is tied to the account,
+// so reconnecting lands on the same seat.
+func playerID(userID string) string {
+	return "u_" + userID
+}
+
+// gameLive reports whether a drop now would cost a player their game.
+// Caller holds g.mu.
+func (g *Game) gameLive() bool {
+	return g.state != "LOBBY" && g.state != "GAME_OVER"
+}
+
+// hold keeps p's place after they dropped. Caller holds g.mu.
+func (g *Game) hold(p *Player) {
+	g.dropped[p.ID] = heldPlayer{player: p, at: time.Now()}
+	log.Printf("[PARTY] %s dropped, holding their seat for %s", p.Nickname, ReconnectGrace)
+}
+
+// reattach seats p in place of old, the same user's previous connection,
+// carrying over their score and round progress. Caller holds g.mu.
+func (g *Game) reattach(p, old *Player) {
+	p.Score, p.Answer, p.Voted, p.joined = old.Score, old.Answer, old.Voted, old.joined
+	p.active, p.idleRounds = old.active, old.idleRounds
+	for i, a := range g.answers {
+		if a == old {
+			g.answers[i] = p
+		}
+	}
+	if g.matchA == old {
+		g.matchA = p
+	}
+	if g.matchB == old {
+		g.matchB = p
+	}
+	if g.bye == old {
+		g.bye = p
+	}
+	g.players[p.ID] = p
+	if g.hostID == "" {
+		g.hostID = p.ID
+	}
+}
+
+// expireHeld gives up the seats held longer than ReconnectGrace. Caller
+// holds g.mu.
+func (g *Game) expireHeld() {
+	for id, h := range g.dropped {
+		if time.Since(h.at) >= ReconnectGrace {
+			delete(g.dropped, id)
+			log.Printf("[PARTY] %s did not come back, seat released", h.player.Nickname)
+		}
+	}
+}
+
+// seated is everyone with a place in the game: the connected players and
+// the ones held for a reconnect. Caller holds g.mu.
+func (g *Game) seated() []*Player {
+	all := make([]*Player, 0, len(g.players)+len(g.dropped))
+	for _, p := range g.players {
+		all = append(all, p)
+	}
+	for _, h := range g.dropped {
+		all = append(all, h.player)
+	}
+	return all
+}
@@ -0,0 +1,69 @@
+package party
+
+import (
+	"testing"
+	"time"
+)
+
+// settle waits until the run loop has finished whatever it took last, by
+// unregistering a stranger (a no-op) twice.
+func settle(g *Game) {
+	stranger := &Player{ID: "stranger"}
+	g.unregister <- stranger
+	g.unregister <- stranger
+}
+
+func connect(g *Game, userID string) *Player {
+	p := &Player{ID: playerID(userID), UserID: userID, Nickname: userID, Send: make(chan []byte, 256)}
+	g.register <- p
+	settle(g)
+	return p
+}
+
+func TestReconnectMidRoundKeepsScore(t *testing.T) {
+	g := NewGame(nil)
+	a, b := connect(g, "a"), connect(g, "b")
+	connect(g, "c")
+
+	g.mu.Lock()
+	g.state, g.timer = "INPUT", 60
+	b.Score, b.Answer, b.active = 700, "my answer", true
+	g.mu.Unlock()
+
+	g.unregister <- b
+	settle(g)
+	g.mu.Lock()
+	held := g.dropped[b.ID].player == b
+	state := g.state
+	g.mu.Unlock()
+	if !held || state != "INPUT" {
+		t.Fatalf("after the drop: seat held %v, state %s", held, state)
+	}
+
+	back := connect(g, "b")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.players[b.ID] != back || len(g.dropped) != 0 {
+		t.Fatalf("reconnect not seated: %v, %d still held", g.players[b.ID] == back, len(g.dropped))
+	}
+	if back.Score != 700 || back.Answer != "my answer" || !back.active {
+		t.Errorf("score %d answer %q active %v after reconnecting", back.Score, back.Answer, back.active)
+	}
+	if g.hostID != a.ID {
+		t.Errorf("host moved to %q", g.hostID)
+	}
+}
+
+func TestHeldSeatExpires(t *testing.T) {
+	g := lobby("a", "b")
+	g.state = "INPUT"
+	b := g.players["b"]
+	delete(g.players, "b")
+	g.hold(b)
+	g.dropped["b"] = heldPlayer{player: b, at: time.Now().Add(-ReconnectGrace)}
+
+	g.expireHeld()
+	if len(g.dropped) != 0 {
+		t.Fatal("seat still held past the grace period")
+	}
+}
@@ -6,21 +6,37 @@ import (
 	"errors"
 	"net/http"
 	"strings"
-	"time"
+	"sync"
+
+	"main/internal/auth"
 )
 
 type Service struct {
 	DB *sql.DB
+
+	mu      sync.Mutex
+	sockets map[string]map[*socket]bool // Open presence sockets by user
+	shown   map[string]string           // Status last announced, by user
+
+	listeners []Listener
 }
 
 func NewService(db *sql.DB) *Service {
-	return &Service{DB: db}
+	s := &Service{
+		DB:      db,
+		sockets: make(map[string]map[*socket]bool),
+		shown:   make(map[string]string),
+	}
+	s.listeners = []Listener{s.pushToSockets}
+	go s.watch()
+	return s
 }
 
 type pingRequest struct {
 	Status string `json:"status"` // "online" | "away" | "offline"
 }
 
+// PingHandler is the polling fallback for clients without a presence socket.
 func (s *Service) PingHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -39,32 +55,47 @@ func (s *Service) PingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status := strings.ToLower(strings.TrimSpace(req.Status))
-	switch status {
-	case "online", "away", "offline":
-	default:
-		status = "online"
-	}
-
-	_, err = s.DB.Exec(`
-		UPDATE users
-		SET status = $1,
-		    last_seen = $2,
-		    updated_at = $2
-		WHERE id = $3
-	`, status, time.Now().UTC(), userID)
-	if err != nil {
+	status := normalizeStatus(req.Status)
+	if err := s.touch(userID, status); err != nil {
 		http.Error(w, "failed to update presence", http.StatusInternalServerError)
 		return
 	}
+	// A live socket is the better source, so pings only speak for users
+	// without one
+	if !s.connected(userID) {
+		s.announce(userID, status)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// touch records userID's chosen status and that they were just seen.
+// last_seen comes from the database clock so it orders against offline_at.
+func (s *Service) touch(userID, status string) error {
+	_, err := s.DB.Exec(`
+		UPDATE users
+		SET status = $1,
+		    last_seen = NOW(),
+		    updated_at = NOW()
+		WHERE id = $2
+	`, status, userID)
+	return err
+}
+
+// normalizeStatus maps anything unknown to "online".
+func normalizeStatus(status string) string {
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch status {
+	case "online", "away", "offline":
+		return status
+	}
+	return "online"
+}
+
 func readUserID(r *http.Request) (string, error) {
-	c, err := r.Cookie("user_id")
-	if err != nil || c.Value == "" {
-		return "", errors.New("no user_id cookie")
+	userID, ok := auth.UserID(r)
+	if !ok {
+		return "", errors.New("no session")
 	}
-	return c.Value, nil
+	return userID, nil
 }
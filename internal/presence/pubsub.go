@@ -0,0 +1,73 @@
+package presence
+
+import "log"
+
+// Listener hears about every status change: userID is now status, and
+// friends are the user's accepted friends to tell.
+type Listener func(userID, status string, friends []string)
+
+// Subscribe adds fn to the listeners told about status changes. The chat hub
+// uses it to reach friends who only have a chat socket open.
+func (s *Service) Subscribe(fn Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// announce records userID's status and, if it changed since the last
+// announcement, passes it with their friends to every listener.
+func (s *Service) announce(userID, status string) {
+	s.mu.Lock()
+	prev, ok := s.shown[userID]
+	if !ok {
+		prev = "offline"
+	}
+	if status == "offline" {
+		delete(s.shown, userID) // Nothing to remember for a user who's gone
+	} else {
+		s.shown[userID] = status
+	}
+	listeners := append([]Listener(nil), s.listeners...)
+	s.mu.Unlock()
+	if prev == status {
+		return
+	}
+
+	friends, err := s.friendIDs(userID)
+	if err != nil {
+		log.Printf("[PRESENCE] Friends of %s: %v", userID, err)
+		return
+	}
+	for _, fn := range listeners {
+		fn(userID, status, friends)
+	}
+}
+
+// connected reports whether userID has a presence socket open here.
+func (s *Service) connected(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sockets[userID]) > 0
+}
+
+func (s *Service) friendIDs(userID string) ([]string, error) {
+	rows, err := s.DB.Query(`
+		SELECT CASE WHEN requester_id = $1 THEN addressee_id ELSE requester_id END
+		FROM friendships
+		WHERE status = 'accepted' AND (requester_id = $1 OR addressee_id = $1)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
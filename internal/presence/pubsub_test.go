@@ -0,0 +1,65 @@
+package presence
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/internal/data/datatest"
+)
+
+// listen opens a fake presence socket for userID on s.
+func listen(s *Service, userID string) *socket {
+	sock := &socket{userID: userID, send: make(chan []byte, 8)}
+	s.mu.Lock()
+	if s.sockets[userID] == nil {
+		s.sockets[userID] = make(map[*socket]bool)
+	}
+	s.sockets[userID][sock] = true
+	s.mu.Unlock()
+	return sock
+}
+
+func TestStatusChangeReachesFriendsOnly(t *testing.T) {
+	_, db := datatest.Store(t)
+	a, b, c := datatest.User(t, db, 0), datatest.User(t, db, 0), datatest.User(t, db, 0)
+	if _, err := db.Exec(`INSERT INTO friendships (requester_id, addressee_id, status) VALUES ($1, $2, 'accepted')`, a, b); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db)
+	friend, stranger := listen(s, b), listen(s, c)
+
+	s.announce(a, "online")
+	select {
+	case raw := <-friend.send:
+		var msg map[string]string
+		json.Unmarshal(raw, &msg)
+		if msg["type"] != "presence" || msg["user"] != a || msg["status"] != "online" {
+			t.Errorf("friend got %v", msg)
+		}
+	default:
+		t.Fatal("friend wasn't told")
+	}
+	if len(stranger.send) != 0 {
+		t.Error("a non-friend heard about it")
+	}
+
+	// Saying the same again isn't a change
+	s.announce(a, "online")
+	if len(friend.send) != 0 {
+		t.Error("unchanged status announced twice")
+	}
+}
+
+func TestSubscribersHearChanges(t *testing.T) {
+	_, db := datatest.Store(t)
+	a := datatest.User(t, db, 0)
+	s := NewService(db)
+	var heard []string
+	s.Subscribe(func(userID, status string, _ []string) { heard = append(heard, userID+" "+status) })
+
+	s.announce(a, "away")
+	s.announce(a, "offline")
+	if len(heard) != 2 || heard[0] != a+" away" || heard[1] != a+" offline" {
+		t.Fatalf("heard %v", heard)
+	}
+}
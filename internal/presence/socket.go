@@ -0,0 +1,159 @@
+package presence
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"main/internal/auth"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	HeartbeatInterval = 20 * time.Second // How often the client beats
+	HeartbeatTimeout  = 45 * time.Second // Silence after which a socket counts as away
+)
+
+// socket is one open /ws/presence connection.
+type socket struct {
+	userID   string
+	conn     *websocket.Conn
+	send     chan []byte
+	status   string    // As chosen in the last heartbeat
+	lastBeat time.Time // Zero until the first heartbeat
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+// HandleWS keeps the caller's presence live for as long as the socket is
+// open. The client sends {"type":"heartbeat","status":...} every
+// HeartbeatInterval and gets {"type":"presence","user":...,"status":...}
+// whenever a friend's status changes.
+func (s *Service) HandleWS(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	sock := &socket{userID: userID, conn: conn, send: make(chan []byte, 32)}
+	s.mu.Lock()
+	if s.sockets[userID] == nil {
+		s.sockets[userID] = make(map[*socket]bool)
+	}
+	s.sockets[userID][sock] = true
+	s.mu.Unlock()
+
+	go func() {
+		for msg := range sock.send {
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+		conn.Close()
+	}()
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var msg struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		}
+		if json.Unmarshal(raw, &msg) != nil || msg.Type != "heartbeat" {
+			continue
+		}
+		status := normalizeStatus(msg.Status)
+		s.mu.Lock()
+		sock.status, sock.lastBeat = status, time.Now()
+		s.mu.Unlock()
+		if err := s.touch(userID, status); err != nil {
+			log.Printf("[PRESENCE] Heartbeat for %s: %v", userID, err)
+		}
+		s.refresh(userID)
+	}
+	s.detach(sock)
+}
+
+// detach drops sock. Closing a user's last socket takes them offline at
+// once rather than waiting for last_seen to age out.
+func (s *Service) detach(sock *socket) {
+	s.mu.Lock()
+	delete(s.sockets[sock.userID], sock)
+	last := len(s.sockets[sock.userID]) == 0
+	if last {
+		delete(s.sockets, sock.userID)
+	}
+	close(sock.send)
+	s.mu.Unlock()
+
+	if last {
+		if _, err := s.DB.Exec(`UPDATE users SET offline_at = NOW() WHERE id = $1`, sock.userID); err != nil {
+			log.Printf("[PRESENCE] Marking %s offline: %v", sock.userID, err)
+		}
+	}
+	s.refresh(sock.userID)
+}
+
+// liveStatus is what userID's sockets say about them: the status chosen in
+// the newest heartbeat, turned to away once every socket has gone quiet.
+// With no socket that has beaten yet they're offline. Caller holds s.mu.
+func (s *Service) liveStatus(userID string, now time.Time) string {
+	status := "offline"
+	var newest time.Time
+	for sock := range s.sockets[userID] {
+		if sock.lastBeat.After(newest) {
+			newest, status = sock.lastBeat, sock.status
+		}
+	}
+	if status == "online" && now.Sub(newest) > HeartbeatTimeout {
+		status = "away"
+	}
+	return status
+}
+
+// refresh announces the status userID's sockets give them.
+func (s *Service) refresh(userID string) {
+	s.mu.Lock()
+	status := s.liveStatus(userID, time.Now())
+	s.mu.Unlock()
+	s.announce(userID, status)
+}
+
+// pushToSockets is the built-in Listener: it tells userID's friends who have
+// a presence socket open here.
+func (s *Service) pushToSockets(userID, status string, friends []string) {
+	msg, _ := json.Marshal(map[string]string{"type": "presence", "user": userID, "status": status})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range friends {
+		for sock := range s.sockets[id] {
+			select {
+			case sock.send <- msg:
+			default:
+			}
+		}
+	}
+}
+
+// watch catches sockets that stopped beating without closing.
+func (s *Service) watch() {
+	ticker := time.NewTicker(HeartbeatInterval / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		users := make([]string, 0, len(s.sockets))
+		for id := range s.sockets {
+			users = append(users, id)
+		}
+		s.mu.Unlock()
+		for _, id := range users {
+			s.refresh(id)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package presence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"main/internal/auth"
+	"main/internal/data/datatest"
+)
+
+func TestLiveStatus(t *testing.T) {
+	now := time.Now()
+	s := &Service{sockets: map[string]map[*socket]bool{
+		"fresh": {{status: "online", lastBeat: now.Add(-time.Second)}: true},
+		"quiet": {{status: "online", lastBeat: now.Add(-HeartbeatTimeout - time.Second)}: true},
+		"mixed": {
+			{status: "online", lastBeat: now.Add(-time.Minute)}: true,
+			{status: "away", lastBeat: now}:                     true,
+		},
+		"silent": {{}: true}, // Connected but hasn't beaten yet
+	}}
+	for user, want := range map[string]string{
+		"fresh": "online", "quiet": "away", "mixed": "away", "silent": "offline", "gone": "offline",
+	} {
+		if got := s.liveStatus(user, now); got != want {
+			t.Errorf("%s: %s, want %s", user, got, want)
+		}
+	}
+}
+
+func TestClosingSocketGoesOffline(t *testing.T) {
+	_, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	s := NewService(db)
+	statuses := make(chan string, 8)
+	s.Subscribe(func(userID, status string, _ []string) {
+		if userID == id {
+			statuses <- status
+		}
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.HandleWS(w, auth.WithUserID(r, id))
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-statuses:
+			if got != want {
+				t.Fatalf("announced %s, want %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("never announced %s", want)
+		}
+	}
+
+	conn.WriteJSON(map[string]string{"type": "heartbeat", "status": "online"})
+	expect("online")
+	conn.Close()
+	expect("offline")
+
+	if s.connected(id) {
+		t.Error("socket still registered after closing")
+	}
+	var offline bool
+	db.QueryRow(`SELECT offline_at IS NOT NULL FROM users WHERE id = $1`, id).Scan(&offline)
+	if !offline {
+		t.Error("offline_at not recorded")
+	}
+}
@@ -0,0 +1,147 @@
+package slotix
+
+import "time"
+
+const (
+	// Gap between autoplay spins. Long enough for the client's reel animation
+	// and well clear of spin's 500ms anti-spam.
+	autoplayInterval = 1500 * time.Millisecond
+	MaxAutoplaySpins = 100
+)
+
+// Why an autoplay run ended, sent to the client in autoplay_ended
+const (
+	AutoplayDone         = "done"          // Played the requested count
+	AutoplayWon          = "won"           // stopOnWin and a spin paid out, or one paid over StopOnWinAbove
+	AutoplayBelowBalance = "below_balance" // Balance dropped, or the next bet would drop it, under stopBelowBalance
+	AutoplayNoCoins      = "not_enough_coins"
+	AutoplayStopped      = "stopped" // Player pressed stop or disconnected
+	AutoplayFailed       = "failed"  // A spin was refused for any other reason
+)
+
+type autoplayConfig struct {
+	Count            int
+	Bet              int
+	StopOnWin        bool
+	StopOnWinAbove   int // Stop after a single win bigger than this, 0 = off
+	StopBelowBalance int // 0 = no floor
+}
+
+type autoplayRun struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (g *Game) autoplaying(p *Player) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.autoplay != nil
+}
+
+func (g *Game) startAutoplay(p *Player, cfg autoplayConfig) {
+	if cfg.Count < 1 || cfg.Count > MaxAutoplaySpins {
+		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Autoplay count must be 1-100"})
+		return
+	}
+
+	p.mu.Lock()
+	if p.autoplay != nil {
+		p.mu.Unlock()
+		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Autoplay is already running"})
+		return
+	}
+	run := &autoplayRun{stop: make(chan struct{}), done: make(chan struct{})}
+	p.autoplay = run
+	p.mu.Unlock()
+
+	g.sendTo(p, map[string]interface{}{"type": "autoplay_started", "count": cfg.Count, "bet": cfg.Bet})
+	go g.runAutoplay(p, cfg, run)
+}
+
+// stopAutoplay cancels p's autoplay, if any. With wait set it blocks until the
+// loop has exited, which readPump needs before p.Send gets closed.
+func (g *Game) stopAutoplay(p *Player, wait bool) {
+	p.mu.Lock()
+	run := p.autoplay
+	if run != nil {
+		p.autoplay = nil
+		close(run.stop)
+	}
+	p.mu.Unlock()
+
+	if run != nil && wait {
+		<-run.done
+	}
+}
+
+func (g *Game) runAutoplay(p *Player, cfg autoplayConfig, run *autoplayRun) {
+	reason := AutoplayDone
+	spins := 0
+	defer func() {
+		p.mu.Lock()
+		if p.autoplay == run {
+			p.autoplay = nil
+		}
+		p.mu.Unlock()
+		g.sendTo(p, map[string]interface{}{"type": "autoplay_ended", "reason": reason, "spins": spins})
+		close(run.done)
+	}()
+
+	// With a floor set, a spin that could take the balance under it is never
+	// started; the balance is tracked from each spin's result
+	balance := -1
+	if cfg.StopBelowBalance > 0 {
+		if u, ok := g.store.GetUserFresh(p.UserID); ok {
+			balance = u.Coins
+		}
+	}
+
+	for spins < cfg.Count {
+		select {
+		case <-run.stop:
+			reason = AutoplayStopped
+			return
+		default:
+		}
+
+		if cfg.StopBelowBalance > 0 && balance >= 0 && balance-cfg.Bet < cfg.StopBelowBalance {
+			reason = AutoplayBelowBalance
+			return
+		}
+		out, refused := g.spin(p, cfg.Bet, "")
+		switch refused {
+		case "":
+		case spinNoCoins:
+			reason = AutoplayNoCoins
+			return
+		default:
+			reason = AutoplayFailed
+			return
+		}
+		spins++
+		balance = out.NewBalance
+
+		if cfg.StopOnWin && out.WinAmount > 0 {
+			reason = AutoplayWon
+			return
+		}
+		if cfg.StopOnWinAbove > 0 && out.WinAmount > cfg.StopOnWinAbove {
+			reason = AutoplayWon
+			return
+		}
+		if cfg.StopBelowBalance > 0 && out.NewBalance < cfg.StopBelowBalance {
+			reason = AutoplayBelowBalance
+			return
+		}
+		if spins == cfg.Count {
+			return
+		}
+
+		select {
+		case <-run.stop:
+			reason = AutoplayStopped
+			return
+		case <-time.After(autoplayInterval):
+		}
+	}
+}
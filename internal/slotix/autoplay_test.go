@@ -0,0 +1,98 @@
+package slotix
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"main/internal/data/datatest"
+)
+
+// autoplayEnd waits for p's autoplay_ended message.
+func autoplayEnd(t *testing.T, p *Player) (reason string, spins int) {
+	t.Helper()
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case raw := <-p.Send:
+			var msg struct {
+				Type   string
+				Reason string
+				Spins  int
+			}
+			json.Unmarshal(raw, &msg)
+			if msg.Type == "autoplay_ended" {
+				return msg.Reason, msg.Spins
+			}
+		case <-deadline:
+			t.Fatal("autoplay never ended")
+		}
+	}
+}
+
+func TestAutoplayStopsAtCount(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 10_000)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 64)}
+
+	g.startAutoplay(p, autoplayConfig{Count: 2, Bet: 10})
+	if reason, spins := autoplayEnd(t, p); reason != AutoplayDone || spins != 2 {
+		t.Fatalf("ended %q after %d spins, want %q after 2", reason, spins, AutoplayDone)
+	}
+	if g.autoplaying(p) {
+		t.Error("still marked as autoplaying")
+	}
+}
+
+func TestAutoplayStopsWithoutCoins(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 50)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 64)}
+
+	g.startAutoplay(p, autoplayConfig{Count: 5, Bet: 100})
+	if reason, spins := autoplayEnd(t, p); reason != AutoplayNoCoins || spins != 0 {
+		t.Fatalf("ended %q after %d spins, want %q after 0", reason, spins, AutoplayNoCoins)
+	}
+}
+
+func TestAutoplayCountLimits(t *testing.T) {
+	g := &Game{}
+	p := &Player{Send: make(chan []byte, 4)}
+	for _, n := range []int{0, MaxAutoplaySpins + 1} {
+		g.startAutoplay(p, autoplayConfig{Count: n, Bet: 10})
+		if g.autoplaying(p) {
+			t.Fatalf("autoplay of %d spins started", n)
+		}
+	}
+}
+
+func TestAutospinStopsAtBalanceFloor(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 64)}
+
+	// A losing 10 coin spin would leave 90, under the floor, so none is played
+	g.startAutoplay(p, autoplayConfig{Count: 10, Bet: 10, StopBelowBalance: 95})
+	if reason, spins := autoplayEnd(t, p); reason != AutoplayBelowBalance || spins != 0 {
+		t.Fatalf("ended %q after %d spins, want %q after 0", reason, spins, AutoplayBelowBalance)
+	}
+	if u, _ := store.GetUserFresh(id); u.Coins != 100 {
+		t.Errorf("balance %d, want 100 untouched", u.Coins)
+	}
+}
+
+func TestAutospinStopsOnDisconnect(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 10_000)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 64)}
+
+	g.startAutoplay(p, autoplayConfig{Count: MaxAutoplaySpins, Bet: 10})
+	g.stopAutoplay(p, true) // What readPump does on the way out
+	if reason, spins := autoplayEnd(t, p); reason != AutoplayStopped || spins > 1 {
+		t.Fatalf("ended %q after %d spins, want %q right away", reason, spins, AutoplayStopped)
+	}
+}
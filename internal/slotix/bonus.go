@@ -0,0 +1,48 @@
+package slotix
+
+// Free spin bonus: FreeSpinTrigger or more bars anywhere on the grid award
+// FreeSpinsAwarded spins that cost nothing and pay FreeSpinMultiplier times
+// the usual line wins. The jackpot is paid as is. Free spins play at the bet
+// that triggered them, whatever the client sends.
+const (
+	FreeSpinTrigger    = 3
+	FreeSpinsAwarded   = 5
+	FreeSpinMultiplier = 2
+)
+
+// takeFreeSpin uses up one of p's free spins and returns the bet it plays
+// at, reporting false when there are none left.
+func (p *Player) takeFreeSpin() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.freeSpins <= 0 {
+		return 0, false
+	}
+	p.freeSpins--
+	return p.freeSpinBet, true
+}
+
+// addFreeSpins grants n more free spins won at bet and returns how many p
+// has now.
+func (p *Player) addFreeSpins(n, bet int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n > 0 {
+		p.freeSpinBet = bet
+	}
+	p.freeSpins += n
+	return p.freeSpins
+}
+
+// countSymbol counts sym anywhere on the grid.
+func countSymbol(reels [][]string, sym string) int {
+	n := 0
+	for _, reel := range reels {
+		for _, s := range reel {
+			if s == sym {
+				n++
+			}
+		}
+	}
+	return n
+}
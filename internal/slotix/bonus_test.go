@@ -0,0 +1,31 @@
+package slotix
+
+import "testing"
+
+func TestCountSymbolTriggersBonus(t *testing.T) {
+	reels := [][]string{
+		{SymbolBar, SymbolCherry, SymbolLemon},
+		{SymbolLemon, SymbolBar, SymbolCherry},
+		{SymbolCherry, SymbolLemon, SymbolBar},
+	}
+	if n := countSymbol(reels, SymbolBar); n != FreeSpinTrigger {
+		t.Fatalf("counted %d bars, want %d", n, FreeSpinTrigger)
+	}
+
+	p := &Player{}
+	if left := p.addFreeSpins(FreeSpinsAwarded, 10); left != 5 {
+		t.Fatalf("%d free spins after the bonus, want 5", left)
+	}
+	for i := 0; i < 5; i++ {
+		bet, ok := p.takeFreeSpin()
+		if !ok {
+			t.Fatalf("free spin %d missing", i+1)
+		}
+		if bet != 10 {
+			t.Fatalf("free spin %d plays at %d, want the triggering 10", i+1, bet)
+		}
+	}
+	if _, ok := p.takeFreeSpin(); ok {
+		t.Fatal("a sixth free spin was handed out")
+	}
+}
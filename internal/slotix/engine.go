@@ -2,11 +2,14 @@ package slotix
 
 import (
 	"encoding/json"
-	"math/rand"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"main/internal/auth"
 	"main/internal/data"
 
 	"github.com/gorilla/websocket"
@@ -57,12 +60,22 @@ var payouts = map[string]int{
 	SymbolJackpot: 100,
 }
 
+// The progressive jackpot is kept in the game_state table under JackpotKey
+// and goes back to JackpotSeed whenever it's won.
+const (
+	JackpotKey  = "slotix_jackpot"
+	JackpotSeed = 1000
+)
+
 type Player struct {
-	UserID   string
-	Nickname string
-	Conn     *websocket.Conn
-	Send     chan []byte
-	mu       sync.Mutex
+	UserID      string
+	Nickname    string
+	Conn        *websocket.Conn
+	Send        chan []byte
+	mu          sync.Mutex
+	autoplay    *autoplayRun // Guarded by mu, nil when not autoplaying
+	freeSpins   int          // Guarded by mu, see bonus.go
+	freeSpinBet int          // Guarded by mu, the bet that won freeSpins
 }
 
 type Game struct {
@@ -73,6 +86,7 @@ type Game struct {
 	unregister   chan *Player
 	jackpot      int
 	lastSpinTime map[string]time.Time
+	seeds        map[string]*fairSeed // User ID -> provably fair seeds, see fair.go
 }
 
 func NewGame(store *data.Store) *Game {
@@ -81,13 +95,26 @@ func NewGame(store *data.Store) *Game {
 		players:      make(map[*Player]bool),
 		register:     make(chan *Player),
 		unregister:   make(chan *Player),
-		jackpot:      1000, // Starting jackpot
+		jackpot:      JackpotSeed,
 		lastSpinTime: make(map[string]time.Time),
+		seeds:        make(map[string]*fairSeed),
+	}
+	if j, err := store.GetCounter(JackpotKey, JackpotSeed); err != nil {
+		log.Printf("[SLOTIX] Could not load the jackpot, starting at %d: %v", JackpotSeed, err)
+	} else {
+		g.jackpot = int(j)
 	}
 	go g.run()
 	return g
 }
 
+// Snapshot reports how many people are at the machines.
+func (g *Game) Snapshot() data.LiveStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return data.LiveStatus{Players: len(g.players)}
+}
+
 func (g *Game) run() {
 	for {
 		select {
@@ -111,67 +138,85 @@ func (g *Game) run() {
 
 func (g *Game) sendWelcome(p *Player) {
 	coins := 0
-	if p.UserID != "" && p.UserID != "guest" {
-		if u, ok := g.store.GetUser(p.UserID); ok {
-			coins = u.Coins
-		}
+	if u, ok := g.store.GetUser(p.UserID); ok {
+		coins = u.Coins
 	}
 
+	g.mu.Lock()
+	seed := g.seedFor(p.UserID)
+	seedHash, clientSeed, nonce := SeedHash(seed.serverSeed), seed.clientSeed, seed.nonce
+	g.mu.Unlock()
+
 	g.sendTo(p, map[string]interface{}{
-		"type":     "welcome",
-		"coins":    coins,
-		"jackpot":  g.jackpot,
-		"nickname": p.Nickname,
+		"type":           "welcome",
+		"coins":          coins,
+		"jackpot":        g.jackpot,
+		"nickname":       p.Nickname,
+		"serverSeedHash": seedHash,
+		"clientSeed":     clientSeed,
+		"nonce":          nonce,
 	})
 }
 
-func (g *Game) spin(p *Player, bet int) {
+// Reasons spin refuses to play
+const (
+	spinTooFast = "too_fast"
+	spinBadBet  = "bad_bet"
+	spinNoCoins = "not_enough_coins"
+	spinFailed  = "failed"
+)
+
+// spinOutcome is what autoplay needs to know about a finished spin
+type spinOutcome struct {
+	WinAmount  int
+	JackpotWon bool
+	NewBalance int
+}
+
+// spin plays one round and sends the result (or an error) to p. The returned
+// reason is empty when the spin was played. clientSeed, when set, becomes the
+// player's client seed from this spin on, see fair.go.
+func (g *Game) spin(p *Player, bet int, clientSeed string) (spinOutcome, string) {
 	// Anti-spam: minimum 500ms between spins
 	g.mu.Lock()
 	lastSpin, exists := g.lastSpinTime[p.UserID]
 	if exists && time.Since(lastSpin) < 500*time.Millisecond {
 		g.mu.Unlock()
 		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Too fast! Wait a moment."})
-		return
+		return spinOutcome{}, spinTooFast
 	}
 	g.lastSpinTime[p.UserID] = time.Now()
-	currentJackpot := g.jackpot
 	g.mu.Unlock()
 
 	// Validate bet
 	if bet < 10 || bet > 1000 {
 		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Bet must be 10-1000"})
-		return
+		return spinOutcome{}, spinBadBet
 	}
 
-	// Check player has enough coins
-	if p.UserID == "" || p.UserID == "guest" {
-		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Must be logged in to play"})
-		return
-	}
+	// A free spin plays the triggering bet without charging it
+	freeBet, free := p.takeFreeSpin()
+	if free {
+		bet = freeBet
+	} else {
+		// Checked and charged in one step, so two spins can't both spend
+		// the same coins
+		if err := g.store.AdjustCoinsWithReason(p.UserID, -bet, data.ReasonSlotixBet, ""); err != nil {
+			if errors.Is(err, data.ErrInsufficientFunds) {
+				g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Not enough coins"})
+				return spinOutcome{}, spinNoCoins
+			}
+			log.Printf("[SLOTIX] Charging a bet for %s failed: %v", p.UserID, err)
+			g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Spin failed, try again"})
+			return spinOutcome{}, spinFailed
+		}
 
-	user, ok := g.store.GetUser(p.UserID)
-	if !ok || user.Coins < bet {
-		g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Not enough coins"})
-		return
+		// Add 5% of bet to jackpot
+		g.addToJackpot(bet / 20)
 	}
 
-	// Deduct bet
-	g.store.AdjustCoins(p.UserID, -bet)
-
-	// Add 5% of bet to jackpot
-	g.mu.Lock()
-	g.jackpot += bet / 20
-	g.mu.Unlock()
-
 	// Spin the reels (3x3 grid)
-	reels := make([][]string, 3)
-	for i := 0; i < 3; i++ {
-		reels[i] = make([]string, 3)
-		for j := 0; j < 3; j++ {
-			reels[i][j] = randomSymbol()
-		}
-	}
+	reels, seedHash, usedClientSeed, nonce := g.fairSpin(p.UserID, clientSeed)
 
 	// Calculate winnings
 	winAmount := 0
@@ -212,12 +257,10 @@ func (g *Game) spin(p *Player, bet int) {
 
 	// Check for jackpot (3 jackpot symbols in middle row)
 	jackpotWon := false
+	jackpotPrize := 0
 	if reels[0][1] == SymbolJackpot && reels[1][1] == SymbolJackpot && reels[2][1] == SymbolJackpot {
-		winAmount += currentJackpot
+		jackpotPrize = g.claimJackpot()
 		jackpotWon = true
-		g.mu.Lock()
-		g.jackpot = 1000 // Reset jackpot
-		g.mu.Unlock()
 	}
 
 	// Wild substitutions - wilds match anything
@@ -231,9 +274,21 @@ func (g *Game) spin(p *Player, bet int) {
 		}
 	}
 
+	if free {
+		winAmount *= FreeSpinMultiplier
+	}
+	winAmount += jackpotPrize
+
+	// Bonus: enough bars anywhere awards free spins
+	freeSpinsWon := 0
+	if countSymbol(reels, SymbolBar) >= FreeSpinTrigger {
+		freeSpinsWon = FreeSpinsAwarded
+	}
+	freeSpinsLeft := p.addFreeSpins(freeSpinsWon, bet)
+
 	// Award winnings
 	if winAmount > 0 {
-		g.store.AdjustCoins(p.UserID, winAmount)
+		g.store.AdjustCoinsWithReason(p.UserID, winAmount, data.ReasonSlotixWin, strings.Join(winLines, ","))
 	}
 
 	// Get updated balance
@@ -247,30 +302,52 @@ func (g *Game) spin(p *Player, bet int) {
 	g.mu.Unlock()
 
 	g.sendTo(p, map[string]interface{}{
-		"type":       "spin_result",
-		"reels":      reels,
-		"winAmount":  winAmount,
-		"winLines":   winLines,
-		"jackpotWon": jackpotWon,
-		"newBalance": newBalance,
-		"jackpot":    newJackpot,
+		"type":          "spin_result",
+		"reels":         reels,
+		"bet":           bet,
+		"winAmount":     winAmount,
+		"winLines":      winLines,
+		"jackpotWon":    jackpotWon,
+		"newBalance":    newBalance,
+		"jackpot":       newJackpot,
+		"freeSpin":      free,
+		"freeSpinsWon":  freeSpinsWon,
+		"freeSpinsLeft": freeSpinsLeft,
+		// Enough to recompute the reels once the seed is revealed
+		"serverSeedHash": seedHash,
+		"clientSeed":     usedClientSeed,
+		"nonce":          nonce,
 	})
+	return spinOutcome{WinAmount: winAmount, JackpotWon: jackpotWon, NewBalance: newBalance}, ""
 }
 
-func randomSymbol() string {
-	totalWeight := 0
-	for _, sw := range symbolWeights {
-		totalWeight += sw.Weight
+// addToJackpot grows the pot, in the database first so it survives a
+// restart. The in-memory copy is only what gets shown.
+func (g *Game) addToJackpot(amount int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	j, err := g.store.AddCounter(JackpotKey, int64(amount))
+	if err != nil {
+		log.Printf("[SLOTIX] Jackpot update failed: %v", err)
+		g.jackpot += amount
+		return
 	}
+	g.jackpot = int(j)
+}
 
-	r := rand.Intn(totalWeight)
-	for _, sw := range symbolWeights {
-		r -= sw.Weight
-		if r < 0 {
-			return sw.Symbol
-		}
+// claimJackpot empties the pot back to JackpotSeed and returns what it held.
+// The swap happens on the locked database row, so two winners at once can't
+// both collect the same pot.
+func (g *Game) claimJackpot() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	won, err := g.store.SwapCounter(JackpotKey, JackpotSeed)
+	if err != nil {
+		log.Printf("[SLOTIX] Jackpot claim failed, paying the in-memory pot: %v", err)
+		won = int64(g.jackpot)
 	}
-	return SymbolCherry
+	g.jackpot = JackpotSeed
+	return int(won)
 }
 
 func checkWildMatch(symbols []string) string {
@@ -311,27 +388,29 @@ func (g *Game) sendTo(p *Player, v interface{}) {
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 func (g *Game) HandleWS(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
+		return
+	}
+	u, ok := g.store.GetUser(userID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	userID := r.URL.Query().Get("userID")
-	nick := "Guest"
-	if userID != "" {
-		if u, ok := g.store.GetUser(userID); ok {
-			nick = u.Nickname
-		}
-	}
-
 	p := &Player{
 		UserID:   userID,
-		Nickname: nick,
+		Nickname: u.Nickname,
 		Conn:     conn,
 		Send:     make(chan []byte, 256),
 	}
 
 	g.register <- p
+	g.store.SetActivity(userID, data.ActivitySlotix)
 	go g.writePump(p)
 	g.readPump(p)
 }
@@ -346,7 +425,13 @@ func (g *Game) writePump(p *Player) {
 }
 
 func (g *Game) readPump(p *Player) {
-	defer func() { g.unregister <- p; p.Conn.Close() }()
+	defer func() {
+		// Wait for autoplay to wind down so it never sends on a closed channel
+		g.stopAutoplay(p, true)
+		g.unregister <- p
+		g.store.ClearActivity(p.UserID, data.ActivitySlotix)
+		p.Conn.Close()
+	}()
 	for {
 		_, data, err := p.Conn.ReadMessage()
 		if err != nil {
@@ -359,8 +444,41 @@ func (g *Game) readPump(p *Player) {
 
 		switch msg["type"] {
 		case "spin":
-			bet := int(msg["bet"].(float64))
-			g.spin(p, bet)
+			if g.autoplaying(p) {
+				g.sendTo(p, map[string]interface{}{"type": "error", "msg": "Autoplay is running"})
+				continue
+			}
+			bet, _ := msg["bet"].(float64)
+			clientSeed, _ := msg["clientSeed"].(string)
+			if len(clientSeed) > 64 {
+				clientSeed = clientSeed[:64]
+			}
+			g.spin(p, int(bet), clientSeed)
+		case "autoplay":
+			count, _ := msg["count"].(float64)
+			bet, _ := msg["bet"].(float64)
+			stopOnWin, _ := msg["stopOnWin"].(bool)
+			stopBelow, _ := msg["stopBelowBalance"].(float64)
+			g.startAutoplay(p, autoplayConfig{
+				Count:            int(count),
+				Bet:              int(bet),
+				StopOnWin:        stopOnWin,
+				StopBelowBalance: int(stopBelow),
+			})
+		case "autospin":
+			// Same run as autoplay, with a win threshold and a balance floor
+			count, _ := msg["count"].(float64)
+			bet, _ := msg["bet"].(float64)
+			stopOnWin, _ := msg["stopOnWin"].(float64)
+			minBalance, _ := msg["minBalance"].(float64)
+			g.startAutoplay(p, autoplayConfig{
+				Count:            int(count),
+				Bet:              int(bet),
+				StopOnWinAbove:   int(stopOnWin),
+				StopBelowBalance: int(minBalance),
+			})
+		case "autoplay_stop", "autospin_stop":
+			g.stopAutoplay(p, false)
 		}
 	}
 }
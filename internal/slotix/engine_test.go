@@ -0,0 +1,93 @@
+package slotix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+// ledgerHas reports whether userID's recent coin history has a reason row.
+func ledgerHas(t *testing.T, store *data.Store, userID, reason string) bool {
+	t.Helper()
+	entries, err := store.GetCoinLedger(userID, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFreeSpinDoesNotDebit(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 16)}
+	p.addFreeSpins(1, 10)
+
+	if _, refused := g.spin(p, 100, ""); refused != "" {
+		t.Fatalf("free spin refused: %s", refused)
+	}
+	if ledgerHas(t, store, id, data.ReasonSlotixBet) {
+		t.Fatal("a free spin charged the bet")
+	}
+}
+
+func TestFreeSpinPlaysTriggeringBet(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 16)}
+	p.addFreeSpins(1, 10)
+
+	if _, refused := g.spin(p, 1000, ""); refused != "" {
+		t.Fatalf("free spin refused: %s", refused)
+	}
+	for len(p.Send) > 0 {
+		var msg struct {
+			Type string
+			Bet  int
+		}
+		json.Unmarshal(<-p.Send, &msg)
+		if msg.Type == "spin_result" {
+			if msg.Bet != 10 {
+				t.Fatalf("free spin played at %d, want the triggering 10", msg.Bet)
+			}
+			return
+		}
+	}
+	t.Fatal("no spin_result")
+}
+
+func TestSpinChargesBet(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 16)}
+
+	if _, refused := g.spin(p, 100, ""); refused != "" {
+		t.Fatalf("spin refused: %s", refused)
+	}
+	if !ledgerHas(t, store, id, data.ReasonSlotixBet) {
+		t.Fatal("the bet wasn't charged")
+	}
+}
+
+func TestSpinRefusedWithoutCoins(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 50)
+	g := NewGame(store)
+	p := &Player{UserID: id, Send: make(chan []byte, 16)}
+
+	if _, refused := g.spin(p, 100, ""); refused != spinNoCoins {
+		t.Fatalf("refused = %q, want %q", refused, spinNoCoins)
+	}
+	if u, _ := store.GetUserFresh(id); u.Coins != 50 {
+		t.Fatalf("coins %d after a refused spin, want 50", u.Coins)
+	}
+}
@@ -0,0 +1,127 @@
+package slotix
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"main/internal/auth"
+)
+
+// Provably fair spins. Each player gets a secret server seed; only its
+// SHA-256 (the commitment) is shown up front. Every spin's reels come from
+// HMAC-SHA256(serverSeed, "clientSeed:nonce:cell"), with the nonce counting
+// spins on that seed. GET /slotix/verify reveals the seed and starts a new
+// one, so the player can rerun FairReels for every spin they made on it and
+// check the seed hashes to the commitment they were shown.
+
+// DefaultClientSeed is used until the player picks their own.
+const DefaultClientSeed = "five3space"
+
+// fairSeed is one player's current seed pair. Guarded by Game.mu.
+type fairSeed struct {
+	serverSeed string
+	clientSeed string
+	nonce      uint64 // Spins played on serverSeed so far
+}
+
+func newFairSeed(clientSeed string) *fairSeed {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("slotix: no randomness for a server seed: %v", err))
+	}
+	return &fairSeed{serverSeed: hex.EncodeToString(buf), clientSeed: clientSeed}
+}
+
+// SeedHash is the commitment shown before any spin on serverSeed.
+func SeedHash(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// FairReels rebuilds a 3x3 spin from its seeds and nonce. It is the only
+// source of reels, so anyone holding the revealed seed gets the same grid.
+func FairReels(serverSeed, clientSeed string, nonce uint64) [][]string {
+	totalWeight := 0
+	for _, sw := range symbolWeights {
+		totalWeight += sw.Weight
+	}
+
+	reels := make([][]string, 3)
+	for i := 0; i < 3; i++ {
+		reels[i] = make([]string, 3)
+		for j := 0; j < 3; j++ {
+			mac := hmac.New(sha256.New, []byte(serverSeed))
+			fmt.Fprintf(mac, "%s:%d:%d", clientSeed, nonce, i*3+j)
+			roll := binary.BigEndian.Uint32(mac.Sum(nil)[:4])
+			reels[i][j] = symbolAt(int(roll % uint32(totalWeight)))
+		}
+	}
+	return reels
+}
+
+// symbolAt maps r in [0, total weight) onto symbolWeights.
+func symbolAt(r int) string {
+	for _, sw := range symbolWeights {
+		r -= sw.Weight
+		if r < 0 {
+			return sw.Symbol
+		}
+	}
+	return SymbolCherry
+}
+
+// seedFor returns userID's seed pair, making one on first use. Caller holds
+// g.mu.
+func (g *Game) seedFor(userID string) *fairSeed {
+	seed, ok := g.seeds[userID]
+	if !ok {
+		seed = newFairSeed(DefaultClientSeed)
+		g.seeds[userID] = seed
+	}
+	return seed
+}
+
+// fairSpin rolls userID's next reels. A non-empty clientSeed replaces the
+// one in use from this spin on.
+func (g *Game) fairSpin(userID, clientSeed string) (reels [][]string, seedHash, usedClientSeed string, nonce uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seed := g.seedFor(userID)
+	if clientSeed != "" {
+		seed.clientSeed = clientSeed
+	}
+	nonce = seed.nonce
+	seed.nonce++
+	return FairReels(seed.serverSeed, seed.clientSeed, nonce), SeedHash(seed.serverSeed), seed.clientSeed, nonce
+}
+
+// HandleVerify reveals the caller's server seed and rotates to a new one.
+// GET /slotix/verify
+func (g *Game) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	g.mu.Lock()
+	old := g.seedFor(userID)
+	next := newFairSeed(old.clientSeed)
+	g.seeds[userID] = next
+	g.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"serverSeed":     old.serverSeed,
+		"serverSeedHash": SeedHash(old.serverSeed),
+		"clientSeed":     old.clientSeed,
+		"spins":          old.nonce, // Nonces 0 to spins-1 were played on this seed
+		"nextSeedHash":   SeedHash(next.serverSeed),
+	})
+}
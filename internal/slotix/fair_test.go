@@ -0,0 +1,68 @@
+package slotix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"main/internal/auth"
+)
+
+func TestFairReelsReproduce(t *testing.T) {
+	a := FairReels("server", "client", 7)
+	if b := FairReels("server", "client", 7); !reflect.DeepEqual(a, b) {
+		t.Fatalf("same seeds gave %v then %v", a, b)
+	}
+
+	// Every input has to matter, or a player could steer one of them
+	differs := 0
+	for _, other := range [][][]string{
+		FairReels("server2", "client", 7),
+		FairReels("server", "client2", 7),
+		FairReels("server", "client", 8),
+	} {
+		if !reflect.DeepEqual(a, other) {
+			differs++
+		}
+	}
+	if differs != 3 {
+		t.Errorf("only %d of 3 changed inputs changed the reels", differs)
+	}
+}
+
+func TestVerifyRevealsCommittedSeed(t *testing.T) {
+	g := &Game{seeds: make(map[string]*fairSeed)}
+	var spun [][][]string
+	var committed string
+	for i := 0; i < 3; i++ {
+		reels, hash, _, nonce := g.fairSpin("u_1", "lucky")
+		if nonce != uint64(i) {
+			t.Fatalf("spin %d had nonce %d", i, nonce)
+		}
+		committed = hash
+		spun = append(spun, reels)
+	}
+
+	rec := httptest.NewRecorder()
+	g.HandleVerify(rec, auth.WithUserID(httptest.NewRequest(http.MethodGet, "/slotix/verify", nil), "u_1"))
+	var out struct {
+		ServerSeed, ServerSeedHash, ClientSeed, NextSeedHash string
+		Spins                                                uint64
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if SeedHash(out.ServerSeed) != committed || out.ServerSeedHash != committed {
+		t.Fatalf("revealed seed hashes to %s, committed to %s", SeedHash(out.ServerSeed), committed)
+	}
+	if out.Spins != 3 || out.ClientSeed != "lucky" || out.NextSeedHash == committed {
+		t.Errorf("spins %d client seed %q next hash %s", out.Spins, out.ClientSeed, out.NextSeedHash)
+	}
+	for n, reels := range spun {
+		if got := FairReels(out.ServerSeed, out.ClientSeed, uint64(n)); !reflect.DeepEqual(got, reels) {
+			t.Errorf("spin %d recomputes to %v, was %v", n, got, reels)
+		}
+	}
+}
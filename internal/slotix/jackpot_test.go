@@ -0,0 +1,56 @@
+package slotix
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"main/internal/data"
+	"main/internal/data/datatest"
+)
+
+// setJackpot puts the shared pot at value for the test and restores it after.
+func setJackpot(t *testing.T, store *data.Store, value int64) {
+	t.Helper()
+	if _, err := store.GetCounter(JackpotKey, JackpotSeed); err != nil {
+		t.Fatal(err)
+	}
+	old, err := store.SwapCounter(JackpotKey, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.SwapCounter(JackpotKey, old) })
+}
+
+func TestNewGameLoadsJackpot(t *testing.T) {
+	store, _ := datatest.Store(t)
+	setJackpot(t, store, 54321)
+
+	if g := NewGame(store); g.jackpot != 54321 {
+		t.Fatalf("jackpot %d after a restart, want the saved 54321", g.jackpot)
+	}
+}
+
+// Two servers (or two spins) hitting the jackpot together: one takes the
+// pot, the other only the fresh seed.
+func TestJackpotClaimedOnce(t *testing.T) {
+	store, _ := datatest.Store(t)
+	setJackpot(t, store, 5000)
+	games := []*Game{NewGame(store), NewGame(store)}
+
+	won := make([]int, len(games))
+	var wg sync.WaitGroup
+	for i, g := range games {
+		wg.Add(1)
+		go func(i int, g *Game) {
+			defer wg.Done()
+			won[i] = g.claimJackpot()
+		}(i, g)
+	}
+	wg.Wait()
+
+	sort.Ints(won)
+	if won[0] != JackpotSeed || won[1] != 5000 {
+		t.Fatalf("claims paid %v, want [%d 5000]", won, JackpotSeed)
+	}
+}
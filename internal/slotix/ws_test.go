@@ -0,0 +1,54 @@
+package slotix
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"main/internal/auth"
+	"main/internal/data/datatest"
+)
+
+// asUser serves g's websocket as if userID were signed in.
+func asUser(g *Game, userID string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.HandleWS(w, auth.WithUserID(r, userID))
+	}))
+}
+
+func TestHandleWSRejectsUnknownUser(t *testing.T) {
+	store, _ := datatest.Store(t)
+	srv := asUser(NewGame(store), "u_missing")
+	defer srv.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err == nil {
+		t.Fatal("upgraded a connection for a user that doesn't exist")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("response %v, want 401", resp)
+	}
+}
+
+func TestHandleWSWelcomesUser(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 100)
+	srv := asUser(NewGame(store), id)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("upgrade refused: %v", err)
+	}
+	defer conn.Close()
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg["type"] != "welcome" {
+		t.Fatalf("first message %v, want welcome", msg["type"])
+	}
+}
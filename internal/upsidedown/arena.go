@@ -0,0 +1,189 @@
+package upsidedown
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Rect is an axis-aligned wall. X/Y is the min corner.
+type Rect struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+func (r Rect) contains(p Vec2, pad float64) bool {
+	return p.X > r.X-pad && p.X < r.X+r.W+pad &&
+		p.Y > r.Y-pad && p.Y < r.Y+r.H+pad
+}
+
+// Map is the arena: a Width x Height field centred on the origin with walls.
+// It is sent to clients on welcome (and on restart) so they can draw it.
+type Map struct {
+	Name      string  `json:"name"`
+	Width     float64 `json:"width"`
+	Height    float64 `json:"height"`
+	Obstacles []Rect  `json:"obstacles"`
+}
+
+const (
+	PlayerRadius = 0.5
+	DemoRadius   = 0.8
+	BossRadius   = 1.6
+
+	// moveStep is how finely a path is sampled when checking for walls, keep
+	// it below the thinnest wall so nobody tunnels through.
+	moveStep = 0.25
+
+	// spawnClearRadius keeps the random generator away from the player start.
+	spawnClearRadius = 10.0
+)
+
+// DefaultMap is the hand-authored layout: a ruined lab with a clear centre.
+func DefaultMap() *Map {
+	return &Map{
+		Name:   "hawkins_lab",
+		Width:  80,
+		Height: 80,
+		Obstacles: []Rect{
+			// Inner corridor walls around the spawn area
+			{X: -20, Y: -14, W: 12, H: 1},
+			{X: 8, Y: -14, W: 12, H: 1},
+			{X: -20, Y: 13, W: 12, H: 1},
+			{X: 8, Y: 13, W: 12, H: 1},
+			// Side blocks
+			{X: -30, Y: -4, W: 4, H: 8},
+			{X: 26, Y: -4, W: 4, H: 8},
+			// Outer rubble
+			{X: -28, Y: 24, W: 6, H: 3},
+			{X: 22, Y: -27, W: 6, H: 3},
+			{X: -5, Y: 28, W: 10, H: 1},
+			{X: -5, Y: -29, W: 10, H: 1},
+		},
+	}
+}
+
+// RandomMap scatters count walls, keeping the start area open.
+func RandomMap(count int) *Map {
+	m := &Map{Name: "random", Width: 80, Height: 80}
+	for len(m.Obstacles) < count {
+		horizontal := rand.Intn(2) == 0
+		w, h := 2+rand.Float64()*10, 1.0
+		if !horizontal {
+			w, h = h, w
+		}
+		r := Rect{
+			X: rand.Float64()*(m.Width-w) - m.Width/2,
+			Y: rand.Float64()*(m.Height-h) - m.Height/2,
+			W: w,
+			H: h,
+		}
+		centre := Vec2{X: r.X + r.W/2, Y: r.Y + r.H/2}
+		if distance(centre, Vec2{}) < spawnClearRadius+math.Max(w, h)/2 {
+			continue
+		}
+		m.Obstacles = append(m.Obstacles, r)
+	}
+	return m
+}
+
+// mapByName picks the layout a host asked for, defaulting to the lab.
+func mapByName(name string) *Map {
+	if name == "random" {
+		return RandomMap(14)
+	}
+	return DefaultMap()
+}
+
+func (m *Map) inBounds(p Vec2, pad float64) bool {
+	return math.Abs(p.X) <= m.Width/2-pad && math.Abs(p.Y) <= m.Height/2-pad
+}
+
+// Blocked reports whether a body of the given radius can't stand at p.
+func (m *Map) Blocked(p Vec2, radius float64) bool {
+	if !m.inBounds(p, radius) {
+		return true
+	}
+	for _, r := range m.Obstacles {
+		if r.contains(p, radius) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clamp pulls p back inside the outer bounds.
+func (m *Map) Clamp(p Vec2, radius float64) Vec2 {
+	hw, hh := m.Width/2-radius, m.Height/2-radius
+	return Vec2{X: math.Max(-hw, math.Min(hw, p.X)), Y: math.Max(-hh, math.Min(hh, p.Y))}
+}
+
+// Move walks from -> to in small steps and stops at the first wall. When the
+// straight line is blocked it tries each axis on its own so bodies slide
+// along walls instead of sticking to them.
+func (m *Map) Move(from, to Vec2, radius float64) Vec2 {
+	to = m.Clamp(to, radius)
+	if end, ok := m.walk(from, to, radius); ok {
+		return end
+	}
+	best := from
+	for _, axis := range []Vec2{{X: to.X, Y: from.Y}, {X: from.X, Y: to.Y}} {
+		end, _ := m.walk(from, axis, radius)
+		if distance(from, end) > distance(from, best) {
+			best = end
+		}
+	}
+	return best
+}
+
+// walk returns the furthest open point along the segment, and whether the
+// whole segment was clear.
+func (m *Map) walk(from, to Vec2, radius float64) (Vec2, bool) {
+	dist := distance(from, to)
+	steps := int(math.Ceil(dist / moveStep))
+	last := from
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		p := Vec2{X: from.X + (to.X-from.X)*t, Y: from.Y + (to.Y-from.Y)*t}
+		if m.Blocked(p, radius) {
+			return last, false
+		}
+		last = p
+	}
+	return last, true
+}
+
+// RandomOpenPoint samples a free spot within halfSize of the origin.
+func (m *Map) RandomOpenPoint(halfSize, radius float64) Vec2 {
+	var p Vec2
+	for i := 0; i < 50; i++ {
+		p = Vec2{X: rand.Float64()*2*halfSize - halfSize, Y: rand.Float64()*2*halfSize - halfSize}
+		if !m.Blocked(p, radius) {
+			return p
+		}
+	}
+	return m.Clamp(p, radius)
+}
+
+// RandomEdgePoint picks a free spot inset from a random edge of the arena.
+func (m *Map) RandomEdgePoint(inset, radius float64) Vec2 {
+	hw, hh := m.Width/2-inset, m.Height/2-inset
+	var p Vec2
+	for i := 0; i < 50; i++ {
+		switch rand.Intn(4) {
+		case 0:
+			p = Vec2{X: -hw, Y: rand.Float64()*2*hh - hh}
+		case 1:
+			p = Vec2{X: hw, Y: rand.Float64()*2*hh - hh}
+		case 2:
+			p = Vec2{X: rand.Float64()*2*hw - hw, Y: -hh}
+		case 3:
+			p = Vec2{X: rand.Float64()*2*hw - hw, Y: hh}
+		}
+		if !m.Blocked(p, radius) {
+			return p
+		}
+	}
+	return p
+}
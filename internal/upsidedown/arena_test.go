@@ -0,0 +1,53 @@
+package upsidedown
+
+import "testing"
+
+// wallMap is an open field split by a thin wall along x = 5.
+func wallMap() *Map {
+	return &Map{Width: 80, Height: 80, Obstacles: []Rect{{X: 5, Y: -20, W: 1, H: 40}}}
+}
+
+func TestMoveThroughWallBlocked(t *testing.T) {
+	m := wallMap()
+	got := m.Move(Vec2{X: 0, Y: 0}, Vec2{X: 10, Y: 0}, PlayerRadius)
+	if got.X > 5-PlayerRadius {
+		t.Fatalf("walked through the wall to %+v", got)
+	}
+	if got.X < 5-PlayerRadius-moveStep {
+		t.Errorf("stopped short of the wall at %+v", got)
+	}
+}
+
+func TestMoveSlidesAlongWall(t *testing.T) {
+	m := wallMap()
+	got := m.Move(Vec2{X: 4, Y: 0}, Vec2{X: 6, Y: 3}, PlayerRadius)
+	if got.X > 5-PlayerRadius || got.Y <= 0 {
+		t.Errorf("expected to slide along the wall, ended at %+v", got)
+	}
+}
+
+func TestMoveClampedToBounds(t *testing.T) {
+	m := wallMap()
+	got := m.Move(Vec2{X: 0, Y: -30}, Vec2{X: 0, Y: -100}, PlayerRadius)
+	if got.Y < -m.Height/2+PlayerRadius {
+		t.Errorf("left the arena: %+v", got)
+	}
+}
+
+func TestAttackStopsAtWall(t *testing.T) {
+	g := &Game{players: make(map[*Player]bool), arena: wallMap()}
+	p := &Player{Pos: Vec2{X: 0, Y: 0}, Alive: true}
+	behind := &Entity{Type: "demogorgon", Pos: Vec2{X: 10, Y: 0}, Active: true, Health: 100}
+	g.entities = []*Entity{behind}
+
+	g.handleAttack(p, 0)
+	if behind.Health != 100 {
+		t.Fatal("hit a demogorgon through a wall")
+	}
+
+	g.arena = &Map{Width: 80, Height: 80}
+	g.handleAttack(p, 0)
+	if behind.Health != 100-AttackDamage {
+		t.Fatalf("health %d in the open, want %d", behind.Health, 100-AttackDamage)
+	}
+}
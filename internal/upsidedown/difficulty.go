@@ -0,0 +1,82 @@
+package upsidedown
+
+import "math"
+
+// Difficulty rises in steps rather than continuously, so there's a moment to
+// warn players about. Each scaled quantity has its own curve: they used to
+// share one ever-growing multiplier, which compounded faster enemies, more
+// spawns and heavier drain into a late game spike.
+const (
+	DifficultyStep    = 30.0 // Seconds per difficulty level
+	TelegraphLead     = 5.0  // Seconds of warning before a step or boss wave
+	EnemyBaseSpeed    = 3.0
+	EnemyContactDPS   = 20.0
+	BossContactDPS    = 40.0 // Flat, the boss is already the escalation
+	BossSpeedMultiple = 1.2
+)
+
+// Curve is a multiplier that starts at 1, gains PerLevel each difficulty
+// level and stops at Max.
+type Curve struct {
+	PerLevel float64
+	Max      float64
+}
+
+// At returns the multiplier for a difficulty level.
+func (c Curve) At(level int) float64 {
+	return math.Min(c.Max, 1+c.PerLevel*float64(level))
+}
+
+var (
+	SpeedCurve  = Curve{PerLevel: 0.08, Max: 1.8} // Enemy movement
+	SpawnCurve  = Curve{PerLevel: 0.4, Max: 4}    // Classic spawn count and rate
+	SanityCurve = Curve{PerLevel: 0.15, Max: 2.5} // Sanity drain in the dark
+	DamageCurve = Curve{PerLevel: 0.15, Max: 2.5} // Insanity drain and contact damage
+	ScoreCurve  = Curve{PerLevel: 0.25, Max: 5}   // Survival score, keeps rewarding late play
+)
+
+// difficultyScale is every curve evaluated at one level.
+type difficultyScale struct {
+	Level  int     `json:"level"`
+	Speed  float64 `json:"speed"`
+	Spawn  float64 `json:"spawn"`
+	Sanity float64 `json:"sanity"`
+	Damage float64 `json:"damage"`
+	Score  float64 `json:"score"`
+}
+
+func difficultyLevel(gameTime float64) int {
+	return int(gameTime / DifficultyStep)
+}
+
+func scaleAt(level int) difficultyScale {
+	return difficultyScale{
+		Level:  level,
+		Speed:  SpeedCurve.At(level),
+		Spawn:  SpawnCurve.At(level),
+		Sanity: SanityCurve.At(level),
+		Damage: DamageCurve.At(level),
+		Score:  ScoreCurve.At(level),
+	}
+}
+
+// telegraph warns everyone shortly before the next difficulty step and, in
+// endless mode, before a boss wave. Each warning goes out once. Caller holds
+// g.mu.
+func (g *Game) telegraph() {
+	next := g.difficulty.Level + 1
+	until := float64(next)*DifficultyStep - g.gameTime
+	if until <= TelegraphLead && g.warnedLevel < next && (g.endlessMode || float64(next)*DifficultyStep < GameDuration) {
+		g.warnedLevel = next
+		g.broadcastJSON(map[string]interface{}{"type": "warning", "kind": "difficulty", "level": next, "in": until})
+	}
+
+	if !g.endlessMode || g.bossActive {
+		return
+	}
+	wave := g.currentWave + 1
+	if g.waveTimer > 0 && g.waveTimer <= TelegraphLead && g.warnedWave < wave && GenerateWave(wave).HasBoss {
+		g.warnedWave = wave
+		g.broadcastJSON(map[string]interface{}{"type": "warning", "kind": "boss", "wave": wave, "in": g.waveTimer})
+	}
+}
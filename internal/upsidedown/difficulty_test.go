@@ -0,0 +1,58 @@
+package upsidedown
+
+import "testing"
+
+func TestEachQuantityFollowsItsCurve(t *testing.T) {
+	curves := map[string]struct {
+		curve *Curve
+		get   func(difficultyScale) float64
+	}{
+		"speed":  {&SpeedCurve, func(s difficultyScale) float64 { return s.Speed }},
+		"spawn":  {&SpawnCurve, func(s difficultyScale) float64 { return s.Spawn }},
+		"sanity": {&SanityCurve, func(s difficultyScale) float64 { return s.Sanity }},
+		"damage": {&DamageCurve, func(s difficultyScale) float64 { return s.Damage }},
+		"score":  {&ScoreCurve, func(s difficultyScale) float64 { return s.Score }},
+	}
+
+	for name, c := range curves {
+		for level := 0; level <= 40; level++ {
+			want := 1 + c.curve.PerLevel*float64(level)
+			if want > c.curve.Max {
+				want = c.curve.Max
+			}
+			if got := c.get(scaleAt(level)); got != want {
+				t.Fatalf("%s at level %d: %v, want %v", name, level, got, want)
+			}
+		}
+	}
+
+	// Retuning one curve moves only its own quantity
+	before := scaleAt(4)
+	saved := SpeedCurve
+	SpeedCurve = Curve{PerLevel: 1, Max: 10}
+	after := scaleAt(4)
+	SpeedCurve = saved
+	if after.Speed != 5 {
+		t.Errorf("retuned speed at level 4: %v, want 5", after.Speed)
+	}
+	after.Speed = before.Speed
+	if after != before {
+		t.Errorf("retuning speed changed other quantities: %+v, was %+v", after, before)
+	}
+}
+
+func TestDifficultyRisesInSteps(t *testing.T) {
+	for _, tc := range []struct {
+		gameTime float64
+		level    int
+	}{
+		{0, 0},
+		{DifficultyStep - 0.01, 0},
+		{DifficultyStep, 1},
+		{10*DifficultyStep + 1, 10},
+	} {
+		if got := difficultyLevel(tc.gameTime); got != tc.level {
+			t.Errorf("at %vs: level %d, want %d", tc.gameTime, got, tc.level)
+		}
+	}
+}
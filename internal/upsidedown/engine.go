@@ -2,12 +2,15 @@ package upsidedown
 
 import (
 	"encoding/json"
+	"log"
 	"math"
 	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"main/internal/auth"
 	"main/internal/data"
 
 	"github.com/google/uuid"
@@ -23,6 +26,18 @@ const (
 	HealthDrainRate   = 1.0  // Per second when sanity is 0
 	LightRestoreRate  = 10.0 // Sanity restore per second near light
 	DemoSpawnInterval = 15   // Seconds between demogorgon spawns
+	DemoHealth        = 100  // Three hits
+	AttackDamage      = 34
+
+	// Movement is client side; the server only caps it. Walking speed matches
+	// the client's, which sprints at 1.8x and doesn't normalise diagonals.
+	PlayerSpeed   = 5.0
+	MaxMoveSpeed  = PlayerSpeed * 1.8 * math.Sqrt2
+	MoveBankLimit = 0.25 // Seconds of movement that can be saved up for a late packet
+
+	// Endless mode: the next wave comes this long after the last one is cleared
+	WaveBreather   = 5.0
+	FirstWaveDelay = 10.0
 )
 
 // Resource types
@@ -62,6 +77,19 @@ type Player struct {
 	DamageResist    float64 `json:"-"`             // Damage resistance percentage
 	Kills           int     `json:"kills"`         // Demogorgons killed this run
 	SelectedClass   ClassID `json:"selectedClass"` // Character class for this run
+
+	Extracted bool `json:"extracted"` // Left the run through the extraction point
+
+	classChoice ClassID // Requested class for the next run, validated in startGame
+	extractHold float64 // Seconds spent on the extraction point so far
+	extractedAt float64 // Game time they got out
+	lastMove    time.Time
+	moveBudget  float64 // Distance they may still cover, see limitMove
+	rewarded    bool    // Run rewards already paid out
+	shards      int     // Ember shards that payout earned, for game_over
+
+	body           *Entity // Left where they went down, see revive.go
+	reviveProgress float64 // Seconds a teammate has stood by the body
 }
 
 type Entity struct {
@@ -84,19 +112,29 @@ type Game struct {
 	register   chan *Player
 	unregister chan *Player
 
-	gameActive bool
-	gameTime   float64
-	spawnTimer float64
-	difficulty float64 // Increases over time
+	gameActive  bool
+	gameTime    float64
+	spawnTimer  float64
+	difficulty  difficultyScale // Current level of every curve, see difficulty.go
+	warnedLevel int             // Last difficulty step telegraphed
+	warnedWave  int             // Last boss wave telegraphed
 
 	// Roguelite additions
 	endlessMode   bool        // If true, no timer - wave-based
 	currentWave   int         // Current wave number (endless mode)
-	waveTimer     float64     // Time until next wave
+	waveTimer     float64     // Time until next wave, 0 while a wave is being fought
+	waveSpeed     float64     // Current wave's demogorgon speed multiplier
 	runConfig     *RunConfig  // Active run modifiers
 	combinedMods  RunModifier // Pre-calculated combined modifiers
 	bossActive    bool        // Is there a boss currently spawned?
 	resourceTimer float64     // Timer for resource spawning
+
+	grid    *spatialGrid // Rebuilt every tick for proximity queries
+	arena   *Map         // Bounds and walls for the current run
+	mapName string       // Layout the host asked for, applied on startGame
+
+	hallucinationSeq int              // IDs for client-only phantoms, see hallucination.go
+	extraction       *ExtractionPoint // Nil until it opens, see extraction.go
 }
 
 func NewGame(store *data.Store) *Game {
@@ -106,11 +144,23 @@ func NewGame(store *data.Store) *Game {
 		entities:   make([]*Entity, 0),
 		register:   make(chan *Player),
 		unregister: make(chan *Player),
+		grid:       newSpatialGrid(GridCellSize),
+		arena:      DefaultMap(),
 	}
 	go g.run()
 	return g
 }
 
+// Snapshot reports the run's head count for the lobby.
+func (g *Game) Snapshot() data.LiveStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return data.LiveStatus{
+		Players: len(g.players),
+		InMatch: g.gameActive,
+	}
+}
+
 func (g *Game) run() {
 	ticker := time.NewTicker(time.Second / TickRate)
 	defer ticker.Stop()
@@ -120,18 +170,29 @@ func (g *Game) run() {
 	for {
 		select {
 		case p := <-g.register:
+			meta := LoadPlayerMeta(g.store, p.UserID) // Read before locking, in case p starts the run
 			g.mu.Lock()
 			g.players[p] = true
 			// Start game if first player or reset if needed
+			var started []*Player
 			if len(g.players) == 1 && !g.gameActive {
-				g.startGame()
+				started = g.startGame(map[*Player]*PlayerMeta{p: meta})
 			}
 			g.mu.Unlock()
+			g.countRuns(started)
 			g.sendWelcome(p)
 
 		case p := <-g.unregister:
+			var owed []payout
 			g.mu.Lock()
 			if _, ok := g.players[p]; ok {
+				// Extracted players have banked their run, don't lose it by leaving
+				if p.Extracted && g.gameActive {
+					if pay, ok := g.rewardPlayer(p); ok {
+						owed = append(owed, pay)
+					}
+				}
+				p.clearBody()
 				delete(g.players, p)
 				close(p.Send)
 				p.Conn.Close()
@@ -141,23 +202,73 @@ func (g *Game) run() {
 				g.gameActive = false
 			}
 			g.mu.Unlock()
+			g.pay(owed)
 
 		case <-ticker.C:
 			now := time.Now()
 			dt := now.Sub(lastTime).Seconds()
 			lastTime = now
-			g.update(dt)
+			g.pay(g.update(dt))
+		}
+	}
+}
+
+// restart begins a new run once the last one is over. The players' metas are
+// read, and the new run counted, outside g.mu.
+func (g *Game) restart() {
+	g.mu.Lock()
+	if g.gameActive {
+		g.mu.Unlock()
+		return
+	}
+	players := make([]*Player, 0, len(g.players))
+	for p := range g.players {
+		players = append(players, p)
+	}
+	g.mu.Unlock()
+
+	metas := make(map[*Player]*PlayerMeta, len(players))
+	for _, p := range players {
+		metas[p] = LoadPlayerMeta(g.store, p.UserID)
+	}
+
+	g.mu.Lock()
+	var started []*Player
+	if !g.gameActive {
+		started = g.startGame(metas)
+	}
+	g.mu.Unlock()
+	g.countRuns(started)
+}
+
+// countRuns adds a run to the meta of everyone startGame started. It writes
+// to the database, so callers release g.mu first.
+func (g *Game) countRuns(players []*Player) {
+	for _, p := range players {
+		if _, err := UpdatePlayerMeta(g.store, p.UserID, func(meta *PlayerMeta) error {
+			meta.TotalRuns++
+			return nil
+		}); err != nil {
+			log.Printf("[UPSIDEDOWN] Failed to save meta for %s: %v", p.UserID, err)
 		}
 	}
 }
 
-func (g *Game) startGame() {
+// startGame resets the arena and every player for a new run, using the
+// metas the caller loaded beforehand; anyone who joined since gets a fresh
+// one. It returns the players it started, for countRuns. Caller holds g.mu.
+func (g *Game) startGame(metas map[*Player]*PlayerMeta) []*Player {
 	g.gameActive = true
 	g.gameTime = 0
-	g.difficulty = 1.0
+	g.difficulty = scaleAt(0)
+	g.warnedLevel = 0
+	g.warnedWave = 0
 	g.spawnTimer = 5.0 // First spawn in 5 seconds
 	g.entities = make([]*Entity, 0)
 	g.resourceTimer = 3.0 // Resource spawn timer
+	g.extraction = nil
+	g.arena = mapByName(g.mapName)
+	g.broadcastJSON(map[string]interface{}{"type": "map", "map": g.arena})
 
 	// Initialize run config if not set
 	if g.runConfig == nil {
@@ -172,7 +283,8 @@ func (g *Game) startGame() {
 	g.combinedMods = g.runConfig.GetCombinedModifiers()
 	g.endlessMode = g.runConfig.EndlessMode
 	g.currentWave = 0
-	g.waveTimer = 10.0 // First wave in 10 seconds (endless mode)
+	g.waveTimer = FirstWaveDelay
+	g.waveSpeed = 1.0
 	g.bossActive = false
 
 	// Spawn initial resources (affected by modifiers)
@@ -188,56 +300,119 @@ func (g *Game) startGame() {
 	}
 
 	// Reset all players with meta-progression bonuses
+	started := make([]*Player, 0, len(g.players))
 	for p := range g.players {
-		// Load player's meta-progression
-		meta := LoadPlayerMeta(g.store, p.UserID)
-
-		// Get character class
-		class := CharacterClasses[g.runConfig.SelectedClass]
-		if !meta.UnlockedClasses[g.runConfig.SelectedClass] {
-			class = CharacterClasses[ClassSurvivor] // Fallback to default
+		meta := metas[p]
+		if meta == nil {
+			meta = NewPlayerMeta()
 		}
-		p.SelectedClass = g.runConfig.SelectedClass
-
-		// Calculate max stats with upgrades and class modifiers
-		baseMaxHealth := float64(MaxHealth) * (1.0 + meta.GetUpgradeBonus(UpgradeMaxHealth))
-		p.MaxHealth = baseMaxHealth * class.HealthMod
-		p.Health = p.MaxHealth
-
-		baseMaxSanity := float64(MaxSanity) * (1.0 + meta.GetUpgradeBonus(UpgradeMaxSanity))
-		p.MaxSanity = baseMaxSanity * class.SanityMod
-		p.Sanity = p.MaxSanity
 
-		// Calculate derived stats
-		p.BaseLightRadius = 3.0 * (1.0 + meta.GetUpgradeBonus(UpgradeLightRadius)) * class.LightMod
-		p.LightRadius = p.BaseLightRadius
-		p.SanityRegenMod = (1.0 + meta.GetUpgradeBonus(UpgradeSanityRegen)) * class.SanityRegenMod
-		p.SpeedMod = (1.0 + meta.GetUpgradeBonus(UpgradeMoveSpeed)) * class.SpeedMod
-		p.DamageResist = meta.GetUpgradeBonus(UpgradeDamageResist)
-		p.FlareDuration = 15.0 * class.FlareDuration
-
-		// Starting flares from upgrades + class
-		startFlares := int(meta.GetUpgradeBonus(UpgradeStartFlares)*100/100) + class.StartingFlares
-		p.AvailableFlares = startFlares
+		// Get character class: the player's pick, else their saved one
+		classID := p.classChoice
+		if classID == "" {
+			classID = meta.SelectedClass
+		}
+		if _, exists := CharacterClasses[classID]; !exists || !meta.UnlockedClasses[classID] {
+			classID = ClassSurvivor // Fallback to default
+		}
+		p.SelectedClass = classID
+		applyClassStats(p, meta, CharacterClasses[classID])
 
 		p.Score = 0
 		p.Kills = 0
 		p.Alive = true
+		p.Extracted = false
+		p.extractHold = 0
+		p.extractedAt = 0
+		p.rewarded = false
+		p.shards = 0
+		p.clearBody()
 		p.HasFlare = false
 		p.FlareTime = 0
-		p.Pos = Vec2{X: rand.Float64()*20 - 10, Y: rand.Float64()*20 - 10}
+		p.Pos = g.arena.RandomOpenPoint(10, PlayerRadius)
+		p.lastMove = time.Time{}
+		started = append(started, p)
+	}
+	return started
+}
+
+// applyClassStats sets a player's run stats from upgrades and class modifiers.
+func applyClassStats(p *Player, meta *PlayerMeta, class CharacterClass) {
+	// Calculate max stats with upgrades and class modifiers
+	baseMaxHealth := float64(MaxHealth) * (1.0 + meta.GetUpgradeBonus(UpgradeMaxHealth))
+	p.MaxHealth = baseMaxHealth * class.HealthMod
+	p.Health = p.MaxHealth
+
+	baseMaxSanity := float64(MaxSanity) * (1.0 + meta.GetUpgradeBonus(UpgradeMaxSanity))
+	p.MaxSanity = baseMaxSanity * class.SanityMod
+	p.Sanity = p.MaxSanity
+
+	// Calculate derived stats
+	p.BaseLightRadius = 3.0 * (1.0 + meta.GetUpgradeBonus(UpgradeLightRadius)) * class.LightMod
+	p.LightRadius = p.BaseLightRadius
+	p.SanityRegenMod = (1.0 + meta.GetUpgradeBonus(UpgradeSanityRegen)) * class.SanityRegenMod
+	p.SpeedMod = (1.0 + meta.GetUpgradeBonus(UpgradeMoveSpeed)) * class.SpeedMod
+	p.DamageResist = meta.GetUpgradeBonus(UpgradeDamageResist)
+	p.FlareDuration = 15.0 * class.FlareDuration
+
+	// Starting flares from upgrades + class. GetUpgradeBonus is a fraction,
+	// Prepared's bonus is whole flares, hence the *100
+	startFlares := int(math.Round(meta.GetUpgradeBonus(UpgradeStartFlares)*100)) + class.StartingFlares
+	p.AvailableFlares = startFlares
+}
 
-		// Increment total runs
-		meta.TotalRuns++
-		SavePlayerMeta(g.store, p.UserID, meta)
+// handleSelectClass picks the class for the player's next run. Only unlocked
+// classes are accepted; the reply carries the stats the run would start with.
+// It reads and saves the meta, so it runs without g.mu.
+func (g *Game) handleSelectClass(p *Player, classID ClassID) {
+	class, exists := CharacterClasses[classID]
+	meta := LoadPlayerMeta(g.store, p.UserID)
+	if !exists || !meta.UnlockedClasses[classID] {
+		g.sendTo(p, map[string]interface{}{
+			"type":  "class_rejected",
+			"class": classID,
+			"error": "class not unlocked",
+		})
+		return
+	}
+
+	meta.SelectedClass = classID
+	if _, err := UpdatePlayerMeta(g.store, p.UserID, func(saved *PlayerMeta) error {
+		saved.SelectedClass = classID
+		return nil
+	}); err != nil {
+		log.Printf("[UPSIDEDOWN] Failed to save meta for %s: %v", p.UserID, err)
 	}
+
+	g.mu.Lock()
+	p.classChoice = classID
+	active := g.gameActive
+	g.mu.Unlock()
+
+	var preview Player
+	applyClassStats(&preview, meta, class)
+	g.sendTo(p, map[string]interface{}{
+		"type":  "class_selected",
+		"class": class,
+		"effective": map[string]interface{}{
+			"maxHealth":     preview.MaxHealth,
+			"maxSanity":     preview.MaxSanity,
+			"lightRadius":   preview.BaseLightRadius,
+			"sanityRegen":   preview.SanityRegenMod,
+			"speed":         preview.SpeedMod,
+			"damageResist":  preview.DamageResist,
+			"flareDuration": preview.FlareDuration,
+			"flares":        preview.AvailableFlares,
+		},
+		"appliesNextRun": active,
+	})
 }
 
 func (g *Game) spawnResource(resType string) {
 	e := &Entity{
 		ID:     "r_" + uuid.NewString()[:8],
 		Type:   resType,
-		Pos:    Vec2{X: rand.Float64()*60 - 30, Y: rand.Float64()*60 - 30},
+		Pos:    g.arena.RandomOpenPoint(30, 0.5),
 		Active: true,
 	}
 	g.entities = append(g.entities, e)
@@ -245,24 +420,15 @@ func (g *Game) spawnResource(resType string) {
 
 func (g *Game) spawnDemogorgon() {
 	// Spawn at edge of map
-	edge := rand.Intn(4)
-	var pos Vec2
-	switch edge {
-	case 0:
-		pos = Vec2{X: -35, Y: rand.Float64()*70 - 35}
-	case 1:
-		pos = Vec2{X: 35, Y: rand.Float64()*70 - 35}
-	case 2:
-		pos = Vec2{X: rand.Float64()*70 - 35, Y: -35}
-	case 3:
-		pos = Vec2{X: rand.Float64()*70 - 35, Y: 35}
-	}
+	pos := g.arena.RandomEdgePoint(5, DemoRadius)
 
 	e := &Entity{
-		ID:     "d_" + uuid.NewString()[:8],
-		Type:   "demogorgon",
-		Pos:    pos,
-		Active: true,
+		ID:        "d_" + uuid.NewString()[:8],
+		Type:      "demogorgon",
+		Pos:       pos,
+		Active:    true,
+		Health:    DemoHealth,
+		MaxHealth: DemoHealth,
 	}
 	g.entities = append(g.entities, e)
 }
@@ -271,10 +437,10 @@ func (g *Game) spawnBoss(health int) {
 	// Boss spawns further out
 	angle := rand.Float64() * 2 * math.Pi
 	dist := 40.0
-	pos := Vec2{
+	pos := g.arena.Clamp(Vec2{
 		X: math.Cos(angle) * dist,
 		Y: math.Sin(angle) * dist,
-	}
+	}, BossRadius)
 
 	e := &Entity{
 		ID:        "boss_" + uuid.NewString()[:8],
@@ -289,67 +455,120 @@ func (g *Game) spawnBoss(health int) {
 	g.entities = append(g.entities, e)
 }
 
-func (g *Game) update(dt float64) {
+func (g *Game) bossAlive() bool {
+	for _, e := range g.entities {
+		if e.IsBoss && e.Active {
+			return true
+		}
+	}
+	return false
+}
+
+// enemiesAlive reports whether any demogorgon, boss included, is still up.
+func (g *Game) enemiesAlive() bool {
+	for _, e := range g.entities {
+		if e.Active && (e.Type == "demogorgon" || e.Type == "demogorgon_boss") {
+			return true
+		}
+	}
+	return false
+}
+
+// radius is how much room e takes up when moving.
+func (e *Entity) radius() float64 {
+	if e.IsBoss {
+		return BossRadius
+	}
+	return DemoRadius
+}
+
+// updateWaves runs endless mode: a wave is spawned, fought until every
+// demogorgon in it is dead, then WaveBreather later the next one comes.
+// Clearing a wave heals the living and revives the fallen. Caller holds g.mu.
+func (g *Game) updateWaves(dt float64) {
+	if g.waveTimer > 0 {
+		g.waveTimer -= dt
+		if g.waveTimer <= 0 {
+			g.startWave()
+		}
+		return
+	}
+	if g.enemiesAlive() {
+		return
+	}
+
+	// Wave cleared
+	g.bossActive = false
+	g.waveTimer = WaveBreather
+	g.broadcastJSON(map[string]interface{}{"type": "wave_cleared", "wave": g.currentWave, "next": WaveBreather})
+	for p := range g.players {
+		if p.Extracted {
+			continue
+		} else if p.Alive {
+			p.Health = math.Min(p.MaxHealth, p.Health+20)
+			p.Sanity = math.Min(p.MaxSanity, p.Sanity+30)
+		} else {
+			// Revive dead players
+			p.Alive = true
+			p.clearBody()
+			p.Health = p.MaxHealth * 0.4
+			p.Sanity = p.MaxSanity * 0.4
+			p.Pos = g.arena.RandomOpenPoint(5, PlayerRadius)
+		}
+	}
+}
+
+// startWave spawns the next wave. Caller holds g.mu.
+func (g *Game) startWave() {
+	g.waveTimer = 0
+	g.currentWave++
+	wave := GenerateWave(g.currentWave)
+	g.waveSpeed = wave.SpeedMod
+
+	count := int(float64(wave.DemogorgonCount) * g.combinedMods.SpawnRateMod)
+	if count < 1 {
+		count = 1 // A wave always has someone to clear
+	}
+	for i := 0; i < count; i++ {
+		g.spawnDemogorgon()
+	}
+	if wave.HasBoss {
+		g.spawnBoss(wave.BossHealth)
+		g.bossActive = true
+	}
+}
+
+// update advances the run by dt. It returns the payouts owed if the run
+// ended, for the caller to pay once g.mu is released.
+func (g *Game) update(dt float64) []payout {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	if !g.gameActive || len(g.players) == 0 {
-		return
+		return nil
 	}
 
 	g.gameTime += dt
-	g.difficulty = 1.0 + g.gameTime/60.0 // Increase difficulty over time
+	g.difficulty = scaleAt(difficultyLevel(g.gameTime))
+	g.telegraph()
 
 	// GAME END CHECK
 	if !g.endlessMode && g.gameTime >= GameDuration {
-		g.endGame()
-		return
+		return g.endGame()
 	}
 
 	// WAVE SYSTEM (Endless Only)
 	if g.endlessMode {
-		g.waveTimer -= dt
-		if g.waveTimer <= 0 && !g.bossActive {
-			g.currentWave++
-			wave := GenerateWave(g.currentWave)
-
-			// Spawn wave enemies
-			count := int(float64(wave.DemogorgonCount) * g.combinedMods.SpawnRateMod)
-			for i := 0; i < count; i++ {
-				g.spawnDemogorgon()
-			}
-
-			if wave.HasBoss {
-				g.spawnBoss(wave.BossHealth)
-				g.bossActive = true
-			}
-
-			// Reset timer for next wave
-			g.waveTimer = 30.0 // New wave every 30s
-
-			// Heal players slightly on wave complete
-			for p := range g.players {
-				if p.Alive {
-					p.Health = math.Min(p.MaxHealth, p.Health+20)
-					p.Sanity = math.Min(p.MaxSanity, p.Sanity+30)
-				} else {
-					// Revive dead players
-					p.Alive = true
-					p.Health = p.MaxHealth * 0.4
-					p.Sanity = p.MaxSanity * 0.4
-					p.Pos = Vec2{X: rand.Float64()*10 - 5, Y: rand.Float64()*10 - 5}
-				}
-			}
-		}
+		g.updateWaves(dt)
 	} else {
 		// CLASSIC SPAWN LOGIC
 		g.spawnTimer -= dt
 		if g.spawnTimer <= 0 {
-			demoCount := int(g.difficulty * g.combinedMods.SpawnRateMod)
+			demoCount := int(g.difficulty.Spawn * g.combinedMods.SpawnRateMod)
 			for i := 0; i < demoCount; i++ {
 				g.spawnDemogorgon()
 			}
-			g.spawnTimer = (DemoSpawnInterval / g.difficulty)
+			g.spawnTimer = (DemoSpawnInterval / g.difficulty.Spawn)
 		}
 	}
 
@@ -366,10 +585,24 @@ func (g *Game) update(dt float64) {
 		g.resourceTimer = 10.0 / g.combinedMods.ResourceMod
 	}
 
+	// Drop consumed/killed entities and re-bucket the rest for this tick
+	active := g.entities[:0]
+	for _, e := range g.entities {
+		if e.Active {
+			active = append(active, e)
+		}
+	}
+	for i := len(active); i < len(g.entities); i++ {
+		g.entities[i] = nil
+	}
+	g.entities = active
+	g.grid.rebuild(g.entities)
+	g.updateExtraction(dt)
+
 	// Update players
 	aliveCount := 0
 	for p := range g.players {
-		if !p.Alive {
+		if !p.inRun() {
 			continue
 		}
 		aliveCount++
@@ -377,15 +610,13 @@ func (g *Game) update(dt float64) {
 		// Check if player is near any light source or has flare
 		nearLight := p.HasFlare && p.FlareTime > 0
 		if !nearLight {
-			for _, e := range g.entities {
-				if e.Active && e.Type == ResourceLightOrb {
-					dist := distance(p.Pos, e.Pos)
-					if dist < 5 {
-						nearLight = true
-						break
-					}
+			g.grid.query(p.Pos, 5, func(e *Entity) bool {
+				if e.Type == ResourceLightOrb {
+					nearLight = true
+					return false
 				}
-			}
+				return true
+			})
 		}
 
 		// Update flare
@@ -403,16 +634,17 @@ func (g *Game) update(dt float64) {
 			p.Sanity = math.Min(p.MaxSanity, p.Sanity+regen*dt)
 			p.LightRadius = 6.0 // Boosted light radius when safe
 		} else {
-			drain := SanityDrainRate * g.difficulty * g.combinedMods.SanityDrainMod
+			drain := SanityDrainRate * g.difficulty.Sanity * g.combinedMods.SanityDrainMod
 			p.Sanity = math.Max(0, p.Sanity-drain*dt)
 			// Dimming light mechanic
 			ratio := p.Sanity / p.MaxSanity
 			p.LightRadius = math.Max(1.0, p.BaseLightRadius*ratio)
 		}
+		g.maybeHallucinate(p, dt)
 
 		// Health drain when insane
 		if p.Sanity <= 0 {
-			drain := HealthDrainRate * g.difficulty * (1.0 - p.DamageResist)
+			drain := HealthDrainRate * g.difficulty.Damage * (1.0 - p.DamageResist)
 			p.Health -= drain * dt
 			if p.Health <= 0 {
 				p.Alive = false
@@ -421,34 +653,24 @@ func (g *Game) update(dt float64) {
 		}
 
 		// Survival score
-		p.Score += int(dt * 10 * g.difficulty * g.combinedMods.EmberMultiplier)
+		p.Score += int(dt * 10 * g.difficulty.Score * g.combinedMods.EmberMultiplier)
 	}
 
-	// Game over if everyone dead
+	g.updateRevives(dt)
+
+	// Game over if everyone is dead or out
 	if aliveCount == 0 && len(g.players) > 0 {
-		g.endGame()
-		return
+		return g.endGame()
 	}
 
-	// Update demogorgons
+	// Update demogorgons, each going after the nearest player it can see
+	detectionRange := 20.0 * g.combinedMods.EnemySightMod
+	targets := g.nearestPlayers(detectionRange)
 	for _, e := range g.entities {
 		if (e.Type != "demogorgon" && e.Type != "demogorgon_boss") || !e.Active {
 			continue
 		}
-
-		// Find nearest alive player
-		var nearestPlayer *Player
-		nearestDist := math.MaxFloat64
-		for p := range g.players {
-			if !p.Alive {
-				continue
-			}
-			dist := distance(e.Pos, p.Pos)
-			if dist < nearestDist {
-				nearestDist = dist
-				nearestPlayer = p
-			}
-		}
+		nearestPlayer := targets[e]
 
 		// Skip if stunned
 		if e.StunnedUntil > g.gameTime {
@@ -458,9 +680,12 @@ func (g *Game) update(dt float64) {
 		if nearestPlayer != nil {
 			// Move towards player
 			// Base speed modified by difficulty, run mods, and entity type
-			speed := 3.0 * g.difficulty * g.combinedMods.EnemySpeedMod
+			speed := EnemyBaseSpeed * g.difficulty.Speed * g.combinedMods.EnemySpeedMod
+			if g.endlessMode {
+				speed *= g.waveSpeed
+			}
 			if e.IsBoss {
-				speed *= 1.2
+				speed *= BossSpeedMultiple
 			}
 			if e.SpeedMod > 0 {
 				speed *= e.SpeedMod
@@ -476,11 +701,13 @@ func (g *Game) update(dt float64) {
 			dist := math.Sqrt(dx*dx + dy*dy)
 
 			// Detect player check
-			detectionRange := 20.0 * g.combinedMods.EnemySightMod
 			if dist < detectionRange {
 				if dist > 0 {
-					e.Pos.X += (dx / dist) * speed * dt
-					e.Pos.Y += (dy / dist) * speed * dt
+					next := Vec2{
+						X: e.Pos.X + (dx/dist)*speed*dt,
+						Y: e.Pos.Y + (dy/dist)*speed*dt,
+					}
+					e.Pos = g.arena.Move(e.Pos, next, e.radius())
 				}
 
 				// Attack
@@ -490,9 +717,9 @@ func (g *Game) update(dt float64) {
 				}
 
 				if dist < attackRange {
-					damage := 20.0 * g.difficulty
+					damage := EnemyContactDPS * g.difficulty.Damage
 					if e.IsBoss {
-						damage = 40.0
+						damage = BossContactDPS
 					}
 					// Apply player resistance
 					damage *= (1.0 - nearestPlayer.DamageResist)
@@ -509,141 +736,235 @@ func (g *Game) update(dt float64) {
 
 	// Check player-resource collisions
 	for p := range g.players {
-		if !p.Alive {
+		if !p.inRun() {
 			continue
 		}
-		for _, e := range g.entities {
-			if !e.Active || e.Type == "demogorgon" {
-				continue
+		g.grid.query(p.Pos, 2, func(e *Entity) bool {
+			if !e.Active {
+				return true
 			}
-			if distance(p.Pos, e.Pos) < 2 {
-				switch e.Type {
-				case ResourceLightOrb:
-					p.Sanity = math.Min(MaxSanity, p.Sanity+30)
-					p.Score += 50
-					e.Active = false
-				case ResourceBattery:
-					p.Health = math.Min(MaxHealth, p.Health+25)
-					p.Score += 30
-					e.Active = false
-				case ResourceFlare:
-					p.AvailableFlares++
-					p.Score += 100
-					e.Active = false
-				}
+			switch e.Type {
+			case ResourceLightOrb:
+				p.Sanity = math.Min(p.MaxSanity, p.Sanity+30)
+				p.Score += 50
+				e.Active = false
+			case ResourceBattery:
+				p.Health = math.Min(p.MaxHealth, p.Health+25)
+				p.Score += 30
+				e.Active = false
+			case ResourceFlare:
+				p.AvailableFlares++
+				p.Score += 100
+				e.Active = false
 			}
-		}
+			return true
+		})
 	}
 
 	g.broadcastState()
+	return nil
 }
 
-func (g *Game) endGame() {
+// endGame finishes the run and returns everyone's payouts, which the caller
+// pays after releasing g.mu. Caller holds g.mu.
+func (g *Game) endGame() []payout {
 	g.gameActive = false
 
 	// Calculate rewards
+	extracted := []string{}
+	shards := make(map[string]int, len(g.players)) // Player ID -> ember shards earned
+	var owed []payout
 	for p := range g.players {
-		if p.UserID == "" || p.UserID == "guest" {
-			continue
+		if p.Extracted {
+			extracted = append(extracted, p.ID)
+		}
+		if pay, ok := g.rewardPlayer(p); ok {
+			owed = append(owed, pay)
 		}
+		shards[p.ID] = p.shards
+	}
+
+	// Send game over
+	g.broadcastJSON(map[string]interface{}{
+		"type":      "game_over",
+		"survived":  g.gameTime,
+		"wave":      g.currentWave, // Send reached wave
+		"extracted": extracted,
+		"shards":    shards,
+	})
+	return owed
+}
 
-		// Rewards based on score
-		coins := p.Score / 10
-		trophies := p.Score / 50
-		exp := p.Score / 5
+// payout is what one player earned from a run, worked out under g.mu and
+// saved by pay once it's released.
+type payout struct {
+	userID               string
+	coins, trophies, exp int
+	shards, kills, wave  int
+	runTime              float64
+	survivor             bool // Lasted the full classic run without extracting
+}
 
-		if p.Alive {
-			// Survival bonus
-			coins += 100
-			trophies += 20
-			exp += 200
-		}
+// rewardPlayer works out p's run payout, once, reporting false if it was
+// already paid. Caller holds g.mu.
+func (g *Game) rewardPlayer(p *Player) (payout, bool) {
+	if p.rewarded {
+		return payout{}, false
+	}
+	p.rewarded = true
+	runTime := g.runTime(p)
+
+	// Rewards based on score
+	coins := p.Score / 10
+	trophies := p.Score / 50
+	exp := p.Score / 5
+
+	if p.Alive {
+		// Survival bonus
+		coins += 100
+		trophies += 20
+		exp += 200
+	}
 
-		// Calculate Roguelite Currency (Ember Shards)
-		shardMultiplier := g.combinedMods.EmberMultiplier
-		shards := CalculateEmberShards(g.gameTime, p.Score, p.Kills, p.Alive, shardMultiplier)
+	// Calculate Roguelite Currency (Ember Shards)
+	shardMultiplier := g.combinedMods.EmberMultiplier
+	shards := CalculateEmberShards(runTime, p.Score, p.Kills, p.Alive, shardMultiplier)
+	if p.Extracted {
+		shards = int(float64(shards) * ExtractionShardMultiplier)
+	}
 
-		// Update backend stats
-		g.store.AdjustEmberShards(p.UserID, shards)
+	p.shards = shards
+	wave := 0
+	if g.endlessMode {
+		wave = g.currentWave
+	}
+	return payout{
+		userID:   p.UserID,
+		coins:    coins,
+		trophies: trophies,
+		exp:      exp,
+		shards:   shards,
+		kills:    p.Kills,
+		wave:     wave,
+		runTime:  runTime,
+		survivor: p.Alive && !p.Extracted && g.gameTime >= GameDuration-1,
+	}, true
+}
 
-		// Update Meta Stats
-		meta := LoadPlayerMeta(g.store, p.UserID)
-		if g.gameTime > meta.BestSurvival {
-			meta.BestSurvival = g.gameTime
-		}
-		meta.TotalKills += p.Kills
-		if g.endlessMode && g.currentWave > meta.HighestWave {
-			meta.HighestWave = g.currentWave
+// pay saves payouts to the database, so callers release g.mu first.
+func (g *Game) pay(payouts []payout) {
+	for _, pay := range payouts {
+		// Shards and run stats go into the meta in one save
+		if _, err := UpdatePlayerMeta(g.store, pay.userID, func(meta *PlayerMeta) error {
+			meta.EmberShards += pay.shards
+			if pay.runTime > meta.BestSurvival {
+				meta.BestSurvival = pay.runTime
+			}
+			meta.TotalKills += pay.kills
+			if pay.wave > meta.HighestWave {
+				meta.HighestWave = pay.wave
+			}
+			return nil
+		}); err != nil {
+			log.Printf("[UPSIDEDOWN] Failed to save meta for %s: %v", pay.userID, err)
 		}
-		SavePlayerMeta(g.store, p.UserID, meta)
 
 		// Use centralized result processor to handle Level Up logic correctly
-		err := g.store.ProcessGameResult(p.UserID, trophies, coins, exp)
-		if err != nil {
-			// Log error if needed, but continue
+		if _, err := g.store.ProcessGameResult(pay.userID, pay.trophies, pay.coins, pay.exp, data.ReasonUpsideDown); err != nil {
+			log.Printf("[UPSIDEDOWN] Failed to save result for %s: %v", pay.userID, err)
 		}
 
 		// Award medal for surviving full duration
-		if p.Alive && g.gameTime >= GameDuration-1 {
-			g.store.AwardMedals(p.UserID, "upside_down_survivor")
+		if pay.survivor {
+			g.store.AwardMedals(pay.userID, "upside_down_survivor")
 		}
 	}
-
-	// Send game over
-	g.broadcastJSON(map[string]interface{}{
-		"type":     "game_over",
-		"survived": g.gameTime,
-		"wave":     g.currentWave, // Send reached wave
-	})
 }
 
 func (g *Game) sendWelcome(p *Player) {
+	g.mu.Lock()
+	arena, active := g.arena, g.gameActive
+	g.mu.Unlock()
+
 	g.sendTo(p, map[string]interface{}{
 		"type":     "welcome",
 		"id":       p.ID,
 		"nickname": p.Nickname,
-		"active":   g.gameActive,
+		"active":   active,
+		"map":      arena,
 	})
 }
 
+// VisibilityMargin is added to a player's light radius when deciding what
+// they can see, so things don't pop in exactly at the edge of the glow.
+const VisibilityMargin = 1.5
+
+// broadcastState sends every player their own view of the world: entities and
+// other players only show up when they're inside the viewer's light. The
+// client never learns about anything lurking in the dark.
 func (g *Game) broadcastState() {
-	// Build player list with sanity-based visibility
-	players := make([]map[string]interface{}, 0)
-	for p := range g.players {
-		players = append(players, map[string]interface{}{
-			"id":          p.ID,
-			"name":        p.Nickname,
-			"pos":         p.Pos,
-			"health":      p.Health,
-			"sanity":      p.Sanity,
-			"score":       p.Score,
-			"alive":       p.Alive,
-			"hasFlare":    p.HasFlare,
-			"flares":      p.AvailableFlares,
-			"lightRadius": p.LightRadius,
-		})
-	}
+	for viewer := range g.players {
+		sight := viewer.LightRadius + VisibilityMargin
 
-	// Only send active entities
-	entities := make([]map[string]interface{}, 0)
-	for _, e := range g.entities {
-		if e.Active {
+		players := make([]map[string]interface{}, 0, len(g.players))
+		for p := range g.players {
+			if p != viewer && !g.illuminated(viewer, p, sight) {
+				continue
+			}
+			players = append(players, map[string]interface{}{
+				"id":          p.ID,
+				"name":        p.Nickname,
+				"pos":         p.Pos,
+				"health":      p.Health,
+				"sanity":      p.Sanity,
+				"score":       p.Score,
+				"alive":       p.Alive,
+				"extracted":   p.Extracted,
+				"hasFlare":    p.HasFlare,
+				"flares":      p.AvailableFlares,
+				"lightRadius": p.LightRadius,
+				"reviving":    p.reviveFraction(),
+				"maxHealth":   p.MaxHealth,
+				"maxSanity":   p.MaxSanity,
+				"speed":       p.SpeedMod, // Movement is client side, so the client scales by this
+			})
+		}
+
+		// Only send active entities the viewer can actually see
+		entities := make([]map[string]interface{}, 0)
+		for _, e := range g.entities {
+			if !e.Active || distance(viewer.Pos, e.Pos) > sight {
+				continue
+			}
 			entities = append(entities, map[string]interface{}{
 				"id":   e.ID,
 				"type": e.Type,
 				"pos":  e.Pos,
 			})
 		}
+
+		g.sendTo(viewer, map[string]interface{}{
+			"type":       "state",
+			"time":       g.gameTime,
+			"maxTime":    GameDuration,
+			"difficulty": g.difficulty,
+			"endless":    g.endlessMode,
+			"wave":       g.currentWave,
+			"players":    players,
+			"entities":   entities,
+			"extraction": g.extraction, // Everyone sees it once it's open
+			"extracting": extractionProgress(viewer),
+		})
 	}
+}
 
-	g.broadcastJSON(map[string]interface{}{
-		"type":       "state",
-		"time":       g.gameTime,
-		"maxTime":    GameDuration,
-		"difficulty": g.difficulty,
-		"players":    players,
-		"entities":   entities,
-	})
+// illuminated reports whether other is lit up for viewer: either inside the
+// viewer's sight or carrying their own light (a burning flare).
+func (g *Game) illuminated(viewer, other *Player, sight float64) bool {
+	if other.HasFlare && other.FlareTime > 0 {
+		return true
+	}
+	return distance(viewer.Pos, other.Pos) <= sight+other.LightRadius
 }
 
 func (g *Game) broadcastJSON(v interface{}) {
@@ -673,66 +994,41 @@ func distance(a, b Vec2) float64 {
 var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 func (g *Game) HandleWS(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.WebsocketUser(w, r)
+	if !ok {
+		return
+	}
+	u, ok := g.store.GetUser(userID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-
-	userID := r.URL.Query().Get("userID")
-	nick := "Stranger"
-	if userID != "" {
-		if u, ok := g.store.GetUser(userID); ok {
-			nick = u.Nickname
-		}
-	}
+	nick := u.Nickname
 
 	// Parse Roguelite Params
+	// Empty means "use the saved selection", resolved in startGame
 	classID := ClassID(r.URL.Query().Get("class"))
-	if classID == "" {
-		classID = ClassSurvivor
-	}
 
 	modsStr := r.URL.Query().Get("mods")
 	endless := r.URL.Query().Get("endless") == "true"
 
 	g.mu.Lock()
+	spawn := g.arena.RandomOpenPoint(10, PlayerRadius)
 	// Host Logic: First player sets the run modifiers
 	// (Check against <= 1 because this player is not registered yet, but might be re-connecting?)
 	// Actually register channel logic handles the counting. But here we can check len(g.players)
 	if len(g.players) == 0 {
-		if modsStr != "" {
-			// simplistic split by comma
-			// would need strings package but trying to avoid new imports if possible
-			// actually we can just pass json since valid ModifierIDs don't have commas
-			// let's assume javascript joins with comma
-			// simple manual split or rely on one mod for now?
-			// Let's assume frontend sends valid data
-			// Actually strings import is likely already there or easy to add.
-			// But let's cheat and assume proper strings.Split wrapper isn't needed if we assume frontend sends correct IDs.
-			// Actually, let's just use strings.Split since "strings" is a standard lib.
-		}
+		g.mapName = r.URL.Query().Get("map")
 
 		// Re-initialize run config
 		g.runConfig = &RunConfig{
-			ActiveModifiers: []ModifierID{}, // Fill this
+			ActiveModifiers: parseModifiers(modsStr),
 			EndlessMode:     endless,
-			SelectedClass:   classID, // This might be ignored in favor of individual class
-		}
-
-		// quick parse mods
-		currentMod := ""
-		for _, char := range modsStr {
-			if char == ',' {
-				if currentMod != "" {
-					g.runConfig.ActiveModifiers = append(g.runConfig.ActiveModifiers, ModifierID(currentMod))
-				}
-				currentMod = ""
-			} else {
-				currentMod += string(char)
-			}
-		}
-		if currentMod != "" {
-			g.runConfig.ActiveModifiers = append(g.runConfig.ActiveModifiers, ModifierID(currentMod))
+			SelectedClass:   classID, // Host pick; each player runs with their own choice
 		}
 	}
 	g.mu.Unlock()
@@ -743,14 +1039,17 @@ func (g *Game) HandleWS(w http.ResponseWriter, r *http.Request) {
 		Nickname:    nick,
 		Conn:        conn,
 		Send:        make(chan []byte, 256),
-		Pos:         Vec2{X: rand.Float64()*20 - 10, Y: rand.Float64()*20 - 10},
+		Pos:         spawn,
+		classChoice: classID,
 		Health:      MaxHealth,
 		Sanity:      MaxSanity,
 		Alive:       true,
 		LightRadius: 3.0,
+		SpeedMod:    1.0,
 	}
 
 	g.register <- p
+	g.store.SetActivity(userID, data.ActivityUpsideDown)
 	go g.writePump(p)
 	g.readPump(p)
 }
@@ -765,7 +1064,11 @@ func (g *Game) writePump(p *Player) {
 }
 
 func (g *Game) readPump(p *Player) {
-	defer func() { g.unregister <- p; p.Conn.Close() }()
+	defer func() {
+		g.unregister <- p
+		g.store.ClearActivity(p.UserID, data.ActivityUpsideDown)
+		p.Conn.Close()
+	}()
 	for {
 		_, data, err := p.Conn.ReadMessage()
 		if err != nil {
@@ -776,19 +1079,33 @@ func (g *Game) readPump(p *Player) {
 			continue
 		}
 
+		// These two touch the database, so they take g.mu themselves
+		switch msg["type"] {
+		case "restart":
+			g.restart()
+			continue
+		case "select_class":
+			if id, ok := msg["class"].(string); ok {
+				g.handleSelectClass(p, ClassID(id))
+			}
+			continue
+		}
+
 		g.mu.Lock()
 		switch msg["type"] {
 		case "move":
-			if p.Alive {
+			if p.inRun() {
 				if pos, ok := msg["pos"].(map[string]interface{}); ok {
-					p.Pos.X = pos["x"].(float64)
-					p.Pos.Y = pos["y"].(float64)
+					x, okX := pos["x"].(float64)
+					y, okY := pos["y"].(float64)
+					if okX && okY {
+						// No faster than they can run, and walls are solid:
+						// slide up to them, never through
+						to := p.limitMove(Vec2{X: x, Y: y}, time.Now())
+						p.Pos = g.arena.Move(p.Pos, to, PlayerRadius)
+					}
 				}
 			}
-		case "restart":
-			if !g.gameActive {
-				g.startGame()
-			}
 		case "use_flare":
 			g.handleFlareUse(p)
 		case "attack":
@@ -801,10 +1118,10 @@ func (g *Game) readPump(p *Player) {
 }
 
 func (g *Game) handleFlareUse(p *Player) {
-	if p.Alive && p.AvailableFlares > 0 && !p.HasFlare {
+	if p.inRun() && p.AvailableFlares > 0 && !p.HasFlare {
 		p.AvailableFlares--
 		p.HasFlare = true
-		p.FlareTime = 15 // 15 seconds duration
+		p.FlareTime = p.FlareDuration
 		p.LightRadius = 10.0
 
 		// Stun/Pushback nearby enemies
@@ -816,8 +1133,8 @@ func (g *Game) handleFlareUse(p *Player) {
 					// Push back
 					dx := e.Pos.X - p.Pos.X
 					dy := e.Pos.Y - p.Pos.Y
-					e.Pos.X += dx * 2
-					e.Pos.Y += dy * 2
+					pushed := Vec2{X: e.Pos.X + dx*2, Y: e.Pos.Y + dy*2}
+					e.Pos = g.arena.Move(e.Pos, pushed, DemoRadius)
 				}
 			}
 		}
@@ -825,17 +1142,17 @@ func (g *Game) handleFlareUse(p *Player) {
 }
 
 func (g *Game) handleAttack(p *Player, angle float64) {
-	if !p.Alive {
+	if !p.inRun() {
 		return
 	}
 
-	// Raycast parameters
+	// Raycast parameters: the swing stops at the first wall in the way
 	reach := 20.0
-	ex := p.Pos.X + math.Cos(angle)*reach
-	ey := p.Pos.Y + math.Sin(angle)*reach
+	end, _ := g.arena.walk(p.Pos, Vec2{X: p.Pos.X + math.Cos(angle)*reach, Y: p.Pos.Y + math.Sin(angle)*reach}, 0)
+	ex, ey := end.X, end.Y
 
 	for _, e := range g.entities {
-		if !e.Active || e.Type != "demogorgon" {
+		if !e.Active || (e.Type != "demogorgon" && e.Type != "demogorgon_boss") {
 			continue
 		}
 
@@ -853,18 +1170,63 @@ func (g *Game) handleAttack(p *Player, angle float64) {
 		cx := p.Pos.X + t*dx
 		cy := p.Pos.Y + t*dy
 
-		// Distance to entity (radius ~1.5)
+		// Distance to entity (radius ~1.5, bosses are bigger targets)
 		distX := e.Pos.X - cx
 		distY := e.Pos.Y - cy
+		hitRadius := 0.7 + e.radius()
 
-		if (distX*distX + distY*distY) < (1.5 * 1.5) {
+		if (distX*distX + distY*distY) < hitRadius*hitRadius {
 			// Hit!
-			e.Health -= 34 // 3 hits to kill
+			e.Health -= AttackDamage // 3 hits to kill
 			if e.Health <= 0 {
 				e.Active = false
+				p.Kills++
 				p.Score += 100
+				if e.IsBoss {
+					p.Score += 400
+				}
 			}
 			break
 		}
 	}
 }
+
+// parseModifiers reads the comma separated ?mods= list. Unknown IDs and
+// repeats are dropped, so a crafted URL can't stack one modifier's ember
+// bonus.
+func parseModifiers(raw string) []ModifierID {
+	mods := []ModifierID{}
+	seen := make(map[ModifierID]bool)
+	for _, part := range strings.Split(raw, ",") {
+		id := ModifierID(strings.TrimSpace(part))
+		if _, ok := RunModifiers[id]; !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		mods = append(mods, id)
+	}
+	return mods
+}
+
+// limitMove caps a move towards to at what p could have covered since their
+// last one at MaxMoveSpeed scaled by their SpeedMod. Unused distance is
+// banked for up to MoveBankLimit, so packets arriving in a bunch still land.
+// Caller holds g.mu.
+func (p *Player) limitMove(to Vec2, now time.Time) Vec2 {
+	speed := MaxMoveSpeed * p.SpeedMod
+	if p.lastMove.IsZero() {
+		p.moveBudget = speed * MoveBankLimit
+	} else {
+		p.moveBudget = math.Min(p.moveBudget+speed*now.Sub(p.lastMove).Seconds(), speed*MoveBankLimit)
+	}
+	p.lastMove = now
+
+	dist := distance(p.Pos, to)
+	if dist <= p.moveBudget {
+		p.moveBudget -= dist
+		return to
+	}
+	t := p.moveBudget / dist
+	p.moveBudget = 0
+	return Vec2{X: p.Pos.X + (to.X-p.Pos.X)*t, Y: p.Pos.Y + (to.Y-p.Pos.Y)*t}
+}
@@ -0,0 +1,231 @@
+package upsidedown
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func testGame() *Game {
+	return &Game{players: make(map[*Player]bool), grid: newSpatialGrid(GridCellSize), arena: DefaultMap()}
+}
+
+func TestScoutStats(t *testing.T) {
+	g := testGame()
+	scout := &Player{Send: make(chan []byte, 64), classChoice: ClassScout}
+	survivor := &Player{Send: make(chan []byte, 64)}
+	g.players[scout], g.players[survivor] = true, true
+
+	meta := NewPlayerMeta()
+	meta.UnlockedClasses[ClassScout] = true
+	started := g.startGame(map[*Player]*PlayerMeta{scout: meta, survivor: NewPlayerMeta()})
+	if len(started) != 2 {
+		t.Fatalf("started %d players, want 2", len(started))
+	}
+
+	if scout.SelectedClass != ClassScout {
+		t.Fatalf("class %q, want scout", scout.SelectedClass)
+	}
+	if math.Abs(scout.SpeedMod/survivor.SpeedMod-1.3) > 1e-9 {
+		t.Errorf("scout speed x%v of a survivor's, want x1.3", scout.SpeedMod/survivor.SpeedMod)
+	}
+	if math.Abs(scout.MaxHealth/survivor.MaxHealth-0.85) > 1e-9 || scout.Health != scout.MaxHealth {
+		t.Errorf("scout health %v/%v against %v, want 85%%", scout.Health, scout.MaxHealth, survivor.MaxHealth)
+	}
+}
+
+func TestFlareLastsClassDuration(t *testing.T) {
+	for _, id := range []ClassID{ClassScout, ClassPyromancer} {
+		g := testGame()
+		p := &Player{Send: make(chan []byte, 64), classChoice: id}
+		g.players[p] = true
+		meta := NewPlayerMeta()
+		meta.UnlockedClasses[id] = true
+		g.startGame(map[*Player]*PlayerMeta{p: meta})
+
+		p.AvailableFlares = 1
+		g.handleFlareUse(p)
+		if want := 15 * CharacterClasses[id].FlareDuration; !p.HasFlare || p.FlareTime != want {
+			t.Errorf("%s flare lit %v for %vs, want %vs", id, p.HasFlare, p.FlareTime, want)
+		}
+	}
+}
+
+func TestLockedClassFallsBack(t *testing.T) {
+	g := testGame()
+	p := &Player{Send: make(chan []byte, 64), classChoice: ClassScout}
+	g.players[p] = true
+
+	g.startGame(map[*Player]*PlayerMeta{p: NewPlayerMeta()})
+	if p.SelectedClass != ClassSurvivor || p.SpeedMod != 1 {
+		t.Errorf("locked scout ran as %q at speed x%v", p.SelectedClass, p.SpeedMod)
+	}
+}
+
+func TestLimitMove(t *testing.T) {
+	now := time.Now()
+	p := &Player{SpeedMod: 1}
+	bank := MaxMoveSpeed * MoveBankLimit
+
+	// A teleport is cut down to what's banked
+	got := p.limitMove(Vec2{X: 100}, now)
+	if math.Abs(got.X-bank) > 1e-9 || got.Y != 0 {
+		t.Fatalf("first move reached %+v, want x=%v", got, bank)
+	}
+	p.Pos = got
+
+	// 50ms later they may cover 50ms at full speed, no more
+	now = now.Add(50 * time.Millisecond)
+	got = p.limitMove(Vec2{X: p.Pos.X + 100}, now)
+	if want := bank + MaxMoveSpeed*0.05; math.Abs(got.X-want) > 1e-9 {
+		t.Fatalf("second move reached x=%v, want %v", got.X, want)
+	}
+	p.Pos = got
+
+	// An ordinary step goes through untouched
+	now = now.Add(50 * time.Millisecond)
+	to := Vec2{X: p.Pos.X + PlayerSpeed*0.05, Y: p.Pos.Y}
+	if got = p.limitMove(to, now); got != to {
+		t.Fatalf("walking step moved to %+v, want %+v", got, to)
+	}
+}
+
+func TestLimitMoveScalesWithSpeed(t *testing.T) {
+	now := time.Now()
+	slow, fast := &Player{SpeedMod: 1}, &Player{SpeedMod: 1.3}
+	slow.limitMove(Vec2{}, now)
+	fast.limitMove(Vec2{}, now)
+
+	now = now.Add(100 * time.Millisecond)
+	a := slow.limitMove(Vec2{X: 100}, now)
+	b := fast.limitMove(Vec2{X: 100}, now)
+	if math.Abs(b.X/a.X-1.3) > 1e-9 {
+		t.Errorf("scout covered x%v of a survivor's distance, want x1.3", b.X/a.X)
+	}
+}
+
+// lastState decodes the newest state message queued for p.
+func lastState(t *testing.T, p *Player) (state struct {
+	Players  []struct{ ID string }
+	Entities []struct{ ID string }
+}) {
+	t.Helper()
+	var raw []byte
+	for len(p.Send) > 0 {
+		raw = <-p.Send
+	}
+	if raw == nil {
+		t.Fatal("no state sent")
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestStateHidesTheDark(t *testing.T) {
+	g := testGame()
+	viewer := &Player{ID: "viewer", Send: make(chan []byte, 64), LightRadius: 5}
+	near := &Player{ID: "near", Send: make(chan []byte, 64), Pos: Vec2{X: 4}, LightRadius: 2}
+	far := &Player{ID: "far", Send: make(chan []byte, 64), Pos: Vec2{X: 40}, LightRadius: 2}
+	flare := &Player{ID: "flare", Send: make(chan []byte, 64), Pos: Vec2{X: -40}, HasFlare: true, FlareTime: 3}
+	for _, p := range []*Player{viewer, near, far, flare} {
+		g.players[p] = true
+	}
+	g.entities = []*Entity{
+		{ID: "close", Type: "demogorgon", Pos: Vec2{X: 6}, Active: true},
+		{ID: "lurker", Type: "demogorgon", Pos: Vec2{X: 30}, Active: true},
+		{ID: "dead", Type: "demogorgon", Pos: Vec2{X: 1}},
+	}
+	g.broadcastState()
+
+	state := lastState(t, viewer)
+	seen := make(map[string]bool)
+	for _, p := range state.Players {
+		seen[p.ID] = true
+	}
+	for _, e := range state.Entities {
+		seen[e.ID] = true
+	}
+	for id, want := range map[string]bool{
+		"viewer": true, "near": true, "flare": true, "close": true,
+		"far": false, "lurker": false, "dead": false,
+	} {
+		if seen[id] != want {
+			t.Errorf("%s visible %v, want %v", id, seen[id], want)
+		}
+	}
+}
+
+func TestClearingAWaveAdvances(t *testing.T) {
+	g := testGame()
+	g.endlessMode = true
+	g.combinedMods.SpawnRateMod = 1
+	p := &Player{ID: "p", Send: make(chan []byte, 64), MaxHealth: 100, MaxSanity: 100}
+	g.players[p] = true
+
+	g.startWave()
+	if g.currentWave != 1 || len(g.entities) != GenerateWave(1).DemogorgonCount {
+		t.Fatalf("wave %d with %d entities", g.currentWave, len(g.entities))
+	}
+	g.updateWaves(1)
+	if g.waveTimer != 0 {
+		t.Fatal("wave counted as cleared with demogorgons still alive")
+	}
+
+	for _, e := range g.entities {
+		e.Active = false
+	}
+	g.updateWaves(1)
+	if g.waveTimer != WaveBreather || !p.Alive {
+		t.Fatalf("after clearing: breather %v, fallen player revived %v", g.waveTimer, p.Alive)
+	}
+	g.updateWaves(WaveBreather)
+	if g.currentWave != 2 || !g.enemiesAlive() {
+		t.Fatalf("wave %d, enemies alive %v after the breather", g.currentWave, g.enemiesAlive())
+	}
+}
+
+func TestBossEveryFifthWave(t *testing.T) {
+	g := testGame()
+	g.endlessMode = true
+	g.combinedMods.SpawnRateMod = 1
+	g.currentWave = 4
+	g.startWave()
+
+	bosses := 0
+	for _, e := range g.entities {
+		if e.IsBoss {
+			bosses++
+			if e.Health != GenerateWave(5).BossHealth {
+				t.Errorf("boss health %d, want %d", e.Health, GenerateWave(5).BossHealth)
+			}
+		}
+	}
+	if bosses != 1 || !g.bossActive {
+		t.Fatalf("%d bosses on wave 5 (active %v), want 1", bosses, g.bossActive)
+	}
+}
+
+func TestRunEndLeavesPayingToCaller(t *testing.T) {
+	g := testGame() // No store: any write under g.mu would panic
+	g.gameActive = true
+	g.gameTime = GameDuration
+	p := &Player{UserID: "u", Send: make(chan []byte, 64), Alive: true, Score: 500}
+	g.players[p] = true
+
+	owed := g.update(0)
+	if len(owed) != 1 || owed[0].userID != "u" || !owed[0].survivor {
+		t.Fatalf("payouts %+v, want one survivor payout for u", owed)
+	}
+	if owed[0].coins != 500/10+100 {
+		t.Errorf("coins %d, want %d", owed[0].coins, 500/10+100)
+	}
+	if g.gameActive {
+		t.Error("run still active after its end")
+	}
+	if _, again := g.rewardPlayer(p); again {
+		t.Error("paid out twice")
+	}
+}
@@ -0,0 +1,73 @@
+package upsidedown
+
+import "math"
+
+const (
+	// Seconds into a run before the extraction point opens.
+	ExtractionOpensAt = 120.0
+	// Seconds a player must stand on it to get out.
+	ExtractionHoldTime = 4.0
+	ExtractionRadius   = 2.5
+	// Ember shards are multiplied by this for players who extract.
+	ExtractionShardMultiplier = 1.5
+)
+
+// ExtractionPoint is the way out. Unlike entities it's sent to everyone as
+// soon as it opens, lit or not, since finding it is the whole point.
+type ExtractionPoint struct {
+	Pos    Vec2    `json:"pos"`
+	Radius float64 `json:"radius"`
+}
+
+// inRun reports whether p is still playing: alive and not yet extracted.
+// Extracted players stay Alive but nothing can touch them anymore.
+func (p *Player) inRun() bool {
+	return p.Alive && !p.Extracted
+}
+
+// updateExtraction opens the extraction point once it's time and moves
+// players who've held it long enough out of the run. Caller holds g.mu.
+func (g *Game) updateExtraction(dt float64) {
+	if g.extraction == nil {
+		if g.gameTime < ExtractionOpensAt {
+			return
+		}
+		g.extraction = &ExtractionPoint{
+			Pos:    g.arena.RandomOpenPoint(20, ExtractionRadius),
+			Radius: ExtractionRadius,
+		}
+		g.broadcastJSON(map[string]interface{}{"type": "extraction_open", "extraction": g.extraction})
+	}
+
+	for p := range g.players {
+		if !p.inRun() {
+			continue
+		}
+		if distance(p.Pos, g.extraction.Pos) > g.extraction.Radius {
+			p.extractHold = 0
+			continue
+		}
+		p.extractHold += dt
+		if p.extractHold < ExtractionHoldTime {
+			continue
+		}
+		p.Extracted = true
+		p.extractedAt = g.gameTime
+		p.HasFlare = false
+		p.FlareTime = 0
+		g.broadcastJSON(map[string]interface{}{"type": "extracted", "id": p.ID, "name": p.Nickname})
+	}
+}
+
+// extractionProgress is how far through the hold p is, 0..1.
+func extractionProgress(p *Player) float64 {
+	return math.Min(1, p.extractHold/ExtractionHoldTime)
+}
+
+// runTime is how long p spent in the run: up to extraction, or the whole game.
+func (g *Game) runTime(p *Player) float64 {
+	if p.Extracted {
+		return p.extractedAt
+	}
+	return g.gameTime
+}
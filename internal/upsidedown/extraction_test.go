@@ -0,0 +1,62 @@
+package upsidedown
+
+import (
+	"testing"
+
+	"main/internal/data/datatest"
+)
+
+func TestExtractionHold(t *testing.T) {
+	g := testGame()
+	on := &Player{ID: "on", Send: make(chan []byte, 64), Alive: true}
+	off := &Player{ID: "off", Send: make(chan []byte, 64), Alive: true, Pos: Vec2{X: 500}}
+	g.players[on], g.players[off] = true, true
+
+	g.gameTime = ExtractionOpensAt - 1
+	g.updateExtraction(1)
+	if g.extraction != nil {
+		t.Fatal("extraction opened early")
+	}
+	g.gameTime = ExtractionOpensAt
+	g.updateExtraction(0)
+	if g.extraction == nil {
+		t.Fatal("extraction didn't open")
+	}
+
+	on.Pos = g.extraction.Pos
+	g.updateExtraction(ExtractionHoldTime / 2)
+	if on.Extracted {
+		t.Fatal("extracted before holding long enough")
+	}
+	g.gameTime += ExtractionHoldTime
+	g.updateExtraction(ExtractionHoldTime / 2)
+	if !on.Extracted || on.inRun() || !on.Alive {
+		t.Fatalf("extracted %v, in run %v, alive %v", on.Extracted, on.inRun(), on.Alive)
+	}
+	if g.runTime(on) != on.extractedAt || off.Extracted || off.extractHold != 0 {
+		t.Errorf("run time %v at %v, far player extracted %v hold %v", g.runTime(on), on.extractedAt, off.Extracted, off.extractHold)
+	}
+}
+
+func TestExtractionShardBonus(t *testing.T) {
+	store, db := datatest.Store(t)
+	g := testGame()
+	g.store = store
+	g.gameTime = 150
+	g.combinedMods.EmberMultiplier = 1
+
+	stayed := &Player{UserID: datatest.User(t, db, 0), Alive: true, Score: 400, Kills: 3}
+	left := &Player{UserID: datatest.User(t, db, 0), Alive: true, Score: 400, Kills: 3, Extracted: true, extractedAt: 150}
+	for _, p := range []*Player{stayed, left} {
+		pay, _ := g.rewardPlayer(p)
+		g.pay([]payout{pay})
+	}
+
+	if want := int(float64(stayed.shards) * ExtractionShardMultiplier); left.shards != want {
+		t.Fatalf("extracted player got %d shards, want %d (x%v of %d)", left.shards, want, ExtractionShardMultiplier, stayed.shards)
+	}
+	meta := LoadPlayerMeta(store, left.UserID)
+	if meta.EmberShards != left.shards {
+		t.Errorf("saved %d shards, want %d", meta.EmberShards, left.shards)
+	}
+}
@@ -0,0 +1,61 @@
+package upsidedown
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// Below this fraction of max sanity the player starts seeing things.
+	HallucinationThreshold = 0.35
+	// Chance per second of a hallucination at zero sanity; it scales down
+	// linearly to nothing at the threshold.
+	MaxHallucinationRate = 0.6
+)
+
+// Hallucination kinds sent to the client.
+const (
+	HallucinationPhantom = "phantom" // A fake demogorgon at the edge of the light
+	HallucinationJitter  = "jitter"  // The screen lurches
+)
+
+// hallucinationRate is how often (per second) p should hallucinate now.
+func hallucinationRate(p *Player) float64 {
+	if p.MaxSanity <= 0 {
+		return 0
+	}
+	ratio := p.Sanity / p.MaxSanity
+	if ratio >= HallucinationThreshold {
+		return 0
+	}
+	return MaxHallucinationRate * (1 - ratio/HallucinationThreshold)
+}
+
+// maybeHallucinate occasionally sends p something that isn't there. These go
+// to p alone and never touch g.entities, so they can't hurt anyone, block
+// anything or show up in scores.
+func (g *Game) maybeHallucinate(p *Player, dt float64) {
+	if !p.Alive || rand.Float64() >= hallucinationRate(p)*dt {
+		return
+	}
+
+	g.hallucinationSeq++
+	msg := map[string]interface{}{
+		"type": "hallucination",
+		"id":   fmt.Sprintf("h_%d", g.hallucinationSeq),
+	}
+	if rand.Float64() < 0.7 {
+		// Just past the edge of their light, so it's glimpsed rather than seen
+		angle := rand.Float64() * 2 * math.Pi
+		dist := p.LightRadius + 1 + rand.Float64()*3
+		pos := g.arena.Clamp(Vec2{X: p.Pos.X + math.Cos(angle)*dist, Y: p.Pos.Y + math.Sin(angle)*dist}, DemoRadius)
+		msg["kind"] = HallucinationPhantom
+		msg["pos"] = pos
+		msg["duration"] = 1.5 + rand.Float64()*2
+	} else {
+		msg["kind"] = HallucinationJitter
+		msg["duration"] = 0.3 + rand.Float64()*0.5
+	}
+	g.sendTo(p, msg)
+}
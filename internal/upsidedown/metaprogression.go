@@ -6,6 +6,7 @@ package upsidedown
 import (
 	"encoding/json"
 	"main/internal/data"
+	"sort"
 )
 
 // ========================================
@@ -366,6 +367,85 @@ func (pm *PlayerMeta) PurchaseClass(classID ClassID) bool {
 	return true
 }
 
+// Why a meta-shop purchase was refused, "" when it can go ahead.
+const (
+	RefuseUnknown         = "unknown"
+	RefuseMaxLevel        = "max_level"
+	RefuseAlreadyUnlocked = "already_unlocked"
+	RefuseNoShards        = "not_enough_shards"
+)
+
+// UpgradeRefusal reports why PurchaseUpgrade would fail.
+func (pm *PlayerMeta) UpgradeRefusal(upgradeType UpgradeType) string {
+	upgrade, ok := Upgrades[upgradeType]
+	switch {
+	case !ok:
+		return RefuseUnknown
+	case pm.UpgradeLevels[upgradeType] >= upgrade.MaxLevel:
+		return RefuseMaxLevel
+	case pm.EmberShards < pm.GetUpgradeCost(upgradeType):
+		return RefuseNoShards
+	}
+	return ""
+}
+
+// ClassRefusal reports why PurchaseClass would fail.
+func (pm *PlayerMeta) ClassRefusal(classID ClassID) string {
+	class, ok := CharacterClasses[classID]
+	switch {
+	case !ok:
+		return RefuseUnknown
+	case pm.UnlockedClasses[classID]:
+		return RefuseAlreadyUnlocked
+	case pm.EmberShards < class.UnlockCost:
+		return RefuseNoShards
+	}
+	return ""
+}
+
+// UpgradeOffer is one meta-shop upgrade as this player sees it.
+type UpgradeOffer struct {
+	Upgrade
+	Level      int  `json:"level"`
+	Cost       int  `json:"cost"` // Price of the next level, 0 once maxed
+	Maxed      bool `json:"maxed"`
+	Affordable bool `json:"affordable"`
+}
+
+// ClassOffer is one class as this player sees it.
+type ClassOffer struct {
+	CharacterClass
+	Unlocked   bool `json:"unlocked"`
+	Affordable bool `json:"affordable"`
+}
+
+// Offers lists every upgrade and class with this player's level and prices,
+// sorted by ID so the shop doesn't reshuffle between loads.
+func (pm *PlayerMeta) Offers() ([]UpgradeOffer, []ClassOffer) {
+	upgrades := make([]UpgradeOffer, 0, len(Upgrades))
+	for t, u := range Upgrades {
+		offer := UpgradeOffer{Upgrade: u, Level: pm.UpgradeLevels[t]}
+		offer.Maxed = offer.Level >= u.MaxLevel
+		if !offer.Maxed {
+			offer.Cost = pm.GetUpgradeCost(t)
+		}
+		offer.Affordable = pm.UpgradeRefusal(t) == ""
+		upgrades = append(upgrades, offer)
+	}
+	sort.Slice(upgrades, func(i, j int) bool { return upgrades[i].Type < upgrades[j].Type })
+
+	classes := make([]ClassOffer, 0, len(CharacterClasses))
+	for id, c := range CharacterClasses {
+		classes = append(classes, ClassOffer{
+			CharacterClass: c,
+			Unlocked:       pm.UnlockedClasses[id],
+			Affordable:     pm.ClassRefusal(id) == "",
+		})
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].ID < classes[j].ID })
+	return upgrades, classes
+}
+
 // ========================================
 // RUN CONFIG (per-run settings)
 // ========================================
@@ -433,6 +513,13 @@ func PlayerMetaFromJSON(jsonStr string) *PlayerMeta {
 	}
 	pm := NewPlayerMeta()
 	json.Unmarshal([]byte(jsonStr), pm)
+	// A stored null would leave these nil and make the next purchase panic
+	if pm.UpgradeLevels == nil {
+		pm.UpgradeLevels = make(map[UpgradeType]int)
+	}
+	if pm.UnlockedClasses == nil {
+		pm.UnlockedClasses = map[ClassID]bool{ClassSurvivor: true}
+	}
 	return pm
 }
 
@@ -461,9 +548,6 @@ func GenerateWave(waveNum int) Wave {
 
 // Store integration helpers
 func LoadPlayerMeta(store *data.Store, userID string) *PlayerMeta {
-	if userID == "" || userID == "guest" {
-		return NewPlayerMeta()
-	}
 	user, ok := store.GetUser(userID)
 	if !ok {
 		return NewPlayerMeta()
@@ -472,9 +556,17 @@ func LoadPlayerMeta(store *data.Store, userID string) *PlayerMeta {
 	return PlayerMetaFromJSON(user.UpsideDownMeta)
 }
 
-func SavePlayerMeta(store *data.Store, userID string, meta *PlayerMeta) {
-	if userID == "" || userID == "guest" {
-		return
-	}
-	store.UpdateUpsideDownMeta(userID, meta.ToJSON())
+// UpdatePlayerMeta applies fn to userID's current meta and saves the result
+// atomically, so concurrent updates (a purchase landing during a run payout)
+// both stick. An error from fn aborts without saving and is returned as is.
+func UpdatePlayerMeta(store *data.Store, userID string, fn func(*PlayerMeta) error) (*PlayerMeta, error) {
+	var meta *PlayerMeta
+	err := store.ModifyUpsideDownMeta(userID, func(blob string) (string, error) {
+		meta = PlayerMetaFromJSON(blob)
+		if err := fn(meta); err != nil {
+			return "", err
+		}
+		return meta.ToJSON(), nil
+	})
+	return meta, err
 }
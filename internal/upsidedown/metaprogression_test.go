@@ -0,0 +1,162 @@
+package upsidedown
+
+import (
+	"encoding/json"
+	"testing"
+
+	"main/internal/data/datatest"
+)
+
+func TestPurchaseUpgrade(t *testing.T) {
+	meta := NewPlayerMeta()
+	cost := meta.GetUpgradeCost(UpgradeMaxHealth)
+	meta.EmberShards = cost + 7
+
+	if r := meta.UpgradeRefusal(UpgradeMaxHealth); r != "" {
+		t.Fatalf("refusal = %q, want none", r)
+	}
+	if !meta.PurchaseUpgrade(UpgradeMaxHealth) {
+		t.Fatal("purchase failed")
+	}
+	if meta.UpgradeLevels[UpgradeMaxHealth] != 1 || meta.EmberShards != 7 {
+		t.Fatalf("level %d shards %d, want 1 and 7", meta.UpgradeLevels[UpgradeMaxHealth], meta.EmberShards)
+	}
+}
+
+func TestPurchaseUpgradeOverMax(t *testing.T) {
+	meta := NewPlayerMeta()
+	meta.UpgradeLevels[UpgradeMaxHealth] = Upgrades[UpgradeMaxHealth].MaxLevel
+	meta.EmberShards = 1_000_000
+
+	if r := meta.UpgradeRefusal(UpgradeMaxHealth); r != RefuseMaxLevel {
+		t.Fatalf("refusal = %q, want %q", r, RefuseMaxLevel)
+	}
+	if meta.PurchaseUpgrade(UpgradeMaxHealth) {
+		t.Fatal("bought past the max level")
+	}
+	if meta.EmberShards != 1_000_000 {
+		t.Fatalf("shards spent on a refused purchase: %d", meta.EmberShards)
+	}
+}
+
+func TestPurchaseUpgradeUnaffordable(t *testing.T) {
+	meta := NewPlayerMeta()
+	meta.EmberShards = meta.GetUpgradeCost(UpgradeMaxHealth) - 1
+
+	if r := meta.UpgradeRefusal(UpgradeMaxHealth); r != RefuseNoShards {
+		t.Fatalf("refusal = %q, want %q", r, RefuseNoShards)
+	}
+	if meta.PurchaseUpgrade(UpgradeMaxHealth) || meta.UpgradeLevels[UpgradeMaxHealth] != 0 {
+		t.Fatal("bought without enough shards")
+	}
+}
+
+func TestPurchaseClass(t *testing.T) {
+	meta := NewPlayerMeta()
+	cost := CharacterClasses[ClassScout].UnlockCost
+
+	meta.EmberShards = cost - 1
+	if r := meta.ClassRefusal(ClassScout); r != RefuseNoShards {
+		t.Fatalf("refusal = %q, want %q", r, RefuseNoShards)
+	}
+
+	meta.EmberShards = cost
+	if !meta.PurchaseClass(ClassScout) || !meta.UnlockedClasses[ClassScout] || meta.EmberShards != 0 {
+		t.Fatal("unlock failed")
+	}
+	if r := meta.ClassRefusal(ClassScout); r != RefuseAlreadyUnlocked {
+		t.Fatalf("refusal = %q, want %q", r, RefuseAlreadyUnlocked)
+	}
+}
+
+func TestMetaFromJSONWithNulls(t *testing.T) {
+	meta := PlayerMetaFromJSON(`{"emberShards":40,"upgradeLevels":null,"unlockedClasses":null}`)
+	if meta.EmberShards != 40 || meta.UpgradeLevels == nil || !meta.UnlockedClasses[ClassSurvivor] {
+		t.Fatalf("meta %+v", meta)
+	}
+	meta.UpgradeLevels[UpgradeMaxHealth]++ // Used to panic
+}
+
+func TestMetaRoundTrip(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	_, err := UpdatePlayerMeta(store, id, func(meta *PlayerMeta) error {
+		meta.EmberShards = 120
+		meta.UpgradeLevels[UpgradeMaxHealth] = 2
+		meta.UpgradeLevels[UpgradeLightRadius] = 1
+		meta.UnlockedClasses[ClassScout] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Crediting shards on their own must leave the rest of the meta alone
+	if err := store.AdjustEmberShards(id, 30); err != nil {
+		t.Fatal(err)
+	}
+	got := LoadPlayerMeta(store, id)
+	if got.EmberShards != 150 {
+		t.Errorf("shards %d, want 150", got.EmberShards)
+	}
+	if got.UpgradeLevels[UpgradeMaxHealth] != 2 || got.UpgradeLevels[UpgradeLightRadius] != 1 || !got.UnlockedClasses[ClassScout] {
+		t.Errorf("upgrades %v classes %v didn't survive", got.UpgradeLevels, got.UnlockedClasses)
+	}
+}
+
+func TestEmberShardMath(t *testing.T) {
+	for _, c := range []struct {
+		time     float64
+		score    int
+		kills    int
+		survived bool
+		mod      float64
+		want     int
+	}{
+		{60, 400, 3, false, 1, 120 + 20 + 30},
+		{60, 400, 3, true, 1, (120 + 20 + 30) * 2},
+		{60, 400, 3, true, 1.5, (120 + 20 + 30) * 3},
+		{0, 19, 0, false, 1, 0},
+	} {
+		if got := CalculateEmberShards(c.time, c.score, c.kills, c.survived, c.mod); got != c.want {
+			t.Errorf("CalculateEmberShards(%v, %d, %d, %v, %v) = %d, want %d", c.time, c.score, c.kills, c.survived, c.mod, got, c.want)
+		}
+	}
+}
+
+func TestRunStatsKeepBest(t *testing.T) {
+	store, db := datatest.Store(t)
+	id := datatest.User(t, db, 0)
+
+	var earned int
+	for _, run := range []struct {
+		time  float64
+		kills int
+	}{{200, 4}, {50, 2}} {
+		g := testGame()
+		g.store = store
+		g.gameTime = run.time
+		g.combinedMods.EmberMultiplier = 1
+		p := &Player{ID: "p", UserID: id, Send: make(chan []byte, 16), Kills: run.kills}
+		g.players[p] = true
+		g.pay(g.endGame())
+		earned += p.shards
+
+		var over struct {
+			Type   string
+			Shards map[string]int
+		}
+		if err := json.Unmarshal(<-p.Send, &over); err != nil || over.Type != "game_over" || over.Shards["p"] != p.shards {
+			t.Fatalf("game over message %+v (%v), want %d shards for p", over, err, p.shards)
+		}
+	}
+
+	meta := LoadPlayerMeta(store, id)
+	if meta.BestSurvival != 200 {
+		t.Errorf("best survival %v, want 200 kept over the shorter run", meta.BestSurvival)
+	}
+	if meta.TotalKills != 6 || meta.EmberShards != earned {
+		t.Errorf("kills %d shards %d, want 6 and %d", meta.TotalKills, meta.EmberShards, earned)
+	}
+}
@@ -0,0 +1,66 @@
+package upsidedown
+
+const (
+	// A living player within ReviveRange of a body for ReviveTime straight
+	// seconds brings them back.
+	ReviveRange  = 2.0
+	ReviveTime   = 3.0
+	ReviveHealth = 30
+	ReviveSanity = 50
+)
+
+// updateRevives leaves a body where each player went down and revives them
+// once a teammate has stood by it long enough. Stepping away, even for a
+// tick, starts the count over. Caller holds g.mu.
+func (g *Game) updateRevives(dt float64) {
+	for p := range g.players {
+		if p.Alive || p.Extracted {
+			continue
+		}
+		if p.body == nil {
+			p.body = &Entity{ID: "body_" + p.ID, Type: "body", Pos: p.Pos, Active: true}
+			g.entities = append(g.entities, p.body)
+		}
+
+		if !g.reviverNear(p) {
+			p.reviveProgress = 0
+			continue
+		}
+		p.reviveProgress += dt
+		if p.reviveProgress < ReviveTime {
+			continue
+		}
+
+		p.Alive = true
+		p.Health = min(ReviveHealth, p.MaxHealth)
+		p.Sanity = min(ReviveSanity, p.MaxSanity)
+		p.clearBody()
+		g.broadcastJSON(map[string]interface{}{"type": "revived", "id": p.ID})
+	}
+}
+
+// reviverNear reports whether a living teammate is close enough to down's
+// body to be reviving them.
+func (g *Game) reviverNear(down *Player) bool {
+	for p := range g.players {
+		if p != down && p.inRun() && distance(p.Pos, down.Pos) <= ReviveRange {
+			return true
+		}
+	}
+	return false
+}
+
+// clearBody removes p's body and any revive in progress, for whenever p is
+// back on their feet.
+func (p *Player) clearBody() {
+	if p.body != nil {
+		p.body.Active = false
+		p.body = nil
+	}
+	p.reviveProgress = 0
+}
+
+// reviveFraction is how far along down's revive is, 0 to 1, for the client.
+func (p *Player) reviveFraction() float64 {
+	return min(p.reviveProgress/ReviveTime, 1)
+}
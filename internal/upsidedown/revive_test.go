@@ -0,0 +1,49 @@
+package upsidedown
+
+import "testing"
+
+func TestReviveHold(t *testing.T) {
+	g := testGame()
+	down := &Player{ID: "down", Send: make(chan []byte, 64), MaxHealth: 100, MaxSanity: 100, Pos: Vec2{X: 10, Y: 10}}
+	helper := &Player{ID: "helper", Send: make(chan []byte, 64), Alive: true, Pos: Vec2{X: 11, Y: 10}}
+	g.players[down], g.players[helper] = true, true
+
+	const tick = 0.5
+	for elapsed := 0.0; elapsed < ReviveTime-tick; elapsed += tick {
+		g.updateRevives(tick)
+	}
+	if down.Alive || down.body == nil || !down.body.Active {
+		t.Fatalf("alive %v body %v before the revive finished", down.Alive, down.body)
+	}
+	if f := down.reviveFraction(); f <= 0 || f >= 1 {
+		t.Errorf("revive fraction %v mid-revive", f)
+	}
+
+	body := down.body
+	g.updateRevives(tick)
+	if !down.Alive || down.Health != ReviveHealth || down.Sanity != ReviveSanity {
+		t.Fatalf("alive %v health %v sanity %v after %vs", down.Alive, down.Health, down.Sanity, ReviveTime)
+	}
+	if body.Active || down.body != nil || down.reviveFraction() != 0 {
+		t.Error("body still lying around after the revive")
+	}
+}
+
+func TestReviveResetsWhenHelperLeaves(t *testing.T) {
+	g := testGame()
+	down := &Player{ID: "down", Send: make(chan []byte, 64), MaxHealth: 100, MaxSanity: 100}
+	helper := &Player{ID: "helper", Send: make(chan []byte, 64), Alive: true, Pos: Vec2{X: 1}}
+	g.players[down], g.players[helper] = true, true
+
+	g.updateRevives(ReviveTime - 0.1)
+	helper.Pos = Vec2{X: ReviveRange + 1}
+	g.updateRevives(0.05)
+	if down.reviveProgress != 0 {
+		t.Fatalf("progress %v kept after the helper walked off", down.reviveProgress)
+	}
+	helper.Pos = Vec2{X: 1}
+	g.updateRevives(0.2)
+	if down.Alive {
+		t.Fatal("revived without a full uninterrupted hold")
+	}
+}
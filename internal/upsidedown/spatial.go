@@ -0,0 +1,92 @@
+package upsidedown
+
+import "math"
+
+// spatialGrid buckets entities into uniform square cells so proximity
+// queries only look at the handful of cells around a point instead of
+// every entity in the run. It is rebuilt once per tick under g.mu.
+type spatialGrid struct {
+	cellSize float64
+	cells    map[gridCell][]*Entity
+}
+
+type gridCell struct {
+	X, Y int
+}
+
+// GridCellSize is picked to be roughly the largest radius queried per tick
+// (light orb aura = 5) so a query touches at most a 3x3 block.
+const GridCellSize = 5.0
+
+func newSpatialGrid(cellSize float64) *spatialGrid {
+	return &spatialGrid{
+		cellSize: cellSize,
+		cells:    make(map[gridCell][]*Entity),
+	}
+}
+
+func (sg *spatialGrid) cellFor(pos Vec2) gridCell {
+	return gridCell{
+		X: int(math.Floor(pos.X / sg.cellSize)),
+		Y: int(math.Floor(pos.Y / sg.cellSize)),
+	}
+}
+
+// rebuild clears the grid and re-inserts every active entity.
+func (sg *spatialGrid) rebuild(entities []*Entity) {
+	for k := range sg.cells {
+		delete(sg.cells, k)
+	}
+	for _, e := range entities {
+		if !e.Active {
+			continue
+		}
+		c := sg.cellFor(e.Pos)
+		sg.cells[c] = append(sg.cells[c], e)
+	}
+}
+
+// query calls fn for every active entity within radius of pos. Returning
+// false from fn stops the scan early.
+func (sg *spatialGrid) query(pos Vec2, radius float64, fn func(e *Entity) bool) {
+	min := sg.cellFor(Vec2{X: pos.X - radius, Y: pos.Y - radius})
+	max := sg.cellFor(Vec2{X: pos.X + radius, Y: pos.Y + radius})
+	for cx := min.X; cx <= max.X; cx++ {
+		for cy := min.Y; cy <= max.Y; cy++ {
+			for _, e := range sg.cells[gridCell{cx, cy}] {
+				if !e.Active || distance(pos, e.Pos) >= radius {
+					continue
+				}
+				if !fn(e) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// nearestPlayers maps each active demogorgon to the closest player in the
+// run within sight of it; demogorgons nobody is near are left out. The grid
+// is asked from each player's side, so the cost follows the demogorgons
+// close to someone rather than every entity times every player. Caller
+// holds g.mu and has rebuilt the grid this tick.
+func (g *Game) nearestPlayers(sight float64) map[*Entity]*Player {
+	targets := make(map[*Entity]*Player)
+	best := make(map[*Entity]float64)
+	for p := range g.players {
+		if !p.inRun() {
+			continue
+		}
+		g.grid.query(p.Pos, sight, func(e *Entity) bool {
+			if e.Type != "demogorgon" && e.Type != "demogorgon_boss" {
+				return true
+			}
+			d := distance(e.Pos, p.Pos)
+			if prev, seen := best[e]; !seen || d < prev {
+				best[e], targets[e] = d, p
+			}
+			return true
+		})
+	}
+	return targets
+}
@@ -0,0 +1,102 @@
+package upsidedown
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// crowdedGame is a run with n demogorgons and resources scattered over the
+// default arena and a few players near the middle.
+func crowdedGame(n int) *Game {
+	rng := rand.New(rand.NewSource(1))
+	g := testGame()
+	for i := 0; i < n; i++ {
+		typ := "demogorgon"
+		if i%3 == 0 {
+			typ = ResourceLightOrb
+		}
+		g.entities = append(g.entities, &Entity{
+			ID:     fmt.Sprint(i),
+			Type:   typ,
+			Active: true,
+			Pos:    Vec2{X: rng.Float64()*80 - 40, Y: rng.Float64()*80 - 40},
+		})
+	}
+	for i := 0; i < 4; i++ {
+		g.players[&Player{Alive: true, Pos: Vec2{X: rng.Float64()*20 - 10, Y: rng.Float64()*20 - 10}}] = true
+	}
+	return g
+}
+
+// naiveNearestPlayers is the scan the grid replaced: every demogorgon
+// against every player.
+func naiveNearestPlayers(g *Game, sight float64) map[*Entity]*Player {
+	targets := make(map[*Entity]*Player)
+	for _, e := range g.entities {
+		if !e.Active || (e.Type != "demogorgon" && e.Type != "demogorgon_boss") {
+			continue
+		}
+		bestDist := math.MaxFloat64
+		for p := range g.players {
+			if d := distance(e.Pos, p.Pos); p.inRun() && d < bestDist {
+				bestDist = d
+				if d < sight {
+					targets[e] = p
+				}
+			}
+		}
+	}
+	return targets
+}
+
+func TestNearestPlayersMatchesNaive(t *testing.T) {
+	g := crowdedGame(400)
+	g.grid.rebuild(g.entities)
+	got, want := g.nearestPlayers(12), naiveNearestPlayers(g, 12)
+	if len(got) != len(want) {
+		t.Fatalf("grid found targets for %d demogorgons, naive scan %d", len(got), len(want))
+	}
+	for e, p := range want {
+		if got[e] != p {
+			t.Errorf("demogorgon %s: grid picked %p, naive %p", e.ID, got[e], p)
+		}
+	}
+}
+
+func TestGridQueryRadius(t *testing.T) {
+	sg := newSpatialGrid(GridCellSize)
+	near := &Entity{Active: true, Pos: Vec2{X: 4.9}}
+	edge := &Entity{Active: true, Pos: Vec2{X: 5}}
+	gone := &Entity{Active: false, Pos: Vec2{X: 1}}
+	sg.rebuild([]*Entity{near, edge, gone})
+
+	var found []*Entity
+	sg.query(Vec2{}, 5, func(e *Entity) bool {
+		found = append(found, e)
+		return true
+	})
+	if len(found) != 1 || found[0] != near {
+		t.Fatalf("query found %v, want only the entity inside the radius", found)
+	}
+}
+
+func BenchmarkProximity(b *testing.B) {
+	for _, n := range []int{100, 500, 2000} {
+		g := crowdedGame(n)
+		b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				naiveNearestPlayers(g, 20)
+			}
+		})
+		// update rebuilds the grid once a tick for every query, so the
+		// rebuild isn't charged to this one
+		g.grid.rebuild(g.entities)
+		b.Run(fmt.Sprintf("grid/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.nearestPlayers(20)
+			}
+		})
+	}
+}
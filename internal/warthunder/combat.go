@@ -0,0 +1,69 @@
+package warthunder
+
+import "math"
+
+// There are no real borders on the map, so reach is approximated: countries
+// listed here are treated as sharing a frontier. Everything else is an
+// overseas expedition.
+var neighbours = map[string][]string{
+	"us": {"br"},
+	"br": {"us", "za"},
+	"ru": {"ua", "cn", "jp"},
+	"ua": {"ru", "de"},
+	"de": {"ua", "fr"},
+	"fr": {"de", "uk"},
+	"uk": {"fr"},
+	"cn": {"ru", "jp"},
+	"jp": {"cn", "ru"},
+	"za": {"mg", "br"},
+	"mg": {"za"},
+}
+
+const (
+	// HomeFieldBonus multiplies the defender's power: fighting on your own soil
+	// with short supply lines.
+	HomeFieldBonus = 1.25
+	// OverextensionPenalty multiplies the attacker's power when the target is
+	// out of reach (no border, no ally next door, no history between them).
+	OverextensionPenalty = 0.7
+	// ReachRelation is how strong a relationship (either way) has to be before
+	// the two countries count as "in contact" without a border.
+	ReachRelation = 30.0
+)
+
+func bordering(a, b string) bool {
+	for _, n := range neighbours[a] {
+		if n == b {
+			return true
+		}
+	}
+	return false
+}
+
+// inReach reports whether attacker can project force into target without
+// being overextended: a shared border, an ally bordering the target, or a
+// strong relationship (friend or foe) standing in for proximity.
+func (g *GameState) inReach(attacker, target *Country) bool {
+	if bordering(attacker.ID, target.ID) {
+		return true
+	}
+	for _, allyID := range attacker.Alliances {
+		if bordering(allyID, target.ID) {
+			return true
+		}
+	}
+	return math.Abs(attacker.Relations[target.ID]) >= ReachRelation
+}
+
+// combatPowers returns attack and defense power for a war between attacker and
+// target, where totalDefense already includes allied contributions.
+func (g *GameState) combatPowers(attacker, target *Country, totalDefense float64) (float64, float64) {
+	attackPower := attacker.Military * (1 + attacker.TechLevel/200) * (attacker.Stability / 100)
+	defensePower := totalDefense * (1 + target.TechLevel/200) * (target.Stability / 100)
+
+	defensePower *= HomeFieldBonus
+	if !g.inReach(attacker, target) {
+		attackPower *= OverextensionPenalty
+	}
+	return attackPower, defensePower
+}
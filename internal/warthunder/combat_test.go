@@ -0,0 +1,44 @@
+package warthunder
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHomeFieldTipsEvenFight(t *testing.T) {
+	g := &GameState{}
+	// Neighbours, the attacker a little stronger on paper
+	ua := &Country{ID: "ua", Military: 120, TechLevel: 50, Stability: 80, Relations: map[string]float64{}}
+	de := &Country{ID: "de", Military: 100, TechLevel: 50, Stability: 80, Relations: map[string]float64{}}
+
+	attack, defense := g.combatPowers(ua, de, de.Military)
+	if raw := defense / HomeFieldBonus; attack <= raw {
+		t.Fatalf("attack %v should beat the raw defense %v", attack, raw)
+	}
+	if attack >= defense {
+		t.Fatalf("attack %v still beats %v with the home-field bonus", attack, defense)
+	}
+	// raid and Attack win with attack/(attack+defense), now under even odds
+	if odds := attack / (attack + defense); odds >= 0.5 {
+		t.Errorf("attacker's odds %v, want under 0.5", odds)
+	}
+}
+
+func TestOverextendedAttackerWeakened(t *testing.T) {
+	g := &GameState{}
+	us := &Country{ID: "us", Military: 100, TechLevel: 50, Stability: 80, Relations: map[string]float64{}}
+	br := &Country{ID: "br", Military: 100, TechLevel: 50, Stability: 80, Relations: map[string]float64{}}
+	mg := &Country{ID: "mg", Military: 100, TechLevel: 50, Stability: 80, Relations: map[string]float64{}}
+
+	near, _ := g.combatPowers(us, br, br.Military)
+	far, _ := g.combatPowers(us, mg, mg.Military)
+	if math.Abs(far/near-OverextensionPenalty) > 1e-9 {
+		t.Fatalf("overseas attack %v against %v next door, want x%v", far, near, OverextensionPenalty)
+	}
+
+	// A strong enough history puts them in contact after all
+	us.Relations["mg"] = -ReachRelation
+	if contact, _ := g.combatPowers(us, mg, mg.Military); contact != near {
+		t.Errorf("attack on a sworn enemy %v, want the bordering %v", contact, near)
+	}
+}
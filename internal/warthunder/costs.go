@@ -0,0 +1,67 @@
+package warthunder
+
+// Action costs in $B. The action methods and the preview sent to the client
+// both read these, so the UI never has to hardcode a price.
+const (
+	DiplomatCost         = 15.0
+	EspionageCost        = 50.0
+	BuildMilitaryCost    = 100.0
+	PropagandaCost       = 30.0
+	FightCorruptionCost  = 50.0
+	FomentRevolutionCost = 120.0
+
+	// First level of each facility; later levels cost a multiple, see production.go.
+	BuildRefineryCost = 80.0
+	BuildFarmCost     = 60.0
+	FundResearchCost  = 90.0
+
+	// InvestEconomy spends a share of GDP and needs at least InvestMinCost.
+	InvestShare   = 0.1
+	InvestMinCost = 50.0
+)
+
+// ActionCost is the preview of one paid action for the current player.
+type ActionCost struct {
+	Cost       float64 `json:"cost"`
+	Affordable bool    `json:"affordable"`
+}
+
+// investCost is what InvestEconomy would spend right now.
+func investCost(player *Country) float64 {
+	return player.Economy * InvestShare
+}
+
+// actionCosts prices every paid action against the player's economy, keyed by
+// the same action names the API accepts. Caller holds the lock.
+func (g *GameState) actionCosts() map[string]ActionCost {
+	player, ok := g.Countries[g.PlayerCountry]
+	if !ok {
+		return nil
+	}
+	fixed := map[string]float64{
+		"diplomat":         DiplomatCost,
+		"espionage":        EspionageCost,
+		"buildMilitary":    BuildMilitaryCost,
+		"propaganda":       PropagandaCost,
+		"fightCorruption":  FightCorruptionCost,
+		"fomentRevolution": FomentRevolutionCost,
+	}
+	costs := make(map[string]ActionCost, len(fixed)+4)
+	for action, cost := range fixed {
+		costs[action] = ActionCost{Cost: cost, Affordable: player.Economy >= cost}
+	}
+	for action, facility := range map[string]string{
+		"buildRefinery": FacilityRefinery,
+		"buildFarms":    FacilityFarm,
+		"fundResearch":  FacilityLab,
+	} {
+		cost := facilityCost(player, facility)
+		costs[action] = ActionCost{
+			Cost:       cost,
+			Affordable: player.Economy >= cost && player.Facilities[facility] < MaxFacilityLevel,
+		}
+	}
+	invest := investCost(player)
+	costs["investEconomy"] = ActionCost{Cost: invest, Affordable: invest >= InvestMinCost}
+	return costs
+}
@@ -26,8 +26,12 @@ type Country struct {
 	Sanctions      []string           `json:"sanctions"` // Countries sanctioning this one
 	IsPlayer       bool               `json:"isPlayer"`
 	IsEliminated   bool               `json:"isEliminated"`
-	Government     string             `json:"government"` // democracy, autocracy, etc
-	Ideology       string             `json:"ideology"`   // liberal, conservative, etc
+	Government     string             `json:"government"`              // democracy, autocracy, etc
+	Ideology       string             `json:"ideology"`                // liberal, conservative, etc
+	Unrest         int                `json:"unrest"`                  // Turns of sustained crisis, see revolution.go
+	Facilities     map[string]int     `json:"facilities"`              // Production upgrades, see production.go
+	RevealedUntil  int                `json:"revealedUntil,omitempty"` // Espionage intel valid through this turn
+	Intel          string             `json:"intel,omitempty"`         // full/estimate, set only in the player's view
 }
 
 // GameState with enhanced features
@@ -36,14 +40,32 @@ type GameState struct {
 	PlayerCountry string              `json:"playerCountry"`
 	Countries     map[string]*Country `json:"countries"`
 	Turn          int                 `json:"turn"`
-	Events        []string            `json:"events"`
+	MaxTurns      int                 `json:"maxTurns"` // Game ends on score after this turn, 0 = unlimited
+	WinnerID      string              `json:"winnerId,omitempty"`
+	Events        EventLog            `json:"events"` // Newest first, see events.go
 	GameOver      bool                `json:"gameOver"`
 	VictoryType   string              `json:"victoryType"`
 	GlobalTension float64             `json:"globalTension"` // 0-100 (higher = more conflict)
 	UNSanctions   map[string]int      `json:"unSanctions"`   // Country ID -> severity
 	TradeDeals    []TradeDeal         `json:"tradeDeals"`
 	Treaties      []Treaty            `json:"treaties"`
+	PendingOffers []Offer             `json:"pendingOffers"` // AI proposals awaiting the player's answer
+	RelationDecay float64             `json:"relationDecay"` // Per-turn pull toward neutral, 0 = frozen relations
 	Mutex         sync.RWMutex        `json:"-"`
+
+	offerSeq int
+	seed     int64         // Starts rng, see reseed
+	rng      *rand.Rand    // Per-game stream for AI decisions, only used under Mutex
+	stop     chan struct{} // Closed when the game is replaced, ends AIRoutine
+
+	// Replay, see replay.go
+	history     []TurnRecord
+	newEvents   int // Events added since the last TurnRecord
+	replaySaved bool
+
+	// Final summary, see summary.go
+	peakEconomy  float64
+	peakMilitary float64
 }
 
 type TradeDeal struct {
@@ -56,6 +78,18 @@ type TradeDeal struct {
 	TurnsLeft int     `json:"turnsLeft"`
 }
 
+// Offer is a proposal an AI country makes to the player
+type Offer struct {
+	ID          string  `json:"id"`
+	From        string  `json:"from"`
+	Type        string  `json:"type"` // alliance, trade
+	Resource    string  `json:"resource,omitempty"`
+	Amount      float64 `json:"amount,omitempty"` // Per turn, trade only
+	Price       float64 `json:"price,omitempty"`  // Per turn, trade only
+	Turns       int     `json:"turns,omitempty"`
+	ExpiresTurn int     `json:"expiresTurn"`
+}
+
 type Treaty struct {
 	ID        string   `json:"id"`
 	Type      string   `json:"type"` // alliance, non-aggression, trade
@@ -63,6 +97,9 @@ type Treaty struct {
 	TurnsLeft int      `json:"turnsLeft"`
 }
 
+// DefaultMaxTurns caps a campaign so AI stalemates still reach an ending
+const DefaultMaxTurns = 100
+
 var activeGames = make(map[string]*GameState)
 var gamesMutex sync.RWMutex
 
@@ -105,6 +142,7 @@ func CreateGame(playerID string, countryID string) *GameState {
 		newC.IsPlayer = (c.ID == countryID)
 		newC.Alliances = []string{}
 		newC.Sanctions = []string{}
+		newC.Facilities = map[string]int{}
 		countries[c.ID] = &newC
 	}
 
@@ -136,14 +174,25 @@ func CreateGame(playerID string, countryID string) *GameState {
 		PlayerCountry: countryID,
 		Countries:     countries,
 		Turn:          1,
-		Events:        []string{"🎯 Your rule begins. Shape the destiny of your nation!"},
+		MaxTurns:      DefaultMaxTurns,
 		GlobalTension: 25.0,
 		UNSanctions:   make(map[string]int),
 		TradeDeals:    []TradeDeal{},
 		Treaties:      []Treaty{},
+		PendingOffers: []Offer{},
+		RelationDecay: DefaultRelationDecay,
+		stop:          make(chan struct{}),
 	}
+	game.reseed(time.Now().UnixNano())
 
+	// Starting over abandons the old game; don't leave its AI ticking forever
+	if old, ok := activeGames[playerID]; ok {
+		close(old.stop)
+	}
 	activeGames[playerID] = game
+	game.Events.Add("🎯 Your rule begins. Shape the destiny of your nation!")
+	game.newEvents = game.Events.Len()
+	game.recordTurn()
 
 	// Start AI routine
 	go game.AIRoutine()
@@ -151,11 +200,16 @@ func CreateGame(playerID string, countryID string) *GameState {
 	return game
 }
 
+// reseed restarts the AI's random stream from seed, so the same seed plays
+// the same AI moves.
+func (g *GameState) reseed(seed int64) {
+	g.seed = seed
+	g.rng = rand.New(rand.NewSource(seed))
+}
+
 func (g *GameState) AddEvent(msg string) {
-	g.Events = append([]string{fmt.Sprintf("📅 Turn %d: %s", g.Turn, msg)}, g.Events...)
-	if len(g.Events) > 100 {
-		g.Events = g.Events[:100]
-	}
+	g.Events.Add(fmt.Sprintf("📅 Turn %d: %s", g.Turn, msg))
+	g.newEvents++
 }
 
 // ACTION: Attack with enhanced mechanics
@@ -192,10 +246,12 @@ func (g *GameState) Attack(targetID string) string {
 	}
 
 	g.AddEvent(fmt.Sprintf("⚔️ WAR! You attacked %s%s", target.Name, defenderNames))
+	if !g.inReach(player, target) {
+		g.AddEvent(fmt.Sprintf("🚢 Supply lines to %s are stretched thin. Our forces are overextended", target.Name))
+	}
 
-	// Combat calculation with more factors
-	attackPower := player.Military * (1 + player.TechLevel/200) * (player.Stability / 100)
-	defensePower := totalDefense * (1 + target.TechLevel/200) * (target.Stability / 100)
+	// Combat calculation with more factors (home field, reach)
+	attackPower, defensePower := g.combatPowers(player, target, totalDefense)
 
 	roll := rand.Float64()
 	winChance := attackPower / (attackPower + defensePower)
@@ -278,12 +334,11 @@ func (g *GameState) Diplomat(targetID string) string {
 		return "Invalid target"
 	}
 
-	cost := 15.0
-	if player.Economy < cost {
+	if player.Economy < DiplomatCost {
 		return "Insufficient funds for diplomatic mission"
 	}
 
-	player.Economy -= cost
+	player.Economy -= DiplomatCost
 	boost := 15.0 + rand.Float64()*15.0
 
 	// Ideology affects diplomacy
@@ -330,21 +385,159 @@ func (g *GameState) FormAlliance(targetID string) string {
 		}
 	}
 
-	player.Alliances = append(player.Alliances, targetID)
+	g.signAlliance(player, target)
+	g.AddEvent(fmt.Sprintf("🛡️ Alliance formed with %s!", target.Name))
+
+	return "success"
+}
+
+// signAlliance links two countries and records the treaty. Caller holds the lock.
+func (g *GameState) signAlliance(player, target *Country) {
+	player.Alliances = append(player.Alliances, target.ID)
 	target.Alliances = append(target.Alliances, player.ID)
 
 	treaty := Treaty{
 		ID:        fmt.Sprintf("alliance_%d", len(g.Treaties)),
 		Type:      "alliance",
-		Members:   []string{player.ID, targetID},
+		Members:   []string{player.ID, target.ID},
 		TurnsLeft: -1, // Permanent until broken
 	}
 	g.Treaties = append(g.Treaties, treaty)
-
-	g.AddEvent(fmt.Sprintf("🛡️ Alliance formed with %s!", target.Name))
 	player.Stability += 5
+}
 
-	return "success"
+func isAllied(c *Country, otherID string) bool {
+	for _, allyID := range c.Alliances {
+		if allyID == otherID {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GameState) hasOfferFrom(countryID string) bool {
+	for _, o := range g.PendingOffers {
+		if o.From == countryID {
+			return true
+		}
+	}
+	return false
+}
+
+// proposeToPlayer lets a friendly AI country put an offer on the table, rolling
+// on the game's rng. Caller holds the lock.
+func (g *GameState) proposeToPlayer(country *Country, rng *rand.Rand) {
+	player := g.Countries[g.PlayerCountry]
+	if player == nil || player.IsEliminated || g.hasOfferFrom(country.ID) {
+		return
+	}
+	relation := country.Relations[player.ID]
+
+	g.offerSeq++
+	offer := Offer{
+		ID:          fmt.Sprintf("offer_%d", g.offerSeq),
+		From:        country.ID,
+		ExpiresTurn: g.Turn + 3,
+	}
+
+	switch {
+	case relation > 60 && !isAllied(country, player.ID) && rng.Float64() < 0.3:
+		offer.Type = "alliance"
+		g.AddEvent(fmt.Sprintf("✉️ %s proposes an alliance with you", country.Name))
+	case relation > 30 && rng.Float64() < 0.2:
+		resource := "oil"
+		if country.Resources["food"] > country.Resources["oil"] {
+			resource = "food"
+		}
+		offer.Type = "trade"
+		offer.Resource = resource
+		offer.Amount = 5 + rng.Float64()*10
+		offer.Price = offer.Amount * (1.5 + rng.Float64())
+		offer.Turns = 5
+		g.AddEvent(fmt.Sprintf("✉️ %s offers to sell you %.0f %s per turn for $%.1fB", country.Name, offer.Amount, resource, offer.Price))
+	default:
+		g.offerSeq--
+		return
+	}
+	g.PendingOffers = append(g.PendingOffers, offer)
+}
+
+// ACTION: Answer an AI proposal
+func (g *GameState) RespondToOffer(offerID string, accept bool) string {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	idx := -1
+	for i, o := range g.PendingOffers {
+		if o.ID == offerID {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return "Offer not found or expired"
+	}
+	offer := g.PendingOffers[idx]
+	g.PendingOffers = append(g.PendingOffers[:idx], g.PendingOffers[idx+1:]...)
+
+	player := g.Countries[g.PlayerCountry]
+	from, ok := g.Countries[offer.From]
+	if !ok || from.IsEliminated {
+		return "Invalid target"
+	}
+
+	if !accept {
+		from.Relations[player.ID] -= 5
+		g.AddEvent(fmt.Sprintf("❌ You turned down %s's %s offer", from.Name, offer.Type))
+		return "rejected"
+	}
+
+	switch offer.Type {
+	case "alliance":
+		if isAllied(player, from.ID) {
+			return "Already allied"
+		}
+		g.signAlliance(player, from)
+		g.AddEvent(fmt.Sprintf("🛡️ Accepted %s's alliance proposal!", from.Name))
+		g.CheckVictoryConditions()
+	case "trade":
+		g.TradeDeals = append(g.TradeDeals, TradeDeal{
+			ID:        offer.ID,
+			Country1:  from.ID,
+			Country2:  player.ID,
+			Resource:  offer.Resource,
+			Amount:    offer.Amount,
+			Price:     offer.Price,
+			TurnsLeft: offer.Turns,
+		})
+		from.Relations[player.ID] += 5
+		g.AddEvent(fmt.Sprintf("📦 Trade deal signed with %s", from.Name))
+	}
+	return "accepted"
+}
+
+// settleTrades moves goods and money for every running deal with the player. Caller holds the lock.
+func (g *GameState) settleTrades(player *Country) {
+	active := g.TradeDeals[:0]
+	for _, d := range g.TradeDeals {
+		if d.Country2 == player.ID && d.TurnsLeft > 0 {
+			seller := g.Countries[d.Country1]
+			if seller != nil && !seller.IsEliminated && player.Economy >= d.Price {
+				player.Economy -= d.Price
+				player.Resources[d.Resource] += d.Amount
+				seller.Economy += d.Price
+			}
+			d.TurnsLeft--
+			if d.TurnsLeft == 0 {
+				if seller != nil {
+					g.AddEvent(fmt.Sprintf("📦 Trade deal with %s has concluded", seller.Name))
+				}
+				continue
+			}
+		}
+		active = append(active, d)
+	}
+	g.TradeDeals = active
 }
 
 // ACTION: Impose Sanctions
@@ -382,15 +575,17 @@ func (g *GameState) Espionage(targetID string) string {
 		return "Invalid target"
 	}
 
-	cost := 50.0
-	if player.Economy < cost {
+	if player.Economy < EspionageCost {
 		return "Insufficient funds for espionage"
 	}
 
-	player.Economy -= cost
+	player.Economy -= EspionageCost
 
 	successChance := (player.TechLevel / 100) * (1 - target.TechLevel/200)
 	if rand.Float64() < successChance {
+		// Any successful op also brings home their real numbers for a while
+		target.RevealedUntil = g.Turn + IntelTurns
+
 		// Success - steal tech or sabotage
 		action := rand.Intn(3)
 		switch action {
@@ -427,8 +622,8 @@ func (g *GameState) InvestEconomy() string {
 
 	player := g.Countries[g.PlayerCountry]
 
-	cost := player.Economy * 0.1
-	if cost < 50 {
+	cost := investCost(player)
+	if cost < InvestMinCost {
 		return "Economy too small for effective investment"
 	}
 
@@ -452,12 +647,11 @@ func (g *GameState) BuildMilitary() string {
 
 	player := g.Countries[g.PlayerCountry]
 
-	cost := 100.0
-	if player.Economy < cost {
+	if player.Economy < BuildMilitaryCost {
 		return "Insufficient funds for military buildup"
 	}
 
-	player.Economy -= cost
+	player.Economy -= BuildMilitaryCost
 	increase := 50 + rand.Float64()*50
 	player.Military += increase
 
@@ -479,12 +673,11 @@ func (g *GameState) Propaganda() string {
 
 	player := g.Countries[g.PlayerCountry]
 
-	cost := 30.0
-	if player.Economy < cost {
+	if player.Economy < PropagandaCost {
 		return "Insufficient funds for propaganda"
 	}
 
-	player.Economy -= cost
+	player.Economy -= PropagandaCost
 	boost := 10 + rand.Float64()*15
 	player.ApprovalRating += boost
 
@@ -504,12 +697,11 @@ func (g *GameState) FightCorruption() string {
 
 	player := g.Countries[g.PlayerCountry]
 
-	cost := 50.0
-	if player.Economy < cost {
+	if player.Economy < FightCorruptionCost {
 		return "Insufficient funds"
 	}
 
-	player.Economy -= cost
+	player.Economy -= FightCorruptionCost
 	reduction := 10 + rand.Float64()*15
 	player.Corruption -= reduction
 
@@ -530,7 +722,12 @@ func (g *GameState) AIRoutine() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
 		g.Mutex.Lock()
 
 		if g.GameOver {
@@ -538,62 +735,11 @@ func (g *GameState) AIRoutine() {
 			return
 		}
 
-		// AI countries take actions
-		for _, country := range g.Countries {
-			if country.IsPlayer || country.IsEliminated {
-				continue
-			}
+		// Memories fade between turns too, at half the turn rate
+		g.decayRelations(g.RelationDecay / 2)
 
-			action := rand.Intn(10)
-			switch action {
-			case 0, 1: // Economic investment
-				if country.Economy > 200 {
-					country.Economy *= 1.05
-				}
-			case 2: // Military buildup
-				if country.Economy > 150 && country.Stability > 40 {
-					country.Economy -= 80
-					country.Military += 30 + rand.Float64()*40
-					g.GlobalTension += 1
-				}
-			case 3: // Improve relations with random country
-				for targetID := range g.Countries {
-					target := g.Countries[targetID]
-					if targetID != country.ID && !target.IsEliminated {
-						country.Relations[targetID] += 5
-						break
-					}
-				}
-			case 4: // Form alliance
-				for targetID, relation := range country.Relations {
-					if relation > 60 && rand.Float64() < 0.1 {
-						target := g.Countries[targetID]
-						if !target.IsEliminated {
-							country.Alliances = append(country.Alliances, targetID)
-							target.Alliances = append(target.Alliances, country.ID)
-							g.AddEvent(fmt.Sprintf("🌍 %s and %s formed an alliance", country.Name, target.Name))
-							break
-						}
-					}
-				}
-			}
-
-			// Random events affect AI countries
-			if rand.Float64() < 0.05 {
-				event := rand.Intn(5)
-				switch event {
-				case 0:
-					country.Stability -= 10
-					g.AddEvent(fmt.Sprintf("📰 Political crisis in %s", country.Name))
-				case 1:
-					country.Economy *= 1.1
-					g.AddEvent(fmt.Sprintf("📰 Economic boom in %s", country.Name))
-				case 2:
-					country.ApprovalRating -= 15
-					g.AddEvent(fmt.Sprintf("📰 Protests erupted in %s", country.Name))
-				}
-			}
-		}
+		// AI countries take actions, see personality.go
+		g.aiTick(g.rng)
 
 		g.Mutex.Unlock()
 	}
@@ -644,11 +790,48 @@ func (g *GameState) CheckVictoryConditions() {
 	}
 }
 
+// CountryScore is the composite used to rank survivors when the turn limit hits
+func (g *GameState) CountryScore(c *Country) float64 {
+	if c.IsEliminated {
+		return 0
+	}
+	return c.Economy/100 + c.Military/10 + c.TechLevel*2 + c.Stability + float64(len(c.Alliances))*25
+}
+
+// endOnScore ranks surviving countries and crowns the best one. Caller holds the lock.
+func (g *GameState) endOnScore() {
+	var best *Country
+	bestScore := -1.0
+	for _, c := range g.Countries {
+		if score := g.CountryScore(c); !c.IsEliminated && score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+
+	g.GameOver = true
+	if best == nil {
+		g.VictoryType = "defeat"
+		return
+	}
+	g.WinnerID = best.ID
+	if best.ID == g.PlayerCountry {
+		g.VictoryType = "score"
+		g.AddEvent(fmt.Sprintf("🏆 SCORE VICTORY! After %d turns your nation stands above all (%.0f pts)", g.Turn, bestScore))
+	} else {
+		g.VictoryType = "defeat"
+		g.AddEvent(fmt.Sprintf("⌛ Time is up. %s leads the world with %.0f pts", best.Name, bestScore))
+	}
+}
+
 // Advance Turn
 func (g *GameState) NextTurn() string {
 	g.Mutex.Lock()
 	defer g.Mutex.Unlock()
 
+	if g.GameOver {
+		return "Game is over"
+	}
+
 	g.Turn++
 	player := g.Countries[g.PlayerCountry]
 
@@ -656,9 +839,28 @@ func (g *GameState) NextTurn() string {
 	growthRate := 0.02 * (player.Stability / 100) * (1 - player.Corruption/200)
 	player.Economy *= (1 + growthRate)
 
-	// Resource production
-	player.Resources["oil"] += 5 + rand.Float64()*10
-	player.Resources["food"] += 8 + rand.Float64()*12
+	// Resource production, boosted by refineries/farms/labs
+	produce(player, 5+rand.Float64()*10, 8+rand.Float64()*12)
+
+	// Trade deals deliver and unanswered offers lapse
+	g.settleTrades(player)
+	offers := g.PendingOffers[:0]
+	for _, o := range g.PendingOffers {
+		if o.ExpiresTurn >= g.Turn {
+			offers = append(offers, o)
+		}
+	}
+	g.PendingOffers = offers
+
+	// Old friendships and grudges fade
+	g.decayRelations(g.RelationDecay)
+
+	// Regimes in prolonged crisis may fall
+	for _, c := range g.Countries {
+		if !c.IsPlayer && !c.IsEliminated {
+			g.updateUnrest(c)
+		}
+	}
 
 	// UN sanctions wear off
 	if g.UNSanctions[player.ID] > 0 {
@@ -684,6 +886,12 @@ func (g *GameState) NextTurn() string {
 
 	g.AddEvent(fmt.Sprintf("📅 Turn %d complete. Economy: $%.1fB, Military: %.0f", g.Turn, player.Economy, player.Military))
 
+	g.CheckVictoryConditions()
+	if !g.GameOver && g.MaxTurns > 0 && g.Turn >= g.MaxTurns {
+		g.endOnScore()
+	}
+	g.recordTurn()
+
 	return "success"
 }
 
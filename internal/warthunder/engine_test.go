@@ -0,0 +1,48 @@
+package warthunder
+
+import "testing"
+
+func TestTurnLimitEndsOnScore(t *testing.T) {
+	g := CreateGame("turnlimit", "mg")
+	defer archiveGame("turnlimit", g)
+	g.MaxTurns = 5
+
+	for i := 0; i < 10 && !g.GameOver; i++ {
+		g.NextTurn()
+	}
+	if !g.GameOver {
+		t.Fatal("game still running past the turn limit")
+	}
+	if g.Turn != g.MaxTurns {
+		t.Errorf("ended on turn %d, want %d", g.Turn, g.MaxTurns)
+	}
+	winner := g.Countries[g.WinnerID]
+	if winner == nil {
+		t.Fatalf("no winner chosen (victory %q)", g.VictoryType)
+	}
+	for _, c := range g.Countries {
+		if g.CountryScore(c) > g.CountryScore(winner) {
+			t.Errorf("%s outscores the winner %s", c.ID, winner.ID)
+		}
+	}
+	// Madagascar can't outscore the superpowers in five turns
+	if g.VictoryType != "defeat" {
+		t.Errorf("victory %q, want defeat", g.VictoryType)
+	}
+}
+
+func TestScoreVictoryForPlayer(t *testing.T) {
+	g := &GameState{
+		PlayerCountry: "us",
+		Turn:          100,
+		Countries: map[string]*Country{
+			"us": {ID: "us", Economy: 20000, Military: 1000, TechLevel: 90, Stability: 80},
+			"cn": {ID: "cn", Economy: 18000, Military: 900, TechLevel: 85, Stability: 70},
+			"ru": {ID: "ru", Economy: 99999, Military: 9999, IsEliminated: true},
+		},
+	}
+	g.endOnScore()
+	if !g.GameOver || g.WinnerID != "us" || g.VictoryType != "score" {
+		t.Fatalf("over %v, winner %q, victory %q", g.GameOver, g.WinnerID, g.VictoryType)
+	}
+}
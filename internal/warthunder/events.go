@@ -0,0 +1,49 @@
+package warthunder
+
+import "encoding/json"
+
+// MaxEvents is how many events a game keeps for the news feed.
+const MaxEvents = 100
+
+// EventLog is a fixed-size ring of the most recent events. Adding is O(1)
+// and never allocates, which matters since the AI logs events on every tick
+// across every running game. The zero value is an empty log.
+type EventLog struct {
+	buf   [MaxEvents]string
+	next  int // Slot the next event goes into
+	count int
+}
+
+// Add records msg, dropping the oldest event once the log is full.
+func (l *EventLog) Add(msg string) {
+	l.buf[l.next] = msg
+	l.next = (l.next + 1) % MaxEvents
+	if l.count < MaxEvents {
+		l.count++
+	}
+}
+
+// Len is how many events are retained, at most MaxEvents.
+func (l *EventLog) Len() int {
+	return l.count
+}
+
+// Newest returns up to n events, newest first.
+func (l *EventLog) Newest(n int) []string {
+	if n > l.count {
+		n = l.count
+	}
+	if n < 0 {
+		n = 0
+	}
+	out := make([]string, n)
+	for i := 0; i < n; i++ {
+		out[i] = l.buf[(l.next-1-i+MaxEvents)%MaxEvents]
+	}
+	return out
+}
+
+// MarshalJSON keeps the API shape of a plain newest-first list.
+func (l *EventLog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Newest(MaxEvents))
+}
@@ -0,0 +1,40 @@
+package warthunder
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestEventLogKeepsNewest(t *testing.T) {
+	var l EventLog
+	if got := l.Newest(10); len(got) != 0 {
+		t.Fatalf("empty log returned %v", got)
+	}
+
+	for i := 0; i < 1000; i++ {
+		l.Add(strconv.Itoa(i))
+	}
+	if l.Len() != MaxEvents {
+		t.Fatalf("len %d after 1000 adds, want %d", l.Len(), MaxEvents)
+	}
+
+	got := l.Newest(MaxEvents + 50)
+	if len(got) != MaxEvents {
+		t.Fatalf("%d events, want %d", len(got), MaxEvents)
+	}
+	for i, e := range got {
+		if want := strconv.Itoa(999 - i); e != want {
+			t.Fatalf("event %d is %q, want %q (newest first)", i, e, want)
+		}
+	}
+
+	var fromJSON []string
+	raw, _ := json.Marshal(&l)
+	if err := json.Unmarshal(raw, &fromJSON); err != nil || len(fromJSON) != MaxEvents || fromJSON[0] != "999" {
+		t.Errorf("JSON %v, %v", fromJSON, err)
+	}
+	if got := l.Newest(-1); len(got) != 0 {
+		t.Errorf("negative n returned %d events", len(got))
+	}
+}
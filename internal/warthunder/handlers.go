@@ -3,9 +3,11 @@ package warthunder
 import (
 	"encoding/json"
 	"html/template"
+	"log"
 	"net/http"
 	"path/filepath"
 
+	"main/internal/auth"
 	"main/internal/data"
 )
 
@@ -13,10 +15,8 @@ import (
 func NewHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := "guest"
-		if c, err := r.Cookie("user_id"); err == nil && c.Value != "" {
-			userID = c.Value
-		} else if q := r.URL.Query().Get("userID"); q != "" {
-			userID = q
+		if id, ok := auth.UserID(r); ok {
+			userID = id
 		}
 
 		lang := r.URL.Query().Get("lang")
@@ -45,14 +45,7 @@ func NewAPIHandler(store *data.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		userID := r.URL.Query().Get("userID")
-		if userID == "" {
-			// fallback to cookie
-			if c, err := r.Cookie("user_id"); err == nil {
-				userID = c.Value
-			}
-		}
-
+		userID, _ := auth.UserID(r)
 		if userID == "" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -71,18 +64,23 @@ func NewAPIHandler(store *data.Store) http.HandlerFunc {
 			}
 
 			game.Mutex.RLock()
-			defer game.Mutex.RUnlock()
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status": "playing",
-				"game":   game,
-			})
+			over := game.GameOver
+			game.Mutex.RUnlock()
+			if over {
+				writeEnded(w, userID, game, "")
+				return
+			}
+
+			writeGame(w, map[string]interface{}{"status": "playing"}, game)
 			return
 		}
 
 		if r.Method == "POST" {
 			var req struct {
-				Action  string `json:"action"`  // start, attack, diplomat, formAlliance, imposeSanctions, espionage, investEconomy, buildMilitary, propaganda, fightCorruption, nextTurn
-				Payload string `json:"payload"` // countryID or empty for self-actions
+				Action        string   `json:"action"`        // start, attack, diplomat, formAlliance, imposeSanctions, espionage, fomentRevolution, investEconomy, buildMilitary, propaganda, fightCorruption, buildRefinery, buildFarms, fundResearch, nextTurn, acceptOffer, rejectOffer
+				Payload       string   `json:"payload"`       // countryID, offerID, or empty for self-actions
+				MaxTurns      int      `json:"maxTurns"`      // Optional on start, defaults to DefaultMaxTurns
+				RelationDecay *float64 `json:"relationDecay"` // Optional on start (0..1), defaults to DefaultRelationDecay
 			}
 
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -93,10 +91,17 @@ func NewAPIHandler(store *data.Store) http.HandlerFunc {
 			// Handle game start
 			if req.Action == "start" {
 				game := CreateGame(userID, req.Payload)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"status": "started",
-					"game":   game,
-				})
+				if req.MaxTurns > 0 {
+					game.Mutex.Lock()
+					game.MaxTurns = req.MaxTurns
+					game.Mutex.Unlock()
+				}
+				if req.RelationDecay != nil && *req.RelationDecay >= 0 && *req.RelationDecay <= 1 {
+					game.Mutex.Lock()
+					game.RelationDecay = *req.RelationDecay
+					game.Mutex.Unlock()
+				}
+				writeGame(w, map[string]interface{}{"status": "started"}, game)
 				return
 			}
 
@@ -126,6 +131,9 @@ func NewAPIHandler(store *data.Store) http.HandlerFunc {
 			case "espionage":
 				msg = game.Espionage(req.Payload)
 
+			case "fomentRevolution":
+				msg = game.FomentRevolution(req.Payload)
+
 			case "investEconomy":
 				msg = game.InvestEconomy()
 
@@ -138,21 +146,104 @@ func NewAPIHandler(store *data.Store) http.HandlerFunc {
 			case "fightCorruption":
 				msg = game.FightCorruption()
 
+			case "buildRefinery":
+				msg = game.BuildRefinery()
+
+			case "buildFarms":
+				msg = game.BuildFarms()
+
+			case "fundResearch":
+				msg = game.FundResearch()
+
 			case "nextTurn":
 				msg = game.NextTurn()
 
+			case "acceptOffer":
+				msg = game.RespondToOffer(req.Payload, true)
+
+			case "rejectOffer":
+				msg = game.RespondToOffer(req.Payload, false)
+
 			default:
 				msg = "Unknown action"
 			}
 
-			// Return updated state
+			// Finished games are kept for the campaign recap
+			if replay, ok := game.takeReplay(); ok {
+				if body, err := json.Marshal(replay); err == nil {
+					if err := store.SaveWarthunderReplay(userID, body); err != nil {
+						log.Printf("[WARTHUNDER] Could not save replay for %s: %v", userID, err)
+					}
+				}
+			}
+
 			game.Mutex.RLock()
-			defer game.Mutex.RUnlock()
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":  "ok",
-				"message": msg,
-				"game":    game,
-			})
+			over := game.GameOver
+			game.Mutex.RUnlock()
+			if over {
+				writeEnded(w, userID, game, msg)
+				return
+			}
+
+			// Return updated state
+			writeGame(w, map[string]interface{}{"status": "ok", "message": msg}, game)
 		}
 	}
 }
+
+// NewReplayHandler returns the user's most recent finished campaign.
+func NewReplayHandler(store *data.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := auth.UserID(r)
+		if userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := store.GetWarthunderReplay(userID)
+		if err != nil {
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+		if body == nil {
+			http.Error(w, "No finished game", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// writeEnded reports a finished game with its summary, then archives it so
+// the next GET goes back to country selection.
+func writeEnded(w http.ResponseWriter, userID string, game *GameState, msg string) {
+	game.Mutex.Lock()
+	summary := game.summary()
+	game.Mutex.Unlock()
+	archiveGame(userID, game)
+
+	resp := map[string]interface{}{
+		"status":      "ended",
+		"victoryType": summary.VictoryType,
+		"summary":     summary,
+	}
+	if msg != "" {
+		resp["message"] = msg
+	}
+	writeGame(w, resp, game)
+}
+
+// writeGame adds the game to resp and encodes it while read-locked, but
+// writes it out after unlocking so a slow client never holds up the AI tick
+// or another request's action.
+func writeGame(w http.ResponseWriter, resp map[string]interface{}, game *GameState) {
+	game.Mutex.RLock()
+	resp["game"] = game
+	body, err := json.Marshal(resp)
+	game.Mutex.RUnlock()
+	if err != nil {
+		http.Error(w, "Could not encode game", http.StatusInternalServerError)
+		return
+	}
+	w.Write(body)
+}
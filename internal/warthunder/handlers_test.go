@@ -0,0 +1,103 @@
+package warthunder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"main/internal/auth"
+)
+
+func call(h http.HandlerFunc, userID, method, body string) *httptest.ResponseRecorder {
+	req := auth.WithUserID(httptest.NewRequest(method, "/api", strings.NewReader(body)), userID)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestCoupEndsGame(t *testing.T) {
+	const userID = "u_coup"
+	h := NewAPIHandler(nil)
+	call(h, userID, http.MethodPost, `{"action":"start","payload":"ru"}`)
+	game := GetGame(userID)
+	defer archiveGame(userID, game)
+
+	// An unpopular autocrat marching an empty army on a superpower
+	game.Mutex.Lock()
+	game.Countries["ru"].ApprovalRating = 0
+	game.Countries["ru"].Military = 0 // Can never win, so every war ends in defeat
+	game.Mutex.Unlock()
+	for i := 0; i < 200 && !game.GameOver; i++ {
+		game.Attack("us")
+	}
+	if !game.GameOver {
+		t.Fatal("no coup after 200 lost wars")
+	}
+
+	var resp struct {
+		Status      string  `json:"status"`
+		VictoryType string  `json:"victoryType"`
+		Summary     Summary `json:"summary"`
+	}
+	if err := json.NewDecoder(call(h, userID, http.MethodGet, "").Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "ended" || resp.VictoryType != "defeat" || resp.Summary.VictoryType != "defeat" {
+		t.Fatalf("status %q victory %q summary %+v, want ended by defeat", resp.Status, resp.VictoryType, resp.Summary)
+	}
+	if resp.Summary.TurnsSurvived != 1 {
+		t.Errorf("survived %d turns, want 1", resp.Summary.TurnsSurvived)
+	}
+
+	// Reported once, then back to country selection
+	if body := call(h, userID, http.MethodGet, "").Body.String(); !strings.Contains(body, `"status":"selection"`) {
+		t.Errorf("next GET: %s", body)
+	}
+}
+
+// Run with -race: reads of the state must never overlap the AI or an action
+// writing to it.
+func TestStateReadsDuringAIAndActions(t *testing.T) {
+	const userID = "u_hammer"
+	h := NewAPIHandler(nil)
+	if rec := call(h, userID, http.MethodPost, `{"action":"start","payload":"de"}`); rec.Code != http.StatusOK {
+		t.Fatalf("start: %d %s", rec.Code, rec.Body)
+	}
+	game := GetGame(userID)
+	defer archiveGame(userID, game)
+
+	var wg sync.WaitGroup
+	run := func(n int, f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				f(i)
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		run(50, func(int) {
+			if rec := call(h, userID, http.MethodGet, ""); rec.Code != http.StatusOK {
+				t.Errorf("GET: %d", rec.Code)
+			}
+		})
+	}
+	run(50, func(int) {
+		// What AIRoutine does on each tick
+		game.Mutex.Lock()
+		game.decayRelations(game.RelationDecay / 2)
+		game.aiTick(game.rng)
+		game.Mutex.Unlock()
+	})
+	actions := []string{"investEconomy", "buildMilitary", "propaganda", "diplomat", "espionage"}
+	run(50, func(i int) {
+		a := actions[i%len(actions)]
+		call(h, userID, http.MethodPost, fmt.Sprintf(`{"action":%q,"payload":"fr"}`, a))
+	})
+	wg.Wait()
+}
@@ -0,0 +1,87 @@
+package warthunder
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// IntelTurns is how long a successful espionage mission keeps a country's
+// real numbers visible.
+const IntelTurns = 3
+
+// Intel levels reported on each country in the player's view.
+const (
+	IntelFull     = "full"
+	IntelEstimate = "estimate"
+)
+
+// MarshalJSON serializes the game as the player sees it: their own country,
+// allies, and recently spied-on countries in full; everyone else rounded to
+// rough estimates with resources, alliances and foreign relations hidden. The current
+// price of each paid action rides along under "actions". Callers hold
+// g.Mutex (at least RLock) like any other read.
+func (g *GameState) MarshalJSON() ([]byte, error) {
+	type alias GameState // Drops the method so we don't recurse
+	view := make(map[string]*Country, len(g.Countries))
+	for id, c := range g.Countries {
+		view[id] = g.intelView(c)
+	}
+	return json.Marshal(&struct {
+		*alias
+		Countries map[string]*Country   `json:"countries"`
+		Actions   map[string]ActionCost `json:"actions"`
+	}{
+		alias:     (*alias)(g),
+		Countries: view,
+		Actions:   g.actionCosts(),
+	})
+}
+
+func (g *GameState) hasIntelOn(c *Country) bool {
+	if c.ID == g.PlayerCountry || c.IsEliminated || c.RevealedUntil >= g.Turn {
+		return true
+	}
+	if player, ok := g.Countries[g.PlayerCountry]; ok && isAllied(player, c.ID) {
+		return true // Allies share their books
+	}
+	return false
+}
+
+// intelView returns c itself when fully known, otherwise a redacted copy.
+func (g *GameState) intelView(c *Country) *Country {
+	if g.hasIntelOn(c) {
+		full := *c
+		full.Intel = IntelFull
+		return &full
+	}
+
+	est := *c
+	est.Intel = IntelEstimate
+	est.Population = int64(roughly(float64(c.Population)))
+	est.Economy = roughly(c.Economy)
+	est.Military = roughly(c.Military)
+	est.TechLevel = banded(c.TechLevel, 20)
+	est.Stability = banded(c.Stability, 20)
+	est.ApprovalRating = banded(c.ApprovalRating, 20)
+	est.Corruption = banded(c.Corruption, 20)
+	est.Resources = nil
+	est.Facilities = nil
+	est.Unrest = 0
+	est.Alliances = nil // Pacts with us would have given full intel
+	// We only know how they feel about us
+	est.Relations = map[string]float64{g.PlayerCountry: banded(c.Relations[g.PlayerCountry], 25)}
+	return &est
+}
+
+// roughly keeps one significant figure: 23000 -> 20000, 1700 -> 2000.
+func roughly(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(math.Abs(v))))
+	return math.Round(v/mag) * mag
+}
+
+func banded(v, step float64) float64 {
+	return math.Round(v/step) * step
+}
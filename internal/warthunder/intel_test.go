@@ -0,0 +1,78 @@
+package warthunder
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func intelGame() *GameState {
+	return &GameState{
+		PlayerCountry: "us",
+		Turn:          5,
+		Countries: map[string]*Country{
+			"us": {ID: "us", Population: 331_449_281, Economy: 23315, Alliances: []string{"uk"}},
+			"uk": {ID: "uk", Population: 67_081_234, Economy: 3131, Alliances: []string{"us"}},
+			"ru": {
+				ID: "ru", Population: 143_449_286, Economy: 1778, Military: 87, TechLevel: 63,
+				Alliances: []string{"by"}, Resources: map[string]float64{"oil": 90},
+				Relations: map[string]float64{"us": -61, "by": 80},
+			},
+			"cn": {ID: "cn", Population: 1_412_000_000, Economy: 17734, RevealedUntil: 5},
+		},
+	}
+}
+
+func viewOf(t *testing.T, g *GameState) map[string]Country {
+	t.Helper()
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Countries map[string]Country `json:"countries"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Countries
+}
+
+func TestIntelRedactsStrangers(t *testing.T) {
+	ru := viewOf(t, intelGame())["ru"]
+	if ru.Intel != IntelEstimate {
+		t.Fatalf("intel %q, want %q", ru.Intel, IntelEstimate)
+	}
+	if ru.Population != 100_000_000 || ru.Economy != 2000 || ru.Military != 90 || ru.TechLevel != 60 {
+		t.Errorf("estimates pop %d econ %v mil %v tech %v", ru.Population, ru.Economy, ru.Military, ru.TechLevel)
+	}
+	if len(ru.Alliances) != 0 || len(ru.Resources) != 0 {
+		t.Errorf("alliances %v and resources %v leaked", ru.Alliances, ru.Resources)
+	}
+	if len(ru.Relations) != 1 || ru.Relations["us"] != -50 {
+		t.Errorf("relations %v, want only ours, banded", ru.Relations)
+	}
+}
+
+func TestIntelFullForSelfAlliesAndSpied(t *testing.T) {
+	g := intelGame()
+	view := viewOf(t, g)
+	for _, id := range []string{"us", "uk", "cn"} {
+		c := view[id]
+		if c.Intel != IntelFull || c.Population != g.Countries[id].Population || c.Economy != g.Countries[id].Economy {
+			t.Errorf("%s: intel %q pop %d econ %v, want the real numbers", id, c.Intel, c.Population, c.Economy)
+		}
+	}
+
+	g.Turn = 6 // Espionage on cn has run out
+	if cn := viewOf(t, g)["cn"]; cn.Intel != IntelEstimate || cn.Population != 1_000_000_000 {
+		t.Errorf("expired intel: %q pop %d", cn.Intel, cn.Population)
+	}
+}
+
+func TestIntelLeavesStateAlone(t *testing.T) {
+	g := intelGame()
+	viewOf(t, g)
+	if ru := g.Countries["ru"]; ru.Population != 143_449_286 || len(ru.Alliances) != 1 {
+		t.Fatal("marshalling redacted the real country")
+	}
+}
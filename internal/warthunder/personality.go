@@ -0,0 +1,206 @@
+package warthunder
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// aiAction is one thing an AI country can do on its tick.
+type aiAction int
+
+const (
+	aiInvest   aiAction = iota // Grow the economy
+	aiArm                      // Trade money for military
+	aiBefriend                 // Warm relations with someone
+	aiAlly                     // Turn a close friend into an ally
+	aiCourt                    // Send the player an offer
+	aiRaid                     // Border clash with a rival AI
+	aiIdle                     // Nothing gets done
+	aiActionCount
+)
+
+// Personality weights how likely each action is. Countries with the same
+// profile still diverge through their own numbers and relations.
+type Personality struct {
+	Name    string
+	Weights [aiActionCount]float64
+}
+
+var (
+	// Autocracies arm up and pick fights
+	warmonger = Personality{Name: "warmonger", Weights: [aiActionCount]float64{
+		aiInvest: 2, aiArm: 4, aiBefriend: 0.5, aiAlly: 0.5, aiCourt: 0.5, aiRaid: 2, aiIdle: 0.5,
+	}}
+	// Corrupt states mostly stand still
+	kleptocrat = Personality{Name: "kleptocrat", Weights: [aiActionCount]float64{
+		aiInvest: 1, aiArm: 1, aiBefriend: 1, aiAlly: 0.5, aiCourt: 1, aiRaid: 0.3, aiIdle: 5,
+	}}
+	// Democracies trade and make friends, and never start a war
+	trader = Personality{Name: "trader", Weights: [aiActionCount]float64{
+		aiInvest: 4, aiArm: 1, aiBefriend: 2, aiAlly: 2, aiCourt: 1.5, aiRaid: 0, aiIdle: 0.5,
+	}}
+	// Anything else keeps the old even spread
+	opportunist = Personality{Name: "opportunist", Weights: [aiActionCount]float64{
+		aiInvest: 2, aiArm: 2, aiBefriend: 1, aiAlly: 1, aiCourt: 1, aiRaid: 0.5, aiIdle: 2.5,
+	}}
+)
+
+// KleptocracyCorruption is where a state stops being run by its government
+// and starts being run for its officials.
+const KleptocracyCorruption = 60.0
+
+// personalityFor reads a country's profile off its current attributes, so a
+// revolution or a corruption purge changes how it behaves.
+func personalityFor(c *Country) Personality {
+	switch {
+	case c.Government == "autocracy":
+		return warmonger
+	case c.Corruption >= KleptocracyCorruption:
+		return kleptocrat
+	case c.Government == "democracy":
+		return trader
+	}
+	return opportunist
+}
+
+// pick chooses an action from the weights using r in [0, 1).
+func (p Personality) pick(r float64) aiAction {
+	total := 0.0
+	for _, w := range p.Weights {
+		total += w
+	}
+	r *= total
+	for a, w := range p.Weights {
+		if r < w {
+			return aiAction(a)
+		}
+		r -= w
+	}
+	return aiIdle
+}
+
+// sortedIDs lists the country IDs in a fixed order so the AI consumes the
+// game's random stream the same way every run.
+func (g *GameState) sortedIDs() []string {
+	ids := make([]string, 0, len(g.Countries))
+	for id := range g.Countries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// aiTick has every AI country take one action. Caller holds g.Mutex.
+func (g *GameState) aiTick(rng *rand.Rand) {
+	ids := g.sortedIDs()
+	for _, id := range ids {
+		country := g.Countries[id]
+		if country.IsPlayer || country.IsEliminated {
+			continue
+		}
+
+		switch personalityFor(country).pick(rng.Float64()) {
+		case aiInvest:
+			if country.Economy > 200 {
+				country.Economy *= 1.05
+			}
+		case aiArm:
+			if country.Economy > 150 && country.Stability > 40 {
+				country.Economy -= 80
+				country.Military += 30 + rng.Float64()*40
+				g.GlobalTension += 1
+			}
+		case aiBefriend:
+			for _, targetID := range ids {
+				if targetID != country.ID && !g.Countries[targetID].IsEliminated {
+					country.Relations[targetID] += 5
+					break
+				}
+			}
+		case aiAlly:
+			for _, targetID := range ids {
+				target := g.Countries[targetID]
+				if targetID == country.ID || target.IsEliminated || isAllied(country, targetID) {
+					continue
+				}
+				if country.Relations[targetID] > 60 && rng.Float64() < 0.1 {
+					country.Alliances = append(country.Alliances, targetID)
+					target.Alliances = append(target.Alliances, country.ID)
+					g.AddEvent(fmt.Sprintf("🌍 %s and %s formed an alliance", country.Name, target.Name))
+					break
+				}
+			}
+		case aiCourt:
+			g.proposeToPlayer(country, rng)
+		case aiRaid:
+			g.raid(country, ids, rng)
+		}
+
+		// Random events affect AI countries
+		if rng.Float64() < 0.05 {
+			switch rng.Intn(5) {
+			case 0:
+				country.Stability -= 10
+				g.AddEvent(fmt.Sprintf("📰 Political crisis in %s", country.Name))
+			case 1:
+				country.Economy *= 1.1
+				g.AddEvent(fmt.Sprintf("📰 Economic boom in %s", country.Name))
+			case 2:
+				country.ApprovalRating -= 15
+				g.AddEvent(fmt.Sprintf("📰 Protests erupted in %s", country.Name))
+			}
+		}
+	}
+}
+
+// raid sends country against its worst-liked rival in reach. It's a border
+// clash, not a conquest: nobody is annexed and the player is never the target.
+func (g *GameState) raid(country *Country, ids []string, rng *rand.Rand) {
+	if country.Military < 100 {
+		return
+	}
+	var target *Country
+	for _, id := range ids {
+		c := g.Countries[id]
+		if c == country || c.IsPlayer || c.IsEliminated || isAllied(country, id) {
+			continue
+		}
+		if country.Relations[id] >= 0 || !g.inReach(country, c) {
+			continue
+		}
+		if target == nil || country.Relations[id] < country.Relations[target.ID] {
+			target = c
+		}
+	}
+	if target == nil {
+		return
+	}
+
+	attackPower, defensePower := g.combatPowers(country, target, target.Military)
+	winner, loser := country, target
+	if rng.Float64() >= attackPower/(attackPower+defensePower) {
+		winner, loser = target, country
+	}
+	spoils := loser.Economy * 0.1
+	winner.Economy += spoils
+	loser.Economy -= spoils
+	winner.Military *= 0.9
+	loser.Military *= 0.8
+	loser.Stability -= 5
+
+	country.Relations[target.ID] = clampRelation(country.Relations[target.ID] - 20)
+	target.Relations[country.ID] = clampRelation(target.Relations[country.ID] - 20)
+	g.GlobalTension += 3
+	g.AddEvent(fmt.Sprintf("⚔️ Border clash: %s attacked %s, %s came out on top", country.Name, target.Name, winner.Name))
+}
+
+func clampRelation(r float64) float64 {
+	if r < -100 {
+		return -100
+	}
+	if r > 100 {
+		return 100
+	}
+	return r
+}
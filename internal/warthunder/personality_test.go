@@ -0,0 +1,52 @@
+package warthunder
+
+import (
+	"strings"
+	"testing"
+)
+
+// raidsBy counts the border clashes an AI with the given government starts
+// against a hated neighbour over many ticks on a fixed seed.
+func raidsBy(government string, ticks int) int {
+	g := &GameState{Countries: map[string]*Country{
+		"aa": {ID: "aa", Name: "Aggressor", Government: government, Economy: 1000, Stability: 80},
+		"bb": {ID: "bb", Name: "Neighbour", Government: "democracy", Economy: 1000, Stability: 80},
+	}}
+	g.reseed(1)
+
+	raids := 0
+	for i := 0; i < ticks; i++ {
+		// Keep the raid possible every tick
+		a, b := g.Countries["aa"], g.Countries["bb"]
+		a.Military, b.Military = 500, 200
+		a.Relations = map[string]float64{"bb": -80}
+		b.Relations = map[string]float64{"aa": -80}
+		g.Events = EventLog{}
+
+		g.aiTick(g.rng)
+		for _, e := range g.Events.Newest(MaxEvents) {
+			if strings.Contains(e, "Aggressor attacked") {
+				raids++
+			}
+		}
+	}
+	return raids
+}
+
+func TestAggressiveProfileRaidsMore(t *testing.T) {
+	if personalityFor(&Country{Government: "autocracy"}).Name != warmonger.Name ||
+		personalityFor(&Country{Government: "democracy"}).Name != trader.Name {
+		t.Fatal("profiles aren't read off the government")
+	}
+
+	aggressive, pacifist := raidsBy("autocracy", 1000), raidsBy("democracy", 1000)
+	if aggressive <= pacifist || aggressive < 100 {
+		t.Fatalf("autocracy raided %d times, democracy %d", aggressive, pacifist)
+	}
+}
+
+func TestSeedReplaysAI(t *testing.T) {
+	if a, b := raidsBy("monarchy", 500), raidsBy("monarchy", 500); a != b {
+		t.Fatalf("same seed raided %d then %d times", a, b)
+	}
+}
@@ -0,0 +1,78 @@
+package warthunder
+
+import (
+	"fmt"
+	"math"
+)
+
+// Facilities a country can build to specialise its output. Each level is
+// permanent and stacks up to MaxFacilityLevel.
+const (
+	FacilityRefinery = "refinery" // More oil per turn
+	FacilityFarm     = "farm"     // More food per turn
+	FacilityLab      = "lab"      // Tech stockpile and tech level growth
+
+	MaxFacilityLevel = 5
+
+	RefineryOilBoost = 0.3 // +30% oil output per level
+	FarmFoodBoost    = 0.3 // +30% food output per level
+	LabTechYield     = 8.0 // Tech units per level per turn
+	LabTechGrowth    = 0.5 // Tech level per level per turn
+)
+
+// facilityBaseCost is the price of the first level; each level after costs
+// one more multiple of it.
+var facilityBaseCost = map[string]float64{
+	FacilityRefinery: BuildRefineryCost,
+	FacilityFarm:     BuildFarmCost,
+	FacilityLab:      FundResearchCost,
+}
+
+func facilityCost(c *Country, facility string) float64 {
+	return facilityBaseCost[facility] * float64(c.Facilities[facility]+1)
+}
+
+// ACTION: Build a refinery
+func (g *GameState) BuildRefinery() string {
+	return g.buildFacility(FacilityRefinery, "🛢️ Refinery expanded to level %d, oil output up")
+}
+
+// ACTION: Build farms
+func (g *GameState) BuildFarms() string {
+	return g.buildFacility(FacilityFarm, "🌾 Farmland expanded to level %d, food output up")
+}
+
+// ACTION: Fund research labs
+func (g *GameState) FundResearch() string {
+	return g.buildFacility(FacilityLab, "🔬 Research labs funded to level %d, tech growth up")
+}
+
+func (g *GameState) buildFacility(facility, event string) string {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	player := g.Countries[g.PlayerCountry]
+	if player.Facilities[facility] >= MaxFacilityLevel {
+		return "Already at maximum level"
+	}
+	cost := facilityCost(player, facility)
+	if player.Economy < cost {
+		return "Insufficient funds"
+	}
+
+	player.Economy -= cost
+	player.Facilities[facility]++
+	g.AddEvent(fmt.Sprintf(event, player.Facilities[facility]))
+	return "success"
+}
+
+// produce adds one turn of resource output for c, scaled by its facilities.
+// oil and food are the turn's unmodified rolls. Caller holds the lock.
+func produce(c *Country, oil, food float64) {
+	c.Resources["oil"] += oil * (1 + RefineryOilBoost*float64(c.Facilities[FacilityRefinery]))
+	c.Resources["food"] += food * (1 + FarmFoodBoost*float64(c.Facilities[FacilityFarm]))
+	if labs := float64(c.Facilities[FacilityLab]); labs > 0 {
+		c.Resources["tech"] += LabTechYield * labs
+		c.TechLevel = math.Min(100, c.TechLevel+LabTechGrowth*labs)
+	}
+}
@@ -0,0 +1,68 @@
+package warthunder
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRefineryRaisesOilAccrual(t *testing.T) {
+	g := &GameState{
+		PlayerCountry: "us",
+		Countries: map[string]*Country{"us": {
+			ID: "us", Economy: 1000,
+			Resources: map[string]float64{"oil": 0, "food": 0}, Facilities: map[string]int{},
+		}},
+	}
+	us := g.Countries["us"]
+	twin := &Country{Resources: map[string]float64{"oil": 0, "food": 0}, Facilities: map[string]int{}}
+
+	if got := g.BuildRefinery(); got != "success" {
+		t.Fatalf("build: %q", got)
+	}
+	if us.Facilities[FacilityRefinery] != 1 || us.Economy != 1000-BuildRefineryCost {
+		t.Fatalf("level %d economy %v after one build", us.Facilities[FacilityRefinery], us.Economy)
+	}
+
+	// Same rolls each turn, so any difference is the refinery
+	rng := rand.New(rand.NewSource(1))
+	for turn := 0; turn < 10; turn++ {
+		oil, food := 5+rng.Float64()*10, 8+rng.Float64()*12
+		produce(us, oil, food)
+		produce(twin, oil, food)
+	}
+	if want := twin.Resources["oil"] * (1 + RefineryOilBoost); math.Abs(us.Resources["oil"]-want) > 1e-9 {
+		t.Errorf("oil with a refinery %v, want %v (plain %v)", us.Resources["oil"], want, twin.Resources["oil"])
+	}
+	if us.Resources["food"] != twin.Resources["food"] {
+		t.Errorf("a refinery changed food: %v vs %v", us.Resources["food"], twin.Resources["food"])
+	}
+}
+
+func TestFacilityCostClimbsToMax(t *testing.T) {
+	g := &GameState{
+		PlayerCountry: "us",
+		Countries:     map[string]*Country{"us": {ID: "us", Economy: 1e6, Facilities: map[string]int{}}},
+	}
+	us := g.Countries["us"]
+
+	spent := 0.0
+	for level := 1; level <= MaxFacilityLevel; level++ {
+		before := us.Economy
+		if got := g.BuildRefinery(); got != "success" {
+			t.Fatalf("level %d: %q", level, got)
+		}
+		if cost := before - us.Economy; cost != BuildRefineryCost*float64(level) {
+			t.Errorf("level %d cost %v, want %v", level, cost, BuildRefineryCost*float64(level))
+		}
+		spent += before - us.Economy
+	}
+	if got := g.BuildRefinery(); got != "Already at maximum level" || us.Economy != 1e6-spent {
+		t.Errorf("past the max: %q, economy %v", got, us.Economy)
+	}
+
+	us.Economy = 0
+	if got := g.FundResearch(); got != "Insufficient funds" || us.Facilities[FacilityLab] != 0 {
+		t.Errorf("broke: %q, lab level %d", got, us.Facilities[FacilityLab])
+	}
+}
@@ -0,0 +1,48 @@
+package warthunder
+
+// DefaultRelationDecay is the fraction of the gap to the neutral baseline
+// that relations close each turn. 0.05 halves a grudge in ~14 turns.
+const DefaultRelationDecay = 0.05
+
+// relationBaseline is where a pair's relations drift when nobody is doing
+// anything: like-minded governments get along a little better.
+func relationBaseline(a, b *Country) float64 {
+	base := 0.0
+	if a.Government == b.Government {
+		base += 10
+	} else {
+		base -= 5
+	}
+	if a.Ideology == b.Ideology {
+		base += 5
+	}
+	return base
+}
+
+// decayRelations pulls every relation toward its baseline by rate. Alliances
+// hold relations up at least at the baseline so allies don't cool into
+// indifference on their own. Caller holds g.Mutex.
+func (g *GameState) decayRelations(rate float64) {
+	if rate <= 0 {
+		return
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	for _, c := range g.Countries {
+		if c.IsEliminated {
+			continue
+		}
+		for otherID, rel := range c.Relations {
+			other, ok := g.Countries[otherID]
+			if !ok || other.IsEliminated {
+				continue
+			}
+			base := relationBaseline(c, other)
+			if isAllied(c, otherID) && rel > base {
+				continue
+			}
+			c.Relations[otherID] = rel + (base-rel)*rate
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package warthunder
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRelationsDriftToBaseline(t *testing.T) {
+	// Different governments, same ideology: baseline -5 + 5 = 0
+	ru := &Country{ID: "ru", Government: "autocracy", Ideology: "nationalist", Relations: map[string]float64{"fr": -100}}
+	fr := &Country{ID: "fr", Government: "democracy", Ideology: "nationalist", Relations: map[string]float64{"ru": 100}}
+	g := &GameState{Countries: map[string]*Country{"ru": ru, "fr": fr}}
+
+	prevGrudge, prevFondness := -100.0, 100.0
+	for turn := 0; turn < 100; turn++ {
+		g.decayRelations(DefaultRelationDecay)
+		grudge, fondness := ru.Relations["fr"], fr.Relations["ru"]
+		if grudge < prevGrudge || grudge > 0 || fondness > prevFondness || fondness < 0 {
+			t.Fatalf("turn %d: %v and %v overshot or moved away from 0", turn, grudge, fondness)
+		}
+		prevGrudge, prevFondness = grudge, fondness
+	}
+	if math.Abs(ru.Relations["fr"]) > 1 || math.Abs(fr.Relations["ru"]) > 1 {
+		t.Fatalf("after 100 turns still at %v and %v, want near 0", ru.Relations["fr"], fr.Relations["ru"])
+	}
+
+	// 0.05 a turn roughly halves a grudge in 14 turns
+	ru.Relations["fr"] = -100
+	for turn := 0; turn < 14; turn++ {
+		g.decayRelations(DefaultRelationDecay)
+	}
+	if r := ru.Relations["fr"]; r < -55 || r > -45 {
+		t.Errorf("after 14 turns a -100 grudge is %v, want about -50", r)
+	}
+}
+
+func TestAllianceHoldsRelationsUp(t *testing.T) {
+	uk := &Country{ID: "uk", Government: "democracy", Alliances: []string{"us"}, Relations: map[string]float64{"us": 90}}
+	us := &Country{ID: "us", Government: "democracy", Alliances: []string{"uk"}, Relations: map[string]float64{"uk": 90}}
+	g := &GameState{Countries: map[string]*Country{"uk": uk, "us": us}, RelationDecay: DefaultRelationDecay}
+	for turn := 0; turn < 50; turn++ {
+		g.decayRelations(g.RelationDecay)
+	}
+	if uk.Relations["us"] != 90 {
+		t.Errorf("allies cooled to %v", uk.Relations["us"])
+	}
+
+	g.decayRelations(0) // Frozen
+	if uk.Relations["us"] != 90 {
+		t.Error("a zero rate moved relations")
+	}
+}
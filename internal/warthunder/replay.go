@@ -0,0 +1,94 @@
+package warthunder
+
+import "time"
+
+// MaxReplayTurns caps how many turn records a game keeps. Past it the oldest
+// records after the opening one are dropped, so a recap always starts at the
+// beginning and ends at the finish.
+const MaxReplayTurns = 200
+
+// CountrySnapshot is the part of a country worth charting in a recap.
+type CountrySnapshot struct {
+	Economy        float64 `json:"economy"`
+	Military       float64 `json:"military"`
+	Stability      float64 `json:"stability"`
+	ApprovalRating float64 `json:"approvalRating"`
+	TechLevel      float64 `json:"techLevel"`
+	Alliances      int     `json:"alliances"`
+	IsEliminated   bool    `json:"isEliminated"`
+}
+
+// TurnRecord is the world as a turn begins plus everything that happened
+// since the previous record.
+type TurnRecord struct {
+	Turn          int                        `json:"turn"`
+	Events        []string                   `json:"events"` // Oldest first
+	GlobalTension float64                    `json:"globalTension"`
+	Countries     map[string]CountrySnapshot `json:"countries"`
+}
+
+// Replay is a finished game, turn by turn.
+type Replay struct {
+	PlayerCountry string       `json:"playerCountry"`
+	WinnerID      string       `json:"winnerId,omitempty"`
+	VictoryType   string       `json:"victoryType"`
+	FinishedAt    time.Time    `json:"finishedAt"`
+	Turns         []TurnRecord `json:"turns"`
+}
+
+// recordTurn appends a record of the current turn. Caller holds the lock.
+func (g *GameState) recordTurn() {
+	events := g.Events.Newest(g.newEvents)
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i] // Replays read oldest first
+	}
+	g.newEvents = 0
+	g.trackPeaks()
+
+	countries := make(map[string]CountrySnapshot, len(g.Countries))
+	for id, c := range g.Countries {
+		countries[id] = CountrySnapshot{
+			Economy:        c.Economy,
+			Military:       c.Military,
+			Stability:      c.Stability,
+			ApprovalRating: c.ApprovalRating,
+			TechLevel:      c.TechLevel,
+			Alliances:      len(c.Alliances),
+			IsEliminated:   c.IsEliminated,
+		}
+	}
+
+	g.history = append(g.history, TurnRecord{
+		Turn:          g.Turn,
+		Events:        events,
+		GlobalTension: g.GlobalTension,
+		Countries:     countries,
+	})
+	if len(g.history) > MaxReplayTurns {
+		g.history = append(g.history[:1], g.history[2:]...)
+	}
+}
+
+// takeReplay returns the game's replay the first time it's called after the
+// game ends, so the caller saves it exactly once.
+func (g *GameState) takeReplay() (Replay, bool) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	if !g.GameOver || g.replaySaved {
+		return Replay{}, false
+	}
+	// Games can end mid-turn (a decisive attack), so close out that turn
+	if g.newEvents > 0 || len(g.history) == 0 || g.history[len(g.history)-1].Turn != g.Turn {
+		g.recordTurn()
+	}
+	g.replaySaved = true
+
+	return Replay{
+		PlayerCountry: g.PlayerCountry,
+		WinnerID:      g.WinnerID,
+		VictoryType:   g.VictoryType,
+		FinishedAt:    time.Now(),
+		Turns:         append([]TurnRecord(nil), g.history...),
+	}, true
+}
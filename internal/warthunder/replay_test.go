@@ -0,0 +1,55 @@
+package warthunder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplayReproducesOutcome(t *testing.T) {
+	g := CreateGame("replay", "mg")
+	defer archiveGame("replay", g)
+	g.MaxTurns = 4
+	for i := 0; i < 10 && !g.GameOver; i++ {
+		g.NextTurn()
+	}
+
+	r, ok := g.takeReplay()
+	if !ok {
+		t.Fatal("no replay for a finished game")
+	}
+	if _, again := g.takeReplay(); again {
+		t.Error("replay handed out twice")
+	}
+	if r.WinnerID != g.WinnerID || r.VictoryType != g.VictoryType || r.PlayerCountry != "mg" {
+		t.Fatalf("replay says %s won by %q, game says %s by %q", r.WinnerID, r.VictoryType, g.WinnerID, g.VictoryType)
+	}
+	if len(r.Turns) != g.MaxTurns || r.Turns[0].Turn != 1 || r.Turns[len(r.Turns)-1].Turn != g.Turn {
+		t.Fatalf("%d records from turn %d to %d, want 1 to %d", len(r.Turns), r.Turns[0].Turn, r.Turns[len(r.Turns)-1].Turn, g.Turn)
+	}
+
+	// The last record is the final world: its scores crown the same winner
+	last := r.Turns[len(r.Turns)-1]
+	best, bestScore := "", -1.0
+	for id, c := range last.Countries {
+		if c != snapshotOf(g.Countries[id]) {
+			t.Errorf("%s: final record %+v, game ended at %+v", id, c, snapshotOf(g.Countries[id]))
+		}
+		score := c.Economy/100 + c.Military/10 + c.TechLevel*2 + c.Stability + float64(c.Alliances)*25
+		if !c.IsEliminated && score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	if best != r.WinnerID {
+		t.Errorf("final record ranks %s first, replay crowns %s", best, r.WinnerID)
+	}
+	if events := strings.Join(last.Events, "\n"); !strings.Contains(events, "Time is up") {
+		t.Errorf("final record events %q miss the ending", events)
+	}
+}
+
+func snapshotOf(c *Country) CountrySnapshot {
+	return CountrySnapshot{
+		Economy: c.Economy, Military: c.Military, Stability: c.Stability, ApprovalRating: c.ApprovalRating,
+		TechLevel: c.TechLevel, Alliances: len(c.Alliances), IsEliminated: c.IsEliminated,
+	}
+}
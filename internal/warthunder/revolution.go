@@ -0,0 +1,119 @@
+package warthunder
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// A country is in unrest while both stability and approval sit below these.
+	RevolutionStability = 30.0
+	RevolutionApproval  = 30.0
+	// Turns of sustained unrest before the regime falls.
+	RevolutionUnrestTurns = 3
+)
+
+var ideologies = []string{"liberal", "conservative", "centrist", "populist", "communist"}
+
+// updateUnrest advances (or cools) a country's unrest counter for this turn
+// and topples the government once it has boiled long enough. Caller holds
+// g.Mutex.
+func (g *GameState) updateUnrest(c *Country) {
+	if c.Stability < RevolutionStability && c.ApprovalRating < RevolutionApproval {
+		c.Unrest++
+	} else if c.Unrest > 0 {
+		c.Unrest--
+	}
+	if c.Unrest >= RevolutionUnrestTurns {
+		g.revolution(c)
+	}
+}
+
+// revolution flips the regime: new government and ideology, old alliances
+// torn up and every relationship restarted from the new baseline.
+func (g *GameState) revolution(c *Country) {
+	oldGov, oldIdeology := c.Government, c.Ideology
+
+	if c.Government == "autocracy" {
+		c.Government = "democracy"
+	} else {
+		c.Government = "autocracy"
+	}
+	for c.Ideology == oldIdeology {
+		c.Ideology = ideologies[rand.Intn(len(ideologies))]
+	}
+
+	for _, allyID := range c.Alliances {
+		if ally, ok := g.Countries[allyID]; ok {
+			ally.Alliances = removeID(ally.Alliances, c.ID)
+		}
+	}
+	c.Alliances = []string{}
+
+	for _, other := range g.Countries {
+		if other.ID == c.ID {
+			continue
+		}
+		base := relationBaseline(c, other)
+		c.Relations[other.ID] = base
+		other.Relations[c.ID] = base
+	}
+
+	c.Unrest = 0
+	c.Stability = 50
+	c.ApprovalRating = 60
+	c.Military *= 0.8 // Purges and defections
+	g.GlobalTension += 10
+
+	g.AddEvent(fmt.Sprintf("🔥 REVOLUTION in %s! The %s %s regime has fallen, a %s %s government takes power",
+		c.Name, oldIdeology, oldGov, c.Ideology, c.Government))
+}
+
+func removeID(ids []string, id string) []string {
+	out := ids[:0]
+	for _, x := range ids {
+		if x != id {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// FomentRevolution is a covert op that stirs unrest in a rival. Harder than
+// regular espionage and far more damaging if exposed.
+func (g *GameState) FomentRevolution(targetID string) string {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+
+	player := g.Countries[g.PlayerCountry]
+	target, ok := g.Countries[targetID]
+	if !ok || target.IsEliminated || target.ID == player.ID {
+		return "Invalid target"
+	}
+	if player.Economy < FomentRevolutionCost {
+		return "Insufficient funds to foment revolution"
+	}
+	player.Economy -= FomentRevolutionCost
+
+	successChance := 0.8 * (player.TechLevel / 100) * (1 - target.Stability/200)
+	if rand.Float64() < successChance {
+		target.Stability -= 12
+		target.ApprovalRating -= 12
+		target.Unrest += 2
+		g.AddEvent(fmt.Sprintf("🕵️ Agitators are stirring the streets of %s. The regime is wobbling", target.Name))
+		if target.Unrest >= RevolutionUnrestTurns &&
+			target.Stability < RevolutionStability && target.ApprovalRating < RevolutionApproval {
+			g.revolution(target)
+		}
+		return "success"
+	}
+
+	g.AddEvent(fmt.Sprintf("🚨 EXPOSED! %s caught our agents plotting regime change", target.Name))
+	target.Relations[player.ID] -= 70
+	if target.Relations[player.ID] < -100 {
+		target.Relations[player.ID] = -100
+	}
+	player.ApprovalRating -= 10
+	g.GlobalTension += 15
+	return "caught"
+}
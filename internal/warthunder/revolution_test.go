@@ -0,0 +1,131 @@
+package warthunder
+
+import (
+	"strings"
+	"testing"
+)
+
+func unrestGame() (*GameState, *Country, *Country) {
+	ru := &Country{
+		ID: "ru", Name: "Russia", Government: "autocracy", Ideology: "nationalist",
+		Stability: 20, ApprovalRating: 20, Military: 100,
+		Alliances: []string{"by"}, Relations: map[string]float64{"by": 90, "us": -80},
+	}
+	by := &Country{
+		ID: "by", Name: "Belarus", Government: "autocracy", Ideology: "nationalist",
+		Alliances: []string{"ru"}, Relations: map[string]float64{"ru": 90},
+	}
+	us := &Country{
+		ID: "us", Name: "USA", Government: "democracy", Ideology: "liberal", IsPlayer: true,
+		Economy: 1000, TechLevel: 100, Relations: map[string]float64{"ru": -80},
+	}
+	g := &GameState{PlayerCountry: "us", Countries: map[string]*Country{"ru": ru, "by": by, "us": us}}
+	return g, ru, us
+}
+
+func TestUnrestBuildsToRevolution(t *testing.T) {
+	g, ru, us := unrestGame()
+
+	for turn := 1; turn < RevolutionUnrestTurns; turn++ {
+		g.updateUnrest(ru)
+		if ru.Government != "autocracy" || ru.Unrest != turn {
+			t.Fatalf("turn %d: government %q unrest %d, want autocracy and %d", turn, ru.Government, ru.Unrest, turn)
+		}
+	}
+	g.updateUnrest(ru)
+
+	if ru.Government != "democracy" || ru.Ideology == "nationalist" {
+		t.Fatalf("after %d turns of unrest: %s %s, want a new democracy", RevolutionUnrestTurns, ru.Ideology, ru.Government)
+	}
+	if ru.Unrest != 0 || ru.Stability != 50 || ru.Military != 80 {
+		t.Errorf("unrest %d stability %v military %v after the revolution", ru.Unrest, ru.Stability, ru.Military)
+	}
+	if len(ru.Alliances) != 0 || len(g.Countries["by"].Alliances) != 0 {
+		t.Errorf("alliances survived: %v and %v", ru.Alliances, g.Countries["by"].Alliances)
+	}
+	if want := relationBaseline(ru, us); ru.Relations["us"] != want || us.Relations["ru"] != want {
+		t.Errorf("relations with us %v and %v, want both reset to %v", ru.Relations["us"], us.Relations["ru"], want)
+	}
+	if ev := g.Events.Newest(1); len(ev) != 1 || !strings.Contains(ev[0], "REVOLUTION in Russia") {
+		t.Errorf("events %v, want the revolution announced", ev)
+	}
+}
+
+func TestUnrestNeedsBothStabilityAndApprovalLow(t *testing.T) {
+	g, ru, _ := unrestGame()
+
+	ru.ApprovalRating = RevolutionApproval // Stability alone is not enough
+	for turn := 0; turn < 2*RevolutionUnrestTurns; turn++ {
+		g.updateUnrest(ru)
+	}
+	if ru.Unrest != 0 || ru.Government != "autocracy" {
+		t.Fatalf("unrest %d government %q with approval at the threshold", ru.Unrest, ru.Government)
+	}
+
+	// Boiling, then calm: the counter cools instead of resetting
+	ru.ApprovalRating = 20
+	g.updateUnrest(ru)
+	g.updateUnrest(ru)
+	ru.Stability = 60
+	g.updateUnrest(ru)
+	if ru.Unrest != 1 {
+		t.Errorf("unrest %d after two bad turns and a good one, want 1", ru.Unrest)
+	}
+}
+
+func TestFomentRevolutionRefusals(t *testing.T) {
+	g, ru, us := unrestGame()
+
+	for _, target := range []string{"us", "xx"} {
+		if got := g.FomentRevolution(target); got != "Invalid target" {
+			t.Errorf("target %q: %q", target, got)
+		}
+	}
+	ru.IsEliminated = true
+	if got := g.FomentRevolution("ru"); got != "Invalid target" {
+		t.Errorf("eliminated target: %q", got)
+	}
+	ru.IsEliminated = false
+
+	us.Economy = FomentRevolutionCost - 1
+	if got := g.FomentRevolution("ru"); got != "Insufficient funds to foment revolution" {
+		t.Errorf("short of funds: %q", got)
+	}
+	if us.Economy != FomentRevolutionCost-1 || ru.Unrest != 0 {
+		t.Errorf("a refused op charged %v or stirred unrest %d", FomentRevolutionCost-1-us.Economy, ru.Unrest)
+	}
+}
+
+func TestFomentRevolutionTipsAShakyRegime(t *testing.T) {
+	// Success is a dice roll; retry on a fresh game until the agents get through
+	for try := 0; try < 50; try++ {
+		g, ru, us := unrestGame()
+		ru.Unrest = 1
+		if g.FomentRevolution("ru") != "success" {
+			continue
+		}
+		if us.Economy != 1000-FomentRevolutionCost {
+			t.Errorf("economy %v, want the op charged", us.Economy)
+		}
+		if ru.Government != "democracy" || ru.Unrest != 0 {
+			t.Fatalf("government %q unrest %d, want the regime toppled at once", ru.Government, ru.Unrest)
+		}
+		return
+	}
+	t.Fatal("no success in 50 tries at a 72% chance")
+}
+
+func TestFomentRevolutionOnlyStirsAStableRegime(t *testing.T) {
+	for try := 0; try < 50; try++ {
+		g, ru, _ := unrestGame()
+		ru.Stability, ru.ApprovalRating = 60, 60
+		if g.FomentRevolution("ru") != "success" {
+			continue
+		}
+		if ru.Government != "autocracy" || ru.Unrest != 2 || ru.Stability != 48 {
+			t.Fatalf("government %q unrest %d stability %v, want stirred but standing", ru.Government, ru.Unrest, ru.Stability)
+		}
+		return
+	}
+	t.Fatal("no success in 50 tries at a 56% chance")
+}
@@ -0,0 +1,48 @@
+package warthunder
+
+import "math"
+
+// Summary is the final word on a finished game, sent once with status "ended".
+type Summary struct {
+	VictoryType   string  `json:"victoryType"`
+	WinnerID      string  `json:"winnerId,omitempty"`
+	TurnsSurvived int     `json:"turnsSurvived"`
+	PeakEconomy   float64 `json:"peakEconomy"`
+	PeakMilitary  float64 `json:"peakMilitary"`
+}
+
+// trackPeaks folds the player's current numbers into the running peaks.
+// Caller holds the lock.
+func (g *GameState) trackPeaks() {
+	player, ok := g.Countries[g.PlayerCountry]
+	if !ok {
+		return
+	}
+	g.peakEconomy = math.Max(g.peakEconomy, player.Economy)
+	g.peakMilitary = math.Max(g.peakMilitary, player.Military)
+}
+
+// summary describes how the game went. Caller holds the lock.
+func (g *GameState) summary() Summary {
+	g.trackPeaks()
+	return Summary{
+		VictoryType:   g.VictoryType,
+		WinnerID:      g.WinnerID,
+		TurnsSurvived: g.Turn,
+		PeakEconomy:   g.peakEconomy,
+		PeakMilitary:  g.peakMilitary,
+	}
+}
+
+// archiveGame drops a finished game from activeGames so the player's next
+// visit starts clean at country selection. The recap lives on as the saved
+// replay. A newer game under the same player is left alone.
+func archiveGame(playerID string, game *GameState) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	if activeGames[playerID] != game {
+		return
+	}
+	delete(activeGames, playerID)
+	close(game.stop)
+}
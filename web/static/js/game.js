@@ -98,7 +98,10 @@ function updateUI() {
     // TIMER
     const t = window.gameState.time;
     let text = "0:00";
-    if (window.gameState.tiebreaker) {
+    if (window.gameState.phase === "countdown") {
+        text = `${Math.ceil(window.gameState.countdown)}...`;
+        timer.style.color = "yellow";
+    } else if (window.gameState.tiebreaker) {
         text = "SUDDEN DEATH";
         timer.style.color = "red";
     } else if (window.gameState.overtime) {
@@ -191,12 +194,21 @@ function updateUI() {
         // --- Logic for Game Over text ---
         const myTeam = window.gameState.myTeam || 0;
         const win = window.gameState.winner === myTeam;
-        gameOverTitle.innerText = win ? "VICTORY!" : "DEFEAT";
-        gameOverTitle.style.color = win ? "#4f4" : "#f44";
+        const draw = window.gameState.winner === -1;
+        if (draw) {
+            gameOverTitle.innerText = "DRAW";
+            gameOverTitle.style.color = "#ccc";
+        } else if (window.gameState.spectating) {
+            gameOverTitle.innerText = window.gameState.winner === 0 ? "BLUE WINS" : "RED WINS";
+            gameOverTitle.style.color = window.gameState.winner === 0 ? "#4af" : "#f44";
+        } else {
+            gameOverTitle.innerText = win ? "VICTORY!" : "DEFEAT";
+            gameOverTitle.style.color = win ? "#4f4" : "#f44";
+        }
         
         if (medalDelta) {
-            medalDelta.textContent = win ? "+30 medals" : "-15 medals";
-            medalDelta.style.color = win ? "#4f4" : "#f99";
+            medalDelta.textContent = draw ? "±0 medals" : win ? "+30 medals" : "-15 medals";
+            medalDelta.style.color = draw ? "#ccc" : win ? "#4f4" : "#f99";
         }
 
         // --- FIX: Ensure Return Button keeps identity ---
@@ -225,6 +237,39 @@ function updateUI() {
 
 window.onGameStateUpdate = updateUI;
 
+const spawnRejectText = {
+    not_playing: 'Match not running',
+    wrong_side: 'Deploy on your side',
+    unknown_card: 'Unknown card',
+    not_in_match: 'Not in this match',
+    not_enough_elixir: 'Not enough elixir',
+    too_close_to_enemy: 'Too close to an enemy',
+    card_not_in_hand: 'Card not in hand',
+    out_of_bounds: 'Outside the arena',
+};
+
+let spawnRejectTimer = null;
+window.onSpawnRejected = (reason) => {
+    let toast = document.getElementById('spawn-reject');
+    if (!toast) {
+        toast = document.createElement('div');
+        toast.id = 'spawn-reject';
+        toast.style.cssText = 'position:fixed;left:50%;bottom:180px;transform:translateX(-50%);padding:6px 14px;border-radius:8px;background:rgba(200,30,30,0.85);color:#fff;font-weight:bold;pointer-events:none;z-index:50;';
+        document.body.appendChild(toast);
+    }
+    toast.textContent = spawnRejectText[reason] || reason;
+    toast.style.display = 'block';
+    clearTimeout(spawnRejectTimer);
+    spawnRejectTimer = setTimeout(() => { toast.style.display = 'none'; }, 1200);
+};
+
+// Spell blasts fade out over half a second where they landed
+const SPELL_FADE_MS = 500;
+let spellBlasts = [];
+window.onSpell = (msg) => {
+    spellBlasts.push({ x: msg.x, y: msg.y, radius: msg.radius, team: msg.team, at: Date.now() });
+};
+
 canvas.addEventListener('mousedown', (e) => {
     if (!selectedCard) return;
     const rect = canvas.getBoundingClientRect();
@@ -329,6 +374,17 @@ function render() {
         }
     });
 
+    const now = Date.now();
+    spellBlasts = spellBlasts.filter(b => now - b.at < SPELL_FADE_MS);
+    spellBlasts.forEach(b => {
+        const v = getVisualCoords(b.x, b.y);
+        const alpha = 1 - (now - b.at) / SPELL_FADE_MS;
+        ctx.fillStyle = b.team === myTeam ? `rgba(255, 140, 0, ${0.5 * alpha})` : `rgba(255, 40, 40, ${0.5 * alpha})`;
+        ctx.beginPath();
+        ctx.arc(v.x * SCALE, v.y * SCALE, b.radius * SCALE, 0, Math.PI * 2);
+        ctx.fill();
+    });
+
     ctx.restore();
     requestAnimationFrame(render);
 }
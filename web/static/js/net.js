@@ -1,18 +1,15 @@
 const urlParams = new URLSearchParams(window.location.search);
-const getCookie = (name) => {
-    const m = document.cookie.match(new RegExp('(?:^|; )' + name.replace(/([$?*|{}()[\]\\/+^])/g, '\\$1') + '=([^;]*)'));
-    return m ? decodeURIComponent(m[1]) : '';
-};
-const cookieUser = getCookie('user_id');
-const userID = window.SERVER_USER_ID || urlParams.get('userID') || 'guest';
+// Who we are comes from the session; the server renders it into the page
+const userID = window.SERVER_USER_ID || '';
 const lang = window.SERVER_LANG || urlParams.get('lang') || 'en';
 window.netParams = { userID, lang };
 document.documentElement.lang = lang;
 
 const protocol = window.location.protocol === "https:" ? "wss" : "ws";
-const socket = new WebSocket(`${protocol}://${window.location.host}/ws?userID=${userID}`);
+const spectateRoom = urlParams.get('spectate');
+const socket = new WebSocket(`${protocol}://${window.location.host}/ws` + (spectateRoom ? `?spectate=${encodeURIComponent(spectateRoom)}` : ''));
 
-socket.onopen = () => console.log("Connected with userID:", userID);
+socket.onopen = () => console.log("Connected");
 socket.onmessage = (event) => {
     const msg = JSON.parse(event.data);
     if (msg.type === "state") {
@@ -24,6 +21,10 @@ socket.onmessage = (event) => {
             window.gameState.overtime = msg.overtime;
             window.gameState.tiebreaker = msg.tiebreaker;
             window.gameState.playerCount = msg.playerCount || 0;
+            window.gameState.phase = msg.phase;
+            window.gameState.countdown = msg.countdown || 0;
+            window.gameState.room = msg.room;
+            window.gameState.spectating = !!msg.spectating;
             if (msg.me) {
                 window.gameState.me = msg.me;
                 window.gameState.myTeam = msg.myTeam;
@@ -32,6 +33,10 @@ socket.onmessage = (event) => {
         if (window.onGameStateUpdate) {
             window.onGameStateUpdate();
         }
+    } else if (msg.type === "spawn_rejected") {
+        if (window.onSpawnRejected) window.onSpawnRejected(msg.reason, msg.key);
+    } else if (msg.type === "spell") {
+        if (window.onSpell) window.onSpell(msg);
     }
 };
 window.net = {
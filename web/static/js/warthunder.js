@@ -26,6 +26,8 @@ async function loadGameState() {
             showView('dashboard');
             updateDashboard();
             startAutoUpdate();
+        } else if (data.status === 'ended') {
+            showEnded(data);
         }
     } catch (error) {
         console.error('Failed to load game:', error);
@@ -124,6 +126,17 @@ function showView(viewName) {
 }
 
 // Update entire dashboard
+// Costs come from the server with every state so they never drift
+function actionCost(action) {
+    const a = gameState && gameState.actions && gameState.actions[action];
+    return a ? Math.round(a.cost) : '?';
+}
+
+function canAfford(action) {
+    const a = gameState && gameState.actions && gameState.actions[action];
+    return !a || a.affordable;
+}
+
 function updateDashboard() {
     if (!gameState) return;
 
@@ -152,6 +165,14 @@ function updateDashboard() {
     document.getElementById('tension-value').textContent = `${tension}%`;
     document.getElementById('tension-fill').style.width = `${tension}%`;
 
+    // Grey out quick actions we can't pay for
+    document.querySelectorAll('.actions-panel [data-action]').forEach(btn => {
+        const action = btn.dataset.action;
+        btn.disabled = !canAfford(action);
+        const price = btn.querySelector('.action-cost');
+        if (price) price.textContent = `$${actionCost(action)}B`;
+    });
+
     // Update event log
     updateEventLog();
 
@@ -288,6 +309,22 @@ function showVictoryScreen() {
     overlay.classList.add('active');
 }
 
+// The server sends "ended" once per finished game, then forgets it, so the
+// next load (Play Again) lands on country selection.
+function showEnded(data) {
+    if (updateInterval) clearInterval(updateInterval);
+    gameState = data.game;
+    showView('dashboard');
+    updateDashboard();
+    showVictoryScreen();
+
+    const s = data.summary;
+    if (s) {
+        document.getElementById('victory-summary').textContent =
+            `Turns survived: ${s.turnsSurvived} · Peak economy: $${s.peakEconomy.toFixed(1)}B · Peak military: ${Math.round(s.peakMilitary)}`;
+    }
+}
+
 // Tab switching
 function switchTab(tabName) {
     currentTab = tabName;
@@ -386,6 +423,8 @@ function createCountryCard(country, context) {
 
     const relationPercent = ((relation + 100) / 200) * 100;
     const relationClass = relation >= 0 ? 'positive' : 'negative';
+    // Estimates come pre-rounded from the server; mark them so nobody trusts them too much
+    const est = country.intel === 'estimate' ? '~' : '';
 
     card.innerHTML = `
         <div class="country-card-header">
@@ -399,11 +438,11 @@ function createCountryCard(country, context) {
         ${country.isEliminated ? '<div style="text-align: center; color: #f5576c; font-weight: bold; margin: 10px 0;">❌ ELIMINATED</div>' : ''}
 
         <div class="country-stats">
-            <div><span>💰 Economy:</span> <span>$${country.economy.toFixed(1)}B</span></div>
-            <div><span>⚔️ Military:</span> <span>${Math.round(country.military)}</span></div>
-            <div><span>📊 Stability:</span> <span>${Math.round(country.stability)}%</span></div>
-            <div><span>📈 Approval:</span> <span>${Math.round(country.approvalRating)}%</span></div>
-            <div><span>🔬 Tech:</span> <span>${Math.round(country.techLevel)}</span></div>
+            <div><span>💰 Economy:</span> <span>${est}$${country.economy.toFixed(1)}B</span></div>
+            <div><span>⚔️ Military:</span> <span>${est}${Math.round(country.military)}</span></div>
+            <div><span>📊 Stability:</span> <span>${est}${Math.round(country.stability)}%</span></div>
+            <div><span>📈 Approval:</span> <span>${est}${Math.round(country.approvalRating)}%</span></div>
+            <div><span>🔬 Tech:</span> <span>${est}${Math.round(country.techLevel)}</span></div>
             ${country.alliances && country.alliances.length > 0 ? `<div><span>🛡️ Allies:</span> <span>${country.alliances.length}</span></div>` : ''}
         </div>
 
@@ -434,7 +473,8 @@ function createCountryCard(country, context) {
 
         if (context === 'diplomacy') {
             const diplomacyBtn = document.createElement('button');
-            diplomacyBtn.textContent = '🤝 Improve Relations';
+            diplomacyBtn.textContent = `🤝 Improve Relations ($${actionCost('diplomat')}B)`;
+            diplomacyBtn.disabled = !canAfford('diplomat');
             diplomacyBtn.onclick = () => performAction('diplomat', country.id);
             actionsContainer.appendChild(diplomacyBtn);
 
@@ -456,9 +496,17 @@ function createCountryCard(country, context) {
 
         if (context === 'espionage') {
             const spyBtn = document.createElement('button');
-            spyBtn.textContent = '🕵️ Espionage ($50B)';
+            spyBtn.textContent = `🕵️ Espionage ($${actionCost('espionage')}B)`;
+            spyBtn.disabled = !canAfford('espionage');
             spyBtn.onclick = () => performAction('espionage', country.id);
             actionsContainer.appendChild(spyBtn);
+
+            const revoBtn = document.createElement('button');
+            revoBtn.textContent = `🔥 Foment Revolution ($${actionCost('fomentRevolution')}B)`;
+            revoBtn.disabled = !canAfford('fomentRevolution');
+            revoBtn.style.background = 'rgba(244, 67, 54, 0.3)';
+            revoBtn.onclick = () => performAction('fomentRevolution', country.id);
+            actionsContainer.appendChild(revoBtn);
         }
     }
 
@@ -487,7 +535,9 @@ async function performAction(action, targetId = null) {
             showNotification(data.message, messageType);
         }
 
-        if (data.game) {
+        if (data.status === 'ended') {
+            showEnded(data);
+        } else if (data.game) {
             gameState = data.game;
             updateDashboard();
         }
@@ -514,6 +564,8 @@ function startAutoUpdate() {
             if (data.status === 'playing' && data.game) {
                 gameState = data.game;
                 updateDashboard();
+            } else if (data.status === 'ended') {
+                showEnded(data);
             }
         } catch (error) {
             console.error('Auto-update failed:', error);